@@ -41,6 +41,10 @@ type Config struct {
 	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS).
 	TLSConfig *tls.Config
 
+	// TrustedProxies are the networks of the gRPC clients that may send queries on behalf of other
+	// clients. The client address in queries from anyone else is ignored.
+	TrustedProxies []*net.IPNet
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
@@ -4,24 +4,37 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
+	"sync"
 
 	"github.com/grpc-ecosystem/grpc-opentracing/go/otgrpc"
 	"github.com/miekg/dns"
 	"github.com/opentracing/opentracing-go"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
 
 	"github.com/coredns/coredns/pb"
 )
 
+const (
+	// MaxBatch is the maximum number of queries in a batch.
+	MaxBatch = 128
+	// MaxStreamQueries is the maximum number of queries that can be in flight on a stream.
+	MaxStreamQueries = 128
+)
+
 // ServergRPC represents an instance of a DNS-over-gRPC server.
 type ServergRPC struct {
 	*Server
 	grpcServer *grpc.Server
 	listenAddr net.Addr
 	tlsConfig  *tls.Config
+	trusted    []*net.IPNet  // proxies whose client addresses we use
+	stop       chan struct{} // closed in Stop, to end the streams
 }
 
 // NewServergRPC returns a new CoreDNS GRPC server and compiles all plugin in to it.
@@ -32,13 +45,17 @@ func NewServergRPC(addr string, group []*Config) (*ServergRPC, error) {
 	}
 	// The *tls* plugin must make sure that multiple conflicting
 	// TLS configuration return an error: it can only be specified once.
-	var tlsConfig *tls.Config
+	var (
+		tlsConfig *tls.Config
+		trusted   []*net.IPNet
+	)
 	for _, conf := range s.zones {
 		// Should we error if some configs *don't* have TLS?
 		tlsConfig = conf.TLSConfig
+		trusted = append(trusted, conf.TrustedProxies...)
 	}
 
-	return &ServergRPC{Server: s, tlsConfig: tlsConfig}, nil
+	return &ServergRPC{Server: s, tlsConfig: tlsConfig, trusted: trusted, stop: make(chan struct{})}, nil
 }
 
 // Serve implements caddy.TCPServer interface.
@@ -101,6 +118,12 @@ func (s *ServergRPC) Stop() (err error) {
 	s.m.Lock()
 	defer s.m.Unlock()
 	if s.grpcServer != nil {
+		// Clients keep their streams open, end them so GracefulStop doesn't wait forever.
+		select {
+		case <-s.stop:
+		default:
+			close(s.stop)
+		}
 		s.grpcServer.GracefulStop()
 	}
 	return
@@ -110,12 +133,162 @@ func (s *ServergRPC) Stop() (err error) {
 // any normal server. We use a custom responseWriter to pick up the bytes we need to write
 // back to the client as a protobuf.
 func (s *ServergRPC) Query(ctx context.Context, in *pb.DnsPacket) (*pb.DnsPacket, error) {
+	a, err := peerAddr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.serve(ctx, a, in)
+}
+
+// Stream answers the queries sent on the stream. Each query is handled in its own goroutine, so the
+// replies can be sent in a different order than the queries were received in; the Id of a query is
+// copied into its reply to match them up. A query that can't be handled ends the stream, as does
+// stopping the server. When a client has MaxStreamQueries queries in flight, we stop receiving until
+// one of them is answered; gRPC's flow control then holds up the client.
+func (s *ServergRPC) Stream(stream pb.DnsService_StreamServer) error {
+	ctx := stream.Context()
+	a, err := peerAddr(ctx)
+	if err != nil {
+		return err
+	}
+
+	queries := make(chan *pb.DnsPacket)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case queries <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		wg     sync.WaitGroup
+		sendMu sync.Mutex // Send may not be called concurrently.
+		errc   = make(chan error, 1)
+		sem    = make(chan struct{}, MaxStreamQueries)
+	)
+	defer wg.Wait() // Sending after we return is not allowed.
+
+	for {
+		select {
+		case in := <-queries:
+			select {
+			case sem <- struct{}{}:
+			case err := <-errc:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.stop:
+				return nil
+			}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				out, err := s.serve(ctx, a, in)
+				if err == nil {
+					sendMu.Lock()
+					err = stream.Send(out)
+					sendMu.Unlock()
+				}
+				if err != nil {
+					select {
+					case errc <- err:
+					default:
+					}
+				}
+			}()
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case err := <-errc:
+			return err
+		case <-s.stop:
+			return nil
+		}
+	}
+}
+
+// Batch answers all queries in the batch, the replies are returned in the same order. A batch with
+// more than MaxBatch queries gets a ResourceExhausted error.
+func (s *ServergRPC) Batch(ctx context.Context, in *pb.DnsBatch) (*pb.DnsBatch, error) {
+	a, err := peerAddr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(in.Packets) > MaxBatch {
+		return nil, grpc.Errorf(codes.ResourceExhausted, "batch of %d queries, at most %d allowed", len(in.Packets), MaxBatch)
+	}
+
+	out := &pb.DnsBatch{Packets: make([]*pb.DnsPacket, len(in.Packets))}
+	errs := make([]error, len(in.Packets))
+	var wg sync.WaitGroup
+	for i := range in.Packets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out.Packets[i], errs[i] = s.serve(ctx, a, in.Packets[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// serve answers the query in, that was received from remote. If in is sent on behalf of another
+// client, as set in in.Client, by a trusted proxy, the query is handled as coming from that client.
+func (s *ServergRPC) serve(ctx context.Context, remote *net.TCPAddr, in *pb.DnsPacket) (*pb.DnsPacket, error) {
 	msg := new(dns.Msg)
 	err := msg.Unpack(in.Msg)
 	if err != nil {
 		return nil, err
 	}
 
+	if in.Client != "" && s.trustedProxy(remote.IP) {
+		remote, err = clientAddr(in.Client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := &gRPCresponse{localAddr: s.listenAddr, remoteAddr: remote, Msg: msg}
+
+	s.ServeDNS(ctx, w, msg)
+
+	packed, err := w.Msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DnsPacket{Msg: packed, Id: in.Id}, nil
+}
+
+// trustedProxy returns true if ip is in the networks of the trusted proxies.
+func (s *ServergRPC) trustedProxy(ip net.IP) bool {
+	for _, n := range s.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAddr returns the address of the peer of the gRPC call.
+func peerAddr(ctx context.Context) (*net.TCPAddr, error) {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
 		return nil, errors.New("no peer in gRPC context")
@@ -125,17 +298,27 @@ func (s *ServergRPC) Query(ctx context.Context, in *pb.DnsPacket) (*pb.DnsPacket
 	if !ok {
 		return nil, fmt.Errorf("no TCP peer in gRPC context: %v", p.Addr)
 	}
+	return a, nil
+}
 
-	w := &gRPCresponse{localAddr: s.listenAddr, remoteAddr: a, Msg: msg}
-
-	s.ServeDNS(ctx, w, msg)
-
-	packed, err := w.Msg.Pack()
+// clientAddr parses the client address of a DnsPacket, this is an IP address or IP:port.
+func clientAddr(client string) (*net.TCPAddr, error) {
+	if ip := net.ParseIP(client); ip != nil {
+		return &net.TCPAddr{IP: ip}, nil
+	}
+	host, port, err := net.SplitHostPort(client)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid client address %q: %s", client, err)
 	}
-
-	return &pb.DnsPacket{Msg: packed}, nil
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid client address %q: not an IP address", client)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 0 || p > 65535 {
+		return nil, fmt.Errorf("invalid client address %q: bad port", client)
+	}
+	return &net.TCPAddr{IP: ip, Port: p}, nil
 }
 
 // Shutdown stops the server (non gracefully).
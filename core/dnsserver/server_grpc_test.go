@@ -0,0 +1,85 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/pb"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestClientAddr(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		expected  string
+		shouldErr bool
+	}{
+		{"10.0.0.1", "10.0.0.1:0", false},
+		{"10.0.0.1:5353", "10.0.0.1:5353", false},
+		{"2001:db8::1", "[2001:db8::1]:0", false},
+		{"[2001:db8::1]:5353", "[2001:db8::1]:5353", false},
+		{"example.org:53", "", true},
+		{"10.0.0.1:port", "", true},
+		{"10.0.0.1:65536", "", true},
+		{"", "", true},
+	} {
+		addr, err := clientAddr(test.input)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error, but there wasn't any", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Expected no error, but there was one: %v", i, err)
+		}
+		if err == nil && addr.String() != test.expected {
+			t.Errorf("Test %d: Expected %s but got %s", i, test.expected, addr)
+		}
+	}
+}
+
+// remotePlugin records the remote address of the queries it sees.
+type remotePlugin struct{ remote string }
+
+func (rp *remotePlugin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	rp.remote = w.RemoteAddr().String()
+	w.WriteMsg(r)
+	return 0, nil
+}
+
+func (rp *remotePlugin) Name() string { return "remoteplugin" }
+
+func TestServeTrustedProxies(t *testing.T) {
+	rp := &remotePlugin{}
+	c := testConfig("grpc", rp)
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+	c.TrustedProxies = []*net.IPNet{trusted}
+
+	s, err := NewServergRPC("127.0.0.1:53", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServergRPC, got %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	msg, _ := m.Pack()
+
+	for i, test := range []struct {
+		peer     string
+		client   string
+		expected string
+	}{
+		{"10.0.0.1", "192.0.2.1:5353", "192.0.2.1:5353"},
+		{"10.0.0.1", "", "10.0.0.1:1053"},
+		{"127.0.0.1", "192.0.2.1:5353", "127.0.0.1:1053"},
+		{"192.0.2.2", "192.0.2.1", "192.0.2.2:1053"},
+	} {
+		remote := &net.TCPAddr{IP: net.ParseIP(test.peer), Port: 1053}
+		if _, err := s.serve(context.Background(), remote, &pb.DnsPacket{Msg: msg, Client: test.client}); err != nil {
+			t.Fatalf("Test %d: Expected no error, got %s", i, err)
+		}
+		if rp.remote != test.expected {
+			t.Errorf("Test %d: Expected query from %s, got %s", i, test.expected, rp.remote)
+		}
+	}
+}
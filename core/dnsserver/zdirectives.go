@@ -15,6 +15,7 @@ var Directives = []string{
 	"nsid",
 	"root",
 	"bind",
+	"trusted_proxies",
 	"debug",
 	"trace",
 	"health",
@@ -25,6 +26,8 @@ var Directives = []string{
 	"dnstap",
 	"chaos",
 	"loadbalance",
+	"rpz",
+	"blocklist",
 	"cache",
 	"rewrite",
 	"dnssec",
@@ -33,14 +36,19 @@ var Directives = []string{
 	"template",
 	"hosts",
 	"route53",
+	"gslb",
 	"federation",
+	"k8s_external",
+	"multicluster",
 	"kubernetes",
 	"file",
 	"auto",
 	"secondary",
 	"etcd",
+	"validator",
 	"forward",
 	"proxy",
+	"recursor",
 	"erratic",
 	"whoami",
 	"on",
@@ -0,0 +1,6 @@
+package coremain
+
+// CheckZone checks the zone file for -checkzone, args holds the origin and the file name. It returns
+// the exit status. It is set by the file plugin, which can't be imported here, as it imports coremain
+// through the metrics plugin.
+var CheckZone func(args []string) int
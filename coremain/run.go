@@ -30,6 +30,7 @@ func init() {
 	flag.BoolVar(&version, "version", false, "Show version")
 	flag.BoolVar(&dnsserver.Quiet, "quiet", false, "Quiet mode (no initialization output)")
 	flag.BoolVar(&logfile, "log", false, "Log to standard output")
+	flag.BoolVar(&checkzone, "checkzone", false, "Check the zone file given as arguments: ORIGIN FILE")
 
 	caddy.RegisterCaddyfileLoader("flag", caddy.LoaderFunc(confLoader))
 	caddy.SetDefaultCaddyfileLoader("default", caddy.LoaderFunc(defaultLoader))
@@ -56,6 +57,13 @@ func Run() {
 
 	flag.Parse()
 
+	if checkzone {
+		if CheckZone == nil {
+			mustLogFatal(errors.New("-checkzone needs the file plugin"))
+		}
+		os.Exit(CheckZone(flag.Args()))
+	}
+
 	if len(flag.Args()) > 0 {
 		mustLogFatal(fmt.Errorf("extra command line arguments: %s", flag.Args()))
 	}
@@ -236,11 +244,12 @@ func setCPU(cpu string) error {
 
 // Flags that control program flow or startup
 var (
-	conf    string
-	cpu     string
-	logfile bool
-	version bool
-	plugins bool
+	conf      string
+	cpu       string
+	logfile   bool
+	version   bool
+	plugins   bool
+	checkzone bool
 )
 
 // Build information obtained with the help of -ldflags
@@ -10,6 +10,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	DnsPacket
+	DnsBatch
 */
 package pb
 
@@ -35,6 +36,10 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type DnsPacket struct {
 	Msg []byte `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+	// Address of the client msg is sent on behalf of, as IP or IP:port; set by proxies.
+	Client string `protobuf:"bytes,2,opt,name=client" json:"client,omitempty"`
+	// Chosen by the sender and copied into the reply, so replies on a stream can be matched to queries.
+	Id uint64 `protobuf:"varint,3,opt,name=id" json:"id,omitempty"`
 }
 
 func (m *DnsPacket) Reset()                    { *m = DnsPacket{} }
@@ -49,8 +54,39 @@ func (m *DnsPacket) GetMsg() []byte {
 	return nil
 }
 
+func (m *DnsPacket) GetClient() string {
+	if m != nil {
+		return m.Client
+	}
+	return ""
+}
+
+func (m *DnsPacket) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type DnsBatch struct {
+	Packets []*DnsPacket `protobuf:"bytes,1,rep,name=packets" json:"packets,omitempty"`
+}
+
+func (m *DnsBatch) Reset()                    { *m = DnsBatch{} }
+func (m *DnsBatch) String() string            { return proto.CompactTextString(m) }
+func (*DnsBatch) ProtoMessage()               {}
+func (*DnsBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *DnsBatch) GetPackets() []*DnsPacket {
+	if m != nil {
+		return m.Packets
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*DnsPacket)(nil), "coredns.dns.DnsPacket")
+	proto.RegisterType((*DnsBatch)(nil), "coredns.dns.DnsBatch")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -65,6 +101,8 @@ const _ = grpc.SupportPackageIsVersion4
 
 type DnsServiceClient interface {
 	Query(ctx context.Context, in *DnsPacket, opts ...grpc.CallOption) (*DnsPacket, error)
+	Stream(ctx context.Context, opts ...grpc.CallOption) (DnsService_StreamClient, error)
+	Batch(ctx context.Context, in *DnsBatch, opts ...grpc.CallOption) (*DnsBatch, error)
 }
 
 type dnsServiceClient struct {
@@ -84,10 +122,52 @@ func (c *dnsServiceClient) Query(ctx context.Context, in *DnsPacket, opts ...grp
 	return out, nil
 }
 
+func (c *dnsServiceClient) Stream(ctx context.Context, opts ...grpc.CallOption) (DnsService_StreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_DnsService_serviceDesc.Streams[0], c.cc, "/coredns.dns.DnsService/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dnsServiceStreamClient{stream}
+	return x, nil
+}
+
+type DnsService_StreamClient interface {
+	Send(*DnsPacket) error
+	Recv() (*DnsPacket, error)
+	grpc.ClientStream
+}
+
+type dnsServiceStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *dnsServiceStreamClient) Send(m *DnsPacket) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *dnsServiceStreamClient) Recv() (*DnsPacket, error) {
+	m := new(DnsPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dnsServiceClient) Batch(ctx context.Context, in *DnsBatch, opts ...grpc.CallOption) (*DnsBatch, error) {
+	out := new(DnsBatch)
+	err := grpc.Invoke(ctx, "/coredns.dns.DnsService/Batch", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for DnsService service
 
 type DnsServiceServer interface {
 	Query(context.Context, *DnsPacket) (*DnsPacket, error)
+	Stream(DnsService_StreamServer) error
+	Batch(context.Context, *DnsBatch) (*DnsBatch, error)
 }
 
 func RegisterDnsServiceServer(s *grpc.Server, srv DnsServiceServer) {
@@ -112,6 +192,50 @@ func _DnsService_Query_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DnsService_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DnsServiceServer).Stream(&dnsServiceStreamServer{stream})
+}
+
+type DnsService_StreamServer interface {
+	Send(*DnsPacket) error
+	Recv() (*DnsPacket, error)
+	grpc.ServerStream
+}
+
+type dnsServiceStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *dnsServiceStreamServer) Send(m *DnsPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *dnsServiceStreamServer) Recv() (*DnsPacket, error) {
+	m := new(DnsPacket)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _DnsService_Batch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DnsBatch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DnsServiceServer).Batch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coredns.dns.DnsService/Batch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DnsServiceServer).Batch(ctx, req.(*DnsBatch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _DnsService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "coredns.dns.DnsService",
 	HandlerType: (*DnsServiceServer)(nil),
@@ -120,21 +244,37 @@ var _DnsService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Query",
 			Handler:    _DnsService_Query_Handler,
 		},
+		{
+			MethodName: "Batch",
+			Handler:    _DnsService_Batch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _DnsService_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "dns.proto",
 }
 
 func init() { proto.RegisterFile("dns.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 120 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x4c, 0xc9, 0x2b, 0xd6,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x4e, 0xce, 0x2f, 0x4a, 0x05, 0x71, 0x53, 0xf2, 0x8a,
-	0x95, 0x64, 0xb9, 0x38, 0x5d, 0xf2, 0x8a, 0x03, 0x12, 0x93, 0xb3, 0x53, 0x4b, 0x84, 0x04, 0xb8,
-	0x98, 0x73, 0x8b, 0xd3, 0x25, 0x18, 0x15, 0x18, 0x35, 0x78, 0x82, 0x40, 0x4c, 0x23, 0x57, 0x2e,
-	0x2e, 0x97, 0xbc, 0xe2, 0xe0, 0xd4, 0xa2, 0xb2, 0xcc, 0xe4, 0x54, 0x21, 0x73, 0x2e, 0xd6, 0xc0,
-	0xd2, 0xd4, 0xa2, 0x4a, 0x21, 0x31, 0x3d, 0x24, 0x33, 0xf4, 0xe0, 0x06, 0x48, 0xe1, 0x10, 0x77,
-	0x62, 0x89, 0x62, 0x2a, 0x48, 0x4a, 0x62, 0x03, 0xdb, 0x6f, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff,
-	0xf5, 0xd1, 0x3f, 0x26, 0x8c, 0x00, 0x00, 0x00,
+	// 207 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe3, 0xe2, 0x4c, 0xc9, 0x2b, 0xd6,
+	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x4e, 0xce, 0x2f, 0x4a, 0x05, 0x71, 0x81, 0x58, 0xc9,
+	0x95, 0x8b, 0xd3, 0x25, 0xaf, 0x38, 0x20, 0x31, 0x39, 0x3b, 0xb5, 0x44, 0x48, 0x80, 0x8b, 0x39,
+	0xb7, 0x38, 0x5d, 0x82, 0x51, 0x81, 0x51, 0x83, 0x27, 0x08, 0xc4, 0x14, 0x12, 0xe3, 0x62, 0x4b,
+	0xce, 0xc9, 0x4c, 0xcd, 0x2b, 0x91, 0x60, 0x02, 0x0a, 0x72, 0x06, 0x41, 0x79, 0x42, 0x7c, 0x5c,
+	0x4c, 0x99, 0x29, 0x12, 0xcc, 0x40, 0x31, 0x96, 0x20, 0x20, 0x4b, 0xc9, 0x86, 0x8b, 0x03, 0x68,
+	0x8c, 0x53, 0x62, 0x49, 0x72, 0x86, 0x90, 0x01, 0x17, 0x7b, 0x01, 0xd8, 0xbc, 0x62, 0xa0, 0x49,
+	0xcc, 0x1a, 0xdc, 0x46, 0x62, 0x7a, 0x48, 0x36, 0xea, 0xc1, 0xad, 0x0b, 0x82, 0x29, 0x33, 0xda,
+	0xc5, 0xc8, 0xc5, 0x05, 0x14, 0x0e, 0x4e, 0x2d, 0x2a, 0xcb, 0x4c, 0x4e, 0x15, 0x32, 0xe7, 0x62,
+	0x0d, 0x2c, 0x4d, 0x2d, 0xaa, 0x14, 0xc2, 0xa1, 0x51, 0x0a, 0x87, 0xb8, 0x90, 0x0d, 0x17, 0x5b,
+	0x70, 0x49, 0x51, 0x6a, 0x62, 0x2e, 0xa9, 0x3a, 0x35, 0x18, 0x0d, 0x18, 0x85, 0x4c, 0xb9, 0x58,
+	0x21, 0x1e, 0x10, 0x45, 0x57, 0x04, 0x16, 0x96, 0xc2, 0x2e, 0xec, 0xc4, 0x12, 0xc5, 0x54, 0x90,
+	0x94, 0xc4, 0x06, 0x0e, 0x5b, 0x63, 0x00, 0xfc, 0x21, 0xd0, 0x67, 0x68, 0x01, 0x00, 0x00,
 }
@@ -33,6 +33,7 @@ type (
 		// In the future this should be something like ZoneMeta that contains all this stuff.
 		transferTo []string
 		noReload   bool
+		strict     bool
 		upstream   upstream.Upstream // Upstream for looking up names during the resolution process.
 
 		duration time.Duration
@@ -55,18 +56,35 @@ func (a Auto) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 
 	a.Zones.RLock()
 	z, ok := a.Zones.Z[zone]
+	m := a.Zones.meta[zone]
 	a.Zones.RUnlock()
 
 	if !ok || z == nil {
 		return dns.RcodeServerFailure, nil
 	}
 
+	if m != nil && m.signer != nil {
+		return m.signer.ServeDNS(ctx, w, r)
+	}
+	return zoneHandler{z}.ServeDNS(ctx, w, r)
+}
+
+// zoneHandler answers queries from a single zone.
+type zoneHandler struct {
+	z *file.Zone
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (h zoneHandler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r, Context: ctx}
+	z := h.z
+
 	if state.QType() == dns.TypeAXFR || state.QType() == dns.TypeIXFR {
 		xfr := file.Xfr{Zone: z}
 		return xfr.ServeDNS(ctx, w, r)
 	}
 
-	answer, ns, extra, result := z.Lookup(state, qname)
+	answer, ns, extra, result := z.Lookup(state, state.Name())
 
 	m := new(dns.Msg)
 	m.SetReply(r)
@@ -90,5 +108,8 @@ func (a Auto) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 	return dns.RcodeSuccess, nil
 }
 
+// Name implements the Handler interface.
+func (h zoneHandler) Name() string { return "auto" }
+
 // Name implements the Handler interface.
 func (a Auto) Name() string { return "auto" }
@@ -0,0 +1,75 @@
+package auto
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestAutoSign(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(path.Join(dir, "Kexample.org.key"), []byte(key.String()+"\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "Kexample.org.private"), []byte(key.PrivateKeyString(priv)), 0600)
+	ioutil.WriteFile(path.Join(dir, "db.example.org"), []byte(zoneContent), 0644)
+	ioutil.WriteFile(path.Join(dir, "db.example.org.conf"), []byte("dnssec Kexample.org.key\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "db.example.net"), []byte(zoneContent), 0644)
+
+	a := Auto{
+		loader: loader{directory: dir, re: regexp.MustCompile(`db\.(.*)`), template: `${1}`, noReload: true},
+		Zones:  &Zones{origins: []string{"."}},
+	}
+	a.Walk()
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		signed bool
+	}{
+		{"www.example.org.", dns.TypeA, true},
+		{"example.org.", dns.TypeDNSKEY, true},
+		{"www.example.net.", dns.TypeA, false},
+	}
+	for _, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		m.SetEdns0(4096, true)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := a.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if len(rec.Msg.Answer) == 0 {
+			t.Errorf("Expected an answer for %s", tc.qname)
+			continue
+		}
+		signed := false
+		for _, rr := range rec.Msg.Answer {
+			signed = signed || rr.Header().Rrtype == dns.TypeRRSIG
+		}
+		if signed != tc.signed {
+			t.Errorf("Expected signed %t for %s, got %t", tc.signed, tc.qname, signed)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package auto
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	ZonesLoaded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "auto",
+		Name:      "zones_loaded",
+		Help:      "The number of zones loaded from a directory.",
+	}, []string{"directory"})
+	ZonesFailed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "auto",
+		Name:      "zones_failed",
+		Help:      "The number of zone files in a directory that failed to load.",
+	}, []string{"directory"})
+	FileErrors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "auto",
+		Name:      "file_errors",
+		Help:      "Zone files that failed to load in the last scan, set to 1.",
+	}, []string{"directory", "file"})
+)
+
+var once sync.Once
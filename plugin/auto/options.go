@@ -0,0 +1,120 @@
+package auto
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/dnssec"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+)
+
+// sidecarExt is the extension of the file with the options for a zone: the options for the zone in
+// db.example.org are read from db.example.org.conf, if it exists.
+const sidecarExt = ".conf"
+
+// options are the settings for one zone: the ones from the configuration, overridden by the ones
+// from the zone's sidecar file.
+type options struct {
+	transferTo []string
+	notifyTo   []string
+	keys       []*dnssec.DNSKEY
+}
+
+// options returns the options for the zone in the file zoneFile. It also returns the modification
+// time of the sidecar file, which is the zero time if there isn't one.
+func (l loader) options(zoneFile string) (options, time.Time, error) {
+	o := options{transferTo: l.transferTo}
+
+	name := zoneFile + sidecarExt
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return o, time.Time{}, nil
+	}
+	if err != nil {
+		return o, time.Time{}, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return o, time.Time{}, err
+	}
+	defer f.Close()
+
+	err = parseOptions(f, name, &o)
+	return o, info.ModTime(), err
+}
+
+// parseOptions parses a sidecar file into o. Each line holds one option:
+//
+//	transfer to ADDRESS...  replaces the transfer to addresses from the configuration
+//	notify ADDRESS...       sends notifies to these addresses as well
+//	dnssec KEY...           signs answers from the zone on the fly with these keys
+//
+// Comments start with #. Key file names are relative to the directory of the sidecar file.
+func parseOptions(r io.Reader, name string, o *options) error {
+	transfer := false
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "transfer":
+			if len(fields) < 3 || fields[1] != "to" {
+				return fmt.Errorf("%s:%d: expected 'transfer to ADDRESS...'", name, n)
+			}
+			if !transfer {
+				o.transferTo, transfer = nil, true
+			}
+			for _, t := range fields[2:] {
+				if t != "*" {
+					var err error
+					if t, err = dnsutil.ParseHostPort(t, "53"); err != nil {
+						return fmt.Errorf("%s:%d: %s", name, n, err)
+					}
+				}
+				o.transferTo = append(o.transferTo, t)
+			}
+		case "notify":
+			if len(fields) < 2 {
+				return fmt.Errorf("%s:%d: expected 'notify ADDRESS...'", name, n)
+			}
+			for _, t := range fields[1:] {
+				t, err := dnsutil.ParseHostPort(t, "53")
+				if err != nil {
+					return fmt.Errorf("%s:%d: %s", name, n, err)
+				}
+				o.notifyTo = append(o.notifyTo, t)
+			}
+		case "dnssec":
+			if len(fields) < 2 {
+				return fmt.Errorf("%s:%d: expected 'dnssec KEY...'", name, n)
+			}
+			for _, k := range fields[1:] {
+				// Kexample.org.+013+45330, with or without the .key or .private extension.
+				base := strings.TrimSuffix(strings.TrimSuffix(k, ".key"), ".private")
+				if !filepath.IsAbs(base) {
+					base = filepath.Join(filepath.Dir(name), base)
+				}
+				key, err := dnssec.ParseKeyFile(base+".key", base+".private")
+				if err != nil {
+					return fmt.Errorf("%s:%d: %s", name, n, err)
+				}
+				o.keys = append(o.keys, key)
+			}
+		default:
+			return fmt.Errorf("%s:%d: unknown option '%s'", name, n, fields[0])
+		}
+	}
+	return scanner.Err()
+}
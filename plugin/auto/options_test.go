@@ -0,0 +1,48 @@
+package auto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		transferTo []string
+		notifyTo   []string
+	}{
+		{"", false, []string{"10.0.0.1:53"}, nil},
+		{"# comment only\n", false, []string{"10.0.0.1:53"}, nil},
+		{"transfer to 10.0.0.2\ntransfer to * 10.0.0.3:5300\n", false, []string{"10.0.0.2:53", "*", "10.0.0.3:5300"}, nil},
+		{"notify 10.0.0.4 # secondary\n", false, []string{"10.0.0.1:53"}, []string{"10.0.0.4:53"}},
+		{"transfer from 10.0.0.2\n", true, nil, nil},
+		{"transfer to\n", true, nil, nil},
+		{"notify\n", true, nil, nil},
+		{"notify *\n", true, nil, nil},
+		{"dnssec\n", true, nil, nil},
+		{"dnssec Kdoes.not.exist.+013+1\n", true, nil, nil},
+		{"no_such_option\n", true, nil, nil},
+	}
+
+	for i, tc := range tests {
+		o := options{transferTo: []string{"10.0.0.1:53"}}
+		err := parseOptions(strings.NewReader(tc.input), "db.example.org.conf", &o)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %q, got %s", i, tc.input, err)
+			continue
+		}
+		if strings.Join(o.transferTo, ",") != strings.Join(tc.transferTo, ",") {
+			t.Errorf("Test %d: expected transfer to %v, got %v", i, tc.transferTo, o.transferTo)
+		}
+		if strings.Join(o.notifyTo, ",") != strings.Join(tc.notifyTo, ",") {
+			t.Errorf("Test %d: expected notify %v, got %v", i, tc.notifyTo, o.notifyTo)
+		}
+	}
+}
@@ -30,6 +30,11 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("auto", err)
 	}
 
+	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, ZonesLoaded, ZonesFailed, FileErrors) })
+		return nil
+	})
+
 	c.OnStartup(func() error {
 		m := dnsserver.GetConfig(c).Handler("prometheus")
 		if m == nil {
@@ -145,6 +150,9 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 			case "no_reload":
 				a.loader.noReload = true
 
+			case "strict":
+				a.loader.strict = true
+
 			case "upstream":
 				args := c.RemainingArgs()
 				if len(args) == 0 {
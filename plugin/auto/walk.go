@@ -1,104 +1,219 @@
 package auto
 
 import (
+	"fmt"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/coredns/coredns/plugin/dnssec"
 	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/plugin/pkg/log"
 
 	"github.com/miekg/dns"
 )
 
 // Walk will recursively walk of the file under l.directory and adds the one that match l.re.
+// Directories and files whose name starts with a dot, such as .git, are skipped. When the walk is
+// done the zones found replace the ones we had in one go, so queries never see a partial set.
 func (a Auto) Walk() error {
+	a.Zones.walk.Lock()
+	defer a.Zones.walk.Unlock()
 
-	// TODO(miek): should add something so that we don't stomp on each other.
+	a.Zones.RLock()
+	oldZones, oldMeta := a.Zones.Z, a.Zones.meta
+	a.Zones.RUnlock()
 
-	toDelete := make(map[string]bool)
-	for _, n := range a.Zones.Names() {
-		toDelete[n] = true
+	zones := make(map[string]*file.Zone)
+	meta := make(map[string]*zoneMeta)
+	failed := make(map[string]string)
+
+	// keep keeps the zone we have for origin, if it was loaded from path.
+	keep := func(origin, path string) {
+		if m, ok := oldMeta[origin]; ok && m.path == path {
+			zones[origin], meta[origin] = oldZones[origin], m
+		}
 	}
 
 	filepath.Walk(a.loader.directory, func(path string, info os.FileInfo, err error) error {
-		if info == nil || info.IsDir() {
+		if info == nil {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != a.loader.directory {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || strings.HasSuffix(info.Name(), sidecarExt) {
 			return nil
 		}
 
-		match, origin := matches(a.loader.re, info.Name(), a.loader.template)
+		match, origin := a.loader.match(path)
 		if !match {
 			return nil
 		}
 
-		if _, ok := a.Zones.Z[origin]; ok {
-			// we already have this zone
-			toDelete[origin] = false
+		if m, ok := meta[origin]; ok {
+			failed[path] = fmt.Sprintf("zone `%s' is already loaded from %s", origin, m.path)
 			return nil
 		}
 
-		reader, err := os.Open(path)
+		opts, modTime, err := a.loader.options(path)
 		if err != nil {
-			log.Warningf("Opening %s failed: %s", path, err)
+			failed[path] = err.Error()
+			keep(origin, path)
 			return nil
 		}
-		defer reader.Close()
 
-		// Serial for loading a zone is 0, because it is a new zone.
-		zo, err := file.Parse(reader, origin, path, 0)
+		if m, ok := oldMeta[origin]; ok && m.path == path && m.modTime.Equal(modTime) {
+			// we already have this zone
+			zones[origin], meta[origin] = oldZones[origin], m
+			return nil
+		}
+
+		zo, m, err := a.loader.load(path, origin, opts)
 		if err != nil {
-			log.Warningf("Parse zone `%s': %v", origin, err)
+			failed[path] = err.Error()
+			keep(origin, path)
 			return nil
 		}
+		m.modTime = modTime
+		zones[origin], meta[origin] = zo, m
+
+		return nil
+	})
 
-		zo.NoReload = a.loader.noReload
-		zo.Upstream = a.loader.upstream
-		zo.TransferTo = a.loader.transferTo
+	a.Zones.swap(zones, meta)
 
-		a.Zones.Add(zo, origin)
+	for origin, zo := range oldZones {
+		if zones[origin] == zo {
+			continue
+		}
+		zo.OnShutdown()
+		if _, ok := zones[origin]; ok {
+			continue
+		}
 
 		if a.metrics != nil {
-			a.metrics.AddZone(origin)
+			a.metrics.RemoveZone(origin)
 		}
 
+		log.Infof("Deleting zone `%s'", origin)
+	}
+
+	for origin, zo := range zones {
+		if oldZones[origin] == zo {
+			continue
+		}
+		zo.Reload()
 		zo.Notify()
 
-		log.Infof("Inserting zone `%s' from: %s", origin, path)
+		if _, ok := oldZones[origin]; !ok && a.metrics != nil {
+			a.metrics.AddZone(origin)
+		}
 
-		toDelete[origin] = false
+		log.Infof("Inserting zone `%s' from: %s", origin, meta[origin].path)
+	}
 
-		return nil
-	})
+	a.report(len(zones), failed)
+	return nil
+}
 
-	for origin, ok := range toDelete {
-		if !ok {
-			continue
-		}
+// report reports the files that failed to load, and exports the numbers of zones that loaded and
+// failed. Failures are only logged when they are new or have changed.
+func (a Auto) report(loaded int, failed map[string]string) {
+	dir := a.loader.directory
 
-		if a.metrics != nil {
-			a.metrics.RemoveZone(origin)
+	a.Zones.Lock()
+	previous := a.Zones.failed
+	a.Zones.failed = failed
+	a.Zones.Unlock()
+
+	paths := make([]string, 0, len(failed))
+	for p := range failed {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if previous[p] != failed[p] {
+			log.Warningf("Failed to load zone file %s: %s", p, failed[p])
 		}
+		FileErrors.WithLabelValues(dir, p).Set(1)
+	}
+	for p := range previous {
+		if _, ok := failed[p]; !ok {
+			log.Infof("Zone file %s loads again", p)
+			FileErrors.DeleteLabelValues(dir, p)
+		}
+	}
 
-		a.Zones.Remove(origin)
+	ZonesLoaded.WithLabelValues(dir).Set(float64(loaded))
+	ZonesFailed.WithLabelValues(dir).Set(float64(len(failed)))
+}
 
-		log.Infof("Deleting zone `%s'", origin)
+// match returns true and the origin if the zone file at path matches l.re. The regular expression is
+// matched against the file name, or against the path relative to l.directory if it contains a /.
+func (l loader) match(path string) (bool, string) {
+	name := filepath.Base(path)
+	if strings.Contains(l.re.String(), "/") {
+		rel, err := filepath.Rel(l.directory, path)
+		if err != nil {
+			return false, ""
+		}
+		name = filepath.ToSlash(rel)
 	}
+	return matches(l.re, name, l.template)
+}
 
-	return nil
+// load parses the zone file at path and configures it with opts.
+func (l loader) load(path, origin string, opts options) (*file.Zone, *zoneMeta, error) {
+	reader, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	// Serial for loading a zone is 0, because it is a new zone.
+	zo, problems, err := file.ParseCheck(reader, origin, path, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if l.strict && problems.HasErrors() {
+		return nil, nil, fmt.Errorf("zone has errors: %s", problems)
+	}
+	problems.Log()
+
+	zo.NoReload = l.noReload
+	zo.Strict = l.strict
+	zo.Upstream = l.upstream
+	zo.TransferTo = opts.transferTo
+	zo.NotifyTo = opts.notifyTo
+
+	m := &zoneMeta{path: path}
+	if len(opts.keys) > 0 {
+		for _, k := range opts.keys {
+			if !strings.EqualFold(k.K.Header().Name, origin) {
+				return nil, nil, fmt.Errorf("key %s (keyid: %d) is not for zone `%s'", k.K.Header().Name, k.K.KeyTag(), origin)
+			}
+		}
+		m.signer = dnssec.New([]string{origin}, opts.keys, zoneHandler{zo}, cache.New(signatureCapacity))
+	}
+	return zo, m, nil
 }
 
 // matches matches re to filename, if is is a match, the subexpression will be used to expand
 // template to an origin. When match is true that origin is returned. Origin is fully qualified.
 func matches(re *regexp.Regexp, filename, template string) (match bool, origin string) {
-	base := path.Base(filename)
-
-	matches := re.FindStringSubmatchIndex(base)
+	matches := re.FindStringSubmatchIndex(filename)
 	if matches == nil {
 		return false, ""
 	}
 
-	by := re.ExpandString(nil, template, base, matches)
+	by := re.ExpandString(nil, template, filename, matches)
 	if by == nil {
 		return false, ""
 	}
@@ -107,3 +222,6 @@ func matches(re *regexp.Regexp, filename, template string) (match bool, origin s
 
 	return true, origin
 }
+
+// signatureCapacity is the number of signatures cached per zone that is signed on the fly.
+const signatureCapacity = 10000
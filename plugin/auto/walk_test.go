@@ -5,7 +5,9 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 var dbFiles = []string{"db.example.org", "aa.example.org"}
@@ -87,3 +89,134 @@ func createFiles() (string, error) {
 
 	return dir, nil
 }
+
+func TestWalkTree(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"db.example.org":            zoneContent,
+		"db.example.org.conf":       "transfer to 127.0.0.1:1\nnotify 127.0.0.1:2\n",
+		"net/db.example.net":        zoneContent,
+		"net/deeper/db.example.com": zoneContent,
+		".git/db.hidden.org":        zoneContent,
+		"db.broken.org":             "not a zone",
+		"db.badoptions.org":         zoneContent,
+		"db.badoptions.org.conf":    "transfer from 127.0.0.1\n",
+	}
+	for name, content := range files {
+		p := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := Auto{
+		loader: loader{directory: dir, re: regexp.MustCompile(`db\.(.*)`), template: `${1}`, transferTo: []string{"127.0.0.1:3"}, noReload: true},
+		Zones:  &Zones{},
+	}
+	a.Walk()
+
+	for _, name := range []string{"example.org.", "example.net.", "example.com."} {
+		if _, ok := a.Zones.Z[name]; !ok {
+			t.Errorf("%s should have been added", name)
+		}
+	}
+	for _, name := range []string{"hidden.org.", "broken.org.", "badoptions.org.", "org.conf."} {
+		if _, ok := a.Zones.Z[name]; ok {
+			t.Errorf("%s should not have been added", name)
+		}
+	}
+	if len(a.Zones.failed) != 2 {
+		t.Errorf("Expected 2 failed files, got %v", a.Zones.failed)
+	}
+	for _, name := range []string{"db.broken.org", "db.badoptions.org"} {
+		if _, ok := a.Zones.failed[path.Join(dir, name)]; !ok {
+			t.Errorf("Expected %s to have failed", name)
+		}
+	}
+
+	org, net := a.Zones.Z["example.org."], a.Zones.Z["example.net."]
+	if x := strings.Join(org.TransferTo, ","); x != "127.0.0.1:1" {
+		t.Errorf("Expected transfer to from the sidecar file, got %s", x)
+	}
+	if x := strings.Join(org.NotifyTo, ","); x != "127.0.0.1:2" {
+		t.Errorf("Expected notify from the sidecar file, got %s", x)
+	}
+	if x := strings.Join(net.TransferTo, ","); x != "127.0.0.1:3" {
+		t.Errorf("Expected transfer to from the configuration, got %s", x)
+	}
+
+	// Changing the sidecar file reloads the zone, the others stay as they are.
+	ioutil.WriteFile(path.Join(dir, "db.example.org.conf"), []byte("transfer to 127.0.0.1:4\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path.Join(dir, "db.example.org.conf"), later, later)
+	a.Walk()
+
+	if a.Zones.Z["example.org."] == org {
+		t.Errorf("Expected example.org. to be reloaded")
+	}
+	if x := strings.Join(a.Zones.Z["example.org."].TransferTo, ","); x != "127.0.0.1:4" {
+		t.Errorf("Expected the new transfer to, got %s", x)
+	}
+	if a.Zones.Z["example.net."] != net {
+		t.Errorf("Expected example.net. to be kept")
+	}
+}
+
+func TestWalkRelativePath(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(path.Join(dir, "org", "example"), 0755)
+	ioutil.WriteFile(path.Join(dir, "org", "example", "zone"), []byte(zoneContent), 0644)
+
+	a := Auto{
+		loader: loader{directory: dir, re: regexp.MustCompile(`^([^/]*)/([^/]*)/zone$`), template: `${2}.${1}`},
+		Zones:  &Zones{},
+	}
+	a.Walk()
+
+	if _, ok := a.Zones.Z["example.org."]; !ok {
+		t.Errorf("Expected example.org. to be loaded from org/example/zone, got %v", a.Zones.Names())
+	}
+}
+
+func TestWalkStrict(t *testing.T) {
+	tempdir, err := ioutil.TempDir(os.TempDir(), "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	// www has a CNAME and other data.
+	broken := zoneContent + "www IN CNAME example.net.\n"
+	if err := ioutil.WriteFile(path.Join(tempdir, "db.example.org"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, strict := range []bool{false, true} {
+		a := Auto{
+			loader: loader{directory: tempdir, re: regexp.MustCompile(`db\.(.*)`), template: `${1}`, noReload: true, strict: strict},
+			Zones:  &Zones{},
+		}
+		a.Walk()
+
+		_, ok := a.Zones.Z["example.org."]
+		if ok == strict {
+			t.Errorf("Expected example.org. to be loaded is %t with strict %t", !strict, strict)
+		}
+		if _, failed := a.Zones.failed[path.Join(tempdir, "db.example.org")]; failed != strict {
+			t.Errorf("Expected db.example.org to have failed is %t with strict %t", strict, strict)
+		}
+	}
+}
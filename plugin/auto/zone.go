@@ -3,7 +3,9 @@ package auto
 
 import (
 	"sync"
+	"time"
 
+	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/file"
 )
 
@@ -12,10 +14,22 @@ import (
 type Zones struct {
 	Z     map[string]*file.Zone // A map mapping zone (origin) to the Zone's data.
 	names []string              // All the keys from the map Z as a string slice.
+	meta  map[string]*zoneMeta  // Where each zone in Z came from, and how it is served.
 
 	origins []string // Any origins from the server block.
 
+	failed map[string]string // Files that failed to load in the last scan, with the error.
+
 	sync.RWMutex
+	walk sync.Mutex // Only one scan at a time.
+}
+
+// zoneMeta is what we keep about a zone, besides its data.
+type zoneMeta struct {
+	path    string    // the zone file
+	modTime time.Time // modification time of the sidecar file, if there is one
+
+	signer plugin.Handler // signs the answers from the zone, nil when not signing on the fly
 }
 
 // Names returns the names from z.
@@ -65,6 +79,7 @@ func (z *Zones) Remove(name string) {
 	}
 
 	delete(z.Z, name)
+	delete(z.meta, name)
 
 	// TODO(miek): just regenerate Names (might be bad if you have a lot of zones...)
 	z.names = []string{}
@@ -74,3 +89,18 @@ func (z *Zones) Remove(name string) {
 
 	z.Unlock()
 }
+
+// swap replaces all zones in z with zones, and returns the ones we had.
+func (z *Zones) swap(zones map[string]*file.Zone, meta map[string]*zoneMeta) (map[string]*file.Zone, map[string]*zoneMeta) {
+	names := make([]string, 0, len(zones))
+	for n := range zones {
+		names = append(names, n)
+	}
+
+	z.Lock()
+	oldZones, oldMeta := z.Z, z.meta
+	z.Z, z.meta, z.names = zones, meta, names
+	z.Unlock()
+
+	return oldZones, oldMeta
+}
@@ -0,0 +1,144 @@
+// Package blocklist implements a plugin that blocks the names in lists of domains.
+package blocklist
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Blocklist answers queries for blocked names itself and passes all others on.
+type Blocklist struct {
+	Next  plugin.Handler
+	Zones []string
+
+	lists    []*list  // block lists and allowlists, in the order they are configured
+	allow    *trie    // names allowed in the configuration
+	sinkhole []net.IP // addresses to answer with, NXDOMAIN when empty
+	ttl      uint32
+	refresh  time.Duration
+}
+
+// New returns a new Blocklist for zones.
+func New(zones []string) *Blocklist {
+	return &Blocklist{Zones: zones, allow: newTrie(), ttl: 3600, refresh: 24 * time.Hour}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (b *Blocklist) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(b.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+
+	l := b.blocked(state.Name())
+	if l == nil {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+	HitCount.WithLabelValues(l.location).Inc()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.RecursionAvailable = true
+	if len(b.sinkhole) == 0 {
+		m.Rcode = dns.RcodeNameError
+	}
+	hdr := dns.RR_Header{Name: state.QName(), Class: dns.ClassINET, Ttl: b.ttl}
+	for _, ip := range b.sinkhole {
+		if ip4 := ip.To4(); ip4 != nil && state.QType() == dns.TypeA {
+			hdr.Rrtype = dns.TypeA
+			m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: ip4})
+		}
+		if ip.To4() == nil && state.QType() == dns.TypeAAAA {
+			hdr.Rrtype = dns.TypeAAAA
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip})
+		}
+	}
+	if len(m.Answer) == 0 {
+		m.Ns = []dns.RR{b.soa(state.Name())}
+	}
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// soa returns the SOA record for negative answers, the blocked name is the apex of its own zone. This
+// lets resolvers cache the NXDOMAIN or empty answer for ttl.
+func (b *Blocklist) soa(name string) dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: name, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: b.ttl},
+		Ns:      "ns.dns." + name,
+		Mbox:    "hostmaster." + name,
+		Serial:  1,
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  86400,
+		Minttl:  b.ttl,
+	}
+}
+
+// blocked returns the first list that blocks name, or nil if it isn't blocked or one of the lists
+// allows it.
+func (b *Blocklist) blocked(name string) *list {
+	if b.allow.match(name) {
+		return nil
+	}
+	var hit *list
+	for _, l := range b.lists {
+		blocked, allowed := l.match(name)
+		if allowed {
+			return nil
+		}
+		if blocked && hit == nil {
+			hit = l
+		}
+	}
+	return hit
+}
+
+// load loads all lists concurrently, the returned channel is closed when they are done. Lists that fail
+// to load keep the names they had.
+func (b *Blocklist) load() <-chan struct{} {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, l := range b.lists {
+		wg.Add(1)
+		go func(l *list) {
+			defer wg.Done()
+			if err := l.load(); err != nil {
+				log.Warningf("Failed to load list %s: %s", l.location, err)
+			}
+		}(l)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// update reloads the lists every refresh, until stop is closed.
+func (b *Blocklist) update(stop chan struct{}) {
+	tick := time.NewTicker(b.refresh)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+			<-b.load()
+		}
+	}
+}
+
+// Name implements the Handler interface.
+func (b *Blocklist) Name() string { return "blocklist" }
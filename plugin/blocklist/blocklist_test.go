@@ -0,0 +1,125 @@
+package blocklist
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func newBlocklist(t *testing.T, lists ...string) *Blocklist {
+	b := New([]string{"."})
+	for i, s := range lists {
+		l := newList("list"+strconv.Itoa(i), strings.HasPrefix(s, "allow:"))
+		blocked, allowed, err := parse(strings.NewReader(strings.TrimPrefix(s, "allow:")))
+		if err != nil {
+			t.Fatalf("Failed to parse list: %s", err)
+		}
+		if l.allow {
+			blocked, allowed = newTrie(), merge(allowed, blocked)
+		}
+		l.blocked, l.allowed = blocked, allowed
+		b.lists = append(b.lists, l)
+	}
+	b.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	return b
+}
+
+func TestBlocklist(t *testing.T) {
+	b := newBlocklist(t, "ads.example.org\n||tracker.example.org^\n", "tracker.example.org\nexample.net\n", "allow:www.example.net")
+	b.allow.insert("ok.ads.example.org.", markName)
+
+	tests := []struct {
+		qname   string
+		blocked bool
+		list    string
+	}{
+		{"ads.example.org.", true, "list0"},
+		{"www.ads.example.org.", true, "list0"},
+		{"ok.ads.example.org.", false, ""},
+		{"a.ok.ads.example.org.", true, "list0"},
+		{"tracker.example.org.", true, "list0"},
+		{"example.net.", true, "list1"},
+		{"www.example.net.", false, ""},
+		{"example.org.", false, ""},
+	}
+	for _, tc := range tests {
+		l := b.blocked(tc.qname)
+		if (l != nil) != tc.blocked {
+			t.Errorf("Expected blocked %t for %s", tc.blocked, tc.qname)
+			continue
+		}
+		if l != nil && l.location != tc.list {
+			t.Errorf("Expected %s to be blocked by %s, got %s", tc.qname, tc.list, l.location)
+		}
+	}
+}
+
+func TestBlocklistResponse(t *testing.T) {
+	b := newBlocklist(t, "ads.example.org\n")
+
+	tests := []struct {
+		sinkhole []net.IP
+		qname    string
+		qtype    uint16
+		rcode    int
+		answer   []string
+		soa      bool
+	}{
+		{nil, "ads.example.org.", dns.TypeA, dns.RcodeNameError, nil, true},
+		{nil, "example.org.", dns.TypeA, dns.RcodeSuccess, nil, false}, // from the next plugin
+		{[]net.IP{net.IPv4zero, net.IPv6zero}, "ads.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"ads.example.org.\t60\tIN\tA\t0.0.0.0"}, false},
+		{[]net.IP{net.IPv4zero, net.IPv6zero}, "ads.example.org.", dns.TypeAAAA, dns.RcodeSuccess, []string{"ads.example.org.\t60\tIN\tAAAA\t::"}, false},
+		{[]net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, "ADS.example.org.", dns.TypeA, dns.RcodeSuccess,
+			[]string{"ADS.example.org.\t60\tIN\tA\t192.0.2.1", "ADS.example.org.\t60\tIN\tA\t192.0.2.2"}, false},
+		{[]net.IP{net.ParseIP("192.0.2.1")}, "ads.example.org.", dns.TypeMX, dns.RcodeSuccess, nil, true},
+		{[]net.IP{net.ParseIP("192.0.2.1")}, "ads.example.org.", dns.TypeAAAA, dns.RcodeSuccess, nil, true},
+	}
+
+	ctx := context.TODO()
+	b.ttl = 60
+	for i, tc := range tests {
+		b.sinkhole = tc.sinkhole
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := b.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if rec.Msg == nil {
+			if rcode != tc.rcode {
+				t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rcode)
+			}
+			continue
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if tc.soa {
+			if len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].Header().Rrtype != dns.TypeSOA || rec.Msg.Ns[0].Header().Name != "ads.example.org." {
+				t.Errorf("Test %d: expected SOA for ads.example.org. in authority section, got %v", i, rec.Msg.Ns)
+			} else if soa := rec.Msg.Ns[0].(*dns.SOA); soa.Minttl != 60 {
+				t.Errorf("Test %d: expected SOA minimum TTL 60, got %d", i, soa.Minttl)
+			}
+		} else if len(rec.Msg.Ns) != 0 {
+			t.Errorf("Test %d: expected empty authority section, got %v", i, rec.Msg.Ns)
+		}
+		if len(rec.Msg.Answer) != len(tc.answer) {
+			t.Errorf("Test %d: expected %d answers, got %v", i, len(tc.answer), rec.Msg.Answer)
+			continue
+		}
+		for j, rr := range rec.Msg.Answer {
+			if rr.String() != tc.answer[j] {
+				t.Errorf("Test %d: expected answer %q, got %q", i, tc.answer[j], rr.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// list is a list of domain names, read from a file or fetched from a URL.
+type list struct {
+	location string
+	allow    bool // every name in the list is allowed, instead of blocked
+
+	mu      sync.RWMutex
+	blocked *trie
+	allowed *trie
+}
+
+func newList(location string, allow bool) *list {
+	return &list{location: location, allow: allow, blocked: newTrie(), allowed: newTrie()}
+}
+
+// match returns whether name is blocked and whether it is allowed by l.
+func (l *list) match(name string) (blocked, allowed bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.blocked.match(name), l.allowed.match(name)
+}
+
+// load reads the list from its location and sets it live. On error the list is left as it was.
+func (l *list) load() error {
+	r, err := open(l.location)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	blocked, allowed, err := parse(r)
+	if err != nil {
+		return fmt.Errorf("%s: %s", l.location, err)
+	}
+	if l.allow {
+		blocked, allowed = newTrie(), merge(allowed, blocked)
+	}
+
+	l.mu.Lock()
+	l.blocked, l.allowed = blocked, allowed
+	l.mu.Unlock()
+
+	EntryCount.WithLabelValues(l.location).Set(float64(blocked.Len() + allowed.Len()))
+	return nil
+}
+
+// merge returns a trie with the names of both a and b.
+func merge(a, b *trie) *trie {
+	var walk func(n *node, name string)
+	walk = func(n *node, name string) {
+		if n.mark != 0 {
+			a.insert(name, n.mark)
+		}
+		for label, c := range n.children {
+			walk(c, label+"."+name)
+		}
+	}
+	walk(&b.root, "")
+	return a
+}
+
+// open opens location, which is a file or a http or https URL.
+func open(location string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.Open(location)
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+var client = &http.Client{Timeout: fetchTimeout}
+
+const fetchTimeout = 1 * time.Minute
+
+// parse parses a list of domain names. Each line can be in one of these formats:
+//
+//	0.0.0.0 ads.example.org tracker.example.org  hosts file format, blocks exactly these names
+//	ads.example.org                              a plain name, blocks the name and all names below it
+//	*.ads.example.org                            blocks all names below ads.example.org
+//	||ads.example.org^                           adblock format, blocks the name and all names below it
+//	@@||ads.example.org^                         adblock exception, allows the name and all names below it
+//
+// Comments start with # or !. Adblock rules with other syntax or options are skipped.
+func parse(r io.Reader) (blocked, allowed *trie, err error) {
+	blocked, allowed = newTrie(), newTrie()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			if i > 0 && line[i-1] != ' ' && line[i-1] != '\t' {
+				// Adblock element hiding rules, such as example.org##.ad.
+				continue
+			}
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '!' || line[0] == '[' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@||") || strings.HasPrefix(line, "||") {
+			t := blocked
+			if strings.HasPrefix(line, "@@") {
+				t, line = allowed, line[2:]
+			}
+			if !strings.HasSuffix(line, "^") {
+				continue
+			}
+			if name := domain(line[2 : len(line)-1]); name != "" {
+				t.insert(name, markName|markBelow)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			for _, f := range fields[1:] {
+				if name := domain(f); name != "" && dns.CountLabel(name) > 1 && name != "localhost.localdomain." {
+					blocked.insert(name, markName)
+				}
+			}
+			continue
+		}
+		if len(fields) != 1 {
+			continue
+		}
+		m := markName | markBelow
+		if strings.HasPrefix(line, "*.") {
+			m, line = markBelow, line[2:]
+		}
+		if name := domain(line); name != "" {
+			blocked.insert(name, m)
+		}
+	}
+	return blocked, allowed, scanner.Err()
+}
+
+// domain returns s as a fully qualified domain name, or the empty string if it is not a valid name.
+func domain(s string) string {
+	if s == "" || net.ParseIP(s) != nil {
+		return ""
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return ""
+		}
+	}
+	if _, ok := dns.IsDomainName(s); !ok {
+		return ""
+	}
+	return strings.ToLower(dns.Fqdn(s))
+}
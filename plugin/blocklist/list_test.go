@@ -0,0 +1,145 @@
+package blocklist
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testList = `# hosts format
+127.0.0.1	localhost localhost.localdomain
+0.0.0.0 ads.example.org tracker.example.org # trailing comment
+::1 ip6-localhost
+! plain names
+malware.example.net
+*.wild.example.com
+[Adblock Plus 2.0]
+||adblock.example.org^
+||options.example.org^$third-party
+@@||ok.ads.example.org^
+example.org##.banner
+not a name
+bad/name.example.org
+`
+
+func TestParse(t *testing.T) {
+	blocked, allowed, err := parse(strings.NewReader(testList))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	tests := []struct {
+		name             string
+		blocked, allowed bool
+	}{
+		{"localhost.", false, false},
+		{"localhost.localdomain.", false, false},
+		{"ip6-localhost.", false, false},
+		{"ads.example.org.", true, false},
+		{"www.ads.example.org.", false, false},
+		{"ok.ads.example.org.", false, true},
+		{"tracker.example.org.", true, false},
+		{"malware.example.net.", true, false},
+		{"www.malware.example.net.", true, false},
+		{"wild.example.com.", false, false},
+		{"www.wild.example.com.", true, false},
+		{"adblock.example.org.", true, false},
+		{"www.adblock.example.org.", true, false},
+		{"options.example.org.", false, false},
+		{"example.org.", false, false},
+		{"bad.", false, false},
+	}
+	for _, tc := range tests {
+		if x := blocked.match(tc.name); x != tc.blocked {
+			t.Errorf("Expected blocked %t for %s, got %t", tc.blocked, tc.name, x)
+		}
+		if x := allowed.match(tc.name); x != tc.allowed {
+			t.Errorf("Expected allowed %t for %s, got %t", tc.allowed, tc.name, x)
+		}
+	}
+	if x := blocked.Len(); x != 5 {
+		t.Errorf("Expected 5 blocked names, got %d", x)
+	}
+}
+
+func TestLoadURL(t *testing.T) {
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprintln(w, "||ads.example.org^")
+	}))
+	defer s.Close()
+
+	l := newList(s.URL+"/list.txt", false)
+	if err := l.load(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if blocked, _ := l.match("www.ads.example.org."); !blocked {
+		t.Errorf("Expected www.ads.example.org. to be blocked")
+	}
+
+	// A failed fetch keeps the names we have.
+	status = http.StatusNotFound
+	if err := l.load(); err == nil {
+		t.Errorf("Expected error for status %d", status)
+	}
+	if blocked, _ := l.match("www.ads.example.org."); !blocked {
+		t.Errorf("Expected www.ads.example.org. to still be blocked")
+	}
+}
+
+func TestLoadAllowlist(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "0.0.0.0 ok.example.org\nfine.example.net\n@@||good.example.com^")
+	}))
+	defer s.Close()
+
+	l := newList(s.URL, true)
+	if err := l.load(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	for _, name := range []string{"ok.example.org.", "www.fine.example.net.", "good.example.com."} {
+		if blocked, allowed := l.match(name); blocked || !allowed {
+			t.Errorf("Expected %s to be allowed only, got blocked %t and allowed %t", name, blocked, allowed)
+		}
+	}
+}
+
+func TestLoadConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprintln(w, "slow.example.org")
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "fast.example.org")
+	}))
+	defer fast.Close()
+
+	b := New([]string{"."})
+	b.lists = []*list{newList(slow.URL, false), newList(fast.URL, false)}
+	done := b.load()
+
+	// The fast list doesn't wait for the slow one.
+	for i := 0; b.blocked("fast.example.org.") == nil; i++ {
+		if i == 100 {
+			t.Fatalf("Expected fast.example.org. to be blocked while the slow list loads")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatalf("Expected load not to be done before the slow list is")
+	default:
+	}
+
+	close(release)
+	<-done
+	if b.blocked("slow.example.org.") == nil {
+		t.Errorf("Expected slow.example.org. to be blocked")
+	}
+}
@@ -0,0 +1,27 @@
+package blocklist
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	HitCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "hits_total",
+		Help:      "Counter of blocked queries per list.",
+	}, []string{"list"})
+	EntryCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "entries",
+		Help:      "The number of names in a list.",
+	}, []string{"list"})
+)
+
+var once sync.Once
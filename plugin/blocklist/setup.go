@@ -0,0 +1,178 @@
+package blocklist
+
+import (
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("blocklist", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+// startupWait is how long startup waits for the lists to load, slower lists are loaded in the background.
+const startupWait = 5 * time.Second
+
+func setup(c *caddy.Controller) error {
+	b, err := blocklistParse(c)
+	if err != nil {
+		return plugin.Error("blocklist", err)
+	}
+
+	stop := make(chan struct{})
+
+	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, HitCount, EntryCount) })
+		select {
+		case <-b.load():
+		case <-time.After(startupWait):
+			log.Warningf("Lists not loaded after %s, loading them in the background", startupWait)
+		}
+		go b.update(stop)
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		b.Next = next
+		return b
+	})
+
+	return nil
+}
+
+func blocklistParse(c *caddy.Controller) (*Blocklist, error) {
+	var b *Blocklist
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		zones := make([]string, len(c.ServerBlockKeys))
+		copy(zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			zones = args
+		}
+		for j := range zones {
+			zones[j] = plugin.Host(zones[j]).Normalize()
+		}
+		b = New(zones)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "list", "allowlist":
+				allow := c.Val() == "allowlist"
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					b.lists = append(b.lists, newList(location(config, a), allow))
+				}
+			case "allow":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					name := domain(strings.TrimPrefix(a, "*."))
+					if name == "" {
+						return nil, c.Errf("invalid name '%s'", a)
+					}
+					m := markName | markBelow
+					if strings.HasPrefix(a, "*.") {
+						m = markBelow
+					}
+					b.allow.insert(name, m)
+				}
+			case "response":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				b.sinkhole = nil
+				switch args[0] {
+				case "nxdomain":
+					if len(args) != 1 {
+						return nil, c.ArgErr()
+					}
+				case "null":
+					if len(args) != 1 {
+						return nil, c.ArgErr()
+					}
+					b.sinkhole = []net.IP{net.IPv4zero, net.IPv6zero}
+				default:
+					for _, a := range args {
+						ip := net.ParseIP(a)
+						if ip == nil {
+							return nil, c.Errf("invalid address '%s'", a)
+						}
+						b.sinkhole = append(b.sinkhole, ip)
+					}
+				}
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return nil, c.Errf("invalid ttl '%s'", args[0])
+				}
+				b.ttl = uint32(ttl)
+			case "refresh":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if d <= 0 {
+					return nil, c.Errf("refresh must be positive, got '%s'", args[0])
+				}
+				b.refresh = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(b.lists) == 0 {
+		return nil, c.Err("no lists")
+	}
+	return b, nil
+}
+
+// location returns the location of a list: URLs as is, and file names with the root from config
+// prepended if they are relative.
+func location(config *dnsserver.Config, l string) string {
+	if strings.HasPrefix(l, "http://") || strings.HasPrefix(l, "https://") {
+		return l
+	}
+	if !path.IsAbs(l) && config.Root != "" {
+		l = path.Join(config.Root, l)
+	}
+	return l
+}
@@ -0,0 +1,100 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupBlocklist(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		lists     int
+		sinkhole  int
+		ttl       uint32
+		refresh   time.Duration
+	}{
+		// positive
+		{`blocklist {
+			list /etc/blocklist.txt
+		}`, false, 1, 0, 3600, 24 * time.Hour},
+		{`blocklist example.org {
+			list /etc/hosts.block https://example.net/adblock.txt
+			allowlist /etc/allow.txt
+			allow ok.example.org *.fine.example.org
+			response null
+			ttl 60
+			refresh 1h
+		}`, false, 3, 2, 60, time.Hour},
+		{`blocklist {
+			list blocked.txt
+			response 192.0.2.1 2001:db8::1
+		}`, false, 1, 2, 3600, 24 * time.Hour},
+		{`blocklist {
+			list blocked.txt
+			response null
+			response nxdomain
+		}`, false, 1, 0, 3600, 24 * time.Hour},
+		// negative
+		{`blocklist`, true, 0, 0, 0, 0},
+		{`blocklist {
+			allowlist /etc/allow.txt
+			list
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+			allow bad/name
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+			response sinkhole
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+			response null 192.0.2.1
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+			ttl -1
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+			refresh 0s
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+			block example.org
+		}`, true, 0, 0, 0, 0},
+		{`blocklist {
+			list blocked.txt
+		}
+		blocklist`, true, 0, 0, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		b, err := blocklistParse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if len(b.lists) != test.lists {
+			t.Errorf("Test %d: expected %d lists, got %d", i, test.lists, len(b.lists))
+		}
+		if len(b.sinkhole) != test.sinkhole {
+			t.Errorf("Test %d: expected %d sinkhole addresses, got %d", i, test.sinkhole, len(b.sinkhole))
+		}
+		if b.ttl != test.ttl || b.refresh != test.refresh {
+			t.Errorf("Test %d: expected ttl %d and refresh %s, got %d and %s", i, test.ttl, test.refresh, b.ttl, b.refresh)
+		}
+	}
+}
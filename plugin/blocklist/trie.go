@@ -0,0 +1,74 @@
+package blocklist
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// mark says which names an entry in the trie matches.
+type mark uint8
+
+const (
+	markName  mark = 1 << iota // the name itself
+	markBelow                  // every name below it
+)
+
+// trie holds domain names, keyed by label from the root down. Names share the nodes of their
+// common suffix, and nodes only get a map for their children when they have any, which keeps large
+// lists small.
+type trie struct {
+	root node
+	size int // number of names inserted
+}
+
+type node struct {
+	children map[string]*node
+	mark     mark
+}
+
+func newTrie() *trie { return &trie{} }
+
+// insert adds name to t. The root itself can't be added.
+func (t *trie) insert(name string, m mark) {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	if len(labels) == 0 {
+		return
+	}
+	n := &t.root
+	for i := len(labels) - 1; i >= 0; i-- {
+		c, ok := n.children[labels[i]]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[string]*node, 1)
+			}
+			c = &node{}
+			n.children[labels[i]] = c
+		}
+		n = c
+	}
+	if n.mark == 0 {
+		t.size++
+	}
+	n.mark |= m
+}
+
+// match returns true if name, or one of its ancestors that covers the names below it, is in t.
+func (t *trie) match(name string) bool {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	n := &t.root
+	for i := len(labels) - 1; i >= 0; i-- {
+		if n.mark&markBelow != 0 {
+			return true
+		}
+		c, ok := n.children[labels[i]]
+		if !ok {
+			return false
+		}
+		n = c
+	}
+	return n.mark&markName != 0
+}
+
+// Len returns the number of names in t.
+func (t *trie) Len() int { return t.size }
@@ -0,0 +1,37 @@
+package blocklist
+
+import "testing"
+
+func TestTrie(t *testing.T) {
+	tr := newTrie()
+	tr.insert("example.org.", markName)
+	tr.insert("ads.example.net.", markName|markBelow)
+	tr.insert("tracker.example.com.", markBelow)
+	tr.insert("Example.org.", markName)
+	tr.insert(".", markName|markBelow)
+
+	if x := tr.Len(); x != 3 {
+		t.Errorf("Expected 3 names, got %d", x)
+	}
+
+	tests := []struct {
+		name  string
+		match bool
+	}{
+		{"example.org.", true},
+		{"EXAMPLE.org.", true},
+		{"www.example.org.", false},
+		{"org.", false},
+		{"ads.example.net.", true},
+		{"a.b.ads.example.net.", true},
+		{"example.net.", false},
+		{"tracker.example.com.", false},
+		{"x.tracker.example.com.", true},
+		{".", false},
+	}
+	for _, tc := range tests {
+		if x := tr.match(tc.name); x != tc.match {
+			t.Errorf("Expected match %t for %s, got %t", tc.match, tc.name, x)
+		}
+	}
+}
@@ -4,15 +4,17 @@ package cache
 import (
 	"encoding/binary"
 	"hash/fnv"
+	"net"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
-	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	"golang.org/x/net/context"
 )
 
 // Cache is plugin that looks up responses in a cache and caches replies.
@@ -21,19 +23,32 @@ type Cache struct {
 	Next  plugin.Handler
 	Zones []string
 
-	ncache *cache.Cache
+	ncache storage
 	ncap   int
 	nttl   time.Duration
 
-	pcache *cache.Cache
+	pcache storage
 	pcap   int
 	pttl   time.Duration
 
+	// Shared storage for both caches, nil when the items are kept in memory.
+	redis *redisClient
+
 	// Prefetch.
 	prefetch   int
 	duration   time.Duration
 	percentage int
 
+	// SERVFAIL responses are cached for failttl, they are not cached when it is zero.
+	failttl time.Duration
+
+	// Zones for which we don't cache successful or denial of existence responses.
+	pexcept []string
+	nexcept []string
+
+	// When true cached replies carry the TTLs as received, instead of the remaining TTL.
+	keepttl bool
+
 	// Testing.
 	now func() time.Time
 }
@@ -44,10 +59,10 @@ func New() *Cache {
 	return &Cache{
 		Zones:      []string{"."},
 		pcap:       defaultCap,
-		pcache:     cache.New(defaultCap),
+		pcache:     newMemory(defaultCap),
 		pttl:       maxTTL,
 		ncap:       defaultCap,
-		ncache:     cache.New(defaultCap),
+		ncache:     newMemory(defaultCap),
 		nttl:       maxNTTL,
 		prefetch:   0,
 		duration:   1 * time.Minute,
@@ -58,14 +73,17 @@ func New() *Cache {
 
 // Return key under which we store the item, -1 will be returned if we don't store the
 // message.
-// Currently we do not cache Truncated, errors zone transfers or dynamic update messages.
+// Currently we do not cache Truncated, errors (except SERVFAIL), zone transfers or dynamic update messages.
 func key(m *dns.Msg, t response.Type, do bool) int {
-	// We don't store truncated responses.
-	if m.Truncated {
+	// We don't store truncated responses, or replies without a question.
+	if m.Truncated || len(m.Question) == 0 {
 		return -1
 	}
 	// Nor errors or Meta or Update
-	if t == response.OtherError || t == response.Meta || t == response.Update {
+	if t == response.OtherError && m.Rcode != dns.RcodeServerFailure {
+		return -1
+	}
+	if t == response.Meta || t == response.Update {
 		return -1
 	}
 
@@ -99,15 +117,63 @@ func hash(qname string, qtype uint16, do bool) uint32 {
 	return h.Sum32()
 }
 
+// subnetKey returns the key for an item that is only valid for clients in addr/scope, as indicated by
+// the EDNS0 client subnet option in the reply. k is the key returned from hash.
+func subnetKey(k uint32, family uint16, scope uint8, addr net.IP) uint32 {
+	h := fnv.New32()
+
+	b := make([]byte, 7)
+	binary.BigEndian.PutUint32(b, k)
+	binary.BigEndian.PutUint16(b[4:], family)
+	b[6] = scope
+	h.Write(b)
+
+	switch family {
+	case 1:
+		h.Write(addr.To4().Mask(net.CIDRMask(int(scope), 32)))
+	case 2:
+		h.Write(addr.To16().Mask(net.CIDRMask(int(scope), 128)))
+	}
+
+	return h.Sum32()
+}
+
+// clientSubnet returns the family, address and prefix length of the client in state. If the request
+// carries an EDNS0 client subnet option that is used, otherwise the client's address is.
+func clientSubnet(state request.Request) (uint16, net.IP, uint8) {
+	if e := edns.Subnet(state.Req); e != nil {
+		return e.Family, e.Address, e.SourceNetmask
+	}
+	ip := net.ParseIP(state.IP())
+	if ip4 := ip.To4(); ip4 != nil {
+		return 1, ip4, 32
+	}
+	return 2, ip, 128
+}
+
 // ResponseWriter is a response writer that caches the reply message.
 type ResponseWriter struct {
 	dns.ResponseWriter
 	*Cache
 	state request.Request
 
+	// subnet receives the reply's ECS option when a plugin further down the chain removes it, because
+	// the client didn't send one. See withSubnet.
+	subnet *dns.EDNS0_SUBNET
+
 	prefetch bool // When true write nothing back to the client.
 }
 
+// withSubnet returns a context that lets w see the reply's ECS option, even when a plugin further down
+// the chain removes it from the reply.
+func (w *ResponseWriter) withSubnet(ctx context.Context) context.Context {
+	if edns.Subnet(w.state.Req) != nil {
+		return ctx
+	}
+	w.subnet = new(dns.EDNS0_SUBNET)
+	return edns.WithReplySubnet(ctx, w.subnet)
+}
+
 // WriteMsg implements the dns.ResponseWriter interface.
 func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	do := false
@@ -128,6 +194,9 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	if msgTTL < duration {
 		duration = msgTTL
 	}
+	if mt == response.OtherError && res.Rcode == dns.RcodeServerFailure {
+		duration = w.failttl
+	}
 
 	if key != -1 && duration > 0 {
 
@@ -145,6 +214,16 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 		return nil
 	}
 
+	// The client subnet option may have been added by a plugin further down the chain; don't hand
+	// it to a client that didn't ask for it.
+	if edns.Subnet(w.state.Req) == nil {
+		edns.RemoveSubnet(res)
+	}
+
+	if w.keepttl {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
 	// Apply capped TTL to this reply to avoid jarring TTL experience 1799 -> 8 (e.g.)
 	ttl := uint32(duration.Seconds())
 	for i := range res.Answer {
@@ -161,27 +240,58 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	return w.ResponseWriter.WriteMsg(res)
 }
 
+// newItem returns an item for m. If m lost its ECS option on the way to us, the one saved in w.subnet is used.
+func (w *ResponseWriter) newItem(m *dns.Msg, duration time.Duration) *item {
+	i := newItem(m, w.now(), duration)
+	if i.scope == 0 && w.subnet != nil && edns.Subnet(m) == nil {
+		i.setSubnet(w.subnet)
+	}
+	return i
+}
+
 func (w *ResponseWriter) set(m *dns.Msg, key int, mt response.Type, duration time.Duration) {
 	if key == -1 || duration == 0 {
 		return
 	}
 
+	qname := m.Question[0].Name
+
 	switch mt {
 	case response.NoError, response.Delegation:
-		i := newItem(m, w.now(), duration)
-		w.pcache.Add(uint32(key), i)
+		if plugin.Zones(w.pexcept).Matches(qname) != "" {
+			return
+		}
+		i := w.newItem(m, duration)
+		add(w.pcache, uint32(key), i, duration)
 
 	case response.NameError, response.NoData:
-		i := newItem(m, w.now(), duration)
-		w.ncache.Add(uint32(key), i)
+		if plugin.Zones(w.nexcept).Matches(qname) != "" {
+			return
+		}
+		i := w.newItem(m, duration)
+		add(w.ncache, uint32(key), i, duration)
 
 	case response.OtherError:
-		// don't cache these
+		// Only SERVFAIL gets here, it is cached as a denial for the (short) servfail duration.
+		if m.Rcode != dns.RcodeServerFailure || plugin.Zones(w.nexcept).Matches(qname) != "" {
+			return
+		}
+		i := w.newItem(m, duration)
+		add(w.ncache, uint32(key), i, duration)
 	default:
 		log.Warningf("Caching called with unknown classification: %d", mt)
 	}
 }
 
+// add adds i to s. If i is only valid for a client subnet it is stored under the subnet's key as well, the
+// copy under the question's key tells get to look for the subnet specific one.
+func add(s storage, key uint32, i *item, ttl time.Duration) {
+	if i.scope > 0 {
+		s.Add(subnetKey(key, i.family, i.scope, i.address), i, ttl)
+	}
+	s.Add(key, i, ttl)
+}
+
 // Write implements the dns.ResponseWriter interface.
 func (w *ResponseWriter) Write(buf []byte) (int, error) {
 	log.Warning("Caching called with Write: not caching reply")
@@ -197,6 +307,8 @@ const (
 	maxNTTL     = 30 * time.Minute
 	failSafeTTL = 5 * time.Second
 
+	maxServfailTTL = 5 * time.Minute // maximum duration SERVFAIL responses can be cached.
+
 	defaultCap = 10000 // default capacity of the cache.
 
 	// Success is the class for caching positive caching.
@@ -7,8 +7,10 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
@@ -171,9 +173,9 @@ func TestCache(t *testing.T) {
 		crr.set(m, k, mt, c.pttl)
 
 		name := plugin.Name(m.Question[0].Name).Normalize()
-		qtype := m.Question[0].Qtype
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
 
-		i, _ := c.get(time.Now().UTC(), name, qtype, do)
+		i, _ := c.get(time.Now().UTC(), state, do)
 		ok := i != nil
 
 		if ok != tc.shouldCache {
@@ -182,7 +184,7 @@ func TestCache(t *testing.T) {
 		}
 
 		if ok {
-			resp := i.toMsg(m, time.Now().UTC())
+			resp := i.toMsg(m, time.Now().UTC(), false)
 
 			if !test.Header(t, tc.Case, resp) {
 				t.Logf("%v\n", resp)
@@ -270,3 +272,121 @@ func zeroTTLBackend() plugin.Handler {
 		return dns.RcodeSuccess, nil
 	})
 }
+
+func TestCacheServfail(t *testing.T) {
+	c := New()
+	c.failttl = 5 * time.Second
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	calls := 0
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		calls++
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		w.WriteMsg(m)
+		return dns.RcodeServerFailure, nil
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(ctx, rec, req)
+		if rec.Msg.Rcode != dns.RcodeServerFailure {
+			t.Errorf("Expected SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected SERVFAIL to be cached, backend called %d times", calls)
+	}
+
+	now = now.Add(6 * time.Second)
+	c.ServeDNS(ctx, &test.ResponseWriter{}, req)
+	if calls != 2 {
+		t.Errorf("Expected cached SERVFAIL to expire, backend called %d times", calls)
+	}
+
+	c.failttl = 0
+	c.ncache = newMemory(defaultCap)
+	c.ServeDNS(ctx, &test.ResponseWriter{}, req)
+	c.ServeDNS(ctx, &test.ResponseWriter{}, req)
+	if calls != 4 {
+		t.Errorf("Expected SERVFAIL not to be cached, backend called %d times", calls)
+	}
+}
+
+func TestCacheKeepTTL(t *testing.T) {
+	c := New()
+	c.keepttl = true
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Next = BackendHandler()
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	ctx := context.TODO()
+
+	c.ServeDNS(ctx, &test.ResponseWriter{}, req)
+	now = now.Add(100 * time.Second)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(ctx, rec, req)
+	if c.pcache.Len() != 1 {
+		t.Fatalf("Expected reply to be cached")
+	}
+	if ttl := rec.Msg.Answer[0].Header().Ttl; ttl != 303 {
+		t.Errorf("Expected TTL %d, got %d", 303, ttl)
+	}
+
+	// Without keepttl the remaining TTL is returned.
+	c.keepttl = false
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(ctx, rec, req)
+	if ttl := rec.Msg.Answer[0].Header().Ttl; ttl != 203 {
+		t.Errorf("Expected TTL %d, got %d", 203, ttl)
+	}
+}
+
+func TestCacheDisable(t *testing.T) {
+	c := New()
+	c.pexcept = []string{"example.org."}
+	c.nexcept = []string{"example.net."}
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Name {
+		case "nx.example.org.", "nx.example.net.":
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example. 300 IN SOA ns.example. hostmaster.example. 1 7200 3600 1209600 300")}
+		default:
+			m.Answer = []dns.RR{test.A(r.Question[0].Name + " 303 IN A 127.0.0.53")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	tests := []struct {
+		qname    string
+		positive int
+		negative int
+	}{
+		{"a.example.org.", 0, 0},
+		{"nx.example.org.", 0, 1},
+		{"a.example.net.", 1, 1},
+		{"nx.example.net.", 1, 1},
+	}
+	ctx := context.TODO()
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion(tc.qname, dns.TypeA)
+		c.ServeDNS(ctx, &test.ResponseWriter{}, req)
+		if x := c.pcache.Len(); x != tc.positive {
+			t.Errorf("Test %d: expected %d items in success cache, got %d", i, tc.positive, x)
+		}
+		if x := c.ncache.Len(); x != tc.negative {
+			t.Errorf("Test %d: expected %d items in denial cache, got %d", i, tc.negative, x)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestCacheSubnet(t *testing.T) {
+	c := New()
+	c.Next = subnetBackend()
+
+	tests := []struct {
+		client   string
+		expected string
+		cached   bool
+	}{
+		{"10.1.1.1", "10.1.1.0", false},
+		{"10.2.2.2", "10.2.2.0", false},
+		{"10.1.1.100", "10.1.1.0", true}, // same /24
+		{"10.2.2.200", "10.2.2.0", true}, // same /24
+		{"10.3.3.3", "10.3.3.0", false},
+	}
+
+	for i, tc := range tests {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		edns.SetSubnet(req, edns.NewSubnet(net.ParseIP(tc.client), 32, 128))
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, req)
+
+		if a := rec.Msg.Answer[0].(*dns.A).A.String(); a != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, a)
+		}
+		// Cached replies don't come from an authoritative server.
+		if cached := !rec.Msg.Authoritative; cached != tc.cached {
+			t.Errorf("Test %d: expected cached to be %t, got %t", i, tc.cached, cached)
+		}
+		e := edns.Subnet(rec.Msg)
+		if e == nil {
+			t.Fatalf("Test %d: expected client subnet option in reply", i)
+		}
+		if e.SourceScope != 24 {
+			t.Errorf("Test %d: expected scope prefix length %d, got %d", i, 24, e.SourceScope)
+		}
+	}
+}
+
+func TestCacheSubnetStrip(t *testing.T) {
+	c := New()
+	c.Next = subnetBackend()
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+
+	if edns.Subnet(rec.Msg) != nil {
+		t.Errorf("expected no client subnet option in reply for a client that didn't send one")
+	}
+}
+
+// subnetBackend returns the client subnet's address, like a CDN would return a nearby address. Like the
+// forward plugin it adds a client subnet option if the request has none. The answer's scope is a /24.
+func subnetBackend() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		e := edns.Subnet(r)
+		if e == nil {
+			e = edns.NewSubnet(net.ParseIP("10.240.0.1"), 24, 56)
+		}
+		e1 := *e
+		edns.TruncateSubnet(&e1, 24, 56)
+		e1.SourceScope = 24
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Response, m.RecursionAvailable, m.Authoritative = true, true, true
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A " + e1.Address.String())}
+		edns.SetSubnet(m, &e1)
+
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestCacheSubnetRequestUntouched(t *testing.T) {
+	c := New()
+	c.Next = subnetBackend()
+
+	for i := 0; i < 2; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		edns.SetSubnet(req, edns.NewSubnet(net.ParseIP("10.1.1.1"), 32, 128))
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, req)
+
+		if e := edns.Subnet(req); e.SourceScope != 0 {
+			t.Errorf("Test %d: expected client request to be left alone, got scope prefix length %d", i, e.SourceScope)
+		}
+	}
+}
+
+func TestCacheSubnetSaved(t *testing.T) {
+	c := New()
+	// Like the forward plugin, remove the client subnet option for a client that didn't send one.
+	next := subnetBackend()
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := next.ServeDNS(ctx, rec, r)
+		edns.SaveReplySubnet(ctx, rec.Msg)
+		edns.RemoveSubnet(rec.Msg)
+		w.WriteMsg(rec.Msg)
+		return rcode, err
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	c.ServeDNS(context.TODO(), &test.ResponseWriter{}, req)
+
+	// The reply is only valid for the client's /24, so a client elsewhere must not get it from the cache.
+	req = new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	edns.SetSubnet(req, edns.NewSubnet(net.ParseIP("10.3.3.3"), 32, 128))
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+
+	if a := rec.Msg.Answer[0].(*dns.A).A.String(); a != "10.3.3.0" {
+		t.Errorf("expected %s, got %s", "10.3.3.0", a)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -28,11 +29,20 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	now := c.now().UTC()
 
-	i, ttl := c.get(now, qname, qtype, do)
+	i, ttl := c.get(now, state, do)
 	if i != nil && ttl > 0 {
-		resp := i.toMsg(r, now)
+		resp := i.toMsg(r, now, c.keepttl)
 
 		state.SizeAndDo(resp)
+		if i.scope > 0 {
+			if e := edns.Subnet(resp); e != nil {
+				// SizeAndDo may have added the client's own OPT record, copy it before we change it.
+				copyOPT(resp)
+				e1 := *e
+				e1.SourceScope = i.scope
+				edns.SetSubnet(resp, &e1)
+			}
+		}
 		resp, _ = state.Scrub(resp)
 		w.WriteMsg(resp)
 
@@ -47,7 +57,7 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 					// that we've gathered sofar. See we copy the frequencies info back
 					// into the new item that was stored in the cache.
 					prr := &ResponseWriter{ResponseWriter: w, Cache: c, prefetch: true, state: state}
-					plugin.NextOrFailure(c.Name(), c.Next, ctx, prr, r)
+					plugin.NextOrFailure(c.Name(), c.Next, prr.withSubnet(ctx), prr, r)
 
 					if i1 := c.exists(qname, qtype, do); i1 != nil {
 						i1.Freq.Reset(now, i.Freq.Hits())
@@ -59,35 +69,64 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	}
 
 	crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state}
-	return plugin.NextOrFailure(c.Name(), c.Next, ctx, crr, r)
+	return plugin.NextOrFailure(c.Name(), c.Next, crr.withSubnet(ctx), crr, r)
 }
 
 // Name implements the Handler interface.
 func (c *Cache) Name() string { return "cache" }
 
-func (c *Cache) get(now time.Time, qname string, qtype uint16, do bool) (*item, int) {
-	k := hash(qname, qtype, do)
+func (c *Cache) get(now time.Time, state request.Request, do bool) (*item, int) {
+	k := hash(state.Name(), state.QType(), do)
 
-	if i, ok := c.ncache.Get(k); ok {
-		cacheHits.WithLabelValues(Denial).Inc()
-		return i.(*item), i.(*item).ttl(now)
+	if i, ok := c.ncache.Get(k); ok && i.matches(state.Name(), state.QType(), do) {
+		if i1 := subnet(c.ncache, k, i, state); i1 != nil {
+			cacheHits.WithLabelValues(Denial).Inc()
+			return i1, i1.ttl(now)
+		}
 	}
 
-	if i, ok := c.pcache.Get(k); ok {
-		cacheHits.WithLabelValues(Success).Inc()
-		return i.(*item), i.(*item).ttl(now)
+	if i, ok := c.pcache.Get(k); ok && i.matches(state.Name(), state.QType(), do) {
+		if i1 := subnet(c.pcache, k, i, state); i1 != nil {
+			cacheHits.WithLabelValues(Success).Inc()
+			return i1, i1.ttl(now)
+		}
 	}
 	cacheMisses.Inc()
 	return nil, 0
 }
 
+// subnet returns i if it is valid for the client in state. If i is only valid for a client subnet, the
+// item stored for the client's subnet is returned instead, or nil when there is none.
+func subnet(s storage, k uint32, i *item, state request.Request) *item {
+	if i.scope == 0 {
+		return i
+	}
+	family, addr, netmask := clientSubnet(state)
+	if family != i.family || netmask < i.scope {
+		return nil
+	}
+	if i1, ok := s.Get(subnetKey(k, family, i.scope, addr)); ok && i1.matches(i.name, i.qtype, i.do) {
+		return i1
+	}
+	return nil
+}
+
+// copyOPT replaces the OPT record in m with a copy.
+func copyOPT(m *dns.Msg) {
+	for j, rr := range m.Extra {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			m.Extra[j] = dns.Copy(rr)
+		}
+	}
+}
+
 func (c *Cache) exists(qname string, qtype uint16, do bool) *item {
 	k := hash(qname, qtype, do)
-	if i, ok := c.ncache.Get(k); ok {
-		return i.(*item)
+	if i, ok := c.ncache.Get(k); ok && i.matches(qname, qtype, do) {
+		return i
 	}
-	if i, ok := c.pcache.Get(k); ok {
-		return i.(*item)
+	if i, ok := c.pcache.Get(k); ok && i.matches(qname, qtype, do) {
+		return i
 	}
 	return nil
 }
@@ -134,6 +173,13 @@ var (
 		Name:      "drops_total",
 		Help:      "The number responses that are not cached, because the reply is malformed.",
 	})
+
+	cacheStorageErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "cache",
+		Name:      "storage_errors_total",
+		Help:      "The number of errors when adding items to, or getting items from, a shared storage.",
+	})
 )
 
 var once sync.Once
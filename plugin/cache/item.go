@@ -1,9 +1,12 @@
 package cache
 
 import (
+	"net"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/plugin/cache/freq"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/miekg/dns"
 )
@@ -20,6 +23,16 @@ type item struct {
 	origTTL uint32
 	stored  time.Time
 
+	// The question the item answers, items are stored under a hash of it and hashes can collide.
+	name  string
+	qtype uint16
+	do    bool
+
+	// EDNS0 client subnet, when scope is non zero the item is only valid for clients in address/scope.
+	family  uint16
+	scope   uint8
+	address net.IP
+
 	*freq.Freq
 }
 
@@ -43,6 +56,15 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	}
 	i.Extra = i.Extra[:j]
 
+	i.name, i.qtype = m.Question[0].Name, m.Question[0].Qtype
+	if opt := m.IsEdns0(); opt != nil {
+		i.do = opt.Do()
+	}
+
+	if e := edns.Subnet(m); e != nil {
+		i.setSubnet(e)
+	}
+
 	i.origTTL = uint32(d.Seconds())
 	i.stored = now.UTC()
 
@@ -51,9 +73,25 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	return i
 }
 
+// setSubnet makes i valid only for clients in the subnet of e, if e has a non zero scope prefix length.
+func (i *item) setSubnet(e *dns.EDNS0_SUBNET) {
+	if e.SourceScope == 0 {
+		return
+	}
+	i.family = e.Family
+	i.address = e.Address
+	// A scope longer than the source prefix length can't be honored, we only know the latter.
+	i.scope = e.SourceScope
+	if e.SourceNetmask < i.scope {
+		i.scope = e.SourceNetmask
+	}
+}
+
 // toMsg turns i into a message, it tailors the reply to m.
 // The Authoritative bit is always set to 0, because the answer is from the cache.
-func (i *item) toMsg(m *dns.Msg, now time.Time) *dns.Msg {
+// When keepttl is true the records keep the TTLs they were received with, otherwise
+// their TTL is the remaining TTL of the item.
+func (i *item) toMsg(m *dns.Msg, now time.Time, keepttl bool) *dns.Msg {
 	m1 := new(dns.Msg)
 	m1.SetReply(m)
 
@@ -67,24 +105,39 @@ func (i *item) toMsg(m *dns.Msg, now time.Time) *dns.Msg {
 	m1.Ns = make([]dns.RR, len(i.Ns))
 	m1.Extra = make([]dns.RR, len(i.Extra))
 
-	ttl := uint32(i.ttl(now))
 	for j, r := range i.Answer {
 		m1.Answer[j] = dns.Copy(r)
-		m1.Answer[j].Header().Ttl = ttl
 	}
 	for j, r := range i.Ns {
 		m1.Ns[j] = dns.Copy(r)
-		m1.Ns[j].Header().Ttl = ttl
 	}
 	for j, r := range i.Extra {
 		m1.Extra[j] = dns.Copy(r)
-		if m1.Extra[j].Header().Rrtype != dns.TypeOPT {
-			m1.Extra[j].Header().Ttl = ttl
+	}
+	if keepttl {
+		return m1
+	}
+
+	ttl := uint32(i.ttl(now))
+	for _, r := range m1.Answer {
+		r.Header().Ttl = ttl
+	}
+	for _, r := range m1.Ns {
+		r.Header().Ttl = ttl
+	}
+	for _, r := range m1.Extra {
+		if r.Header().Rrtype != dns.TypeOPT {
+			r.Header().Ttl = ttl
 		}
 	}
 	return m1
 }
 
+// matches returns true if i is the answer to the question qname, qtype and do.
+func (i *item) matches(qname string, qtype uint16, do bool) bool {
+	return i.qtype == qtype && i.do == do && strings.EqualFold(i.name, qname)
+}
+
 func (i *item) ttl(now time.Time) int {
 	ttl := int(i.origTTL) - int(now.UTC().Sub(i.stored).Seconds())
 	return ttl
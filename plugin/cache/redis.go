@@ -0,0 +1,409 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/cache/freq"
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	redisTimeout    = 500 * time.Millisecond // timeout for connecting to the server, and for each command.
+	redisIdle       = 16                     // maximum number of idle connections kept open.
+	redisBackoff    = 1 * time.Second        // first wait before trying an unreachable server again.
+	redisMaxBackoff = 30 * time.Second       // the wait doubles with every failed attempt, up to this.
+	redisPort       = "6379"
+
+	redisPrefix = "coredns:cache:" // prefix of all keys we set.
+)
+
+// redisStorage is a storage that keeps the items in a server speaking the Redis protocol, so several
+// CoreDNS instances can share a cache. Items are set with an expiry of their TTL, the server removes them.
+// While the server can't be reached the items are kept in memory.
+type redisStorage struct {
+	client   *redisClient
+	prefix   string
+	fallback *memory
+}
+
+func newRedisStorage(client *redisClient, class string, size int) *redisStorage {
+	return &redisStorage{client: client, prefix: redisPrefix + class + ":", fallback: newMemory(size)}
+}
+
+func (r *redisStorage) key(key uint32) string { return r.prefix + strconv.FormatUint(uint64(key), 16) }
+
+// Add implements the storage interface.
+func (r *redisStorage) Add(key uint32, i *item, ttl time.Duration) {
+	ms := int64(ttl / time.Millisecond)
+	if ms <= 0 {
+		return
+	}
+	buf, err := encodeItem(i)
+	if err != nil {
+		cacheStorageErrors.Inc()
+		return
+	}
+	if _, err := r.client.do("SET", r.key(key), string(buf), "PX", strconv.FormatInt(ms, 10)); err != nil {
+		cacheStorageErrors.Inc()
+		r.fallback.Add(key, i, ttl)
+	}
+}
+
+// Get implements the storage interface.
+func (r *redisStorage) Get(key uint32) (*item, bool) {
+	v, err := r.client.do("GET", r.key(key))
+	if err != nil {
+		cacheStorageErrors.Inc()
+		return r.fallback.Get(key)
+	}
+	buf, ok := v.([]byte)
+	if !ok {
+		return nil, false
+	}
+	i, err := decodeItem(buf)
+	if err != nil {
+		cacheStorageErrors.Inc()
+		return nil, false
+	}
+	return i, true
+}
+
+// Len implements the storage interface. The server is shared, we can't tell how many items are ours.
+func (r *redisStorage) Len() int { return 0 }
+
+// itemVersion is the version of the encoding of an item, it is the first byte of an encoded item.
+const itemVersion = 2
+
+// encodeItem encodes i as: version (1 byte), time stored (8 bytes, Unix seconds), original TTL (4 bytes),
+// client subnet family (2 bytes), scope (1 byte), DO bit (1 byte), address length (1 byte), address, and
+// the message holding the question, flags and sections of i in wire format.
+func encodeItem(i *item) ([]byte, error) {
+	m := new(dns.Msg)
+	m.Question = []dns.Question{{Name: i.name, Qtype: i.qtype, Qclass: dns.ClassINET}}
+	m.Rcode = i.Rcode
+	m.Authoritative = i.Authoritative
+	m.AuthenticatedData = i.AuthenticatedData
+	m.RecursionAvailable = i.RecursionAvailable
+	m.Answer = i.Answer
+	m.Ns = i.Ns
+	m.Extra = i.Extra
+	m.Compress = true
+	msg, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 18, 18+len(i.address)+len(msg))
+	buf[0] = itemVersion
+	binary.BigEndian.PutUint64(buf[1:], uint64(i.stored.Unix()))
+	binary.BigEndian.PutUint32(buf[9:], i.origTTL)
+	binary.BigEndian.PutUint16(buf[13:], i.family)
+	buf[15] = i.scope
+	if i.do {
+		buf[16] = 1
+	}
+	buf[17] = byte(len(i.address))
+	buf = append(buf, i.address...)
+	return append(buf, msg...), nil
+}
+
+// decodeItem decodes an item encoded with encodeItem.
+func decodeItem(buf []byte) (*item, error) {
+	if len(buf) < 18 || buf[0] != itemVersion {
+		return nil, errItem
+	}
+	n := int(buf[17])
+	if len(buf) < 18+n {
+		return nil, errItem
+	}
+	m := new(dns.Msg)
+	if err := m.Unpack(buf[18+n:]); err != nil {
+		return nil, err
+	}
+	if len(m.Question) != 1 {
+		return nil, errItem
+	}
+
+	i := &item{
+		Rcode:              m.Rcode,
+		Authoritative:      m.Authoritative,
+		AuthenticatedData:  m.AuthenticatedData,
+		RecursionAvailable: m.RecursionAvailable,
+		Answer:             m.Answer,
+		Ns:                 m.Ns,
+		Extra:              m.Extra,
+		stored:             time.Unix(int64(binary.BigEndian.Uint64(buf[1:])), 0).UTC(),
+		origTTL:            binary.BigEndian.Uint32(buf[9:]),
+		family:             binary.BigEndian.Uint16(buf[13:]),
+		scope:              buf[15],
+		name:               m.Question[0].Name,
+		qtype:              m.Question[0].Qtype,
+		do:                 buf[16] == 1,
+		Freq:               new(freq.Freq),
+	}
+	if n > 0 {
+		i.address = net.IP(append([]byte{}, buf[18:18+n]...))
+	}
+	return i, nil
+}
+
+var (
+	errItem      = errors.New("malformed cache item")
+	errRedisDown = errors.New("redis server unreachable")
+)
+
+// redisClient is a client for a server speaking the Redis protocol (RESP). It keeps a pool of idle
+// connections, a connection is only used by one command at a time. When the server can't be reached,
+// commands fail right away until it is time to try again.
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+
+	mu      sync.Mutex
+	idle    []*redisConn
+	closed  bool
+	retry   time.Time     // when to try an unreachable server again, zero when it is reachable
+	backoff time.Duration // current wait between attempts
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// redisError is an error returned by the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// newRedisClient returns a client for the server at s, which is either a HOST[:PORT] or a URL of the
+// form redis://[:PASSWORD@]HOST[:PORT][/DB].
+func newRedisClient(s string) (*redisClient, error) {
+	r := &redisClient{addr: s}
+	if strings.HasPrefix(s, "redis://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		r.addr = u.Host
+		if u.Host != "" && u.Port() == "" {
+			r.addr = net.JoinHostPort(u.Hostname(), redisPort)
+		}
+		if u.User != nil {
+			if p, ok := u.User.Password(); ok {
+				r.password = p
+			} else {
+				r.password = u.User.Username()
+			}
+		}
+		if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+			r.db, err = strconv.Atoi(db)
+			if err != nil || r.db < 0 {
+				return nil, fmt.Errorf("invalid redis database: %q", db)
+			}
+		}
+	}
+	if r.addr == "" {
+		return nil, fmt.Errorf("no redis server in %q", s)
+	}
+	if _, _, err := net.SplitHostPort(r.addr); err != nil {
+		r.addr = net.JoinHostPort(r.addr, redisPort)
+	}
+	return r, nil
+}
+
+// do sends the command args to the server and returns the reply: a string, an int64, a []byte, a
+// []interface{} or nil.
+func (r *redisClient) do(args ...string) (interface{}, error) {
+	if !r.available() {
+		return nil, errRedisDown
+	}
+	c, err := r.get()
+	if err != nil {
+		r.failed(err)
+		return nil, err
+	}
+	v, err := c.do(args...)
+	if err != nil {
+		if _, ok := err.(redisError); !ok {
+			// The state of the connection is unknown.
+			c.Close()
+			r.failed(err)
+			return nil, err
+		}
+	}
+	r.put(c)
+	return v, err
+}
+
+// available returns true if the server should be tried. Once the wait is over, only the first caller
+// tries; the others keep failing until the attempt succeeds or the next wait is over.
+func (r *redisClient) available() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.retry.IsZero() {
+		return true
+	}
+	now := time.Now()
+	if now.Before(r.retry) {
+		return false
+	}
+	r.retry = now.Add(r.backoff)
+	return true
+}
+
+// failed marks the server unreachable and doubles the wait before the next attempt.
+func (r *redisClient) failed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.backoff == 0 {
+		log.Warningf("Redis server %s is unreachable, caching in memory: %s", r.addr, err)
+		r.backoff = redisBackoff
+	} else if r.backoff *= 2; r.backoff > redisMaxBackoff {
+		r.backoff = redisMaxBackoff
+	}
+	r.retry = time.Now().Add(r.backoff)
+}
+
+// get returns an idle connection, or a new one.
+func (r *redisClient) get() (*redisConn, error) {
+	r.mu.Lock()
+	if n := len(r.idle); n > 0 {
+		c := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return c, nil
+	}
+	r.mu.Unlock()
+
+	conn, err := net.DialTimeout("tcp", r.addr, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	if r.password != "" {
+		if _, err := c.do("AUTH", r.password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(r.db)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// put returns c to the pool of idle connections, the server is reachable.
+func (r *redisClient) put(c *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.retry.IsZero() {
+		log.Infof("Redis server %s is reachable again", r.addr)
+		r.retry, r.backoff = time.Time{}, 0
+	}
+	if r.closed || len(r.idle) >= redisIdle {
+		c.Close()
+		return
+	}
+	r.idle = append(r.idle, c)
+}
+
+// Close closes the idle connections, connections in use are closed when they are returned.
+func (r *redisClient) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.idle {
+		c.Close()
+	}
+	r.idle = nil
+	r.closed = true
+	return nil
+}
+
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	c.SetDeadline(time.Now().Add(redisTimeout))
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, a := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(a)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, a...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read reads a reply from the server.
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed redis reply: %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], err = c.read(); err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+			}
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("malformed redis reply: %q", line)
+}
@@ -0,0 +1,344 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// redisServer is a stand-in for a Redis server, it implements the few commands the cache uses.
+type redisServer struct {
+	ln       net.Listener
+	password string
+
+	sync.Mutex
+	values map[string]redisValue
+	now    time.Time
+}
+
+type redisValue struct {
+	v      string
+	expire time.Time
+}
+
+func newRedisServer(t *testing.T, password string) *redisServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	s := &redisServer{ln: ln, password: password, values: map[string]redisValue{}, now: time.Now()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *redisServer) Addr() string { return s.ln.Addr().String() }
+
+func (s *redisServer) Close() { s.ln.Close() }
+
+// advance moves the clock of the server forward by d.
+func (s *redisServer) advance(d time.Duration) {
+	s.Lock()
+	s.now = s.now.Add(d)
+	s.Unlock()
+}
+
+func (s *redisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	auth := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !auth && cmd != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		switch {
+		case cmd == "AUTH" && len(args) == 2:
+			if args[1] != s.password {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			auth = true
+			io.WriteString(conn, "+OK\r\n")
+		case cmd == "SELECT" && len(args) == 2:
+			io.WriteString(conn, "+OK\r\n")
+		case cmd == "SET" && len(args) == 5 && strings.ToUpper(args[3]) == "PX":
+			ms, _ := strconv.Atoi(args[4])
+			s.Lock()
+			s.values[args[1]] = redisValue{v: args[2], expire: s.now.Add(time.Duration(ms) * time.Millisecond)}
+			s.Unlock()
+			io.WriteString(conn, "+OK\r\n")
+		case cmd == "GET" && len(args) == 2:
+			s.Lock()
+			v, ok := s.values[args[1]]
+			if ok && !s.now.Before(v.expire) {
+				delete(s.values, args[1])
+				ok = false
+			}
+			s.Unlock()
+			if !ok {
+				io.WriteString(conn, "$-1\r\n")
+				continue
+			}
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v.v), v.v)
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("malformed command: %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		l, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, l+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:l])
+	}
+	return args, nil
+}
+
+func newRedisCache(t *testing.T, addr string) *Cache {
+	r, err := newRedisClient(addr)
+	if err != nil {
+		t.Fatalf("Failed to create redis client: %s", err)
+	}
+	c := New()
+	c.redis = r
+	c.pcache = newRedisStorage(r, Success, defaultCap)
+	c.ncache = newRedisStorage(r, Denial, defaultCap)
+	return c
+}
+
+func TestRedisStorage(t *testing.T) {
+	s := newRedisServer(t, "secret")
+	defer s.Close()
+
+	// Two caches sharing a server, as two replicas would.
+	c1 := newRedisCache(t, "redis://:secret@"+s.Addr()+"/2")
+	defer c1.redis.Close()
+	c2 := newRedisCache(t, "redis://:secret@"+s.Addr()+"/2")
+	defer c2.redis.Close()
+
+	calls := 0
+	c1.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		calls++
+		return BackendHandler().ServeDNS(ctx, w, r)
+	})
+	c2.Next = c1.Next
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	ctx := context.TODO()
+
+	c1.ServeDNS(ctx, &test.ResponseWriter{}, req)
+	if calls != 1 {
+		t.Fatalf("Expected backend to be called once, got %d", calls)
+	}
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c2.ServeDNS(ctx, rec, req)
+	if calls != 1 {
+		t.Errorf("Expected reply from shared cache, backend called %d times", calls)
+	}
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != "127.0.0.53" {
+		t.Errorf("Expected cached answer, got %v", rec.Msg)
+	}
+
+	// The server expires the item after its TTL.
+	s.advance(304 * time.Second)
+	c2.ServeDNS(ctx, &test.ResponseWriter{}, req)
+	if calls != 2 {
+		t.Errorf("Expected item to expire, backend called %d times", calls)
+	}
+}
+
+func TestRedisStorageDown(t *testing.T) {
+	s := newRedisServer(t, "")
+	addr := s.Addr()
+	s.Close()
+
+	c := newRedisCache(t, addr)
+	defer c.redis.Close()
+	calls := 0
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		calls++
+		return BackendHandler().ServeDNS(ctx, w, r)
+	})
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected answer from backend, got %v", rec.Msg)
+	}
+
+	// The server isn't tried again for a while, the response is cached in memory instead.
+	if c.redis.retry.IsZero() || c.redis.backoff != redisBackoff {
+		t.Errorf("Expected server to be marked unreachable, retry %s and backoff %s", c.redis.retry, c.redis.backoff)
+	}
+	if _, err := c.redis.do("GET", "a"); err != errRedisDown {
+		t.Errorf("Expected %q, got %v", errRedisDown, err)
+	}
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if calls != 1 || rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected answer from the memory cache, backend called %d times", calls)
+	}
+}
+
+func TestRedisStorageCollision(t *testing.T) {
+	s := newRedisServer(t, "")
+	defer s.Close()
+
+	c := newRedisCache(t, s.Addr())
+	defer c.redis.Close()
+	c.Next = BackendHandler()
+
+	// Store the answer for example.org. under the key of example.net., as if their hashes collided.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Answer = []dns.RR{test.A("example.org. 300 IN A 127.0.0.1")}
+	c.pcache.Add(hash("example.net.", dns.TypeA, false), newItem(m, time.Now(), 300*time.Second), 300*time.Second)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.net.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Name != "example.net." {
+		t.Errorf("Expected answer for example.net. from backend, got %v", rec.Msg)
+	}
+}
+
+func TestRedisAuth(t *testing.T) {
+	s := newRedisServer(t, "secret")
+	defer s.Close()
+
+	r, _ := newRedisClient("redis://:wrong@" + s.Addr())
+	defer r.Close()
+	if _, err := r.do("GET", "a"); err == nil {
+		t.Errorf("Expected error with wrong password")
+	}
+}
+
+func TestEncodeItem(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Rcode = dns.RcodeSuccess
+	m.AuthenticatedData = true
+	m.RecursionAvailable = true
+	m.Answer = []dns.RR{test.A("example.org. 300 IN A 127.0.0.1")}
+	m.Ns = []dns.RR{test.NS("example.org. 300 IN NS ns.example.org.")}
+	m.Extra = []dns.RR{test.A("ns.example.org. 300 IN A 127.0.0.2")}
+
+	i := newItem(m, time.Unix(1500000000, 0), 300*time.Second)
+	i.family, i.scope, i.address = 1, 24, net.ParseIP("10.1.1.0").To4()
+
+	buf, err := encodeItem(i)
+	if err != nil {
+		t.Fatalf("Failed to encode item: %s", err)
+	}
+	i1, err := decodeItem(buf)
+	if err != nil {
+		t.Fatalf("Failed to decode item: %s", err)
+	}
+	if i1.Rcode != i.Rcode || i1.AuthenticatedData != i.AuthenticatedData || i1.RecursionAvailable != i.RecursionAvailable {
+		t.Errorf("Expected flags of %v, got %v", i, i1)
+	}
+	if !i1.stored.Equal(i.stored) || i1.origTTL != i.origTTL {
+		t.Errorf("Expected stored %s and TTL %d, got %s and %d", i.stored, i.origTTL, i1.stored, i1.origTTL)
+	}
+	if !i1.matches("example.org.", dns.TypeA, false) {
+		t.Errorf("Expected item for example.org. A, got %s %d %t", i1.name, i1.qtype, i1.do)
+	}
+	if i1.family != i.family || i1.scope != i.scope || !i1.address.Equal(i.address) {
+		t.Errorf("Expected subnet %s/%d, got %s/%d", i.address, i.scope, i1.address, i1.scope)
+	}
+	for _, s := range [][2][]dns.RR{{i.Answer, i1.Answer}, {i.Ns, i1.Ns}, {i.Extra, i1.Extra}} {
+		if fmt.Sprint(s[0]) != fmt.Sprint(s[1]) {
+			t.Errorf("Expected %v, got %v", s[0], s[1])
+		}
+	}
+
+	if _, err := decodeItem(buf[:10]); err == nil {
+		t.Errorf("Expected error for truncated item")
+	}
+}
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		addr      string
+		password  string
+		db        int
+	}{
+		{"10.0.0.1", false, "10.0.0.1:6379", "", 0},
+		{"redis.example.org:6380", false, "redis.example.org:6380", "", 0},
+		{"redis://redis.example.org", false, "redis.example.org:6379", "", 0},
+		{"redis://:secret@10.0.0.1:6380/3", false, "10.0.0.1:6380", "secret", 3},
+		{"redis://secret@[::1]", false, "[::1]:6379", "secret", 0},
+		{"redis://10.0.0.1/db", true, "", "", 0},
+		{"redis:///1", true, "", "", 0},
+	}
+	for i, tc := range tests {
+		r, err := newRedisClient(tc.input)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if r.addr != tc.addr || r.password != tc.password || r.db != tc.db {
+			t.Errorf("Test %d: expected %s %q %d, got %s %q %d", i, tc.addr, tc.password, tc.db, r.addr, r.password, r.db)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
-	"github.com/coredns/coredns/plugin/pkg/cache"
 
 	"github.com/mholt/caddy"
 )
@@ -35,11 +34,16 @@ func setup(c *caddy.Controller) error {
 			metrics.MustRegister(c,
 				cacheSize, cacheCapacity,
 				cacheHits, cacheMisses,
-				cachePrefetches, cacheDrops)
+				cachePrefetches, cacheDrops,
+				cacheStorageErrors)
 		})
 		return nil
 	})
 
+	if ca.redis != nil {
+		c.OnShutdown(ca.redis.Close)
+	}
+
 	// Initialize all counters and gauges.
 	cacheSize.WithLabelValues(Success)
 	cacheSize.WithLabelValues(Denial)
@@ -83,6 +87,10 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 			}
 		}
 
+		for i := range origins {
+			origins[i] = plugin.Host(origins[i]).Normalize()
+		}
+
 		// Refinements? In an extra block.
 		for c.NextBlock() {
 			switch c.Val() {
@@ -166,19 +174,83 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 					}
 					ca.percentage = num
 				}
+			case "servfail":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if d < 0 || d > maxServfailTTL {
+					return nil, fmt.Errorf("servfail duration should fall in range [0, %s]: %s", maxServfailTTL, d)
+				}
+				ca.failttl = d
+			case "keepttl":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				ca.keepttl = true
+			case "disable":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				zones := origins
+				if len(args) > 1 {
+					zones = args[1:]
+					for i := range zones {
+						zones[i] = plugin.Host(zones[i]).Normalize()
+					}
+				}
+				switch args[0] {
+				case Success:
+					ca.pexcept = append(ca.pexcept, zones...)
+				case Denial:
+					ca.nexcept = append(ca.nexcept, zones...)
+				default:
+					return nil, fmt.Errorf("cache type for disable should be %q or %q: %q", Success, Denial, args[0])
+				}
+
+			case "storage":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				switch args[0] {
+				case "memory":
+					if len(args) != 1 {
+						return nil, c.ArgErr()
+					}
+					ca.redis = nil
+				case "redis":
+					if len(args) != 2 {
+						return nil, c.ArgErr()
+					}
+					r, err := newRedisClient(args[1])
+					if err != nil {
+						return nil, err
+					}
+					ca.redis = r
+				default:
+					return nil, fmt.Errorf("unknown cache storage: %q", args[0])
+				}
 
 			default:
 				return nil, c.ArgErr()
 			}
 		}
 
-		for i := range origins {
-			origins[i] = plugin.Host(origins[i]).Normalize()
-		}
 		ca.Zones = origins
 
-		ca.pcache = cache.New(ca.pcap)
-		ca.ncache = cache.New(ca.ncap)
+		if ca.redis != nil {
+			ca.pcache = newRedisStorage(ca.redis, Success, ca.pcap)
+			ca.ncache = newRedisStorage(ca.redis, Denial, ca.ncap)
+			continue
+		}
+		ca.pcache = newMemory(ca.pcap)
+		ca.ncache = newMemory(ca.ncap)
 	}
 
 	return ca, nil
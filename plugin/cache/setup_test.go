@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -94,3 +95,128 @@ func TestSetup(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupOptions(t *testing.T) {
+	tests := []struct {
+		input           string
+		shouldErr       bool
+		expectedFailttl time.Duration
+		expectedKeepttl bool
+		expectedPexcept []string
+		expectedNexcept []string
+	}{
+		{`cache`, false, 0, false, nil, nil},
+		{`cache {
+				servfail 10s
+			}`, false, 10 * time.Second, false, nil, nil},
+		{`cache {
+				keepttl
+			}`, false, 0, true, nil, nil},
+		{`cache {
+				disable success example.org
+				disable denial example.org example.net
+			}`, false, 0, false, []string{"example.org."}, []string{"example.org.", "example.net."}},
+
+		// fails
+		{`cache {
+				servfail
+			}`, true, 0, false, nil, nil},
+		{`cache {
+				servfail 10m
+			}`, true, 0, false, nil, nil},
+		{`cache {
+				servfail -1s
+			}`, true, 0, false, nil, nil},
+		{`cache {
+				keepttl 10
+			}`, true, 0, false, nil, nil},
+		{`cache {
+				disable
+			}`, true, 0, false, nil, nil},
+		{`cache {
+				disable error example.org
+			}`, true, 0, false, nil, nil},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ca, err := cacheParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %v: Expected error but found nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if ca.failttl != test.expectedFailttl {
+			t.Errorf("Test %v: Expected failttl %v but found: %v", i, test.expectedFailttl, ca.failttl)
+		}
+		if ca.keepttl != test.expectedKeepttl {
+			t.Errorf("Test %v: Expected keepttl %v but found: %v", i, test.expectedKeepttl, ca.keepttl)
+		}
+		if !reflect.DeepEqual(ca.pexcept, test.expectedPexcept) {
+			t.Errorf("Test %v: Expected disabled success zones %v but found: %v", i, test.expectedPexcept, ca.pexcept)
+		}
+		if !reflect.DeepEqual(ca.nexcept, test.expectedNexcept) {
+			t.Errorf("Test %v: Expected disabled denial zones %v but found: %v", i, test.expectedNexcept, ca.nexcept)
+		}
+	}
+}
+
+func TestSetupStorage(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		redis     string
+	}{
+		{`cache`, false, ""},
+		{`cache {
+				storage memory
+			}`, false, ""},
+		{`cache {
+				storage redis 10.0.0.1
+			}`, false, "10.0.0.1:6379"},
+		{`cache {
+				storage redis redis://:secret@redis.example.org:6380/1
+			}`, false, "redis.example.org:6380"},
+
+		// fails
+		{`cache {
+				storage
+			}`, true, ""},
+		{`cache {
+				storage redis
+			}`, true, ""},
+		{`cache {
+				storage memory 10.0.0.1
+			}`, true, ""},
+		{`cache {
+				storage memcache 10.0.0.1
+			}`, true, ""},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ca, err := cacheParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %v: Expected error but found nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.redis == "" {
+			if _, ok := ca.pcache.(*memory); !ok || ca.redis != nil {
+				t.Errorf("Test %v: Expected memory storage", i)
+			}
+			continue
+		}
+		if _, ok := ca.pcache.(*redisStorage); !ok || ca.redis == nil || ca.redis.addr != test.redis {
+			t.Errorf("Test %v: Expected redis storage at %s", i, test.redis)
+		}
+	}
+}
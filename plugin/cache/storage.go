@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+)
+
+// storage is where the cache keeps its items. The cache has a storage for successful and one for denial
+// of existence responses. Any error from the storage is counted and treated as a cache miss.
+type storage interface {
+	// Add stores i under key, it may be removed from the storage once ttl has passed.
+	Add(key uint32, i *item, ttl time.Duration)
+	// Get returns the item stored under key.
+	Get(key uint32) (*item, bool)
+	// Len returns the number of items in the storage, or 0 if the storage can't tell cheaply.
+	Len() int
+}
+
+// memory is the default storage, it keeps the items in memory in a cache.Cache. Expired items are
+// not removed, they are overwritten or evicted when the cache is full.
+type memory struct {
+	c *cache.Cache
+}
+
+func newMemory(size int) *memory { return &memory{c: cache.New(size)} }
+
+// Add implements the storage interface.
+func (m *memory) Add(key uint32, i *item, _ time.Duration) { m.c.Add(key, i) }
+
+// Get implements the storage interface.
+func (m *memory) Get(key uint32) (*item, bool) {
+	i, ok := m.c.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return i.(*item), true
+}
+
+// Len implements the storage interface.
+func (m *memory) Len() int { return m.c.Len() }
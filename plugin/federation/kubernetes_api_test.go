@@ -2,6 +2,7 @@ package federation
 
 import (
 	"github.com/coredns/coredns/plugin/kubernetes"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
 
 	api "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -9,123 +10,96 @@ import (
 
 type APIConnFederationTest struct{}
 
-func (APIConnFederationTest) HasSynced() bool                        { return true }
-func (APIConnFederationTest) Run()                                   { return }
-func (APIConnFederationTest) Stop() error                            { return nil }
-func (APIConnFederationTest) SvcIndexReverse(string) []*api.Service  { return nil }
-func (APIConnFederationTest) EpIndexReverse(string) []*api.Endpoints { return nil }
-func (APIConnFederationTest) Modified() int64                        { return 0 }
+func (APIConnFederationTest) HasSynced() bool                           { return true }
+func (APIConnFederationTest) Run()                                      { return }
+func (APIConnFederationTest) Stop() error                               { return nil }
+func (APIConnFederationTest) SvcIndexReverse(string) []*object.Service  { return nil }
+func (APIConnFederationTest) EpIndexReverse(string) []*object.Endpoints { return nil }
+func (APIConnFederationTest) Modified() int64                           { return 0 }
+func (APIConnFederationTest) IngressIndex(string) []*object.Ingress     { return nil }
 
-func (APIConnFederationTest) PodIndex(string) []*api.Pod {
-	a := []*api.Pod{{
-		ObjectMeta: meta.ObjectMeta{
-			Namespace: "podns",
-		},
-		Status: api.PodStatus{
-			PodIP: "10.240.0.1", // Remote IP set in test.ResponseWriter
-		},
+func (APIConnFederationTest) PodIndex(string) []*object.Pod {
+	a := []*object.Pod{{
+		Namespace: "podns",
+		PodIP:     "10.240.0.1", // Remote IP set in test.ResponseWriter
 	}}
 	return a
 }
 
-func (APIConnFederationTest) SvcIndex(string) []*api.Service {
-	svcs := []*api.Service{
+func (APIConnFederationTest) SvcIndex(string) []*object.Service {
+	svcs := []*object.Service{
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: "10.0.0.1",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-			},
+			Name:      "svc1",
+			Namespace: "testns",
+			ClusterIP: "10.0.0.1",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: api.ClusterIPNone,
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
+			ClusterIP: api.ClusterIPNone,
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "external",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ExternalName: "ext.interwebs.test",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-			},
+			Name:         "external",
+			Namespace:    "testns",
+			ExternalName: "ext.interwebs.test",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
 		},
 	}
 	return svcs
 }
 
-func (APIConnFederationTest) ServiceList() []*api.Service {
-	svcs := []*api.Service{
+func (APIConnFederationTest) ServiceList() []*object.Service {
+	svcs := []*object.Service{
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: "10.0.0.1",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-			},
+			Name:      "svc1",
+			Namespace: "testns",
+			ClusterIP: "10.0.0.1",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: api.ClusterIPNone,
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
+			ClusterIP: api.ClusterIPNone,
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "external",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ExternalName: "ext.interwebs.test",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-			},
+			Name:         "external",
+			Namespace:    "testns",
+			ExternalName: "ext.interwebs.test",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
 		},
 	}
 	return svcs
 }
 
-func (APIConnFederationTest) EpIndex(string) []*api.Endpoints {
-	eps := []*api.Endpoints{
+func (APIConnFederationTest) EpIndex(string) []*object.Endpoints {
+	eps := []*object.Endpoints{
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP:       "172.0.0.1",
 							Hostname: "ep1a",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -134,27 +108,25 @@ func (APIConnFederationTest) EpIndex(string) []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
+			Name:      "svc1",
+			Namespace: "testns",
 		},
 	}
 	return eps
 }
 
-func (APIConnFederationTest) EndpointsList() []*api.Endpoints {
-	eps := []*api.Endpoints{
+func (APIConnFederationTest) EndpointsList() []*object.Endpoints {
+	eps := []*object.Endpoints{
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP:       "172.0.0.1",
 							Hostname: "ep1a",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -163,23 +135,19 @@ func (APIConnFederationTest) EndpointsList() []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
+			Name:      "svc1",
+			Namespace: "testns",
 		},
 	}
 	return eps
 }
 
-func (APIConnFederationTest) GetNodeByName(name string) (*api.Node, error) {
-	return &api.Node{
-		ObjectMeta: meta.ObjectMeta{
-			Name: "test.node.foo.bar",
-			Labels: map[string]string{
-				kubernetes.LabelRegion: "fd-r",
-				kubernetes.LabelZone:   "fd-az",
-			},
+func (APIConnFederationTest) GetNodeByName(name string) (*object.Node, error) {
+	return &object.Node{
+		Name: "test.node.foo.bar",
+		Labels: map[string]string{
+			kubernetes.LabelRegion: "fd-r",
+			kubernetes.LabelZone:   "fd-az",
 		},
 	}, nil
 }
@@ -0,0 +1,92 @@
+package file
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// CatalogVersion is the version of the catalog zone schema we produce and understand, see RFC 9432.
+const CatalogVersion = "2"
+
+// NewCatalog returns a catalog zone (RFC 9432) with origin name that lists members. Each member gets
+// an ID derived from its name, so the IDs stay the same when the catalog is rebuilt.
+func NewCatalog(name string, members []string, serial uint32) *Zone {
+	name = dns.Fqdn(strings.ToLower(name))
+	z := NewZone(name, "")
+	z.NoReload = true
+
+	hdr := func(owner string, t uint16) dns.RR_Header {
+		return dns.RR_Header{Name: owner, Rrtype: t, Class: dns.ClassINET, Ttl: 0}
+	}
+
+	z.Insert(&dns.SOA{Hdr: hdr(name, dns.TypeSOA), Ns: "invalid.", Mbox: "invalid.", Serial: serial, Refresh: 3600, Retry: 600, Expire: 2147483646, Minttl: 0})
+	z.Insert(&dns.NS{Hdr: hdr(name, dns.TypeNS), Ns: "invalid."})
+	z.Insert(&dns.TXT{Hdr: hdr("version."+name, dns.TypeTXT), Txt: []string{CatalogVersion}})
+
+	sorted := append([]string{}, members...)
+	sort.Strings(sorted)
+	for _, m := range sorted {
+		m = dns.Fqdn(strings.ToLower(m))
+		z.Insert(&dns.PTR{Hdr: hdr(catalogID(m)+".zones."+name, dns.TypePTR), Ptr: m})
+	}
+	return z
+}
+
+// catalogID returns the member ID for member.
+func catalogID(member string) string {
+	sum := sha1.Sum([]byte(member))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CatalogMembers returns the members of the catalog zone z, mapping each member zone to its ID. It
+// returns an error if z isn't a catalog of a version we understand. Members that are listed more than
+// once, and IDs with more than one member are ignored, as are the properties of the members.
+func CatalogMembers(z *Zone) (map[string]string, error) {
+	if z.Apex.SOA == nil {
+		return nil, fmt.Errorf("catalog `%s' has no SOA record", z.origin)
+	}
+	zones := "zones." + z.origin
+	version := "version." + z.origin
+
+	ids := map[string][]string{} // ID -> members
+	versions := []string{}
+	for _, rr := range z.All() {
+		name := strings.ToLower(rr.Header().Name)
+		switch x := rr.(type) {
+		case *dns.TXT:
+			if name == version {
+				versions = append(versions, strings.Join(x.Txt, ""))
+			}
+		case *dns.PTR:
+			if !dns.IsSubDomain(zones, name) || dns.CountLabel(name) != dns.CountLabel(zones)+1 {
+				continue // properties, such as group.ID.zones, or junk
+			}
+			id := name[:len(name)-len(zones)-1]
+			ids[id] = append(ids[id], strings.ToLower(dns.Fqdn(x.Ptr)))
+		}
+	}
+	if len(versions) != 1 || versions[0] != CatalogVersion {
+		return nil, fmt.Errorf("catalog `%s' has version %q, want %q", z.origin, versions, CatalogVersion)
+	}
+
+	members := map[string]string{}
+	seen := map[string]int{}
+	for id, ms := range ids {
+		if len(ms) != 1 {
+			continue
+		}
+		members[ms[0]] = id
+		seen[ms[0]]++
+	}
+	for m, n := range seen {
+		if n > 1 {
+			delete(members, m)
+		}
+	}
+	return members, nil
+}
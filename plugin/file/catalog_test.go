@@ -0,0 +1,74 @@
+package file
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	z := NewCatalog("catalog.example", []string{"example.org", "Example.NET."}, 10)
+
+	members, err := CatalogMembers(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"example.org.": catalogID("example.org."),
+		"example.net.": catalogID("example.net."),
+	}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("Expected members %v, got %v", want, members)
+	}
+
+	if p := Check("catalog.example.", z.All(), nil, ""); p.HasErrors() {
+		t.Errorf("Expected no errors in the catalog, got %s", p)
+	}
+}
+
+func TestCatalogMembers(t *testing.T) {
+	tests := []struct {
+		zone    string
+		members map[string]string
+		err     bool
+	}{
+		{dbCatalog, map[string]string{"example.org.": "a", "example.com.": "d"}, false},
+		{strings.Replace(dbCatalog, `"2"`, `"1"`, 1), nil, true},
+		{strings.Replace(dbCatalog, "version", "versions", 1), nil, true},
+	}
+
+	for i, tc := range tests {
+		z, err := Parse(strings.NewReader(tc.zone), "catalog.example.", "stdin", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members, err := CatalogMembers(z)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(members, tc.members) {
+			t.Errorf("Test %d: expected members %v, got %v", i, tc.members, members)
+		}
+	}
+}
+
+// Member b has two zones, example.net is listed twice and a group property is set for a.
+const dbCatalog = `$TTL 0
+@                  IN SOA invalid. invalid. 1 3600 600 2147483646 0
+@                  IN NS  invalid.
+version            IN TXT "2"
+a.zones            IN PTR example.org.
+group.a.zones      IN TXT "group1"
+b.zones            IN PTR example.info.
+b.zones            IN PTR example.biz.
+c.zones            IN PTR example.net.
+c2.zones           IN PTR example.net.
+d.zones            IN PTR Example.COM.
+`
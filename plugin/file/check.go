@@ -0,0 +1,289 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/miekg/dns"
+)
+
+// Severity is how bad a Problem is.
+type Severity int
+
+const (
+	// Warning is for data that is suspect, but can be served.
+	Warning Severity = iota
+	// Error is for data that is broken: it doesn't conform to the RFCs or will not resolve.
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is a problem with a zone, found by Check.
+type Problem struct {
+	File     string
+	Line     int // Line of the record in File, 0 if not known.
+	Severity Severity
+	Msg      string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.File == "":
+		return fmt.Sprintf("%s: %s", p.Severity, p.Msg)
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s: %s", p.File, p.Severity, p.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Msg)
+}
+
+// Problems is a list of problems.
+type Problems []Problem
+
+// HasErrors returns true if one of the problems is an error.
+func (ps Problems) HasErrors() bool {
+	for _, p := range ps {
+		if p.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps Problems) String() string {
+	s := make([]string, len(ps))
+	for i, p := range ps {
+		s[i] = p.String()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Log logs the problems.
+func (ps Problems) Log() {
+	for _, p := range ps {
+		if p.Severity == Error {
+			log.Errorf("%s", p)
+			continue
+		}
+		log.Warningf("%s", p)
+	}
+}
+
+// Check checks the records of the zone origin for problems, like named-checkzone does. Lines holds
+// the line number of each record in fileName, it may be nil when they aren't known.
+func Check(origin string, rrs []dns.RR, lines []int, fileName string) Problems {
+	origin = strings.ToLower(dns.Fqdn(origin))
+	c := checker{origin: origin, file: fileName, lines: lines}
+	if len(lines) != len(rrs) {
+		c.lines = nil
+	}
+
+	// The types at each name, and where the zone cuts are.
+	types := map[string]map[uint16]int{} // name -> type -> index of the first record
+	cuts := map[string]bool{}
+	soa := 0
+	for i, rr := range rrs {
+		h := rr.Header()
+		name := strings.ToLower(h.Name)
+		if h.Rrtype == dns.TypeSOA && name != origin {
+			c.add(i, Error, "%s: SOA record is not at the apex of %s", name, origin)
+			continue
+		}
+		if !dns.IsSubDomain(origin, name) {
+			msg := "out-of-zone data"
+			if h.Rrtype == dns.TypeA || h.Rrtype == dns.TypeAAAA {
+				msg = "out-of-zone glue"
+			}
+			c.add(i, Error, "%s: %s %s", name, msg, dns.TypeToString[h.Rrtype])
+			continue
+		}
+		if types[name] == nil {
+			types[name] = map[uint16]int{}
+		}
+		if _, ok := types[name][h.Rrtype]; !ok {
+			types[name][h.Rrtype] = i
+		}
+
+		switch h.Rrtype {
+		case dns.TypeSOA:
+			if soa++; soa > 1 {
+				c.add(i, Error, "%s: more than one SOA record", name)
+			}
+		case dns.TypeNS:
+			if name != origin {
+				cuts[name] = true
+			}
+		case dns.TypeCNAME:
+			if name == origin {
+				c.add(i, Error, "%s: CNAME at the zone apex", name)
+			} else if j := types[name][dns.TypeCNAME]; j != i {
+				c.add(i, Error, "%s: more than one CNAME record", name)
+			}
+		}
+	}
+	if soa == 0 {
+		c.add(-1, Error, "%s: no SOA record", origin)
+	}
+	if _, ok := types[origin][dns.TypeNS]; !ok {
+		c.add(-1, Error, "%s: no NS records at the zone apex", origin)
+	}
+
+	// cut returns the zone cut name is at or below, or the empty string if it isn't.
+	cut := func(name string) string {
+		for n := name; n != origin; {
+			if cuts[n] {
+				return n
+			}
+			i, end := dns.NextLabel(n, 0)
+			if end {
+				break
+			}
+			n = n[i:]
+		}
+		return ""
+	}
+
+	glue := map[string]bool{} // names used as glue
+	for i, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		if !dns.IsSubDomain(origin, name) {
+			continue
+		}
+		switch x := rr.(type) {
+		case *dns.CNAME:
+			if name == origin {
+				continue // already reported
+			}
+			for t := range types[name] {
+				if t != dns.TypeCNAME && t != dns.TypeRRSIG && t != dns.TypeNSEC {
+					c.add(i, Error, "%s: CNAME and other data", name)
+					break
+				}
+			}
+		case *dns.NS:
+			target := strings.ToLower(x.Ns)
+			if !dns.IsSubDomain(origin, target) {
+				continue
+			}
+			if _, ok := types[target][dns.TypeCNAME]; ok {
+				c.add(i, Error, "%s: NS target %s is a CNAME", name, target)
+				continue
+			}
+			_, a := types[target][dns.TypeA]
+			_, aaaa := types[target][dns.TypeAAAA]
+			if a || aaaa {
+				glue[target] = true
+				continue
+			}
+			if cut(target) != "" {
+				c.add(i, Error, "%s: missing glue for NS target %s", name, target)
+			} else {
+				c.add(i, Error, "%s: NS target %s has no address records", name, target)
+			}
+		case *dns.MX:
+			if _, ok := types[strings.ToLower(x.Mx)][dns.TypeCNAME]; ok {
+				c.add(i, Warning, "%s: MX target %s is a CNAME", name, x.Mx)
+			}
+		case *dns.SRV:
+			if _, ok := types[strings.ToLower(x.Target)][dns.TypeCNAME]; ok {
+				c.add(i, Warning, "%s: SRV target %s is a CNAME", name, x.Target)
+			}
+		}
+	}
+
+	// Data at or below a zone cut is not served from this zone, except for glue and the records of
+	// the delegation itself.
+	for i, rr := range rrs {
+		h := rr.Header()
+		name := strings.ToLower(h.Name)
+		if !dns.IsSubDomain(origin, name) {
+			continue
+		}
+		cn := cut(name)
+		if cn == "" {
+			continue
+		}
+		switch {
+		case name == cn && (h.Rrtype == dns.TypeNS || h.Rrtype == dns.TypeDS || h.Rrtype == dns.TypeRRSIG || h.Rrtype == dns.TypeNSEC):
+		case h.Rrtype == dns.TypeA || h.Rrtype == dns.TypeAAAA:
+			if !glue[name] {
+				c.add(i, Warning, "%s: glue %s is not used by any NS record", name, dns.TypeToString[h.Rrtype])
+			}
+		default:
+			c.add(i, Warning, "%s: %s is below the delegation to %s and will not be served", name, dns.TypeToString[h.Rrtype], cn)
+		}
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return c.problems
+}
+
+type checker struct {
+	origin   string
+	file     string
+	lines    []int
+	problems Problems
+}
+
+// add adds a problem for the record at index i, i is -1 for problems with the zone as a whole.
+func (c *checker) add(i int, s Severity, format string, a ...interface{}) {
+	p := Problem{File: c.file, Severity: s, Msg: fmt.Sprintf(format, a...)}
+	if i >= 0 && c.lines != nil {
+		p.Line = c.lines[i]
+	}
+	c.problems = append(c.problems, p)
+}
+
+// recordLines returns the line each record in the zone file text starts on, in the order the records
+// are parsed. It returns nil if it can't tell, i.e. when the file uses $INCLUDE or $GENERATE.
+func recordLines(text []byte) []int {
+	var lines []int
+	depth := 0
+	for n, line := range bytes.Split(text, []byte("\n")) {
+		data := stripComment(line)
+		if depth == 0 && len(bytes.TrimSpace(data)) > 0 {
+			if d := bytes.TrimSpace(data); d[0] == '$' {
+				upper := strings.ToUpper(string(d))
+				if strings.HasPrefix(upper, "$INCLUDE") || strings.HasPrefix(upper, "$GENERATE") {
+					return nil
+				}
+			} else {
+				lines = append(lines, n+1)
+			}
+		}
+		depth += bytes.Count(data, []byte("(")) - bytes.Count(data, []byte(")"))
+		if depth < 0 {
+			depth = 0
+		}
+	}
+	return lines
+}
+
+// stripComment returns line without its comment and without the contents of quoted strings.
+func stripComment(line []byte) []byte {
+	out := make([]byte, 0, len(line))
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == ';':
+			return out
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
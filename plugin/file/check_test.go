@@ -0,0 +1,120 @@
+package file
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		zone     string
+		problems []string
+	}{
+		{dbMiekNL, nil},
+		{dbCheckOK, nil},
+		{dbCheckCNAMEApex, []string{"stdin:4: error: example.org.: CNAME at the zone apex"}},
+		{dbCheckCNAMEData, []string{"stdin:6: error: www.example.org.: CNAME and other data"}},
+		{dbCheckOutOfZone, []string{"stdin:6: error: ns.example.net.: out-of-zone glue A"}},
+		{dbCheckNoGlue, []string{
+			"stdin:7: error: sub.example.org.: missing glue for NS target ns.sub.example.org.",
+			"stdin:8: warning: www.sub.example.org.: TXT is below the delegation to sub.example.org. and will not be served",
+		}},
+		{dbCheckSOAMismatch, []string{
+			"stdin: error: example.org.: no SOA record",
+			"stdin:2: error: example.net.: SOA record is not at the apex of example.org.",
+		}},
+	}
+
+	for i, tc := range tests {
+		_, problems, err := ParseCheck(strings.NewReader(tc.zone), "example.org.", "stdin", 0)
+		if tc.zone == dbMiekNL {
+			_, problems, err = ParseCheck(strings.NewReader(tc.zone), testzone, "stdin", 0)
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		got := []string{}
+		for _, p := range problems {
+			got = append(got, p.String())
+		}
+		if len(got) != len(tc.problems) {
+			t.Errorf("Test %d: expected %d problems, got %d: %v", i, len(tc.problems), len(got), got)
+			continue
+		}
+		for j := range got {
+			if got[j] != tc.problems[j] {
+				t.Errorf("Test %d: expected problem %q, got %q", i, tc.problems[j], got[j])
+			}
+		}
+		if problems.HasErrors() != (len(tc.problems) > 0) {
+			t.Errorf("Test %d: expected HasErrors to be %t", i, len(tc.problems) > 0)
+		}
+	}
+}
+
+func TestRecordLines(t *testing.T) {
+	text := `$ORIGIN example.org.
+@ IN SOA ns1 hostmaster ( ; comment (
+	1 2 3 4 5 )
+
+  ; a comment
+@ IN TXT "a ; (quoted" string
+  IN NS ns1
+`
+	want := []int{2, 6, 7}
+	if got := recordLines([]byte(text)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines %v, got %v", want, got)
+	}
+
+	if got := recordLines([]byte("$INCLUDE other.db\n@ IN NS ns1\n")); got != nil {
+		t.Errorf("Expected no lines with $INCLUDE, got %v", got)
+	}
+}
+
+const dbCheckOK = `$TTL 3600
+@    IN SOA ns1 hostmaster 1 7200 3600 1209600 3600
+     IN NS  ns1
+     IN NS  ns.example.net.
+ns1  IN A   192.0.2.1
+sub  IN NS  ns.sub
+ns.sub IN A 192.0.2.2
+www  IN CNAME ns1
+`
+
+const dbCheckCNAMEApex = `$TTL 3600
+@    IN SOA ns.example.net. hostmaster 1 7200 3600 1209600 3600
+     IN NS  ns.example.net.
+     IN CNAME example.net.
+`
+
+const dbCheckCNAMEData = `$TTL 3600
+@    IN SOA ns.example.net. hostmaster 1 7200 3600 1209600 3600
+     IN NS  ns.example.net.
+
+www  IN A   192.0.2.1
+www  IN CNAME example.net.
+`
+
+const dbCheckOutOfZone = `$TTL 3600
+@    IN SOA ns.example.net. hostmaster 1 7200 3600 1209600 3600
+     IN NS  ns.example.net.
+; glue for a name that is not in this zone
+
+ns.example.net. IN A 192.0.2.1
+`
+
+const dbCheckNoGlue = `$TTL 3600
+@    IN SOA ns.example.net. hostmaster (
+        1 7200 3600 1209600 3600 )
+     IN NS  ns.example.net.
+
+; no address for ns.sub
+sub  IN NS  ns.sub
+www.sub IN TXT "occluded"
+`
+
+const dbCheckSOAMismatch = `$TTL 3600
+example.net. IN SOA ns.example.net. hostmaster 1 7200 3600 1209600 3600
+@    IN NS  ns.example.net.
+`
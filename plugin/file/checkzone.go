@@ -0,0 +1,43 @@
+package file
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/coredns/coredns/coremain"
+)
+
+func init() { coremain.CheckZone = checkZone }
+
+// checkZone checks the zone file in args[1] for the zone args[0], like named-checkzone does. It prints
+// the problems found and returns the exit status: 0 if the zone has no errors, 1 if it has.
+func checkZone(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: coredns -checkzone ORIGIN FILE")
+		return 2
+	}
+	origin, fileName := args[0], args[1]
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	z, problems, err := ParseCheck(f, origin, fileName, 0)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if problems.HasErrors() {
+		return 1
+	}
+
+	fmt.Printf("zone %s: loaded serial %d\n", origin, z.Apex.SOA.Serial)
+	fmt.Println("OK")
+	return 0
+}
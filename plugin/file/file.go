@@ -2,6 +2,7 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 
@@ -68,7 +69,7 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeSuccess, nil
 	}
 
-	if z.Expired != nil && *z.Expired {
+	if z.expired() {
 		log.Errorf("Zone %s is expired", zone)
 		return dns.RcodeServerFailure, nil
 	}
@@ -120,6 +121,23 @@ func (s *serialErr) Error() string {
 // If serial >= 0 it will reload the zone, if the SOA hasn't changed
 // it returns an error indicating nothing was read.
 func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
+	return parseZone(f, origin, fileName, serial, nil)
+}
+
+// ParseCheck is like Parse, but also checks the zone with Check and returns the problems found.
+// The problems carry the line numbers of the records in fileName.
+func ParseCheck(f io.Reader, origin, fileName string, serial int64) (*Zone, Problems, error) {
+	buf := &bytes.Buffer{}
+	rrs := []dns.RR{}
+	z, err := parseZone(io.TeeReader(f, buf), origin, fileName, serial, func(rr dns.RR) { rrs = append(rrs, rr) })
+	if err != nil {
+		return nil, nil, err
+	}
+	return z, Check(origin, rrs, recordLines(buf.Bytes()), fileName), nil
+}
+
+// parseZone implements Parse; each, when not nil, is called for every record in the zone.
+func parseZone(f io.Reader, origin, fileName string, serial int64, each func(dns.RR)) (*Zone, error) {
 	tokens := dns.ParseZone(f, dns.Fqdn(origin), fileName)
 	z := NewZone(origin, fileName)
 	seenSOA := false
@@ -140,6 +158,9 @@ func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
 		if err := z.Insert(x.RR); err != nil {
 			return nil, err
 		}
+		if each != nil {
+			each(x.RR)
+		}
 	}
 	if !seenSOA {
 		return nil, fmt.Errorf("file %q has no SOA record", fileName)
@@ -35,9 +35,10 @@ func (z *Zone) isNotify(state request.Request) bool {
 	return false
 }
 
-// Notify will send notifies to all configured TransferTo IP addresses.
+// Notify will send notifies to all configured TransferTo and NotifyTo IP addresses.
 func (z *Zone) Notify() {
-	go notify(z.origin, z.TransferTo)
+	to := append(append([]string{}, z.TransferTo...), z.NotifyTo...)
+	go notify(z.origin, to)
 }
 
 // notify sends notifies to the configured remote servers. It will try up to three times
@@ -0,0 +1,112 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// LoadCache loads the zone from z.CacheFile, the copy of the zone that is written after every
+// transfer. The copy is only used if the zone hasn't expired, i.e. when the last transfer or refresh
+// was less than the SOA's expire time ago. An expired copy is removed.
+func (z *Zone) LoadCache() error {
+	if z.CacheFile == "" {
+		return nil
+	}
+	f, err := os.Open(z.CacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	z1, err := Parse(f, z.origin, z.CacheFile, 0)
+	if err != nil {
+		return err
+	}
+
+	age := time.Since(info.ModTime())
+	if expire := time.Duration(z1.Apex.SOA.Expire) * time.Second; age > expire {
+		log.Infof("Not loading `%s' from %s: it expired %s ago", z.origin, z.CacheFile, age-expire)
+		os.Remove(z.CacheFile)
+		return nil
+	}
+
+	z.reloadMu.Lock()
+	z.Tree = z1.Tree
+	z.Apex = z1.Apex
+	z.refreshed = info.ModTime()
+	*z.Expired = false
+	z.reloadMu.Unlock()
+	log.Infof("Loaded `%s' with serial %d from %s, last refreshed %s ago", z.origin, z1.Apex.SOA.Serial, z.CacheFile, age)
+
+	if z.OnTransfer != nil {
+		z.OnTransfer(z)
+	}
+	return nil
+}
+
+// writeCache writes the zone to z.CacheFile. The file is replaced in one go, so a crash never leaves
+// half a zone behind.
+func (z *Zone) writeCache() error {
+	if z.CacheFile == "" {
+		return nil
+	}
+	f, err := ioutil.TempFile(filepath.Dir(z.CacheFile), "."+filepath.Base(z.CacheFile))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after the rename
+
+	w := bufio.NewWriter(f)
+	for _, rr := range z.All() {
+		fmt.Fprintln(w, rr.String())
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), z.CacheFile)
+}
+
+// refresh records the zone is current: it was transferred, or the primary has the serial we have.
+func (z *Zone) refresh() {
+	now := time.Now()
+	z.reloadMu.Lock()
+	z.refreshed = now
+	if *z.Expired {
+		*z.Expired = false
+		log.Infof("Zone `%s' is current again, no longer expired", z.origin)
+	}
+	z.reloadMu.Unlock()
+
+	if z.CacheFile != "" {
+		os.Chtimes(z.CacheFile, now, now)
+	}
+}
+
+// expiresIn returns how long the zone can be served without a refresh.
+func (z *Zone) expiresIn() time.Duration {
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
+
+	left := time.Duration(z.Apex.SOA.Expire)*time.Second - time.Since(z.refreshed)
+	if left < time.Second {
+		left = time.Second
+	}
+	return left
+}
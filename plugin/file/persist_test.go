@@ -0,0 +1,65 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	z, err := Parse(strings.NewReader(dbMiekNL), testzone, "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.CacheFile = filepath.Join(dir, "db.miek.nl")
+	if err := z.writeCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	z1 := NewZone(testzone, "stdin")
+	z1.CacheFile = z.CacheFile
+	called := false
+	z1.OnTransfer = func(*Zone) { called = true }
+	if err := z1.LoadCache(); err != nil {
+		t.Fatal(err)
+	}
+	if z1.Apex.SOA == nil || z1.Apex.SOA.Serial != z.Apex.SOA.Serial {
+		t.Fatalf("Expected zone to be loaded from the cache with serial %d", z.Apex.SOA.Serial)
+	}
+	if len(z1.All()) != len(z.All()) {
+		t.Errorf("Expected %d records from the cache, got %d", len(z.All()), len(z1.All()))
+	}
+	if !called {
+		t.Errorf("Expected OnTransfer to be called")
+	}
+	// The SOA's expire is 604800s, so we still have almost all of it.
+	if left := z1.expiresIn(); left < 604000*time.Second {
+		t.Errorf("Expected the zone to expire in about a week, got %s", left)
+	}
+
+	// Refreshed more than expire ago.
+	past := time.Now().Add(-8 * 24 * time.Hour)
+	if err := os.Chtimes(z.CacheFile, past, past); err != nil {
+		t.Fatal(err)
+	}
+	z2 := NewZone(testzone, "stdin")
+	z2.CacheFile = z.CacheFile
+	if err := z2.LoadCache(); err != nil {
+		t.Fatal(err)
+	}
+	if z2.Apex.SOA != nil {
+		t.Errorf("Expected an expired zone not to be loaded")
+	}
+	if _, err := os.Stat(z.CacheFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the expired zone to be removed from the cache")
+	}
+}
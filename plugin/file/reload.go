@@ -31,13 +31,18 @@ func (z *Zone) Reload() error {
 				}
 
 				serial := z.SOASerialIfDefined()
-				zone, err := Parse(reader, z.origin, z.file, serial)
+				zone, problems, err := ParseCheck(reader, z.origin, z.file, serial)
 				if err != nil {
 					if _, ok := err.(*serialErr); !ok {
 						log.Errorf("Parsing zone %q: %v", z.origin, err)
 					}
 					continue
 				}
+				problems.Log()
+				if z.Strict && problems.HasErrors() {
+					log.Errorf("Not reloading zone %q in %q: it has errors", z.origin, z.file)
+					continue
+				}
 
 				// copy elements we need
 				z.reloadMu.Lock()
@@ -47,6 +52,9 @@ func (z *Zone) Reload() error {
 
 				log.Infof("Successfully reloaded zone %q in %q with serial %d", z.origin, z.file, z.Apex.SOA.Serial)
 				z.Notify()
+				if z.OnTransfer != nil {
+					z.OnTransfer(z)
+				}
 
 			case <-z.reloadShutdown:
 				tick.Stop()
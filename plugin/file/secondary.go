@@ -1,6 +1,7 @@
 package file
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -9,7 +10,8 @@ import (
 	"github.com/miekg/dns"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the masters, parses it and sets it live. The masters are tried in
+// the order they are configured in; a zone with a serial older than the one we have is not used.
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
@@ -17,14 +19,16 @@ func (z *Zone) TransferIn() error {
 	m := new(dns.Msg)
 	m.SetAxfr(z.origin)
 
-	z1 := z.CopyWithoutApex()
 	var (
 		Err error
 		tr  string
+		rrs []dns.RR
+		z1  *Zone
 	)
 
 Transfer:
 	for _, tr = range z.TransferFrom {
+		z1 = z.CopyWithoutApex()
 		t := new(dns.Transfer)
 		c, err := t.In(m, tr)
 		if err != nil {
@@ -32,6 +36,7 @@ Transfer:
 			Err = err
 			continue Transfer
 		}
+		rrs = rrs[:0]
 		for env := range c {
 			if env.Error != nil {
 				log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
@@ -44,19 +49,55 @@ Transfer:
 					Err = err
 					continue Transfer
 				}
+				// The transfer ends with the SOA it started with.
+				if _, ok := rr.(*dns.SOA); ok && len(rrs) > 0 {
+					continue
+				}
+				rrs = append(rrs, rr)
 			}
 		}
+		if z1.Apex.SOA == nil {
+			log.Errorf("Failed to transfer `%s' from %q: no SOA record", z.origin, tr)
+			Err = fmt.Errorf("no SOA record in transfer of `%s' from %q", z.origin, tr)
+			continue Transfer
+		}
+		if serial := z.SOASerialIfDefined(); serial >= 0 && less(z1.Apex.SOA.Serial, uint32(serial)) {
+			log.Warningf("Not using transfer of `%s' from %q: serial %d is older than ours, %d", z.origin, tr, z1.Apex.SOA.Serial, serial)
+			Err = fmt.Errorf("serial %d of `%s' from %q is older than %d", z1.Apex.SOA.Serial, z.origin, tr, serial)
+			continue Transfer
+		}
 		Err = nil
 		break
 	}
 	if Err != nil {
+		z.failed(Err)
 		return Err
 	}
 
+	problems := Check(z.origin, rrs, nil, tr)
+	problems.Log()
+	if z.Strict && problems.HasErrors() {
+		log.Errorf("Not loading transferred zone `%s' from %s: it has errors", z.origin, tr)
+		err := fmt.Errorf("zone `%s' transferred from %s has errors", z.origin, tr)
+		z.failed(err)
+		return err
+	}
+
+	now := time.Now()
+	z.reloadMu.Lock()
 	z.Tree = z1.Tree
 	z.Apex = z1.Apex
+	z.primary, z.lastTransfer = tr, now
 	*z.Expired = false
+	z.reloadMu.Unlock()
+	z.refresh()
 	log.Infof("Transferred: %s from %s", z.origin, tr)
+	if err := z.writeCache(); err != nil {
+		log.Errorf("Failed to save `%s' to %s: %s", z.origin, z.CacheFile, err)
+	}
+	if z.OnTransfer != nil {
+		z.OnTransfer(z)
+	}
 	return nil
 }
 
@@ -87,6 +128,9 @@ Transfer:
 		}
 	}
 	if serial == -1 {
+		if Err == nil {
+			Err = fmt.Errorf("no SOA for `%s' from %v", z.origin, z.TransferFrom)
+		}
 		return false, Err
 	}
 	if z.Apex.SOA == nil {
@@ -106,18 +150,27 @@ func less(a, b uint32) bool {
 // Update updates the secondary zone according to its SOA. It will run for the life time of the server
 // and uses the SOA parameters. Every refresh it will check for a new SOA number. If that fails (for all
 // server) it wil retry every retry interval. If the zone failed to transfer before the expire, the zone
-// will be marked expired.
+// will be marked expired. Update returns when OnShutdown is called.
 func (z *Zone) Update() error {
 	// If we don't have a SOA, we don't have a zone, wait for it to appear.
-	for z.Apex.SOA == nil {
-		time.Sleep(1 * time.Second)
+	for z.SOASerialIfDefined() < 0 {
+		select {
+		case <-time.After(1 * time.Second):
+		case <-z.reloadShutdown:
+			return nil
+		}
 	}
 	retryActive := false
 
 Restart:
-	refresh := time.Second * time.Duration(z.Apex.SOA.Refresh)
-	retry := time.Second * time.Duration(z.Apex.SOA.Retry)
-	expire := time.Second * time.Duration(z.Apex.SOA.Expire)
+	z.reloadMu.RLock()
+	soa := z.Apex.SOA
+	z.reloadMu.RUnlock()
+
+	refresh := time.Second * time.Duration(soa.Refresh)
+	retry := time.Second * time.Duration(soa.Retry)
+	expire := z.expiresIn() // less than the SOA's expire, when loaded from the cache
+	z.setNextRefresh(time.Now().Add(refresh))
 
 	refreshTicker := time.NewTicker(refresh)
 	retryTicker := time.NewTicker(retry)
@@ -125,11 +178,15 @@ Restart:
 
 	for {
 		select {
+		case <-z.reloadShutdown:
+			refreshTicker.Stop()
+			retryTicker.Stop()
+			expireTicker.Stop()
+			return nil
+
 		case <-expireTicker.C:
-			if !retryActive {
-				break
-			}
-			*z.Expired = true
+			// Not refreshed within the expire time: stop serving the zone until it is.
+			z.expire()
 
 		case <-retryTicker.C:
 			if !retryActive {
@@ -141,21 +198,26 @@ Restart:
 			ok, err := z.shouldTransfer()
 			if err != nil {
 				log.Warningf("Failed retry check %s", err)
+				z.failed(err)
+				z.setNextRefresh(time.Now().Add(retry))
 				continue
 			}
 
 			if ok {
 				if err := z.TransferIn(); err != nil {
 					// transfer failed, leave retryActive true
+					z.setNextRefresh(time.Now().Add(retry))
 					break
 				}
-				retryActive = false
-				// transfer OK, possible new SOA, stop timers and redo
-				refreshTicker.Stop()
-				retryTicker.Stop()
-				expireTicker.Stop()
-				goto Restart
+			} else {
+				z.refresh()
 			}
+			retryActive = false
+			// transfer OK or zone current, possible new SOA, stop timers and redo
+			refreshTicker.Stop()
+			retryTicker.Stop()
+			expireTicker.Stop()
+			goto Restart
 
 		case <-refreshTicker.C:
 
@@ -164,6 +226,8 @@ Restart:
 			ok, err := z.shouldTransfer()
 			if err != nil {
 				log.Warningf("Failed refresh check %s", err)
+				z.failed(err)
+				z.setNextRefresh(time.Now().Add(retry))
 				retryActive = true
 				continue
 			}
@@ -171,16 +235,19 @@ Restart:
 			if ok {
 				if err := z.TransferIn(); err != nil {
 					// transfer failed
+					z.setNextRefresh(time.Now().Add(retry))
 					retryActive = true
 					break
 				}
-				retryActive = false
-				// transfer OK, possible new SOA, stop timers and redo
-				refreshTicker.Stop()
-				retryTicker.Stop()
-				expireTicker.Stop()
-				goto Restart
+			} else {
+				z.refresh()
 			}
+			retryActive = false
+			// transfer OK or zone current, possible new SOA, stop timers and redo
+			refreshTicker.Stop()
+			retryTicker.Stop()
+			expireTicker.Stop()
+			goto Restart
 		}
 	}
 }
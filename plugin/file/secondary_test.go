@@ -3,6 +3,7 @@ package file
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
@@ -138,6 +139,99 @@ func TestTransferIn(t *testing.T) {
 	}
 }
 
+func TestTransferInFailover(t *testing.T) {
+	current := soa{250}
+
+	dns.HandleFunc(testZone, current.Handler)
+	defer dns.HandleRemove(testZone)
+
+	s, addrstr, err := test.TCPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{"127.0.0.1:1", addrstr} // nothing listens on the first one
+
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("unable to run TransferIn: %v", err)
+	}
+	if st := z.TransferState(); st.Primary != addrstr || st.Serial != 250 || st.LastTransfer.IsZero() {
+		t.Fatalf("expected zone with serial 250 transferred from %s, got %+v", addrstr, st)
+	}
+
+	// A primary that went back in time isn't used.
+	older := soa{200}
+	dns.HandleFunc(testZone, older.Handler)
+	if err := z.TransferIn(); err == nil {
+		t.Fatalf("expected TransferIn to fail for an older serial")
+	}
+	if st := z.TransferState(); st.Serial != 250 || st.LastError == "" {
+		t.Fatalf("expected zone with serial 250 and an error, got %+v", st)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{"127.0.0.1:1"}
+	if z.expired() {
+		t.Fatalf("zone without data should not be expired")
+	}
+
+	z.Insert(test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 3600 600 86400 300", testZone)))
+	z.refreshed = time.Now().Add(-time.Hour)
+	if z.expired() {
+		t.Fatalf("zone refreshed an hour ago should not be expired")
+	}
+
+	z.refreshed = time.Now().Add(-25 * time.Hour)
+	if !z.expired() {
+		t.Fatalf("zone refreshed 25 hours ago should be expired")
+	}
+	if st := z.TransferState(); !st.Expired || !st.Expires.Before(time.Now()) {
+		t.Fatalf("expected expired state, got %+v", st)
+	}
+}
+
+func TestExpiredRefresh(t *testing.T) {
+	current := soa{250}
+
+	dns.HandleFunc(testZone, current.Handler)
+	defer dns.HandleRemove(testZone)
+
+	s, addrstr, err := test.TCPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{addrstr}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("unable to run TransferIn: %v", err)
+	}
+	z.Apex.SOA.Expire = 86400 // the test primary's SOA has all timers at zero
+
+	z.expire()
+	if !z.expired() {
+		t.Fatalf("expected zone to be expired")
+	}
+
+	// The primary has the same serial: the zone is current, as in Update.
+	should, err := z.shouldTransfer()
+	if err != nil {
+		t.Fatalf("unable to run shouldTransfer: %v", err)
+	}
+	if should {
+		t.Fatalf("shouldTransfer should return false for serial: %d", current.serial)
+	}
+	z.refresh()
+	if z.expired() {
+		t.Fatalf("expected refreshed zone not to be expired")
+	}
+}
+
 func TestIsNotify(t *testing.T) {
 	z := new(Zone)
 	z.Expired = new(bool)
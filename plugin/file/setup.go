@@ -1,8 +1,10 @@
 package file
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -54,6 +56,8 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 	z := make(map[string]*Zone)
 	names := []string{}
 	origins := []string{}
+	catalogs := map[string][]string{} // catalog -> member zones
+	catalogNames := []string{}
 
 	config := dnsserver.GetConfig(c)
 
@@ -81,18 +85,22 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 			return Zones{}, err
 		}
 
+		problems := Problems{}
 		for i := range origins {
 			origins[i] = plugin.Host(origins[i]).Normalize()
-			zone, err := Parse(reader, origins[i], fileName, 0)
+			zone, p, err := ParseCheck(reader, origins[i], fileName, 0)
 			if err == nil {
 				z[origins[i]] = zone
 			} else {
 				return Zones{}, err
 			}
+			problems = append(problems, p...)
 			names = append(names, origins[i])
 		}
 
 		noReload := false
+		strict := false
+		catalog := ""
 		upstr := upstream.Upstream{}
 		t := []string{}
 		var e error
@@ -108,6 +116,22 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 			case "no_reload":
 				noReload = true
 
+			case "strict":
+				strict = true
+
+			case "catalog":
+				if !c.NextArg() {
+					return Zones{}, c.ArgErr()
+				}
+				catalog = plugin.Host(c.Val()).Normalize()
+				keys := make([]string, len(c.ServerBlockKeys))
+				for i := range c.ServerBlockKeys {
+					keys[i] = plugin.Host(c.ServerBlockKeys[i]).Normalize()
+				}
+				if plugin.Zones(keys).Matches(catalog) == "" {
+					return Zones{}, c.Errf("catalog zone %s is not in the zones of the server block", catalog)
+				}
+
 			case "upstream":
 				args := c.RemainingArgs()
 				upstr, err = upstream.NewUpstream(args)
@@ -124,9 +148,44 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 					z[origin].TransferTo = append(z[origin].TransferTo, t...)
 				}
 				z[origin].NoReload = noReload
+				z[origin].Strict = strict
 				z[origin].Upstream = upstr
 			}
 		}
+
+		problems.Log()
+		if strict && problems.HasErrors() {
+			return Zones{}, c.Errf("zone file %s has errors", fileName)
+		}
+
+		if catalog != "" {
+			if _, ok := catalogs[catalog]; !ok {
+				catalogNames = append(catalogNames, catalog)
+			}
+			catalogs[catalog] = append(catalogs[catalog], origins...)
+		}
+	}
+
+	// The serial of a catalog changes on every start, so secondaries pick up any new members.
+	serial := uint32(time.Now().Unix())
+	for _, catalog := range catalogNames {
+		if _, ok := z[catalog]; ok {
+			return Zones{}, fmt.Errorf("catalog zone %s is also loaded from a file", catalog)
+		}
+		cz := NewCatalog(catalog, catalogs[catalog], serial)
+		// Whoever may transfer a member may transfer the catalog.
+		seen := map[string]bool{}
+		for _, m := range catalogs[catalog] {
+			for _, t := range z[m].TransferTo {
+				if !seen[t] {
+					cz.TransferTo = append(cz.TransferTo, t)
+					seen[t] = true
+				}
+			}
+		}
+		z[catalog] = cz
+		names = append(names, catalog)
 	}
+
 	return Zones{Z: z, Names: names}, nil
 }
@@ -21,6 +21,12 @@ func TestFileParse(t *testing.T) {
 	}
 	defer rm()
 
+	zoneFileName3, rm, err := test.TempFile(".", dbCheckCNAMEData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
 	tests := []struct {
 		inputFileRules string
 		shouldErr      bool
@@ -67,6 +73,18 @@ func TestFileParse(t *testing.T) {
 			true,
 			Zones{Names: []string{}},
 		},
+		{
+			`file ` + zoneFileName3 + ` example.org.`,
+			false,
+			Zones{Names: []string{"example.org."}},
+		},
+		{
+			`file ` + zoneFileName3 + ` example.org. {
+				strict
+			}`,
+			true,
+			Zones{Names: []string{}},
+		},
 	}
 
 	for i, test := range tests {
@@ -89,3 +107,67 @@ func TestFileParse(t *testing.T) {
 		}
 	}
 }
+
+func TestFileParseCatalog(t *testing.T) {
+	zoneFileName, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		inputFileRules string
+		keys           []string
+		shouldErr      bool
+	}{
+		{
+			`file ` + zoneFileName + ` miek.nl {
+				transfer to 10.0.0.1
+				catalog catalog.example
+			}`,
+			[]string{"."}, false,
+		},
+		{
+			`file ` + zoneFileName + ` miek.nl {
+				catalog catalog.example
+			}`,
+			[]string{"miek.nl."}, true,
+		},
+		{
+			`file ` + zoneFileName + ` miek.nl {
+				catalog
+			}`,
+			[]string{"."}, true,
+		},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.inputFileRules)
+		c.ServerBlockKeys = test.keys
+		zones, err := fileParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		cz, ok := zones.Z["catalog.example."]
+		if !ok {
+			t.Fatalf("Test %d expected catalog zone catalog.example.", i)
+		}
+		members, err := CatalogMembers(cz)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		if _, ok := members["miek.nl."]; !ok {
+			t.Errorf("Test %d expected miek.nl. in the catalog, got %v", i, members)
+		}
+		if len(cz.TransferTo) != 1 || cz.TransferTo[0] != "10.0.0.1:53" {
+			t.Errorf("Test %d expected transfer to 10.0.0.1:53 for the catalog, got %v", i, cz.TransferTo)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package file
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// TransferState is the state of a secondary zone.
+type TransferState struct {
+	Serial        int64     // Serial of the zone, -1 if we don't have the zone (yet).
+	Primary       string    // Primary of the last successful transfer.
+	LastTransfer  time.Time // Time of the last successful transfer.
+	LastRefresh   time.Time // Last time the zone was transferred or the primary had the same serial.
+	NextRefresh   time.Time // When the zone will be checked for a new serial.
+	Expires       time.Time // When the zone expires if it isn't refreshed, zero if we don't have the zone.
+	Expired       bool      // The zone expired and isn't served.
+	LastError     string    // Last error of a transfer or of checking the serial.
+	LastErrorTime time.Time
+}
+
+// TransferState returns the state of the secondary zone z.
+func (z *Zone) TransferState() TransferState {
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
+
+	s := TransferState{
+		Serial:        -1,
+		Primary:       z.primary,
+		LastTransfer:  z.lastTransfer,
+		LastRefresh:   z.refreshed,
+		NextRefresh:   z.nextRefresh,
+		Expired:       z.isExpired(),
+		LastErrorTime: z.lastErrorTime,
+	}
+	if z.Apex.SOA != nil {
+		s.Serial = int64(z.Apex.SOA.Serial)
+		if !z.refreshed.IsZero() {
+			s.Expires = z.refreshed.Add(time.Duration(z.Apex.SOA.Expire) * time.Second)
+		}
+	}
+	if z.lastError != nil {
+		s.LastError = z.lastError.Error()
+	}
+	return s
+}
+
+// expired returns true if the secondary zone z expired: it wasn't refreshed within the SOA's expire
+// time. Expired zones are not served.
+func (z *Zone) expired() bool {
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
+	return z.isExpired()
+}
+
+// isExpired is expired without the locking.
+func (z *Zone) isExpired() bool {
+	if z.Expired != nil && *z.Expired {
+		return true
+	}
+	if z.Apex.SOA == nil || z.refreshed.IsZero() {
+		return false
+	}
+	return time.Since(z.refreshed) > time.Duration(z.Apex.SOA.Expire)*time.Second
+}
+
+// expire marks z as expired.
+func (z *Zone) expire() {
+	z.reloadMu.Lock()
+	defer z.reloadMu.Unlock()
+	if !*z.Expired {
+		*z.Expired = true
+		log.Errorf("Zone `%s' expired, last refreshed at %s", z.origin, z.refreshed.Format(time.RFC3339))
+	}
+}
+
+// failed records err as the last error of z.
+func (z *Zone) failed(err error) {
+	z.reloadMu.Lock()
+	z.lastError, z.lastErrorTime = err, time.Now()
+	z.reloadMu.Unlock()
+}
+
+// setNextRefresh records when z will be refreshed next.
+func (z *Zone) setNextRefresh(t time.Time) {
+	z.reloadMu.Lock()
+	z.nextRefresh = t
+	z.reloadMu.Unlock()
+}
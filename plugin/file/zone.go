@@ -6,6 +6,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/coredns/coredns/plugin/file/tree"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -23,11 +24,23 @@ type Zone struct {
 	Apex Apex
 
 	TransferTo   []string
+	NotifyTo     []string // Addresses that get notifies, on top of the ones in TransferTo.
 	StartupOnce  sync.Once
 	TransferFrom []string
 	Expired      *bool
+	OnTransfer   func(*Zone) // Called after every successful transfer into the zone, load from CacheFile, or reload from disk.
+	CacheFile    string      // Where a secondary zone is saved after a transfer, to be loaded on startup.
+	refreshed    time.Time   // Last time a secondary zone was transferred or found to be current.
+
+	// What we report in TransferState, refreshed and Expired are reported as well.
+	primary       string
+	lastTransfer  time.Time
+	nextRefresh   time.Time
+	lastError     error
+	lastErrorTime time.Time
 
 	NoReload       bool
+	Strict         bool // Refuse to load the zone when Check finds errors in it.
 	reloadMu       sync.RWMutex
 	reloadShutdown chan bool
 	Upstream       upstream.Upstream // Upstream for looking up names during the resolution process
@@ -60,6 +73,8 @@ func NewZone(name, file string) *Zone {
 func (z *Zone) Copy() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferTo = z.TransferTo
+	z1.NotifyTo = z.NotifyTo
+	z1.Strict = z.Strict
 	z1.TransferFrom = z.TransferFrom
 	z1.Expired = z.Expired
 
@@ -71,6 +86,8 @@ func (z *Zone) Copy() *Zone {
 func (z *Zone) CopyWithoutApex() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferTo = z.TransferTo
+	z1.NotifyTo = z.NotifyTo
+	z1.Strict = z.Strict
 	z1.TransferFrom = z.TransferFrom
 	z1.Expired = z.Expired
 
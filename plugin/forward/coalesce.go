@@ -0,0 +1,79 @@
+package forward
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// coalesced is like exchange, but identical queries that are in flight at the same time are only sent
+// upstream once, all of them get a copy of the reply.
+func (f *Forward) coalesced(ctx context.Context, state, upstream request.Request) (*dns.Msg, error) {
+	leader := false
+	v, err := f.inflight.Do(key(state, upstream), func() (interface{}, error) {
+		leader = true
+		return f.exchange(ctx, state, upstream)
+	})
+	if leader {
+		if err != nil {
+			return nil, err
+		}
+		// Others may be copying the reply, so we can't hand out the original.
+		return v.(*dns.Msg).Copy(), nil
+	}
+
+	// Without a reply we didn't coalesce anything; a query rejected by max_concurrent is counted by our caller.
+	if err != nil {
+		return nil, err
+	}
+	ret := v.(*dns.Msg)
+	// The key is a hash, on the off chance of a collision ask upstream ourselves.
+	if len(ret.Question) == 0 || !strings.EqualFold(ret.Question[0].Name, state.QName()) ||
+		ret.Question[0].Qtype != state.QType() || ret.Question[0].Qclass != state.QClass() {
+		return f.exchange(ctx, state, upstream)
+	}
+
+	CoalescedCount.Add(1)
+	ret = ret.Copy()
+	ret.Id = state.Req.Id
+	return ret, nil
+}
+
+// key returns the key for coalescing queries. Queries that may get a different reply, because they differ
+// in transport, buffer size, flags or EDNS0 options, get different keys.
+func key(state, upstream request.Request) uint32 {
+	h := fnv.New32()
+
+	h.Write([]byte(strings.ToLower(state.QName())))
+	b := make([]byte, 6)
+	binary.BigEndian.PutUint16(b, state.QType())
+	binary.BigEndian.PutUint16(b[2:], state.QClass())
+	binary.BigEndian.PutUint16(b[4:], uint16(state.Size()))
+	h.Write(b)
+	h.Write([]byte(state.Proto()))
+
+	flags := []byte{0, 0, 0}
+	if state.Req.CheckingDisabled {
+		flags[0] = 1
+	}
+	if state.Req.RecursionDesired {
+		flags[1] = 1
+	}
+	if state.Do() {
+		flags[2] = 1
+	}
+	h.Write(flags)
+
+	if o := upstream.Req.IsEdns0(); o != nil {
+		for _, e := range o.Option {
+			h.Write([]byte(e.String()))
+		}
+	}
+
+	return h.Sum32()
+}
@@ -0,0 +1,158 @@
+package forward
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+func TestCoalesce(t *testing.T) {
+	var queries int32
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(100 * time.Millisecond)
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A(r.Question[0].Name+" IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.coalesce = true
+	f.SetProxy(NewProxy(s.Addr, nil))
+	defer f.Close()
+
+	const n = 10
+	var wg sync.WaitGroup
+	recs := make([]*dnstest.Recorder, n)
+	for i := 0; i < n; i++ {
+		m := new(dns.Msg)
+		// Two different names, each should go upstream once.
+		if i%2 == 0 {
+			m.SetQuestion("example.org.", dns.TypeA)
+		} else {
+			m.SetQuestion("example.net.", dns.TypeA)
+		}
+		recs[i] = dnstest.NewRecorder(&test.ResponseWriter{})
+
+		wg.Add(1)
+		go func(i int, m *dns.Msg) {
+			defer wg.Done()
+			f.ServeDNS(context.TODO(), recs[i], m)
+		}(i, m)
+	}
+	wg.Wait()
+
+	if x := atomic.LoadInt32(&queries); x != 2 {
+		t.Errorf("Expected 2 upstream queries, got %d", x)
+	}
+	for i, rec := range recs {
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected a reply", i)
+		}
+		if len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Test %d: expected 1 answer, got %d", i, len(rec.Msg.Answer))
+		}
+		if rec.Msg.Answer[0].Header().Name != rec.Msg.Question[0].Name {
+			t.Errorf("Test %d: expected answer for %s, got %s", i, rec.Msg.Question[0].Name, rec.Msg.Answer[0].Header().Name)
+		}
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(100 * time.Millisecond)
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.maxConcurrent = 2
+	f.SetProxy(NewProxy(s.Addr, nil))
+	defer f.Close()
+
+	const n = 5
+	var wg sync.WaitGroup
+	var refused int32
+	for i := 0; i < n; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+
+		wg.Add(1)
+		go func(m *dns.Msg) {
+			defer wg.Done()
+			rcode, err := f.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+			if rcode == dns.RcodeRefused && err == errLimitExceeded {
+				atomic.AddInt32(&refused, 1)
+			}
+		}(m)
+	}
+	wg.Wait()
+
+	if x := atomic.LoadInt32(&refused); x != n-2 {
+		t.Errorf("Expected %d refused queries, got %d", n-2, x)
+	}
+	if x := atomic.LoadInt64(&f.concurrent); x != 0 {
+		t.Errorf("Expected no queries in flight, got %d", x)
+	}
+}
+
+func TestCoalesceMaxConcurrent(t *testing.T) {
+	f := New()
+	f.coalesce = true
+	defer f.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	state := request.Request{W: &test.ResponseWriter{}, Req: m}
+
+	// A leader that is rejected by max_concurrent once the followers have joined it.
+	release := make(chan struct{})
+	go f.inflight.Do(key(state, state), func() (interface{}, error) {
+		<-release
+		return nil, errLimitExceeded
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	coalesced, rejected := counter(CoalescedCount), counter(MaxConcurrentRejectCount)
+
+	const n = 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m.Copy())
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Rejected followers are only counted as rejected.
+	if x := counter(MaxConcurrentRejectCount) - rejected; x != n {
+		t.Errorf("Expected %d rejected queries, got %v", n, x)
+	}
+	if x := counter(CoalescedCount) - coalesced; x != 0 {
+		t.Errorf("Expected no coalesced queries, got %v", x)
+	}
+}
+
+func counter(c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	c.Write(m)
+	return m.GetCounter().GetValue()
+}
@@ -16,8 +16,11 @@ import (
 	"golang.org/x/net/context"
 )
 
+// rtt returns the exponentially weighted moving average of the round trip time to p.
+func (p *Proxy) rtt() time.Duration { return time.Duration(atomic.LoadInt64(&p.avgRtt)) }
+
 func (p *Proxy) readTimeout() time.Duration {
-	rtt := time.Duration(atomic.LoadInt64(&p.avgRtt))
+	rtt := p.rtt()
 
 	if rtt < minTimeout {
 		return minTimeout
@@ -29,7 +32,7 @@ func (p *Proxy) readTimeout() time.Duration {
 }
 
 func (p *Proxy) updateRtt(newRtt time.Duration) {
-	rtt := time.Duration(atomic.LoadInt64(&p.avgRtt))
+	rtt := p.rtt()
 	atomic.AddInt64(&p.avgRtt, int64((newRtt-rtt)/rttCount))
 }
 
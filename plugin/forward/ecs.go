@@ -0,0 +1,52 @@
+package forward
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// subnet returns a request that carries an EDNS0 client subnet option. If the client sent one, it is
+// forwarded with the source prefix length capped to what is configured, otherwise we add one derived
+// from the client's address. The original request is left untouched, as other plugins may still look at it.
+func (f *Forward) subnet(state request.Request) request.Request {
+	req := state.Req.Copy()
+
+	if e := edns.Subnet(req); e != nil {
+		// Don't modify the option in place, it may be shared with the original request.
+		e1 := *e
+		edns.TruncateSubnet(&e1, f.ecsV4, f.ecsV6)
+		edns.SetSubnet(req, &e1)
+		return request.Request{W: state.W, Req: req}
+	}
+
+	ip := net.ParseIP(state.IP())
+	if ip == nil {
+		return state
+	}
+	edns.SetSubnet(req, edns.NewSubnet(ip, f.ecsV4, f.ecsV6))
+	return request.Request{W: state.W, Req: req}
+}
+
+// restore undoes what subnet did to the reply's EDNS0 state. A client that sent no ECS option doesn't get
+// one back, the option is handed to the plugins before us through ctx instead. A client that did send one
+// gets its own option back, with the scope prefix length the upstream returned. A client that sent no OPT
+// record doesn't get one.
+func (f *Forward) restore(ctx context.Context, state request.Request, ret *dns.Msg) {
+	if e := edns.Subnet(state.Req); e == nil {
+		edns.SaveReplySubnet(ctx, ret)
+		edns.RemoveSubnet(ret)
+	} else if e1 := edns.Subnet(ret); e1 != nil {
+		e2 := *e
+		e2.SourceScope = e1.SourceScope
+		edns.SetSubnet(ret, &e2)
+	}
+
+	if state.Req.IsEdns0() == nil {
+		edns.RemoveOPT(ret)
+	}
+}
@@ -0,0 +1,136 @@
+package forward
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestForwardSubnet(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		// Echo the address we've seen back in the answer.
+		if e := edns.Subnet(r); e != nil {
+			ret.Answer = append(ret.Answer, test.A("example.org. IN A "+e.Address.String()))
+		}
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.ecs = true
+	f.ecsV4 = 16
+	f.SetProxy(NewProxy(s.Addr, nil /* not TLS */))
+	defer f.Close()
+
+	tests := []struct {
+		client   *dns.EDNS0_SUBNET
+		expected string
+	}{
+		{nil, "10.240.0.0"}, // address of test.ResponseWriter
+		{edns.NewSubnet(net.ParseIP("192.168.1.1"), 24, 56), "192.168.0.0"},
+		{edns.NewSubnet(net.ParseIP("192.168.1.1"), 8, 56), "192.0.0.0"},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.client != nil {
+			edns.SetSubnet(m, tc.client)
+		}
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Test %d: expected 1 RR in the answer section, got %d", i, len(rec.Msg.Answer))
+		}
+		if a := rec.Msg.Answer[0].(*dns.A).A.String(); a != tc.expected {
+			t.Errorf("Test %d: expected upstream to see %s, got %s", i, tc.expected, a)
+		}
+		// The client's request must not be altered.
+		if tc.client == nil && edns.Subnet(m) != nil {
+			t.Errorf("Test %d: expected client request to be left alone", i)
+		}
+	}
+}
+
+func TestForwardSubnetRestore(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		if e := edns.Subnet(r); e != nil {
+			e1 := *e
+			e1.SourceScope = 16
+			ret.SetEdns0(dns.DefaultMsgSize, false)
+			edns.SetSubnet(ret, &e1)
+		}
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.ecs = true
+	f.ecsV4 = 16
+	f.SetProxy(NewProxy(s.Addr, nil /* not TLS */))
+	defer f.Close()
+
+	tests := []struct {
+		opt    bool
+		client *dns.EDNS0_SUBNET
+	}{
+		{false, nil},
+		{true, nil},
+		{true, edns.NewSubnet(net.ParseIP("192.168.1.1"), 24, 56)},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.opt {
+			m.SetEdns0(dns.DefaultMsgSize, false)
+		}
+		if tc.client != nil {
+			edns.SetSubnet(m, tc.client)
+		}
+
+		saved := new(dns.EDNS0_SUBNET)
+		ctx := edns.WithReplySubnet(context.TODO(), saved)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := f.ServeDNS(ctx, rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		if opt := rec.Msg.IsEdns0() != nil; opt != tc.opt {
+			t.Errorf("Test %d: expected OPT record in reply to be %t, got %t", i, tc.opt, opt)
+		}
+		e := edns.Subnet(rec.Msg)
+		if tc.client == nil {
+			if e != nil {
+				t.Errorf("Test %d: expected no client subnet option in reply", i)
+			}
+			if saved.SourceScope != 16 {
+				t.Errorf("Test %d: expected saved scope prefix length %d, got %d", i, 16, saved.SourceScope)
+			}
+			continue
+		}
+		if e == nil {
+			t.Fatalf("Test %d: expected client subnet option in reply", i)
+		}
+		if e.SourceNetmask != tc.client.SourceNetmask || !e.Address.Equal(tc.client.Address) {
+			t.Errorf("Test %d: expected client's subnet %s/%d, got %s/%d", i, tc.client.Address, tc.client.SourceNetmask, e.Address, e.SourceNetmask)
+		}
+		if e.SourceScope != 16 {
+			t.Errorf("Test %d: expected scope prefix length %d, got %d", i, 16, e.SourceScope)
+		}
+	}
+}
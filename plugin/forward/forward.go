@@ -7,9 +7,11 @@ package forward
 import (
 	"crypto/tls"
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/singleflight"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -24,6 +26,8 @@ type Forward struct {
 	p          Policy
 	hcInterval time.Duration
 
+	domains []*domain // upstreams for specific domains below from
+
 	from    string
 	ignored []string
 
@@ -34,15 +38,34 @@ type Forward struct {
 
 	forceTCP bool // also here for testing
 
+	// EDNS0 client subnet, ecs is true when we add or forward the option.
+	ecs   bool
+	ecsV4 uint8
+	ecsV6 uint8
+
+	maxConcurrent int64
+	concurrent    int64 // number of queries in flight, accessed atomically
+	overflow      int   // rcode returned when maxConcurrent is exceeded
+
+	coalesce bool
+	inflight *singleflight.Group
+
 	Next plugin.Handler
 }
 
 // New returns a new Forward.
 func New() *Forward {
-	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, p: new(random), from: ".", hcInterval: hcDuration}
+	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, p: new(random), from: ".", hcInterval: hcDuration,
+		ecsV4: defaultEcsV4, ecsV6: defaultEcsV6, overflow: dns.RcodeRefused, inflight: new(singleflight.Group)}
 	return f
 }
 
+// domain holds the upstreams for queries in (and below) name.
+type domain struct {
+	name    string
+	proxies []*Proxy
+}
+
 // SetProxy appends p to the proxy list and starts healthchecking.
 func (f *Forward) SetProxy(p *Proxy) {
 	f.proxies = append(f.proxies, p)
@@ -63,12 +86,82 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
 	}
 
+	// upstream is the request we send to the upstreams, this differs from state when we add ECS.
+	upstream := state
+	if f.ecs {
+		upstream = f.subnet(state)
+	}
+
+	var (
+		ret *dns.Msg
+		err error
+	)
+	if f.coalesce {
+		ret, err = f.coalesced(ctx, state, upstream)
+	} else {
+		ret, err = f.exchange(ctx, state, upstream)
+	}
+	if err == errLimitExceeded {
+		MaxConcurrentRejectCount.Add(1)
+		return f.overflow, err
+	}
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	// Check if the reply is correct; if not return FormErr.
+	if !state.Match(ret) {
+		formerr := state.ErrorMessage(dns.RcodeFormatError)
+		w.WriteMsg(formerr)
+		return 0, nil
+	}
+
+	if f.ecs {
+		f.restore(ctx, state, ret)
+	}
+
+	ret.Compress = true
+	// When using force_tcp the upstream can send a message that is too big for
+	// the udp buffer, hence we need to truncate the message to at least make it
+	// fit the udp buffer.
+	ret, _ = state.Scrub(ret)
+
+	w.WriteMsg(ret)
+
+	return 0, nil
+}
+
+// acquire counts a query as in flight. It returns false if max_concurrent queries were already in
+// flight. release must be called after acquire, whatever it returned.
+func (f *Forward) acquire() bool {
+	if f.maxConcurrent == 0 {
+		return true
+	}
+	return atomic.AddInt64(&f.concurrent, 1) <= f.maxConcurrent
+}
+
+// release undoes acquire.
+func (f *Forward) release() {
+	if f.maxConcurrent > 0 {
+		atomic.AddInt64(&f.concurrent, -1)
+	}
+}
+
+// exchange sends upstream to the upstreams until one of them replies, or until we run out of time.
+// If max_concurrent is set and that many queries are already in flight, errLimitExceeded is returned.
+func (f *Forward) exchange(ctx context.Context, state, upstream request.Request) (*dns.Msg, error) {
+	defer f.release()
+	if !f.acquire() {
+		return nil, errLimitExceeded
+	}
+
 	fails := 0
 	var span, child ot.Span
 	var upstreamErr error
 	span = ot.SpanFromContext(ctx)
 	i := 0
-	list := f.list()
+	proxies := f.upstreams(state.Name())
+	list := f.p.List(proxies)
 	deadline := time.Now().Add(defaultTimeout)
 
 	for time.Now().Before(deadline) {
@@ -82,13 +175,13 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		i++
 		if proxy.Down(f.maxfails) {
 			fails++
-			if fails < len(f.proxies) {
+			if fails < len(proxies) {
 				continue
 			}
 			// All upstream proxies are dead, assume healtcheck is completely broken and randomly
 			// select an upstream to connect to.
 			r := new(random)
-			proxy = r.List(f.proxies)[0]
+			proxy = r.List(proxies)[0]
 
 			HealthcheckBrokenCount.Add(1)
 		}
@@ -103,7 +196,7 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			err error
 		)
 		for {
-			ret, err = proxy.connect(ctx, state, f.forceTCP, true)
+			ret, err = proxy.connect(ctx, upstream, f.forceTCP, true)
 			if err != nil && err == errCachedClosed { // Remote side closed conn, can only happen with TCP.
 				continue
 			}
@@ -118,40 +211,27 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		upstreamErr = err
 
 		if err != nil {
+			FailureCount.WithLabelValues(proxy.addr).Add(1)
+
 			// Kick off health check to see if *our* upstream is broken.
 			if f.maxfails != 0 {
 				proxy.Healthcheck()
 			}
 
-			if fails < len(f.proxies) {
+			if fails < len(proxies) {
 				continue
 			}
 			break
 		}
 
-		// Check if the reply is correct; if not return FormErr.
-		if !state.Match(ret) {
-			formerr := state.ErrorMessage(dns.RcodeFormatError)
-			w.WriteMsg(formerr)
-			return 0, nil
-		}
-
-		ret.Compress = true
-		// When using force_tcp the upstream can send a message that is too big for
-		// the udp buffer, hence we need to truncate the message to at least make it
-		// fit the udp buffer.
-		ret, _ = state.Scrub(ret)
-
-		w.WriteMsg(ret)
-
-		return 0, nil
+		return ret, nil
 	}
 
 	if upstreamErr != nil {
-		return dns.RcodeServerFailure, upstreamErr
+		return nil, upstreamErr
 	}
 
-	return dns.RcodeServerFailure, errNoHealthy
+	return nil, errNoHealthy
 }
 
 func (f *Forward) match(state request.Request) bool {
@@ -177,14 +257,42 @@ func (f *Forward) isAllowedDomain(name string) bool {
 	return true
 }
 
-// List returns a set of proxies to be used for this client depending on the policy in f.
-func (f *Forward) list() []*Proxy { return f.p.List(f.proxies) }
+// upstreams returns the proxies for name, these are the proxies of the most specific domain that
+// matches name, or the default ones if none match.
+func (f *Forward) upstreams(name string) []*Proxy {
+	var d *domain
+	for _, x := range f.domains {
+		if !plugin.Name(x.name).Matches(name) {
+			continue
+		}
+		if d == nil || dns.CountLabel(x.name) > dns.CountLabel(d.name) {
+			d = x
+		}
+	}
+	if d == nil {
+		return f.proxies
+	}
+	return d.proxies
+}
+
+// all returns all proxies, including the ones for specific domains.
+func (f *Forward) all() []*Proxy {
+	all := append([]*Proxy{}, f.proxies...)
+	for _, d := range f.domains {
+		all = append(all, d.proxies...)
+	}
+	return all
+}
+
+// List returns a set of proxies to be used for name depending on the policy in f.
+func (f *Forward) list(name string) []*Proxy { return f.p.List(f.upstreams(name)) }
 
 var (
 	errInvalidDomain = errors.New("invalid domain for forward")
 	errNoHealthy     = errors.New("no healthy proxies")
 	errNoForward     = errors.New("no forwarder defined")
 	errCachedClosed  = errors.New("cached connection was closed by peer")
+	errLimitExceeded = errors.New("max concurrent queries exceeded")
 )
 
 // policy tells forward what policy for selecting upstream it uses.
@@ -195,4 +303,9 @@ const (
 	roundRobinPolicy
 )
 
-const defaultTimeout = 5 * time.Second
+const (
+	defaultTimeout = 5 * time.Second
+
+	defaultEcsV4 = 24
+	defaultEcsV6 = 56
+)
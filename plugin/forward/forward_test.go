@@ -41,3 +41,52 @@ func TestForward(t *testing.T) {
 		t.Errorf("Expected 127.0.0.1, got: %s", resp.Answer[0].(*dns.A).A.String())
 	}
 }
+
+func TestForwardDomain(t *testing.T) {
+	// dnstest servers share a handler, so reply with the address of the server that got the query.
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.TXT(r.Question[0].Name+" IN TXT "+w.LocalAddr().String()))
+		w.WriteMsg(ret)
+	}
+	s1 := dnstest.NewServer(handler)
+	defer s1.Close()
+	s2 := dnstest.NewServer(handler)
+	defer s2.Close()
+	s3 := dnstest.NewServer(handler)
+	defer s3.Close()
+
+	f := New()
+	f.SetProxy(NewProxy(s1.Addr, nil))
+	f.domains = []*domain{
+		{name: "example.org.", proxies: []*Proxy{NewProxy(s2.Addr, nil)}},
+		{name: "sub.example.org.", proxies: []*Proxy{NewProxy(s3.Addr, nil)}},
+	}
+	for _, d := range f.domains {
+		d.proxies[0].start(f.hcInterval)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		qname    string
+		expected string
+	}{
+		{"example.net.", s1.Addr},
+		{"example.org.", s2.Addr},
+		{"www.example.org.", s2.Addr},
+		{"sub.example.org.", s3.Addr},
+		{"www.sub.example.org.", s3.Addr},
+	}
+	for i, tc := range tests {
+		state := request.Request{W: &test.ResponseWriter{}, Req: new(dns.Msg)}
+		state.Req.SetQuestion(tc.qname, dns.TypeTXT)
+		resp, err := f.Forward(state)
+		if err != nil {
+			t.Fatalf("Test %d: expected to receive reply, but didn't: %s", i, err)
+		}
+		if txt := resp.Answer[0].(*dns.TXT).Txt[0]; txt != tc.expected {
+			t.Errorf("Test %d: expected %s, got: %s", i, tc.expected, txt)
+		}
+	}
+}
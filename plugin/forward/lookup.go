@@ -12,23 +12,31 @@ import (
 )
 
 // Forward forward the request in state as-is. Unlike Lookup that adds EDNS0 suffix to the message.
-// Forward may be called with a nil f, an error is returned in that case.
+// Forward may be called with a nil f, an error is returned in that case. Forward counts towards
+// max_concurrent, like the queries ServeDNS forwards.
 func (f *Forward) Forward(state request.Request) (*dns.Msg, error) {
 	if f == nil {
 		return nil, errNoForward
 	}
 
+	defer f.release()
+	if !f.acquire() {
+		MaxConcurrentRejectCount.Add(1)
+		return nil, errLimitExceeded
+	}
+
 	fails := 0
 	var upstreamErr error
-	for _, proxy := range f.list() {
+	list := f.list(state.Name())
+	for _, proxy := range list {
 		if proxy.Down(f.maxfails) {
 			fails++
-			if fails < len(f.proxies) {
+			if fails < len(list) {
 				continue
 			}
 			// All upstream proxies are dead, assume healtcheck is complete broken and randomly
 			// select an upstream to connect to.
-			proxy = f.list()[0]
+			proxy = f.list(state.Name())[0]
 		}
 
 		ret, err := proxy.connect(context.Background(), state, f.forceTCP, true)
@@ -37,7 +45,7 @@ func (f *Forward) Forward(state request.Request) (*dns.Msg, error) {
 		upstreamErr = err
 
 		if err != nil {
-			if fails < len(f.proxies) {
+			if fails < len(list) {
 				continue
 			}
 			break
@@ -40,3 +40,31 @@ func TestLookup(t *testing.T) {
 		t.Errorf("Expected 127.0.0.1, got: %s", resp.Answer[0].(*dns.A).A.String())
 	}
 }
+
+func TestLookupMaxConcurrent(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.SetProxy(NewProxy(s.Addr, nil /* no TLS */))
+	defer f.Close()
+	f.maxConcurrent = 1
+	f.concurrent = 1 // a query is in flight
+
+	state := request.Request{W: &test.ResponseWriter{}, Req: new(dns.Msg)}
+	if _, err := f.Lookup(state, "example.org.", dns.TypeA); err != errLimitExceeded {
+		t.Fatalf("Expected %q, got %v", errLimitExceeded, err)
+	}
+	if f.concurrent != 1 {
+		t.Errorf("Expected 1 query in flight, got %d", f.concurrent)
+	}
+
+	f.concurrent = 0
+	if _, err := f.Lookup(state, "example.org.", dns.TypeA); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
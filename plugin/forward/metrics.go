@@ -29,6 +29,12 @@ var (
 		Buckets:   plugin.TimeBuckets,
 		Help:      "Histogram of the time each request took.",
 	}, []string{"to"})
+	FailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "failure_count_total",
+		Help:      "Counter of failed exchanges per upstream.",
+	}, []string{"to"})
 	HealthcheckFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: "forward",
@@ -41,6 +47,18 @@ var (
 		Name:      "healthcheck_broken_count_total",
 		Help:      "Counter of the number of complete failures of the healtchecks.",
 	})
+	MaxConcurrentRejectCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "max_concurrent_reject_count_total",
+		Help:      "Counter of the number of queries rejected because too many queries were in flight.",
+	})
+	CoalescedCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "coalesced_count_total",
+		Help:      "Counter of the number of queries that were answered with the reply of an identical query in flight.",
+	})
 	SocketGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: "forward",
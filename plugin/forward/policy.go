@@ -2,7 +2,9 @@ package forward
 
 import (
 	"math/rand"
+	"sort"
 	"sync/atomic"
+	"time"
 )
 
 // Policy defines a policy we use for selecting upstreams.
@@ -53,3 +55,46 @@ func (r *roundRobin) List(p []*Proxy) []*Proxy {
 
 	return robin
 }
+
+// sequential is a policy that always selects hosts in the order they are configured.
+type sequential struct{}
+
+func (s *sequential) String() string { return "sequential" }
+
+func (s *sequential) List(p []*Proxy) []*Proxy { return p }
+
+// leastLatency is a policy that orders hosts by their average round trip time, the fastest first.
+// Every so often the first host is swapped with a random other one, so that slower hosts get their
+// latency measured again.
+type leastLatency struct{}
+
+func (l *leastLatency) String() string { return "least_latency" }
+
+func (l *leastLatency) List(p []*Proxy) []*Proxy {
+	if len(p) < 2 {
+		return p
+	}
+
+	type latency struct {
+		p   *Proxy
+		rtt time.Duration
+	}
+	ls := make([]latency, len(p))
+	for i := range p {
+		ls[i] = latency{p[i], p[i].rtt()}
+	}
+	sort.SliceStable(ls, func(i, j int) bool { return ls[i].rtt < ls[j].rtt })
+
+	fast := make([]*Proxy, len(p))
+	for i := range ls {
+		fast[i] = ls[i].p
+	}
+	if rand.Intn(exploreRate) == 0 {
+		i := 1 + rand.Intn(len(fast)-1)
+		fast[0], fast[i] = fast[i], fast[0]
+	}
+	return fast
+}
+
+// exploreRate is the 1 in exploreRate chance that leastLatency doesn't put the fastest host first.
+const exploreRate = 20
@@ -0,0 +1,55 @@
+package forward
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSequential(t *testing.T) {
+	p := []*Proxy{NewProxy("10.0.0.1:53", nil), NewProxy("10.0.0.2:53", nil), NewProxy("10.0.0.3:53", nil)}
+	s := &sequential{}
+	for i := 0; i < 3; i++ {
+		list := s.List(p)
+		for j := range p {
+			if list[j] != p[j] {
+				t.Errorf("Expected %s at position %d, got %s", p[j].addr, j, list[j].addr)
+			}
+		}
+	}
+}
+
+func TestLeastLatency(t *testing.T) {
+	p := []*Proxy{NewProxy("10.0.0.1:53", nil), NewProxy("10.0.0.2:53", nil), NewProxy("10.0.0.3:53", nil)}
+	p[0].avgRtt = int64(300 * time.Millisecond)
+	p[1].avgRtt = int64(10 * time.Millisecond)
+	p[2].avgRtt = int64(100 * time.Millisecond)
+
+	l := &leastLatency{}
+	first := 0
+	for i := 0; i < 1000; i++ {
+		list := l.List(p)
+		if len(list) != len(p) {
+			t.Fatalf("Expected %d proxies, got %d", len(p), len(list))
+		}
+		if list[0] == p[1] {
+			first++
+		}
+	}
+	// With exploring 1 in 20 times, we expect about 950 times the fastest proxy to be first.
+	if first < 850 || first == 1000 {
+		t.Errorf("Expected the fastest proxy first most, but not all, of the time, got %d out of 1000", first)
+	}
+
+	// Latency moves, so should the order.
+	p[1].updateRtt(2 * time.Second)
+	p[1].updateRtt(2 * time.Second)
+	first = 0
+	for i := 0; i < 100; i++ {
+		if l.List(p)[0] == p[2] {
+			first++
+		}
+	}
+	if first < 70 {
+		t.Errorf("Expected the new fastest proxy first, got %d out of 100", first)
+	}
+}
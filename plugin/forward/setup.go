@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
@@ -13,6 +14,7 @@ import (
 	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func init() {
@@ -30,6 +32,11 @@ func setup(c *caddy.Controller) error {
 	if f.Len() > max {
 		return plugin.Error("forward", fmt.Errorf("more than %d TOs configured: %d", max, f.Len()))
 	}
+	for _, d := range f.domains {
+		if len(d.proxies) > max {
+			return plugin.Error("forward", fmt.Errorf("more than %d TOs configured for %s: %d", max, d.name, len(d.proxies)))
+		}
+	}
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		f.Next = next
@@ -38,7 +45,8 @@ func setup(c *caddy.Controller) error {
 
 	c.OnStartup(func() error {
 		once.Do(func() {
-			metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, HealthcheckFailureCount, SocketGauge)
+			metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, FailureCount, HealthcheckFailureCount, HealthcheckBrokenCount,
+				MaxConcurrentRejectCount, CoalescedCount, SocketGauge)
 		})
 		return f.OnStartup()
 	})
@@ -52,7 +60,7 @@ func setup(c *caddy.Controller) error {
 
 // OnStartup starts a goroutines for all proxies.
 func (f *Forward) OnStartup() (err error) {
-	for _, p := range f.proxies {
+	for _, p := range f.all() {
 		p.start(f.hcInterval)
 	}
 	return nil
@@ -60,7 +68,7 @@ func (f *Forward) OnStartup() (err error) {
 
 // OnShutdown stops all configured proxies.
 func (f *Forward) OnShutdown() error {
-	for _, p := range f.proxies {
+	for _, p := range f.all() {
 		p.close()
 	}
 	return nil
@@ -72,7 +80,8 @@ func (f *Forward) Close() { f.OnShutdown() }
 func parseForward(c *caddy.Controller) (*Forward, error) {
 	f := New()
 
-	protocols := map[int]int{}
+	// tls holds the proxies that need the TLS config, which is only known at the end.
+	var tls []*Proxy
 
 	i := 0
 	for c.Next() {
@@ -91,44 +100,23 @@ func parseForward(c *caddy.Controller) (*Forward, error) {
 			return f, c.ArgErr()
 		}
 
-		// A bit fiddly, but first check if we've got protocols and if so add them back in when we create the proxies.
-		protocols = make(map[int]int)
-		for i := range to {
-			protocols[i], to[i] = protocol(to[i])
-		}
-
-		// If parseHostPortOrFile expands a file with a lot of nameserver our accounting in protocols doesn't make
-		// any sense anymore... For now: lets don't care.
-		toHosts, err := dnsutil.ParseHostPortOrFile(to...)
+		proxies, tlsProxies, err := parseTo(to)
 		if err != nil {
 			return f, err
 		}
+		f.proxies = append(f.proxies, proxies...)
+		tls = append(tls, tlsProxies...)
 
-		for i, h := range toHosts {
-			// Double check the port, if e.g. is 53 and the transport is TLS make it 853.
-			// This can be somewhat annoying because you *can't* have TLS on port 53 then.
-			switch protocols[i] {
-			case TLS:
-				h1, p, err := net.SplitHostPort(h)
+		for c.NextBlock() {
+			if c.Val() == "domain" {
+				d, tlsProxies, err := parseDomain(c, f.from)
 				if err != nil {
-					break
-				}
-
-				// This is more of a bug in dnsutil.ParseHostPortOrFile that defaults to
-				// 53 because it doesn't know about the tls:// // and friends (that should be fixed). Hence
-				// Fix the port number here, back to what the user intended.
-				if p == "53" {
-					h = net.JoinHostPort(h1, "853")
+					return f, err
 				}
+				f.domains = append(f.domains, d)
+				tls = append(tls, tlsProxies...)
+				continue
 			}
-
-			// We can't set tlsConfig here, because we haven't parsed it yet.
-			// We set it below at the end of parseBlock, use nil now.
-			p := NewProxy(h, nil /* no TLS */)
-			f.proxies = append(f.proxies, p)
-		}
-
-		for c.NextBlock() {
 			if err := parseBlock(c, f); err != nil {
 				return f, err
 			}
@@ -138,14 +126,78 @@ func parseForward(c *caddy.Controller) (*Forward, error) {
 	if f.tlsServerName != "" {
 		f.tlsConfig.ServerName = f.tlsServerName
 	}
-	for i := range f.proxies {
-		// Only set this for proxies that need it.
+	// Only set this for proxies that need it.
+	for _, p := range tls {
+		p.SetTLSConfig(f.tlsConfig)
+	}
+	for _, p := range f.all() {
+		p.SetExpire(f.expire)
+	}
+	return f, nil
+}
+
+// parseDomain parses: domain NAME TO...
+func parseDomain(c *caddy.Controller, from string) (*domain, []*Proxy, error) {
+	args := c.RemainingArgs()
+	if len(args) < 2 {
+		return nil, nil, c.ArgErr()
+	}
+	name := plugin.Host(args[0]).Normalize()
+	if !plugin.Name(from).Matches(name) {
+		return nil, nil, fmt.Errorf("domain %s is not in %s", name, from)
+	}
+
+	proxies, tls, err := parseTo(args[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return &domain{name: name, proxies: proxies}, tls, nil
+}
+
+// parseTo creates the proxies for the upstreams in to. It returns all proxies and the ones that
+// need TLS.
+func parseTo(to []string) ([]*Proxy, []*Proxy, error) {
+	// A bit fiddly, but first check if we've got protocols and if so add them back in when we create the proxies.
+	protocols := make(map[int]int)
+	for i := range to {
+		protocols[i], to[i] = protocol(to[i])
+	}
+
+	// If parseHostPortOrFile expands a file with a lot of nameserver our accounting in protocols doesn't make
+	// any sense anymore... For now: lets don't care.
+	toHosts, err := dnsutil.ParseHostPortOrFile(to...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var proxies, tls []*Proxy
+	for i, h := range toHosts {
+		// Double check the port, if e.g. is 53 and the transport is TLS make it 853.
+		// This can be somewhat annoying because you *can't* have TLS on port 53 then.
+		switch protocols[i] {
+		case TLS:
+			h1, p, err := net.SplitHostPort(h)
+			if err != nil {
+				break
+			}
+
+			// This is more of a bug in dnsutil.ParseHostPortOrFile that defaults to
+			// 53 because it doesn't know about the tls:// // and friends (that should be fixed). Hence
+			// Fix the port number here, back to what the user intended.
+			if p == "53" {
+				h = net.JoinHostPort(h1, "853")
+			}
+		}
+
+		// We can't set tlsConfig here, because we haven't parsed it yet.
+		// We set it at the end of parseForward, use nil now.
+		p := NewProxy(h, nil /* no TLS */)
+		proxies = append(proxies, p)
 		if protocols[i] == TLS {
-			f.proxies[i].SetTLSConfig(f.tlsConfig)
+			tls = append(tls, p)
 		}
-		f.proxies[i].SetExpire(f.expire)
 	}
-	return f, nil
+	return proxies, tls, nil
 }
 
 func parseBlock(c *caddy.Controller, f *Forward) error {
@@ -225,9 +277,67 @@ func parseBlock(c *caddy.Controller, f *Forward) error {
 			f.p = &random{}
 		case "round_robin":
 			f.p = &roundRobin{}
+		case "sequential":
+			f.p = &sequential{}
+		case "least_latency":
+			f.p = &leastLatency{}
 		default:
 			return c.Errf("unknown policy '%s'", x)
 		}
+	case "max_concurrent":
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 2 {
+			return c.ArgErr()
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return fmt.Errorf("max_concurrent must be positive: %d", n)
+		}
+		f.maxConcurrent = int64(n)
+		if len(args) > 1 {
+			switch x := strings.ToUpper(args[1]); x {
+			case "REFUSED":
+				f.overflow = dns.RcodeRefused
+			case "SERVFAIL":
+				f.overflow = dns.RcodeServerFailure
+			default:
+				return c.Errf("unknown rcode '%s'", args[1])
+			}
+		}
+	case "coalesce":
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		f.coalesce = true
+	case "ecs":
+		args := c.RemainingArgs()
+		if len(args) > 2 {
+			return c.ArgErr()
+		}
+		f.ecs = true
+		if len(args) > 0 {
+			v4, err := strconv.ParseUint(args[0], 10, 8)
+			if err != nil {
+				return err
+			}
+			if v4 > 32 {
+				return fmt.Errorf("invalid IPv4 source prefix length: %d", v4)
+			}
+			f.ecsV4 = uint8(v4)
+		}
+		if len(args) > 1 {
+			v6, err := strconv.ParseUint(args[1], 10, 8)
+			if err != nil {
+				return err
+			}
+			if v6 > 128 {
+				return fmt.Errorf("invalid IPv6 source prefix length: %d", v6)
+			}
+			f.ecsV6 = uint8(v6)
+		}
 
 	default:
 		return c.Errf("unknown property '%s'", c.Val())
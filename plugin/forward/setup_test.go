@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func TestSetup(t *testing.T) {
@@ -105,3 +106,140 @@ func TestSetupTLS(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupEcs(t *testing.T) {
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		expectedV4 uint8
+		expectedV6 uint8
+	}{
+		// positive
+		{"forward . 127.0.0.1 {\necs\n}\n", false, 24, 56},
+		{"forward . 127.0.0.1 {\necs 16\n}\n", false, 16, 56},
+		{"forward . 127.0.0.1 {\necs 32 64\n}\n", false, 32, 64},
+		// negative
+		{"forward . 127.0.0.1 {\necs 33\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\necs 24 129\n}\n", true, 0, 0},
+		{"forward . 127.0.0.1 {\necs 24 56 1\n}\n", true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if !f.ecs {
+			t.Errorf("Test %d: expected ecs to be enabled", i)
+		}
+		if f.ecsV4 != test.expectedV4 || f.ecsV6 != test.expectedV6 {
+			t.Errorf("Test %d: expected %d/%d, got %d/%d", i, test.expectedV4, test.expectedV6, f.ecsV4, f.ecsV6)
+		}
+	}
+}
+
+func TestSetupDomain(t *testing.T) {
+	tests := []struct {
+		input           string
+		shouldErr       bool
+		expectedDomains map[string]int // domain -> number of upstreams
+		expectedErr     string
+	}{
+		// positive
+		{`forward . 127.0.0.1 {
+domain example.org 10.0.0.1 10.0.0.2
+domain example.net 10.0.0.3
+}`, false, map[string]int{"example.org.": 2, "example.net.": 1}, ""},
+		{`forward example.org 127.0.0.1 {
+domain sub.example.org tls://10.0.0.1
+tls_servername dns.example.org
+}`, false, map[string]int{"sub.example.org.": 1}, ""},
+		// negative
+		{"forward . 127.0.0.1 {\ndomain example.org\n}\n", true, nil, "Wrong argument count"},
+		{"forward example.org 127.0.0.1 {\ndomain example.net 10.0.0.1\n}\n", true, nil, "not in"},
+		{"forward . 127.0.0.1 {\ndomain example.org a.b.c.d\n}\n", true, nil, "not an IP"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found %s for input %s", i, err, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErr, err, test.input)
+			}
+			continue
+		}
+
+		domains := map[string]int{}
+		for _, d := range f.domains {
+			domains[d.name] = len(d.proxies)
+		}
+		if !reflect.DeepEqual(domains, test.expectedDomains) {
+			t.Errorf("Test %d: expected: %v, got: %v", i, test.expectedDomains, domains)
+		}
+	}
+}
+
+func TestSetupMaxConcurrent(t *testing.T) {
+	tests := []struct {
+		input            string
+		shouldErr        bool
+		expectedMax      int64
+		expectedOverflow int
+		expectedCoalesce bool
+	}{
+		// positive
+		{"forward . 127.0.0.1", false, 0, dns.RcodeRefused, false},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100\n}\n", false, 100, dns.RcodeRefused, false},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100 SERVFAIL\n}\n", false, 100, dns.RcodeServerFailure, false},
+		{"forward . 127.0.0.1 {\nmax_concurrent 100 refused\ncoalesce\n}\n", false, 100, dns.RcodeRefused, true},
+		// negative
+		{"forward . 127.0.0.1 {\nmax_concurrent\n}\n", true, 0, 0, false},
+		{"forward . 127.0.0.1 {\nmax_concurrent 0\n}\n", true, 0, 0, false},
+		{"forward . 127.0.0.1 {\nmax_concurrent 10 NXDOMAIN\n}\n", true, 0, 0, false},
+		{"forward . 127.0.0.1 {\ncoalesce yes\n}\n", true, 0, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found %s for input %s", i, err, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if f.maxConcurrent != test.expectedMax {
+			t.Errorf("Test %d: expected: %d, got: %d", i, test.expectedMax, f.maxConcurrent)
+		}
+		if f.overflow != test.expectedOverflow {
+			t.Errorf("Test %d: expected: %d, got: %d", i, test.expectedOverflow, f.overflow)
+		}
+		if f.coalesce != test.expectedCoalesce {
+			t.Errorf("Test %d: expected: %t, got: %t", i, test.expectedCoalesce, f.coalesce)
+		}
+	}
+}
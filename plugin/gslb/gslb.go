@@ -0,0 +1,119 @@
+// Package gslb implements a plugin that serves address records from pools of health checked endpoints.
+package gslb
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// GSLB is a plugin that answers A and AAAA queries for names that are backed by a pool of endpoints.
+// Only healthy endpoints are returned, if none are healthy the backup pool is used.
+type GSLB struct {
+	Next  plugin.Handler
+	Zones []string
+
+	pools   map[string]*pool
+	records map[string]*record // record name -> record
+	ttl     uint32
+}
+
+// record ties a name to a primary and an optional backup pool.
+type record struct {
+	primary *pool
+	backup  *pool
+}
+
+// New returns a new, empty, GSLB.
+func New(zones []string) *GSLB {
+	return &GSLB{
+		Zones:   zones,
+		pools:   map[string]*pool{},
+		records: map[string]*record{},
+		ttl:     defaultTTL,
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (g *GSLB) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	if plugin.Zones(g.Zones).Matches(qname) == "" {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+	rec, ok := g.records[qname]
+	if !ok {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+
+	qtype := state.QType()
+	if qtype != dns.TypeA && qtype != dns.TypeAAAA {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative, m.Compress = true, true
+	m.Answer = g.answer(state.QName(), qtype, rec)
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// answer returns the records of type qtype for the healthy endpoints in rec's primary pool. If there
+// are none, the backup pool is tried. If neither has healthy endpoints, all endpoints of the primary
+// pool are returned, as we assume the health checking itself is broken.
+func (g *GSLB) answer(qname string, qtype uint16, rec *record) []dns.RR {
+	if up := rec.primary.healthy(); len(up) > 0 {
+		return g.rrs(qname, qtype, up)
+	}
+	if rec.backup != nil {
+		if up := rec.backup.healthy(); len(up) > 0 {
+			FailoverCount.WithLabelValues(rec.primary.name).Inc()
+			return g.rrs(qname, qtype, up)
+		}
+	}
+	return g.rrs(qname, qtype, rec.primary.endpoints)
+}
+
+// rrs returns the A or AAAA records for the endpoints.
+func (g *GSLB) rrs(qname string, qtype uint16, endpoints []*endpoint) []dns.RR {
+	rrs := []dns.RR{}
+	for _, e := range endpoints {
+		hdr := dns.RR_Header{Name: qname, Rrtype: qtype, Class: dns.ClassINET, Ttl: g.ttl}
+		ip4 := e.addr.To4()
+		switch {
+		case qtype == dns.TypeA && ip4 != nil:
+			rrs = append(rrs, &dns.A{Hdr: hdr, A: ip4})
+		case qtype == dns.TypeAAAA && ip4 == nil:
+			rrs = append(rrs, &dns.AAAA{Hdr: hdr, AAAA: e.addr})
+		}
+	}
+	return rrs
+}
+
+// Name implements the plugin.Handler interface.
+func (g *GSLB) Name() string { return "gslb" }
+
+// OnStartup starts the health checking of all pools.
+func (g *GSLB) OnStartup() error {
+	for _, p := range g.pools {
+		p.start()
+	}
+	return nil
+}
+
+// OnShutdown stops the health checking of all pools.
+func (g *GSLB) OnShutdown() error {
+	for _, p := range g.pools {
+		p.stop()
+	}
+	return nil
+}
+
+const defaultTTL = 30
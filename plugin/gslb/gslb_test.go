@@ -0,0 +1,91 @@
+package gslb
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestGSLB(t *testing.T) {
+	g := New([]string{"example.org."})
+	web := g.pool("web")
+	web.endpoints = []*endpoint{newEndpoint(web, net.ParseIP("10.0.0.1")), newEndpoint(web, net.ParseIP("10.0.0.2"))}
+	backup := g.pool("backup")
+	backup.endpoints = []*endpoint{newEndpoint(backup, net.ParseIP("10.1.0.1")), newEndpoint(backup, net.ParseIP("2001:db8::1"))}
+	g.records["www.example.org."] = &record{primary: web, backup: backup}
+	g.Next = test.NextHandler(dns.RcodeRefused, nil)
+
+	tests := []struct {
+		qname    string
+		qtype    uint16
+		down     []*endpoint
+		rcode    int
+		expected []string
+	}{
+		{"www.example.org.", dns.TypeA, nil, dns.RcodeSuccess, []string{"10.0.0.1", "10.0.0.2"}},
+		{"WWW.example.org.", dns.TypeA, nil, dns.RcodeSuccess, []string{"10.0.0.1", "10.0.0.2"}},
+		{"www.example.org.", dns.TypeA, web.endpoints[:1], dns.RcodeSuccess, []string{"10.0.0.2"}},
+		// Failover to the backup pool.
+		{"www.example.org.", dns.TypeA, web.endpoints, dns.RcodeSuccess, []string{"10.1.0.1"}},
+		{"www.example.org.", dns.TypeAAAA, web.endpoints, dns.RcodeSuccess, []string{"2001:db8::1"}},
+		// No AAAA in the primary pool: no data.
+		{"www.example.org.", dns.TypeAAAA, nil, dns.RcodeSuccess, nil},
+		// Everything down, use the primary pool.
+		{"www.example.org.", dns.TypeA, append(web.endpoints, backup.endpoints...), dns.RcodeSuccess, []string{"10.0.0.1", "10.0.0.2"}},
+		// Not ours.
+		{"www.example.org.", dns.TypeMX, nil, dns.RcodeRefused, nil},
+		{"ftp.example.org.", dns.TypeA, nil, dns.RcodeRefused, nil},
+		{"www.example.net.", dns.TypeA, nil, dns.RcodeRefused, nil},
+	}
+
+	for i, tc := range tests {
+		for _, p := range []*pool{web, backup} {
+			for _, e := range p.endpoints {
+				e.set(1)
+			}
+		}
+		for _, e := range tc.down {
+			e.set(0)
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, _ := g.ServeDNS(context.TODO(), rec, m)
+
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rcode)
+			continue
+		}
+		if rcode != dns.RcodeSuccess {
+			continue
+		}
+		if !rec.Msg.Authoritative || rec.Msg.RecursionAvailable {
+			t.Errorf("Test %d: expected an authoritative answer without recursion available", i)
+		}
+		if len(rec.Msg.Answer) != len(tc.expected) {
+			t.Errorf("Test %d: expected %d records, got %d", i, len(tc.expected), len(rec.Msg.Answer))
+			continue
+		}
+		for j, rr := range rec.Msg.Answer {
+			var a string
+			switch x := rr.(type) {
+			case *dns.A:
+				a = x.A.String()
+			case *dns.AAAA:
+				a = x.AAAA.String()
+			}
+			if a != tc.expected[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.expected[j], a)
+			}
+			if rr.Header().Name != tc.qname {
+				t.Errorf("Test %d: expected owner name %s, got %s", i, tc.qname, rr.Header().Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package gslb
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	EndpointHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "gslb",
+		Name:      "endpoint_healthy",
+		Help:      "Gauge that is 1 when an endpoint is healthy and 0 when it is not.",
+	}, []string{"pool", "address"})
+	HealthcheckFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "gslb",
+		Name:      "healthcheck_failure_count_total",
+		Help:      "Counter of the number of failed health checks per endpoint.",
+	}, []string{"pool", "address"})
+	FailoverCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "gslb",
+		Name:      "failover_count_total",
+		Help:      "Counter of answers that were served from the backup pool.",
+	}, []string{"pool"})
+)
+
+var once sync.Once
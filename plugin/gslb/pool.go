@@ -0,0 +1,190 @@
+package gslb
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/up"
+
+	"github.com/miekg/dns"
+)
+
+// pool is a set of endpoints that share a health check.
+type pool struct {
+	name      string
+	endpoints []*endpoint
+
+	check    *check // nil means no health checking, all endpoints are healthy
+	interval time.Duration
+	rise     int
+	fall     int
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newPool(name string) *pool {
+	return &pool{name: name, interval: defaultInterval, rise: defaultRise, fall: defaultFall}
+}
+
+// healthy returns the healthy endpoints in p.
+func (p *pool) healthy() []*endpoint {
+	up := make([]*endpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if e.Healthy() {
+			up = append(up, e)
+		}
+	}
+	return up
+}
+
+// start starts health checking all endpoints. Every interval a check is scheduled on the endpoint's
+// probe. The probe makes sure only one check is in flight, and keeps on checking a failing endpoint
+// every interval until it passes.
+func (p *pool) start() {
+	if p.check == nil {
+		return
+	}
+	p.done = make(chan struct{})
+	p.stopped = make(chan struct{})
+	for _, e := range p.endpoints {
+		e.probe.Start(p.interval)
+		EndpointHealthy.WithLabelValues(p.name, e.addr.String()).Set(1)
+	}
+
+	go func() {
+		defer close(p.stopped)
+		tick := time.NewTicker(p.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-tick.C:
+				for _, e := range p.endpoints {
+					e.probe.Do(e.Check)
+				}
+			}
+		}
+	}()
+}
+
+// stop stops the health checking. It does nothing if start didn't start it.
+func (p *pool) stop() {
+	if p.done == nil {
+		return
+	}
+	close(p.done)
+	p.done = nil
+	// Wait for the scheduling goroutine, so it doesn't block on a stopped probe.
+	<-p.stopped
+	for _, e := range p.endpoints {
+		e.probe.Stop()
+	}
+}
+
+// endpoint is a single address in a pool.
+type endpoint struct {
+	addr net.IP
+	pool *pool
+
+	probe   *up.Probe
+	healthy int32 // 1 is healthy, accessed atomically
+
+	// oks and fails are the number of consecutive passed and failed checks. They are only
+	// touched from the probe, which runs one check at a time.
+	oks   int
+	fails int
+}
+
+func newEndpoint(p *pool, addr net.IP) *endpoint {
+	return &endpoint{addr: addr, pool: p, probe: up.New(), healthy: 1}
+}
+
+// Healthy returns true when e is healthy.
+func (e *endpoint) Healthy() bool { return atomic.LoadInt32(&e.healthy) == 1 }
+
+// Check is used as the up.Func in the up.Probe. An endpoint is marked unhealthy after fall consecutive
+// failures and healthy again after rise consecutive successes.
+func (e *endpoint) Check() error {
+	err := e.pool.check.do(e.addr)
+	if err != nil {
+		HealthcheckFailureCount.WithLabelValues(e.pool.name, e.addr.String()).Inc()
+		e.oks = 0
+		e.fails++
+		if e.fails >= e.pool.fall {
+			e.set(0)
+		}
+		return err
+	}
+
+	e.fails = 0
+	e.oks++
+	if e.oks >= e.pool.rise {
+		e.set(1)
+	}
+	return nil
+}
+
+func (e *endpoint) set(healthy int32) {
+	atomic.StoreInt32(&e.healthy, healthy)
+	EndpointHealthy.WithLabelValues(e.pool.name, e.addr.String()).Set(float64(healthy))
+}
+
+// check is a TCP, HTTP or DNS health check.
+type check struct {
+	proto string // tcp, http or dns
+	port  string
+	arg   string // the path for http, the query name for dns
+}
+
+// do runs the check against addr.
+func (c *check) do(addr net.IP) error {
+	hostport := net.JoinHostPort(addr.String(), c.port)
+
+	switch c.proto {
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", hostport, hcTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case "http":
+		client := &http.Client{Timeout: hcTimeout}
+		resp, err := client.Get("http://" + hostport + c.arg)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("unhealthy status: %s", resp.Status)
+		}
+		return nil
+
+	case "dns":
+		m := new(dns.Msg)
+		m.SetQuestion(c.arg, dns.TypeNS)
+		client := &dns.Client{Net: "udp", ReadTimeout: hcTimeout, WriteTimeout: hcTimeout}
+		ret, _, err := client.Exchange(m, hostport)
+		if err != nil {
+			return err
+		}
+		if ret.Rcode != dns.RcodeSuccess && ret.Rcode != dns.RcodeNameError {
+			return fmt.Errorf("unhealthy rcode: %s", dns.RcodeToString[ret.Rcode])
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown health check: %s", c.proto)
+}
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultRise     = 2
+	defaultFall     = 3
+
+	hcTimeout = 2 * time.Second
+)
@@ -0,0 +1,81 @@
+package gslb
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+
+	"github.com/miekg/dns"
+)
+
+func TestEndpointThresholds(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	p := newPool("web")
+	p.check = &check{proto: "tcp", port: port}
+	p.rise, p.fall = 2, 2
+	e := newEndpoint(p, net.ParseIP("127.0.0.1"))
+
+	if err := e.Check(); err != nil {
+		t.Fatalf("Expected check to pass, got %s", err)
+	}
+	l.Close()
+
+	e.Check()
+	if !e.Healthy() {
+		t.Errorf("Expected endpoint to be healthy after 1 failure")
+	}
+	e.Check()
+	if e.Healthy() {
+		t.Errorf("Expected endpoint to be unhealthy after 2 failures")
+	}
+
+	l, err = net.Listen("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer l.Close()
+
+	e.Check()
+	if e.Healthy() {
+		t.Errorf("Expected endpoint to be unhealthy after 1 success")
+	}
+	e.Check()
+	if !e.Healthy() {
+		t.Errorf("Expected endpoint to be healthy after 2 successes")
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	rcode := int32(dns.RcodeSuccess)
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetRcode(r, int(atomic.LoadInt32(&rcode)))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	host, port, _ := net.SplitHostPort(s.Addr)
+	c := &check{proto: "dns", port: port, arg: "."}
+	if err := c.do(net.ParseIP(host)); err != nil {
+		t.Errorf("Expected check to pass, got %s", err)
+	}
+
+	atomic.StoreInt32(&rcode, dns.RcodeServerFailure)
+	if err := c.do(net.ParseIP(host)); err == nil {
+		t.Errorf("Expected check to fail")
+	}
+}
+
+func TestPoolStopNotStarted(t *testing.T) {
+	p := newPool("web")
+	p.check = &check{proto: "tcp", port: "80"}
+	// Stopping a pool that was never started, e.g. because startup failed, must not panic.
+	p.stop()
+}
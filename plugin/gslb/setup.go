@@ -0,0 +1,224 @@
+package gslb
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("gslb", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	g, err := gslbParse(c)
+	if err != nil {
+		return plugin.Error("gslb", err)
+	}
+
+	c.OnStartup(func() error {
+		once.Do(func() {
+			metrics.MustRegister(c, EndpointHealthy, HealthcheckFailureCount, FailoverCount)
+		})
+		return g.OnStartup()
+	})
+	c.OnShutdown(g.OnShutdown)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		g.Next = next
+		return g
+	})
+
+	return nil
+}
+
+func gslbParse(c *caddy.Controller) (*GSLB, error) {
+	var g *GSLB
+	// record name -> pool names, resolved once all pools are known.
+	records := map[string][]string{}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		origins := make([]string, len(c.ServerBlockKeys))
+		copy(origins, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			origins = args
+		}
+		for j := range origins {
+			origins[j] = plugin.Host(origins[j]).Normalize()
+		}
+		g = New(origins)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "pool":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				p := g.pool(args[0])
+				for _, a := range args[1:] {
+					ip := net.ParseIP(a)
+					if ip == nil {
+						return nil, fmt.Errorf("not an IP address: %q", a)
+					}
+					p.endpoints = append(p.endpoints, newEndpoint(p, ip))
+				}
+
+			case "check":
+				args := c.RemainingArgs()
+				if len(args) < 3 || len(args) > 4 {
+					return nil, c.ArgErr()
+				}
+				chk, err := parseCheck(args[1:])
+				if err != nil {
+					return nil, err
+				}
+				g.pool(args[0]).check = chk
+
+			case "interval":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[1])
+				if err != nil {
+					return nil, err
+				}
+				if d <= 0 {
+					return nil, fmt.Errorf("interval must be positive: %s", d)
+				}
+				g.pool(args[0]).interval = d
+
+			case "threshold":
+				args := c.RemainingArgs()
+				if len(args) != 3 {
+					return nil, c.ArgErr()
+				}
+				rise, err := strconv.Atoi(args[1])
+				if err != nil {
+					return nil, err
+				}
+				fall, err := strconv.Atoi(args[2])
+				if err != nil {
+					return nil, err
+				}
+				if rise <= 0 || fall <= 0 {
+					return nil, fmt.Errorf("thresholds must be positive: %d %d", rise, fall)
+				}
+				p := g.pool(args[0])
+				p.rise, p.fall = rise, fall
+
+			case "record":
+				args := c.RemainingArgs()
+				if len(args) < 2 || len(args) > 3 {
+					return nil, c.ArgErr()
+				}
+				name := plugin.Name(args[0]).Normalize()
+				if plugin.Zones(g.Zones).Matches(name) == "" {
+					return nil, fmt.Errorf("record %q is not in zones %v", name, g.Zones)
+				}
+				records[name] = args[1:]
+
+			case "ttl":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				ttl, err := strconv.Atoi(c.Val())
+				if err != nil {
+					return nil, err
+				}
+				if ttl < 0 || ttl > 3600 {
+					return nil, fmt.Errorf("ttl must be in range [0, 3600]: %d", ttl)
+				}
+				g.ttl = uint32(ttl)
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	for name, p := range g.pools {
+		if len(p.endpoints) == 0 {
+			return nil, fmt.Errorf("pool %q has no addresses", name)
+		}
+	}
+	for name, pools := range records {
+		rec := &record{}
+		for i, pn := range pools {
+			p, ok := g.pools[pn]
+			if !ok {
+				return nil, fmt.Errorf("record %q uses unknown pool %q", name, pn)
+			}
+			if i == 0 {
+				rec.primary = p
+				continue
+			}
+			rec.backup = p
+		}
+		g.records[name] = rec
+	}
+
+	return g, nil
+}
+
+// pool returns the pool with name, creating it if it doesn't exist yet.
+func (g *GSLB) pool(name string) *pool {
+	p, ok := g.pools[name]
+	if !ok {
+		p = newPool(name)
+		g.pools[name] = p
+	}
+	return p
+}
+
+// parseCheck parses: tcp|http|dns PORT [PATH|QNAME]
+func parseCheck(args []string) (*check, error) {
+	chk := &check{proto: args[0]}
+
+	port, err := strconv.ParseUint(args[1], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check port: %s", args[1])
+	}
+	chk.port = strconv.FormatUint(port, 10)
+
+	switch chk.proto {
+	case "tcp":
+		if len(args) > 2 {
+			return nil, fmt.Errorf("tcp health check takes no argument: %s", args[2])
+		}
+	case "http":
+		chk.arg = "/"
+		if len(args) > 2 {
+			if !strings.HasPrefix(args[2], "/") {
+				return nil, fmt.Errorf("http health check path must start with a slash: %s", args[2])
+			}
+			chk.arg = args[2]
+		}
+	case "dns":
+		chk.arg = "."
+		if len(args) > 2 {
+			chk.arg = plugin.Name(args[2]).Normalize()
+		}
+	default:
+		return nil, fmt.Errorf("unknown health check: %s", chk.proto)
+	}
+	return chk, nil
+}
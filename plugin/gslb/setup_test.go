@@ -0,0 +1,114 @@
+package gslb
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupGSLB(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		// positive
+		{`gslb example.org {
+			pool web 10.0.0.1 10.0.0.2 2001:db8::1
+			record www.example.org web
+		}`, false},
+		{`gslb example.org {
+			pool web 10.0.0.1 10.0.0.2
+			pool backup 10.1.0.1
+			check web http 80 /healthz
+			check backup tcp 80
+			interval web 5s
+			threshold web 1 2
+			record www.example.org web backup
+			ttl 10
+		}`, false},
+		{`gslb example.org {
+			pool ns 10.0.0.1
+			check ns dns 53 example.org
+			record ns.example.org ns
+		}`, false},
+		// negative
+		{`gslb example.org {
+			pool web 10.0.0.1
+			record www.example.net web
+		}`, true},
+		{`gslb example.org {
+			pool web 10.0.0.1
+			record www.example.org web2
+		}`, true},
+		{`gslb example.org {
+			pool web a.b.c.d
+		}`, true},
+		{`gslb example.org {
+			check web tcp 80
+		}`, true},
+		{`gslb example.org {
+			pool web 10.0.0.1
+			check web udp 80
+		}`, true},
+		{`gslb example.org {
+			pool web 10.0.0.1
+			check web tcp 80 /healthz
+		}`, true},
+		{`gslb example.org {
+			pool web 10.0.0.1
+			threshold web 0 1
+		}`, true},
+		{`gslb example.org {
+			blaat
+		}`, true},
+		{`gslb
+		gslb`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		_, err := gslbParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil && !test.shouldErr {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+	}
+}
+
+func TestSetupGSLBPool(t *testing.T) {
+	c := caddy.NewTestController("dns", `gslb example.org {
+		pool web 10.0.0.1 10.0.0.2
+		pool backup 10.1.0.1
+		check web http 8080
+		threshold web 1 5
+		record www.example.org web backup
+	}`)
+	g, err := gslbParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	rec, ok := g.records["www.example.org."]
+	if !ok {
+		t.Fatalf("Expected record for www.example.org.")
+	}
+	if rec.primary.name != "web" || rec.backup.name != "backup" {
+		t.Errorf("Expected web and backup pools, got %s and %s", rec.primary.name, rec.backup.name)
+	}
+	p := rec.primary
+	if len(p.endpoints) != 2 {
+		t.Errorf("Expected 2 endpoints, got %d", len(p.endpoints))
+	}
+	if p.check.proto != "http" || p.check.port != "8080" || p.check.arg != "/" {
+		t.Errorf("Unexpected check: %v", p.check)
+	}
+	if p.rise != 1 || p.fall != 5 {
+		t.Errorf("Expected thresholds 1 and 5, got %d and %d", p.rise, p.fall)
+	}
+	if p.interval != defaultInterval {
+		t.Errorf("Expected default interval, got %s", p.interval)
+	}
+}
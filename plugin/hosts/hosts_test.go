@@ -12,7 +12,7 @@ import (
 )
 
 func TestLookupA(t *testing.T) {
-	h := Hosts{Next: test.ErrorHandler(), Hostsfile: &Hostsfile{Origins: []string{"."}}}
+	h := Hosts{Next: test.ErrorHandler(), Hostsfile: &Hostsfile{Origins: []string{"."}}, ttl: 3600}
 	h.parseReader(strings.NewReader(hostsExample))
 
 	ctx := context.TODO()
@@ -10,8 +10,10 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -68,35 +70,94 @@ type Hostsfile struct {
 	// We need a copy here as we want to use it to initialize the maps for parse.
 	inline *hostsMap
 
-	// path to the hosts file
-	path string
+	// paths to the hosts files, or directories with hosts files
+	paths []string
 
-	// mtime and size are only read and modified by a single goroutine
+	// noReverse disables the PTR records for the entries
+	noReverse bool
+
+	// stats holds the modification time and size of the files last read, it's only read and
+	// modified by a single goroutine
+	stats map[string]fileStat
+}
+
+type fileStat struct {
 	mtime time.Time
 	size  int64
 }
 
-// readHosts determines if the cached data needs to be updated based on the size and modification time of the hostsfile.
+// readHosts determines if the cached data needs to be updated based on the size and modification time of the hosts
+// files, and if files were added to or removed from the directories.
 func (h *Hostsfile) readHosts() {
-	file, err := os.Open(h.path)
-	if err != nil {
-		// We already log a warning if the file doesn't exist or can't be opened on setup. No need to return the error here.
-		return
+	names, stats := h.files()
+	if len(stats) == len(h.stats) {
+		changed := false
+		for name, st := range stats {
+			if old, ok := h.stats[name]; !ok || !old.mtime.Equal(st.mtime) || old.size != st.size {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return
+		}
 	}
-	defer file.Close()
 
-	stat, err := file.Stat()
-	if err == nil && h.mtime.Equal(stat.ModTime()) && h.size == stat.Size() {
-		return
+	readers := []io.Reader{}
+	for _, name := range names {
+		file, err := os.Open(name)
+		if err != nil {
+			// The file was removed since we looked, we'll see that on the next change.
+			continue
+		}
+		defer file.Close()
+		// Make sure the last line of a file doesn't run into the first line of the next one.
+		readers = append(readers, file, strings.NewReader("\n"))
 	}
 
 	h.Lock()
 	defer h.Unlock()
-	h.parseReader(file)
+	h.parseReader(io.MultiReader(readers...))
 
 	// Update the data cache.
-	h.mtime = stat.ModTime()
-	h.size = stat.Size()
+	h.stats = stats
+}
+
+// files returns the names of the hosts files in h.paths, in order, and their modification time and
+// size. Every regular file in a directory is a hosts file, except hidden files.
+func (h *Hostsfile) files() ([]string, map[string]fileStat) {
+	names := []string{}
+	stats := make(map[string]fileStat)
+	add := func(name string, fi os.FileInfo) {
+		if _, ok := stats[name]; ok {
+			return
+		}
+		names = append(names, name)
+		stats[name] = fileStat{fi.ModTime(), fi.Size()}
+	}
+
+	for _, p := range h.paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			// We already log a warning if the file doesn't exist or can't be opened on setup.
+			continue
+		}
+		if !fi.IsDir() {
+			add(p, fi)
+			continue
+		}
+		// ReadDir sorts by name.
+		dir, err := ioutil.ReadDir(p)
+		if err != nil {
+			continue
+		}
+		for _, fi := range dir {
+			if fi.Mode().IsRegular() && !strings.HasPrefix(fi.Name(), ".") {
+				add(filepath.Join(p, fi.Name()), fi)
+			}
+		}
+	}
+	return names, stats
 }
 
 func (h *Hostsfile) initInline(inline []string) {
@@ -147,6 +208,9 @@ func (h *Hostsfile) parse(r io.Reader, override *hostsMap) *hostsMap {
 			default:
 				continue
 			}
+			if h.noReverse {
+				continue
+			}
 			hmap.byAddr[addr.String()] = append(hmap.byAddr[addr.String()], name)
 		}
 	}
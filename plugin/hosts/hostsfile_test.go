@@ -5,7 +5,10 @@
 package hosts
 
 import (
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -208,7 +211,7 @@ func testStaticAddr(t *testing.T, ent staticIPEntry, h *Hostsfile) {
 		ent.out[i] = absDomainName(ent.out[i])
 	}
 	if !reflect.DeepEqual(hosts, ent.out) {
-		t.Errorf("%s, lookupStaticAddr(%s) = %v; want %v", h.path, ent.in, hosts, h)
+		t.Errorf("%s, lookupStaticAddr(%s) = %v; want %v", h.paths, ent.in, hosts, h)
 	}
 }
 
@@ -236,3 +239,61 @@ func TestHostCacheModification(t *testing.T) {
 	}
 	testStaticAddr(t, entip, h)
 }
+
+func TestReadHostsFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hostsd := filepath.Join(dir, "hosts.d")
+	if err := os.Mkdir(hostsd, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// No newline at the end, the files must not run into each other.
+	write(filepath.Join(dir, "hosts"), "10.0.0.1 a.example.org")
+	write(filepath.Join(hostsd, "b"), "10.0.0.2 b.example.org\n")
+	write(filepath.Join(hostsd, ".c"), "10.0.0.3 c.example.org\n")
+
+	h := &Hostsfile{
+		Origins: []string{"."},
+		hmap:    newHostsMap(),
+		paths:   []string{filepath.Join(dir, "hosts"), hostsd, filepath.Join(dir, "missing")},
+	}
+	h.readHosts()
+
+	for _, ent := range []staticHostEntry{
+		{"a.example.org", []string{"10.0.0.1"}, nil},
+		{"b.example.org", []string{"10.0.0.2"}, nil},
+		{"c.example.org", nil, nil}, // hidden files are skipped
+		{"d.example.org", nil, nil},
+	} {
+		testStaticHost(t, ent, h)
+	}
+
+	// A new file in the directory is picked up.
+	write(filepath.Join(hostsd, "d"), "10.0.0.4 d.example.org\n")
+	h.readHosts()
+	testStaticHost(t, staticHostEntry{"d.example.org", []string{"10.0.0.4"}, nil}, h)
+
+	// And so is one that is removed.
+	os.Remove(filepath.Join(hostsd, "b"))
+	h.readHosts()
+	testStaticHost(t, staticHostEntry{"b.example.org", nil, nil}, h)
+}
+
+func TestNoReverse(t *testing.T) {
+	h := &Hostsfile{Origins: []string{"."}, noReverse: true}
+	h.parseReader(strings.NewReader("10.0.0.1 a.example.org\n"))
+
+	testStaticHost(t, staticHostEntry{"a.example.org", []string{"10.0.0.1"}, nil}, h)
+	if hosts := h.LookupStaticAddr("10.0.0.1"); len(hosts) != 0 {
+		t.Errorf("Expected no names for 10.0.0.1, got %v", hosts)
+	}
+}
@@ -0,0 +1,73 @@
+package hosts
+
+import (
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"golang.org/x/sys/unix"
+)
+
+// notify watches dirs with inotify and returns a channel that receives a value after something in
+// them changed, until stop is closed. If reading the events fails, we fall back to polling.
+func notify(dirs []string, stop chan struct{}) (<-chan time.Time, error) {
+	// The descriptor is blocking and read with unix.Read; wrapping a non-blocking one in an os.File
+	// only works on Go versions where os.File uses the runtime poller for it.
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ATTRIB
+	wds := []uint32{}
+	for _, dir := range dirs {
+		wd, err := unix.InotifyAddWatch(fd, dir, mask)
+		if err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+		wds = append(wds, uint32(wd))
+	}
+
+	// Closing the descriptor doesn't unblock a read on it, but removing the watches does: each one
+	// queues an IN_IGNORED event. The descriptor is closed after that, so we don't remove watches
+	// from a descriptor number that has been reused.
+	removed := make(chan struct{})
+	go func() {
+		<-stop
+		for _, wd := range wds {
+			unix.InotifyRmWatch(fd, wd)
+		}
+		close(removed)
+	}()
+
+	changed := make(chan time.Time, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			// We don't care what changed, readHosts checks all files.
+			_, err := unix.Read(fd, buf)
+			select {
+			case <-stop:
+				<-removed
+				unix.Close(fd)
+				return
+			default:
+			}
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				log.Warningf("Can not watch hosts files, checking them every %s: %s", pollInterval, err)
+				poll(changed, stop)
+				<-removed
+				unix.Close(fd)
+				return
+			}
+			select {
+			case changed <- time.Now():
+			default:
+			}
+		}
+	}()
+	return changed, nil
+}
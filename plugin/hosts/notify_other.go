@@ -0,0 +1,13 @@
+// +build !linux
+
+package hosts
+
+import (
+	"errors"
+	"time"
+)
+
+// notify is only implemented on Linux.
+func notify(dirs []string, stop chan struct{}) (<-chan time.Time, error) {
+	return nil, errors.New("not supported on this platform")
+}
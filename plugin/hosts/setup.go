@@ -1,10 +1,11 @@
 package hosts
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -26,27 +27,18 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("hosts", err)
 	}
 
-	parseChan := make(chan bool)
+	stop := make(chan struct{})
 
 	c.OnStartup(func() error {
+		// Watch before reading, so we don't miss a change in between.
+		changed := h.watch(stop)
 		h.readHosts()
-
-		go func() {
-			ticker := time.NewTicker(5 * time.Second)
-			for {
-				select {
-				case <-parseChan:
-					return
-				case <-ticker.C:
-					h.readHosts()
-				}
-			}
-		}()
+		go h.reload(changed, stop)
 		return nil
 	})
 
 	c.OnShutdown(func() error {
-		close(parseChan)
+		close(stop)
 		return nil
 	})
 
@@ -61,9 +53,9 @@ func setup(c *caddy.Controller) error {
 func hostsParse(c *caddy.Controller) (Hosts, error) {
 	var h = Hosts{
 		Hostsfile: &Hostsfile{
-			path: "/etc/hosts",
 			hmap: newHostsMap(),
 		},
+		ttl: 3600,
 	}
 
 	config := dnsserver.GetConfig(c)
@@ -78,23 +70,12 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 
 		args := c.RemainingArgs()
 		if len(args) >= 1 {
-			h.path = args[0]
-			args = args[1:]
-
-			if !path.IsAbs(h.path) && config.Root != "" {
-				h.path = path.Join(config.Root, h.path)
-			}
-			s, err := os.Stat(h.path)
+			p, err := hostsPath(config, args[0])
 			if err != nil {
-				if os.IsNotExist(err) {
-					log.Warningf("File does not exist: %s", h.path)
-				} else {
-					return h, c.Errf("unable to access hosts file '%s': %v", h.path, err)
-				}
-			}
-			if s != nil && s.IsDir() {
-				log.Warningf("Hosts file %q is a directory", h.path)
+				return h, c.Errf("%s", err)
 			}
+			h.paths = append(h.paths, p)
+			args = args[1:]
 		}
 
 		origins := make([]string, len(c.ServerBlockKeys))
@@ -110,6 +91,33 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 
 		for c.NextBlock() {
 			switch c.Val() {
+			case "file":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return h, c.ArgErr()
+				}
+				for _, a := range args {
+					p, err := hostsPath(config, a)
+					if err != nil {
+						return h, c.Errf("%s", err)
+					}
+					h.paths = append(h.paths, p)
+				}
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return h, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(args[0], 10, 32)
+				if err != nil {
+					return h, c.Errf("ttl must be a number of seconds: %q", args[0])
+				}
+				h.ttl = uint32(ttl)
+			case "no_reverse":
+				if len(c.RemainingArgs()) != 0 {
+					return h, c.ArgErr()
+				}
+				h.noReverse = true
 			case "fallthrough":
 				h.Fall.SetZonesFromArgs(c.RemainingArgs())
 			default:
@@ -123,7 +131,27 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 		}
 	}
 
+	if len(h.paths) == 0 {
+		h.paths = []string{"/etc/hosts"}
+	}
+
 	h.initInline(inline)
 
 	return h, nil
 }
+
+// hostsPath returns the path of the hosts file or directory p. If p is relative the root from
+// config is prepended to it.
+func hostsPath(config *dnsserver.Config, p string) (string, error) {
+	if !path.IsAbs(p) && config.Root != "" {
+		p = path.Join(config.Root, p)
+	}
+	_, err := os.Stat(p)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("unable to access hosts file '%s': %v", p, err)
+		}
+		log.Warningf("File does not exist: %s", p)
+	}
+	return p, nil
+}
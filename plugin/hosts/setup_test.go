@@ -1,6 +1,7 @@
 package hosts
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/pkg/fall"
@@ -77,8 +78,8 @@ func TestHostsParse(t *testing.T) {
 		} else if err != nil && !test.shouldErr {
 			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
 		} else if !test.shouldErr {
-			if h.path != test.expectedPath {
-				t.Fatalf("Test %d expected %v, got %v", i, test.expectedPath, h.path)
+			if h.paths[0] != test.expectedPath {
+				t.Fatalf("Test %d expected %v, got %v", i, test.expectedPath, h.paths[0])
 			}
 		} else {
 			if !h.Fall.Equal(test.expectedFallthrough) {
@@ -168,3 +169,83 @@ func TestHostsInlineParse(t *testing.T) {
 	}
 
 }
+
+func TestHostsParseOptions(t *testing.T) {
+	tests := []struct {
+		inputFileRules    string
+		shouldErr         bool
+		expectedPaths     []string
+		expectedTTL       uint32
+		expectedNoReverse bool
+	}{
+		{
+			`hosts`,
+			false, []string{"/etc/hosts"}, 3600, false,
+		},
+		{
+			`hosts /etc/hosts {
+				file /etc/hosts.d /tmp/hosts
+				ttl 60
+				no_reverse
+			}`,
+			false, []string{"/etc/hosts", "/etc/hosts.d", "/tmp/hosts"}, 60, true,
+		},
+		{
+			`hosts {
+				file /etc/hosts.d
+				file /tmp/hosts
+			}`,
+			false, []string{"/etc/hosts.d", "/tmp/hosts"}, 3600, false,
+		},
+		{
+			`hosts {
+				ttl 0
+			}`,
+			false, []string{"/etc/hosts"}, 0, false,
+		},
+		{
+			`hosts {
+				file
+			}`,
+			true, nil, 0, false,
+		},
+		{
+			`hosts {
+				ttl -1
+			}`,
+			true, nil, 0, false,
+		},
+		{
+			`hosts {
+				ttl 10 20
+			}`,
+			true, nil, 0, false,
+		},
+		{
+			`hosts {
+				no_reverse example.org
+			}`,
+			true, nil, 0, false,
+		},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.inputFileRules)
+		h, err := hostsParse(c)
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		} else if !test.shouldErr {
+			if !reflect.DeepEqual(h.paths, test.expectedPaths) {
+				t.Fatalf("Test %d expected paths %v, got %v", i, test.expectedPaths, h.paths)
+			}
+			if h.ttl != test.expectedTTL {
+				t.Fatalf("Test %d expected ttl %d, got %d", i, test.expectedTTL, h.ttl)
+			}
+			if h.noReverse != test.expectedNoReverse {
+				t.Fatalf("Test %d expected no_reverse %t, got %t", i, test.expectedNoReverse, h.noReverse)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// pollInterval is how often the hosts files are checked when changes can't be watched.
+const pollInterval = 5 * time.Second
+
+// watch starts watching the hosts files and returns a channel that receives a value after one of
+// them changed, until stop is closed. If the files can't be watched the channel receives a value
+// every pollInterval.
+func (h *Hostsfile) watch(stop chan struct{}) <-chan time.Time {
+	changed, err := notify(h.dirs(), stop)
+	if err == nil {
+		return changed
+	}
+	log.Warningf("Can not watch hosts files, checking them every %s: %s", pollInterval, err)
+
+	polled := make(chan time.Time, 1)
+	go poll(polled, stop)
+	return polled
+}
+
+// poll sends a value on changed every pollInterval, until stop is closed.
+func poll(changed chan<- time.Time, stop chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case t := <-ticker.C:
+			select {
+			case changed <- t:
+			default:
+			}
+		}
+	}
+}
+
+// reload re-reads the hosts files each time changed receives a value, until stop is closed.
+func (h *Hostsfile) reload(changed <-chan time.Time, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-changed:
+			h.readHosts()
+		}
+	}
+}
+
+// dirs returns the directories to watch for changes to the hosts files. For a file this is the
+// directory it lives in, so we see it being created or replaced.
+func (h *Hostsfile) dirs() []string {
+	dirs := []string{}
+	seen := make(map[string]bool)
+	for _, p := range h.paths {
+		dir := p
+		if fi, err := os.Stat(p); err != nil || !fi.IsDir() {
+			dir = filepath.Dir(p)
+		}
+		if !seen[dir] {
+			dirs = append(dirs, dir)
+			seen[dir] = true
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,76 @@
+package hosts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "hosts")
+	if err := ioutil.WriteFile(name, []byte("10.0.0.1 a.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Hostsfile{Origins: []string{"."}, hmap: newHostsMap(), paths: []string{name}}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	changed := h.watch(stop)
+	h.readHosts()
+	go h.reload(changed, stop)
+
+	// Replace the file, like editors do. The size differs, as the modification time may not have
+	// changed yet.
+	tmp := filepath.Join(dir, ".hosts.tmp")
+	if err := ioutil.WriteFile(tmp, []byte("10.0.0.22 a.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without inotify we poll, allow for that.
+	deadline := time.Now().Add(2 * pollInterval)
+	for time.Now().Before(deadline) {
+		if ips := h.LookupStaticHostV4("a.example.org"); len(ips) == 1 && ips[0].String() == "10.0.0.22" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected a.example.org to be reloaded, got %v", h.LookupStaticHostV4("a.example.org"))
+}
+
+func TestWatchStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stop := make(chan struct{})
+	changed, err := notify([]string{dir}, stop)
+	if err != nil {
+		t.Skipf("Can not watch %s: %s", dir, err)
+	}
+	close(stop)
+
+	// The reader must have returned, a change after stop isn't reported.
+	time.Sleep(50 * time.Millisecond)
+	if err := ioutil.WriteFile(filepath.Join(dir, "hosts"), []byte("10.0.0.1 a.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+		t.Errorf("Expected no change to be reported after stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,121 @@
+package external
+
+import (
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// serveApex serves requests that hit the zone's apex. A reply is written back to the client.
+func (e *External) serveApex(state request.Request) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	switch state.QType() {
+	case dns.TypeSOA:
+		m.Answer = []dns.RR{e.soa(state)}
+	case dns.TypeNS:
+		m.Answer = []dns.RR{e.ns(state)}
+
+		addr := e.externalAddrFunc(state)
+		for _, rr := range e.a(addr, state) {
+			rr.Header().Name = m.Answer[0].(*dns.NS).Ns
+			m.Extra = append(m.Extra, rr)
+		}
+		for _, rr := range e.aaaa(addr, state) {
+			rr.Header().Name = m.Answer[0].(*dns.NS).Ns
+			m.Extra = append(m.Extra, rr)
+		}
+	default:
+		m.Ns = []dns.RR{e.soa(state)}
+	}
+
+	m.Authoritative, m.RecursionAvailable, m.Compress = true, true, true
+
+	state.SizeAndDo(m)
+	state.W.WriteMsg(m)
+	return 0, nil
+}
+
+// serveSubApex serves requests that hit the zones fake 'dns' subdomain where our nameservers live.
+func (e *External) serveSubApex(state request.Request) (int, error) {
+	base, _ := dnsutil.TrimZone(state.Name(), state.Zone)
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative, m.RecursionAvailable, m.Compress = true, true, true
+
+	// base is either dns. or ns1.dns (or another name), if it's longer return nxdomain
+	switch labels := dns.CountLabel(base); labels {
+	default:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{e.soa(state)}
+		state.SizeAndDo(m)
+		state.W.WriteMsg(m)
+		return 0, nil
+	case 2:
+		if ns := dns.SplitDomainName(base)[0]; ns != ns1 {
+			// nxdomain
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{e.soa(state)}
+			state.SizeAndDo(m)
+			state.W.WriteMsg(m)
+			return 0, nil
+		}
+
+		addr := e.externalAddrFunc(state)
+		for _, rr := range e.a(addr, state) {
+			rr.Header().Name = state.QName()
+			switch state.QType() {
+			case dns.TypeA:
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+		for _, rr := range e.aaaa(addr, state) {
+			rr.Header().Name = state.QName()
+			switch state.QType() {
+			case dns.TypeAAAA:
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+
+		if len(m.Answer) == 0 {
+			m.Ns = []dns.RR{e.soa(state)}
+		}
+
+		state.SizeAndDo(m)
+		state.W.WriteMsg(m)
+		return 0, nil
+
+	case 1:
+		// nodata for the dns empty non-terminal
+		m.Ns = []dns.RR{e.soa(state)}
+		state.SizeAndDo(m)
+		state.W.WriteMsg(m)
+		return 0, nil
+	}
+}
+
+func (e *External) soa(state request.Request) *dns.SOA {
+	header := dns.RR_Header{Name: state.Zone, Rrtype: dns.TypeSOA, Ttl: e.ttl, Class: dns.ClassINET}
+
+	soa := &dns.SOA{Hdr: header,
+		Mbox:    dnsutil.Join([]string{e.hostmaster, e.apex, state.Zone}),
+		Ns:      dnsutil.Join([]string{ns1, e.apex, state.Zone}),
+		Serial:  e.serialFunc(state),
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  86400,
+		Minttl:  e.ttl,
+	}
+	return soa
+}
+
+func (e *External) ns(state request.Request) *dns.NS {
+	header := dns.RR_Header{Name: state.Zone, Rrtype: dns.TypeNS, Ttl: e.ttl, Class: dns.ClassINET}
+	ns := &dns.NS{Hdr: header, Ns: dnsutil.Join([]string{ns1, e.apex, state.Zone})}
+
+	return ns
+}
+
+const ns1 = "ns1"
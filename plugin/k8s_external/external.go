@@ -0,0 +1,113 @@
+/*
+Package external implements external names for kubernetes clusters.
+
+This plugin only handles three "kinds" of queries:
+
+* A, AAAA and SRV queries for names of services with external (load balancer or external) IPs.
+* NS, SOA queries for the apex of the zones.
+* A, AAAA queries for the nameserver names in the apex.
+
+The data for the services comes from the kubernetes plugin, without it this plugin is a noop.
+*/
+package external
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Externaler defines the interface that a plugin should implement in order to be used by External.
+type Externaler interface {
+	// External returns a slice of msg.Services that are looked up in the backend and match
+	// the request, and the rcode to use when there are none.
+	External(request.Request) ([]msg.Service, int)
+	// ExternalAddress returns the addresses of the nameserver for the zones.
+	ExternalAddress(state request.Request) []msg.Service
+	// Serial returns the serial for the SOA record.
+	Serial(state request.Request) uint32
+}
+
+// External serves the load balancer and external IPs of kubernetes services.
+type External struct {
+	Next  plugin.Handler
+	Zones []string
+
+	hostmaster string
+	apex       string
+	ttl        uint32
+
+	externalFunc     func(request.Request) ([]msg.Service, int)
+	externalAddrFunc func(request.Request) []msg.Service
+	serialFunc       func(request.Request) uint32
+}
+
+// New returns a new and initialized *External.
+func New() *External {
+	e := &External{hostmaster: "hostmaster", ttl: 5, apex: "dns"}
+	return e
+}
+
+// ServeDNS implements the plugin.Handle interface.
+func (e *External) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(e.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(e.Name(), e.Next, ctx, w, r)
+	}
+
+	if e.externalFunc == nil {
+		return plugin.NextOrFailure(e.Name(), e.Next, ctx, w, r)
+	}
+
+	state.Zone = zone
+	if state.Name() == zone { // apex query
+		return e.serveApex(state)
+	}
+	if dns.IsSubDomain(e.apex+"."+zone, state.Name()) {
+		// dns subdomain test for ns. and dns. queries
+		return e.serveSubApex(state)
+	}
+
+	svc, rcode := e.externalFunc(state)
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative, m.RecursionAvailable, m.Compress = true, true, true
+
+	if len(svc) == 0 {
+		m.Rcode = rcode
+		m.Ns = []dns.RR{e.soa(state)}
+		state.SizeAndDo(m)
+		w.WriteMsg(m)
+		return 0, nil
+	}
+
+	switch state.QType() {
+	case dns.TypeA:
+		m.Answer = e.a(svc, state)
+	case dns.TypeAAAA:
+		m.Answer = e.aaaa(svc, state)
+	case dns.TypeSRV:
+		m.Answer, m.Extra = e.srv(svc, state)
+	default:
+		m.Ns = []dns.RR{e.soa(state)}
+	}
+
+	// If we did have records, but queried for the wrong qtype return a nodata response.
+	if len(m.Answer) == 0 {
+		m.Ns = []dns.RR{e.soa(state)}
+	}
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	w.WriteMsg(m)
+	return 0, nil
+}
+
+// Name implements the Handler interface.
+func (e *External) Name() string { return "k8s_external" }
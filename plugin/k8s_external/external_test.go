@@ -0,0 +1,189 @@
+package external
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExternal(t *testing.T) {
+	k := kubernetes.New([]string{"cluster.local."})
+	k.Namespaces = map[string]bool{"testns": true}
+	k.APIConn = &external{}
+
+	e := New()
+	e.Zones = []string{"example.com."}
+	e.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	e.externalFunc = k.External
+	e.externalAddrFunc = k.ExternalAddress
+	e.serialFunc = k.Serial
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := e.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+		if tc.Error != nil {
+			continue
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		test.SortAndCheck(t, resp, tc)
+	}
+}
+
+var tests = []test.Case{
+	// Load balancer ingress IP
+	{
+		Qname: "svc1.testns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.A("svc1.testns.example.com.	5	IN	A	1.2.3.4")},
+	},
+	// External IPs
+	{
+		Qname: "svc6.testns.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.AAAA("svc6.testns.example.com.	5	IN	AAAA	1:2::5")},
+	},
+	{
+		Qname: "_http._tcp.svc1.testns.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.SRV("_http._tcp.svc1.testns.example.com.	5	IN	SRV	0 100 80 svc1.testns.example.com.")},
+		Extra:  []dns.RR{test.A("svc1.testns.example.com.	5	IN	A	1.2.3.4")},
+	},
+	{
+		Qname: "_https._tcp.svc1.testns.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	// NODATA
+	{
+		Qname: "svc1.testns.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	{
+		Qname: "testns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	// Services without an external IP don't exist.
+	{
+		Qname: "svc0.testns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	// Not exposed namespace.
+	{
+		Qname: "svc1.otherns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	// Apex
+	{
+		Qname: "example.com.", Qtype: dns.TypeSOA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	{
+		Qname: "example.com.", Qtype: dns.TypeNS, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.NS("example.com.	5	IN	NS	ns1.dns.example.com.")},
+		Extra:  []dns.RR{test.A("ns1.dns.example.com.	5	IN	A	127.0.0.1")},
+	},
+	{
+		Qname: "example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	{
+		Qname: "dns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+	{
+		Qname: "ns1.dns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.A("ns1.dns.example.com.	5	IN	A	127.0.0.1")},
+	},
+	{
+		Qname: "ns2.dns.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.dns.example.com. 0 7200 1800 86400 5")},
+	},
+}
+
+type external struct{}
+
+func (external) HasSynced() bool                                 { return true }
+func (external) Run()                                            { return }
+func (external) Stop() error                                     { return nil }
+func (external) EpIndexReverse(string) []*object.Endpoints       { return nil }
+func (external) SvcIndexReverse(string) []*object.Service        { return nil }
+func (external) Modified() int64                                 { return 0 }
+func (external) IngressIndex(string) []*object.Ingress           { return nil }
+func (external) EpIndex(s string) []*object.Endpoints            { return nil }
+func (external) EndpointsList() []*object.Endpoints              { return nil }
+func (external) GetNodeByName(name string) (*object.Node, error) { return nil, nil }
+func (external) SvcIndex(s string) []*object.Service             { return svcIndexExternal[s] }
+func (external) PodIndex(string) []*object.Pod                   { return nil }
+
+func (external) GetNamespaceByName(name string) (*api.Namespace, error) {
+	if name != "testns" && name != "otherns" {
+		return nil, errors.New("namespace not found")
+	}
+	return &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: name}}, nil
+}
+
+func (external) ServiceList() []*object.Service {
+	var svcs []*object.Service
+	for _, svc := range svcIndexExternal {
+		svcs = append(svcs, svc...)
+	}
+	return svcs
+}
+
+var svcIndexExternal = map[string][]*object.Service{
+	"svc0.testns": {
+		{
+			Name:      "svc0",
+			Namespace: "testns",
+			Type:      api.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.1",
+			Ports:     []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+		},
+	},
+	"svc1.testns": {
+		{
+			Name:        "svc1",
+			Namespace:   "testns",
+			Type:        api.ServiceTypeLoadBalancer,
+			ClusterIP:   "10.0.0.1",
+			Ports:       []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+			ExternalIPs: []string{"1.2.3.4"},
+		},
+	},
+	"svc6.testns": {
+		{
+			Name:        "svc6",
+			Namespace:   "testns",
+			Type:        api.ServiceTypeClusterIP,
+			ClusterIP:   "10.0.0.3",
+			ExternalIPs: []string{"1:2::5"},
+			Ports:       []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+		},
+	},
+	"svc1.otherns": {
+		{
+			Name:        "svc1",
+			Namespace:   "otherns",
+			Type:        api.ServiceTypeClusterIP,
+			ClusterIP:   "10.0.0.4",
+			ExternalIPs: []string{"1.2.3.5"},
+			Ports:       []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+		},
+	},
+}
@@ -0,0 +1,87 @@
+package external
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func (e *External) a(services []msg.Service, state request.Request) (records []dns.RR) {
+	dup := make(map[string]struct{})
+
+	for _, s := range services {
+		ip := net.ParseIP(s.Host)
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+		if _, ok := dup[s.Host]; ok {
+			continue
+		}
+		dup[s.Host] = struct{}{}
+		rr := s.NewA(state.QName(), ip)
+		rr.Hdr.Ttl = e.ttl
+		records = append(records, rr)
+	}
+	return records
+}
+
+func (e *External) aaaa(services []msg.Service, state request.Request) (records []dns.RR) {
+	dup := make(map[string]struct{})
+
+	for _, s := range services {
+		ip := net.ParseIP(s.Host)
+		if ip == nil || ip.To4() != nil {
+			continue
+		}
+		if _, ok := dup[s.Host]; ok {
+			continue
+		}
+		dup[s.Host] = struct{}{}
+		rr := s.NewAAAA(state.QName(), ip)
+		rr.Hdr.Ttl = e.ttl
+		records = append(records, rr)
+	}
+	return records
+}
+
+// srv returns the SRV records for services, the target is the name of the service, i.e. the qname
+// without the port and protocol labels. The addresses of the target are returned as extra records.
+func (e *External) srv(services []msg.Service, state request.Request) (records, extra []dns.RR) {
+	target := state.QName()
+	// Strip _port._protocol.
+	for i := 0; i < 2; i++ {
+		if off, end := dns.NextLabel(target, 0); !end && len(target) > 0 && target[0] == '_' {
+			target = target[off:]
+		}
+	}
+
+	ports := make(map[int]struct{})
+	for _, s := range services {
+		if _, ok := ports[s.Port]; ok {
+			continue
+		}
+		ports[s.Port] = struct{}{}
+		records = append(records, &dns.SRV{
+			Hdr:    dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: e.ttl},
+			Weight: 100, Port: uint16(s.Port), Target: target,
+		})
+	}
+	if len(records) > 1 {
+		weight := uint16(100 / len(records))
+		if weight == 0 {
+			weight = 1
+		}
+		for _, r := range records {
+			r.(*dns.SRV).Weight = weight
+		}
+	}
+
+	addr := request.Request{W: state.W, Req: new(dns.Msg)}
+	addr.Req.SetQuestion(target, dns.TypeA)
+	extra = append(extra, e.a(services, addr)...)
+	extra = append(extra, e.aaaa(services, addr)...)
+	return records, extra
+}
@@ -0,0 +1,59 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input        string
+		shouldErr    bool
+		expectedZone string
+		expectedApex string
+		expectedTTL  uint32
+	}{
+		{`k8s_external`, false, "", "dns", 5},
+		{`k8s_external example.org`, false, "example.org.", "dns", 5},
+		{`k8s_external example.org {
+			apex testdns
+		}`, false, "example.org.", "testdns", 5},
+		{`k8s_external example.org {
+			ttl 300
+		}`, false, "example.org.", "dns", 300},
+		{`k8s_external example.org {
+			ttl 3601
+		}`, true, "", "", 0},
+		{`k8s_external example.org {
+			blaat
+		}`, true, "", "", 0},
+		{`k8s_external
+		k8s_external`, true, "", "", 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		e, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found %s for input %s", i, err, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if test.expectedZone != "" && e.Zones[0] != test.expectedZone {
+			t.Errorf("Test %d, expected zone %q for input %s, got: %q", i, test.expectedZone, test.input, e.Zones[0])
+		}
+		if e.apex != test.expectedApex {
+			t.Errorf("Test %d, expected apex %q for input %s, got: %q", i, test.expectedApex, test.input, e.apex)
+		}
+		if e.ttl != test.expectedTTL {
+			t.Errorf("Test %d, expected ttl %d for input %s, got: %d", i, test.expectedTTL, test.input, e.ttl)
+		}
+	}
+}
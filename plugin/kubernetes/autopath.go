@@ -4,7 +4,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/request"
 
-	api "k8s.io/api/core/v1"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
 )
 
 // AutoPath implements the AutoPathFunc call from the autopath plugin.
@@ -39,8 +39,8 @@ func (k *Kubernetes) AutoPath(state request.Request) []string {
 	return search
 }
 
-// podWithIP return the api.Pod for source IP ip. It returns nil if nothing can be found.
-func (k *Kubernetes) podWithIP(ip string) *api.Pod {
+// podWithIP return the object.Pod for source IP ip. It returns nil if nothing can be found.
+func (k *Kubernetes) podWithIP(ip string) *object.Pod {
 	ps := k.APIConn.PodIndex(ip)
 	if len(ps) == 0 {
 		return nil
@@ -7,8 +7,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+
 	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,18 +29,20 @@ const (
 	svcIPIndex            = "ServiceIP"
 	epNameNamespaceIndex  = "EndpointNameNamespace"
 	epIPIndex             = "EndpointsIP"
+	ingressHostIndex      = "IngressHost"
 )
 
 type dnsController interface {
-	ServiceList() []*api.Service
-	SvcIndex(string) []*api.Service
-	SvcIndexReverse(string) []*api.Service
-	PodIndex(string) []*api.Pod
-	EpIndex(string) []*api.Endpoints
-	EpIndexReverse(string) []*api.Endpoints
-	EndpointsList() []*api.Endpoints
-
-	GetNodeByName(string) (*api.Node, error)
+	ServiceList() []*object.Service
+	SvcIndex(string) []*object.Service
+	SvcIndexReverse(string) []*object.Service
+	PodIndex(string) []*object.Pod
+	EpIndex(string) []*object.Endpoints
+	EpIndexReverse(string) []*object.Endpoints
+	EndpointsList() []*object.Endpoints
+	IngressIndex(string) []*object.Ingress
+
+	GetNodeByName(string) (*object.Node, error)
 	GetNamespaceByName(string) (*api.Namespace, error)
 
 	Run()
@@ -57,15 +63,19 @@ type dnsControl struct {
 
 	selector labels.Selector
 
-	svcController cache.Controller
-	podController cache.Controller
-	epController  cache.Controller
-	nsController  cache.Controller
+	svcController  cache.Controller
+	podController  cache.Controller
+	epController   cache.Controller
+	nsController   cache.Controller
+	ingController  cache.Controller
+	nodeController cache.Controller
 
-	svcLister cache.Indexer
-	podLister cache.Indexer
-	epLister  cache.Indexer
-	nsLister  storeToNamespaceLister
+	svcLister  cache.Indexer
+	podLister  cache.Indexer
+	epLister   cache.Indexer
+	nsLister   storeToNamespaceLister
+	ingLister  cache.Indexer
+	nodeLister cache.Indexer
 
 	// stopLock is used to enforce only a single call to Stop is active.
 	// Needed because we allow stopping through an http endpoint and
@@ -78,7 +88,11 @@ type dnsControl struct {
 type dnsControlOpts struct {
 	initPodCache       bool
 	initEndpointsCache bool
+	initIngressCache   bool
+	initNodeCache      bool
 	resyncPeriod       time.Duration
+	// sliceClient is set when EndpointSlices are watched instead of Endpoints.
+	sliceClient rest.Interface
 	// Label handling.
 	labelSelector *meta.LabelSelector
 	selector      labels.Selector
@@ -92,7 +106,7 @@ func newdnsController(kubeClient *kubernetes.Clientset, opts dnsControlOpts) *dn
 		stopCh:   make(chan struct{}),
 	}
 
-	dns.svcLister, dns.svcController = cache.NewIndexerInformer(
+	dns.svcLister, dns.svcController = object.NewIndexerInformer(
 		&cache.ListWatch{
 			ListFunc:  serviceListFunc(dns.client, namespace, dns.selector),
 			WatchFunc: serviceWatchFunc(dns.client, namespace, dns.selector),
@@ -100,10 +114,11 @@ func newdnsController(kubeClient *kubernetes.Clientset, opts dnsControlOpts) *dn
 		&api.Service{},
 		opts.resyncPeriod,
 		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
-		cache.Indexers{svcNameNamespaceIndex: svcNameNamespaceIndexFunc, svcIPIndex: svcIPIndexFunc})
+		cache.Indexers{svcNameNamespaceIndex: svcNameNamespaceIndexFunc, svcIPIndex: svcIPIndexFunc},
+		object.ToService)
 
 	if opts.initPodCache {
-		dns.podLister, dns.podController = cache.NewIndexerInformer(
+		dns.podLister, dns.podController = object.NewIndexerInformer(
 			&cache.ListWatch{
 				ListFunc:  podListFunc(dns.client, namespace, dns.selector),
 				WatchFunc: podWatchFunc(dns.client, namespace, dns.selector),
@@ -111,11 +126,23 @@ func newdnsController(kubeClient *kubernetes.Clientset, opts dnsControlOpts) *dn
 			&api.Pod{},
 			opts.resyncPeriod,
 			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
-			cache.Indexers{podIPIndex: podIPIndexFunc})
+			cache.Indexers{podIPIndex: podIPIndexFunc},
+			object.ToPod)
 	}
 
-	if opts.initEndpointsCache {
-		dns.epLister, dns.epController = cache.NewIndexerInformer(
+	if opts.initEndpointsCache && opts.sliceClient != nil {
+		dns.epLister, dns.epController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  endpointSliceListFunc(opts.sliceClient, namespace, dns.selector),
+				WatchFunc: endpointSliceWatchFunc(opts.sliceClient, namespace, dns.selector),
+			},
+			&object.EndpointSlice{},
+			opts.resyncPeriod,
+			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+			cache.Indexers{epNameNamespaceIndex: epNameNamespaceIndexFunc, epIPIndex: epIPIndexFunc},
+			object.ToEndpointSlice)
+	} else if opts.initEndpointsCache {
+		dns.epLister, dns.epController = object.NewIndexerInformer(
 			&cache.ListWatch{
 				ListFunc:  endpointsListFunc(dns.client, namespace, dns.selector),
 				WatchFunc: endpointsWatchFunc(dns.client, namespace, dns.selector),
@@ -123,7 +150,35 @@ func newdnsController(kubeClient *kubernetes.Clientset, opts dnsControlOpts) *dn
 			&api.Endpoints{},
 			opts.resyncPeriod,
 			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
-			cache.Indexers{epNameNamespaceIndex: epNameNamespaceIndexFunc, epIPIndex: epIPIndexFunc})
+			cache.Indexers{epNameNamespaceIndex: epNameNamespaceIndexFunc, epIPIndex: epIPIndexFunc},
+			object.ToEndpoints)
+	}
+
+	if opts.initIngressCache {
+		dns.ingLister, dns.ingController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  ingressListFunc(dns.client, namespace, dns.selector),
+				WatchFunc: ingressWatchFunc(dns.client, namespace, dns.selector),
+			},
+			&extensions.Ingress{},
+			opts.resyncPeriod,
+			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+			cache.Indexers{ingressHostIndex: ingressHostIndexFunc},
+			object.ToIngress)
+	}
+
+	if opts.initNodeCache {
+		// Nodes are not filtered by the label selector, it selects the objects we answer for.
+		dns.nodeLister, dns.nodeController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  nodeListFunc(dns.client),
+				WatchFunc: nodeWatchFunc(dns.client),
+			},
+			&api.Node{},
+			opts.resyncPeriod,
+			cache.ResourceEventHandlerFuncs{},
+			cache.Indexers{},
+			object.ToNode)
 	}
 
 	dns.nsLister.Store, dns.nsController = cache.NewInformer(
@@ -142,49 +197,43 @@ type storeToNamespaceLister struct {
 }
 
 func podIPIndexFunc(obj interface{}) ([]string, error) {
-	p, ok := obj.(*api.Pod)
+	p, ok := obj.(*object.Pod)
 	if !ok {
-		return nil, errors.New("obj was not an *api.Pod")
+		return nil, errors.New("obj was not an *object.Pod")
 	}
-	return []string{p.Status.PodIP}, nil
+	return []string{p.PodIP}, nil
 }
 
 func svcIPIndexFunc(obj interface{}) ([]string, error) {
-	svc, ok := obj.(*api.Service)
+	svc, ok := obj.(*object.Service)
 	if !ok {
-		return nil, errors.New("obj was not an *api.Service")
+		return nil, errors.New("obj was not an *object.Service")
 	}
-	return []string{svc.Spec.ClusterIP}, nil
+	return []string{svc.ClusterIP}, nil
 }
 
 func svcNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
-	s, ok := obj.(*api.Service)
+	s, ok := obj.(*object.Service)
 	if !ok {
-		return nil, errors.New("obj was not an *api.Service")
+		return nil, errors.New("obj was not an *object.Service")
 	}
-	return []string{s.ObjectMeta.Name + "." + s.ObjectMeta.Namespace}, nil
+	return []string{s.Index}, nil
 }
 
 func epNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
-	s, ok := obj.(*api.Endpoints)
+	s, ok := obj.(*object.Endpoints)
 	if !ok {
-		return nil, errors.New("obj was not an *api.Endpoints")
+		return nil, errors.New("obj was not an *object.Endpoints")
 	}
-	return []string{s.ObjectMeta.Name + "." + s.ObjectMeta.Namespace}, nil
+	return []string{s.Index}, nil
 }
 
 func epIPIndexFunc(obj interface{}) ([]string, error) {
-	ep, ok := obj.(*api.Endpoints)
+	ep, ok := obj.(*object.Endpoints)
 	if !ok {
-		return nil, errors.New("obj was not an *api.Endpoints")
-	}
-	var idx []string
-	for _, eps := range ep.Subsets {
-		for _, addr := range eps.Addresses {
-			idx = append(idx, addr.IP)
-		}
+		return nil, errors.New("obj was not an *object.Endpoints")
 	}
-	return idx, nil
+	return ep.IndexIP, nil
 }
 
 func serviceListFunc(c *kubernetes.Clientset, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
@@ -291,6 +340,26 @@ func namespaceWatchFunc(c *kubernetes.Clientset, s labels.Selector) func(options
 	}
 }
 
+func nodeListFunc(c *kubernetes.Clientset) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		listV1, err := c.CoreV1().Nodes().List(opts)
+		if err != nil {
+			return nil, err
+		}
+		return listV1, err
+	}
+}
+
+func nodeWatchFunc(c *kubernetes.Clientset) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		w, err := c.CoreV1().Nodes().Watch(options)
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+}
+
 // Stop stops the  controller.
 func (dns *dnsControl) Stop() error {
 	dns.stopLock.Lock()
@@ -300,6 +369,7 @@ func (dns *dnsControl) Stop() error {
 	if !dns.shutdown {
 		close(dns.stopCh)
 		dns.shutdown = true
+		dns.forgetCacheObjects()
 
 		return nil
 	}
@@ -316,6 +386,12 @@ func (dns *dnsControl) Run() {
 	if dns.podController != nil {
 		go dns.podController.Run(dns.stopCh)
 	}
+	if dns.ingController != nil {
+		go dns.ingController.Run(dns.stopCh)
+	}
+	if dns.nodeController != nil {
+		go dns.nodeController.Run(dns.stopCh)
+	}
 	go dns.nsController.Run(dns.stopCh)
 	<-dns.stopCh
 }
@@ -332,13 +408,21 @@ func (dns *dnsControl) HasSynced() bool {
 		c = dns.podController.HasSynced()
 	}
 	d := dns.nsController.HasSynced()
-	return a && b && c && d
+	e := true
+	if dns.ingController != nil {
+		e = dns.ingController.HasSynced()
+	}
+	f := true
+	if dns.nodeController != nil {
+		f = dns.nodeController.HasSynced()
+	}
+	return a && b && c && d && e && f
 }
 
-func (dns *dnsControl) ServiceList() (svcs []*api.Service) {
+func (dns *dnsControl) ServiceList() (svcs []*object.Service) {
 	os := dns.svcLister.List()
 	for _, o := range os {
-		s, ok := o.(*api.Service)
+		s, ok := o.(*object.Service)
 		if !ok {
 			continue
 		}
@@ -347,7 +431,7 @@ func (dns *dnsControl) ServiceList() (svcs []*api.Service) {
 	return svcs
 }
 
-func (dns *dnsControl) PodIndex(ip string) (pods []*api.Pod) {
+func (dns *dnsControl) PodIndex(ip string) (pods []*object.Pod) {
 	if dns.podLister == nil {
 		return nil
 	}
@@ -356,7 +440,7 @@ func (dns *dnsControl) PodIndex(ip string) (pods []*api.Pod) {
 		return nil
 	}
 	for _, o := range os {
-		p, ok := o.(*api.Pod)
+		p, ok := o.(*object.Pod)
 		if !ok {
 			continue
 		}
@@ -365,7 +449,7 @@ func (dns *dnsControl) PodIndex(ip string) (pods []*api.Pod) {
 	return pods
 }
 
-func (dns *dnsControl) SvcIndex(idx string) (svcs []*api.Service) {
+func (dns *dnsControl) SvcIndex(idx string) (svcs []*object.Service) {
 	if dns.svcLister == nil {
 		return nil
 	}
@@ -374,7 +458,7 @@ func (dns *dnsControl) SvcIndex(idx string) (svcs []*api.Service) {
 		return nil
 	}
 	for _, o := range os {
-		s, ok := o.(*api.Service)
+		s, ok := o.(*object.Service)
 		if !ok {
 			continue
 		}
@@ -383,7 +467,7 @@ func (dns *dnsControl) SvcIndex(idx string) (svcs []*api.Service) {
 	return svcs
 }
 
-func (dns *dnsControl) SvcIndexReverse(ip string) (svcs []*api.Service) {
+func (dns *dnsControl) SvcIndexReverse(ip string) (svcs []*object.Service) {
 	if dns.svcLister == nil {
 		return nil
 	}
@@ -393,7 +477,7 @@ func (dns *dnsControl) SvcIndexReverse(ip string) (svcs []*api.Service) {
 	}
 
 	for _, o := range os {
-		s, ok := o.(*api.Service)
+		s, ok := o.(*object.Service)
 		if !ok {
 			continue
 		}
@@ -402,7 +486,7 @@ func (dns *dnsControl) SvcIndexReverse(ip string) (svcs []*api.Service) {
 	return svcs
 }
 
-func (dns *dnsControl) EpIndex(idx string) (ep []*api.Endpoints) {
+func (dns *dnsControl) EpIndex(idx string) (ep []*object.Endpoints) {
 	if dns.epLister == nil {
 		return nil
 	}
@@ -411,7 +495,7 @@ func (dns *dnsControl) EpIndex(idx string) (ep []*api.Endpoints) {
 		return nil
 	}
 	for _, o := range os {
-		e, ok := o.(*api.Endpoints)
+		e, ok := o.(*object.Endpoints)
 		if !ok {
 			continue
 		}
@@ -420,7 +504,7 @@ func (dns *dnsControl) EpIndex(idx string) (ep []*api.Endpoints) {
 	return ep
 }
 
-func (dns *dnsControl) EpIndexReverse(ip string) (ep []*api.Endpoints) {
+func (dns *dnsControl) EpIndexReverse(ip string) (ep []*object.Endpoints) {
 	if dns.svcLister == nil {
 		return nil
 	}
@@ -429,7 +513,7 @@ func (dns *dnsControl) EpIndexReverse(ip string) (ep []*api.Endpoints) {
 		return nil
 	}
 	for _, o := range os {
-		e, ok := o.(*api.Endpoints)
+		e, ok := o.(*object.Endpoints)
 		if !ok {
 			continue
 		}
@@ -438,13 +522,13 @@ func (dns *dnsControl) EpIndexReverse(ip string) (ep []*api.Endpoints) {
 	return ep
 }
 
-func (dns *dnsControl) EndpointsList() (eps []*api.Endpoints) {
+func (dns *dnsControl) EndpointsList() (eps []*object.Endpoints) {
 	if dns.epLister == nil {
 		return nil
 	}
 	os := dns.epLister.List()
 	for _, o := range os {
-		ep, ok := o.(*api.Endpoints)
+		ep, ok := o.(*object.Endpoints)
 		if !ok {
 			continue
 		}
@@ -454,14 +538,28 @@ func (dns *dnsControl) EndpointsList() (eps []*api.Endpoints) {
 }
 
 // GetNodeByName return the node by name. If nothing is found an error is
-// returned. This query causes a roundtrip to the k8s API server, so use
-// sparingly. Currently this is only used for Federation.
-func (dns *dnsControl) GetNodeByName(name string) (*api.Node, error) {
+// returned. Unless nodes are cached, this query causes a roundtrip to the
+// k8s API server, so use sparingly.
+func (dns *dnsControl) GetNodeByName(name string) (*object.Node, error) {
+	if dns.nodeLister != nil {
+		o, exists, err := dns.nodeLister.GetByKey(name)
+		if err != nil {
+			return &object.Node{}, err
+		}
+		if !exists {
+			return &object.Node{}, fmt.Errorf("node not found")
+		}
+		n, ok := o.(*object.Node)
+		if !ok {
+			return &object.Node{}, errors.New("obj was not an *object.Node")
+		}
+		return n, nil
+	}
 	v1node, err := dns.client.CoreV1().Nodes().Get(name, meta.GetOptions{})
 	if err != nil {
-		return &api.Node{}, err
+		return &object.Node{}, err
 	}
-	return v1node, nil
+	return object.ToNode(v1node).(*object.Node), nil
 }
 
 // GetNamespaceByName returns the namespace by name. If nothing is found an
@@ -491,14 +589,39 @@ func (dns *dnsControl) updateModifed() {
 	atomic.StoreInt64(&dns.modified, unix)
 }
 
-func (dns *dnsControl) Add(obj interface{})    { dns.updateModifed() }
-func (dns *dnsControl) Delete(obj interface{}) { dns.updateModifed() }
+// forgetCacheObjects removes the objects in our caches from CacheObjects. Several controllers can be
+// running, i.e. during a reload.
+func (dns *dnsControl) forgetCacheObjects() {
+	listers := map[string]cache.Indexer{"services": dns.svcLister, "endpoints": dns.epLister, "pods": dns.podLister, "ingresses": dns.ingLister}
+	for t, l := range listers {
+		if l == nil {
+			continue
+		}
+		CacheObjects.WithLabelValues(t).Sub(float64(len(l.ListKeys())))
+	}
+}
+
+// Add implements the cache.ResourceEventHandler interface.
+func (dns *dnsControl) Add(obj interface{}) {
+	if t := cacheType(obj); t != "" {
+		CacheObjects.WithLabelValues(t).Inc()
+	}
+	dns.updateModifed()
+}
+
+// Delete implements the cache.ResourceEventHandler interface.
+func (dns *dnsControl) Delete(obj interface{}) {
+	if t := cacheType(obj); t != "" {
+		CacheObjects.WithLabelValues(t).Dec()
+	}
+	dns.updateModifed()
+}
 
 func (dns *dnsControl) Update(objOld, newObj interface{}) {
 	// endpoint updates can come frequently, make sure
 	// it's a change we care about
-	if o, ok := objOld.(*api.Endpoints); ok {
-		n := newObj.(*api.Endpoints)
+	if o, ok := objOld.(*object.Endpoints); ok {
+		n := newObj.(*object.Endpoints)
 		if endpointsEquivalent(o, n) {
 			return
 		}
@@ -508,7 +631,7 @@ func (dns *dnsControl) Update(objOld, newObj interface{}) {
 
 // endpointsEquivalent checks if the update to an endpoint is something
 // that matters to us: ready addresses, host names, ports (including names for SRV)
-func endpointsEquivalent(a, b *api.Endpoints) bool {
+func endpointsEquivalent(a, b *object.Endpoints) bool {
 	// supposedly we should be able to rely on
 	// these being sorted and able to be compared
 	// they are supposed to be in a canonical format
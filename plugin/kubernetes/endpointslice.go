@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func init() {
+	// The clientset doesn't know about EndpointSlices, add them so we can decode them.
+	scheme.Scheme.AddKnownTypes(object.SliceGroupVersion, &object.EndpointSlice{}, &object.EndpointSliceList{})
+	meta.AddToGroupVersion(scheme.Scheme, object.SliceGroupVersion)
+}
+
+// endpointSliceClient returns a client for the EndpointSlice API, or nil if the API server doesn't
+// serve EndpointSlices; the Endpoints API is then used.
+func endpointSliceClient(c *kubernetes.Clientset, config *rest.Config) (rest.Interface, error) {
+	resources, err := c.Discovery().ServerResourcesForGroupVersion(object.SliceGroupVersion.String())
+	if err != nil {
+		log.Infof("Watching Endpoints, EndpointSlices are not available: %s", err)
+		return nil, nil
+	}
+	found := false
+	for _, r := range resources.APIResources {
+		if r.Name == "endpointslices" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		log.Infof("Watching Endpoints, EndpointSlices are not available")
+		return nil, nil
+	}
+
+	cc := *config
+	gv := object.SliceGroupVersion
+	cc.GroupVersion = &gv
+	cc.APIPath = "/apis"
+	// There is no protobuf encoding for our copy of the EndpointSlice types.
+	cc.ContentType = runtime.ContentTypeJSON
+	cc.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	if cc.UserAgent == "" {
+		cc.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	log.Infof("Watching EndpointSlices")
+	return rest.RESTClientFor(&cc)
+}
+
+func endpointSliceListFunc(c rest.Interface, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if s != nil {
+			opts.LabelSelector = s.String()
+		}
+		list := &object.EndpointSliceList{}
+		err := c.Get().
+			Namespace(ns).
+			Resource("endpointslices").
+			VersionedParams(&opts, scheme.ParameterCodec).
+			Do().
+			Into(list)
+		if err != nil {
+			return nil, err
+		}
+		return list, err
+	}
+}
+
+func endpointSliceWatchFunc(c rest.Interface, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		if s != nil {
+			options.LabelSelector = s.String()
+		}
+		options.Watch = true
+		w, err := c.Get().
+			Namespace(ns).
+			Resource("endpointslices").
+			VersionedParams(&options, scheme.ParameterCodec).
+			Watch()
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+}
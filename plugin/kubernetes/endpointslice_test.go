@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestDecodeEndpointSlice(t *testing.T) {
+	data := `{
+	"apiVersion": "discovery.k8s.io/v1beta1",
+	"kind": "EndpointSlice",
+	"metadata": {"name": "svc1-abcde", "namespace": "testns", "labels": {"kubernetes.io/service-name": "svc1"}},
+	"addressType": "IPv4",
+	"endpoints": [{"addresses": ["172.0.0.1"], "conditions": {"ready": true}, "topology": {"kubernetes.io/hostname": "node1"}}],
+	"ports": [{"name": "http", "protocol": "TCP", "port": 80}]
+}`
+	codec := scheme.Codecs.UniversalDecoder(object.SliceGroupVersion)
+	obj, err := runtime.Decode(codec, []byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	slice, ok := obj.(*object.EndpointSlice)
+	if !ok {
+		t.Fatalf("Expected *object.EndpointSlice, got %T", obj)
+	}
+	if slice.Name != "svc1-abcde" || len(slice.Endpoints) != 1 || slice.Endpoints[0].Topology["kubernetes.io/hostname"] != "node1" {
+		t.Errorf("Unexpected slice: %+v", slice)
+	}
+	if len(slice.Ports) != 1 || *slice.Ports[0].Port != 80 {
+		t.Errorf("Unexpected ports: %+v", slice.Ports)
+	}
+}
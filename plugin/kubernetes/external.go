@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// External implements the ExternalFunc call from the k8s_external plugin.
+// It returns any services matching the query, as well as the rcode to use.
+func (k *Kubernetes) External(state request.Request) ([]msg.Service, int) {
+	base, _ := dnsutil.TrimZone(state.Name(), state.Zone)
+
+	segs := dns.SplitDomainName(base)
+	last := len(segs) - 1
+	if last < 0 {
+		return nil, dns.RcodeServerFailure
+	}
+	// We are dealing with a fairly normal domain name here, but we still need to have the service
+	// and the namespace:
+	// service.namespace.<base>
+	//
+	// for SRV it looks like:
+	// _port._protocol.service.namespace.<base>
+	port := "*"
+	protocol := "*"
+	namespace := segs[last]
+	if !k.namespaceExposed(namespace) || !k.namespace(namespace) {
+		return nil, dns.RcodeNameError
+	}
+
+	last--
+	if last < 0 {
+		return nil, dns.RcodeSuccess
+	}
+
+	service := segs[last]
+	last--
+	if last == 1 {
+		protocol = stripUnderscore(segs[last])
+		port = stripUnderscore(segs[last-1])
+		last -= 2
+	}
+
+	if last != -1 {
+		// too long
+		return nil, dns.RcodeNameError
+	}
+
+	zonePath := msg.Path(state.Zone, "coredns")
+	rcode := dns.RcodeNameError
+	services := []msg.Service{}
+
+	for _, svc := range k.APIConn.SvcIndex(object.ServiceKey(service, namespace)) {
+		if len(svc.ExternalIPs) == 0 {
+			continue
+		}
+		if port == "*" && protocol == "*" {
+			rcode = dns.RcodeSuccess
+		}
+
+		for _, ip := range svc.ExternalIPs {
+			for _, p := range svc.Ports {
+				if !(match(port, p.Name) && match(protocol, string(p.Protocol))) {
+					continue
+				}
+				rcode = dns.RcodeSuccess
+				s := msg.Service{Host: ip, Port: int(p.Port), TTL: k.ttl}
+				s.Key = strings.Join([]string{zonePath, svc.Namespace, svc.Name}, "/")
+				services = append(services, s)
+			}
+		}
+	}
+	return services, rcode
+}
+
+// ExternalAddress returns the external service addresses of the CoreDNS service, these are used as
+// the addresses of the nameserver of the k8s_external zones. If CoreDNS isn't behind a service with
+// external addresses, the local address is returned.
+func (k *Kubernetes) ExternalAddress(state request.Request) []msg.Service {
+	localIP := k.interfaceAddrsFunc()
+
+	name, namespace := k.localService(localIP)
+	if name != "" {
+		for _, svc := range k.APIConn.SvcIndex(object.ServiceKey(name, namespace)) {
+			if len(svc.ExternalIPs) == 0 {
+				continue
+			}
+			services := make([]msg.Service, len(svc.ExternalIPs))
+			for i, ip := range svc.ExternalIPs {
+				services[i] = msg.Service{Host: ip, TTL: k.ttl}
+			}
+			return services
+		}
+	}
+	return []msg.Service{{Host: localIP.String(), TTL: k.ttl}}
+}
+
+// localService returns the name and namespace of the service that has localIP as an endpoint.
+func (k *Kubernetes) localService(localIP net.IP) (name, namespace string) {
+	for _, ep := range k.APIConn.EpIndexReverse(localIP.String()) {
+		for _, eps := range ep.Subsets {
+			for _, addr := range eps.Addresses {
+				if localIP.Equal(net.ParseIP(addr.IP)) {
+					return ep.Name, ep.Namespace
+				}
+			}
+		}
+	}
+	return "", ""
+}
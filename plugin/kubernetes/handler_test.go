@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coredns/coredns/plugin/kubernetes/object"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
 
@@ -282,170 +283,123 @@ func TestServeDNS(t *testing.T) {
 
 type APIConnServeTest struct{}
 
-func (APIConnServeTest) HasSynced() bool                        { return true }
-func (APIConnServeTest) Run()                                   { return }
-func (APIConnServeTest) Stop() error                            { return nil }
-func (APIConnServeTest) EpIndexReverse(string) []*api.Endpoints { return nil }
-func (APIConnServeTest) SvcIndexReverse(string) []*api.Service  { return nil }
-func (APIConnServeTest) Modified() int64                        { return time.Now().Unix() }
+func (APIConnServeTest) HasSynced() bool                           { return true }
+func (APIConnServeTest) Run()                                      { return }
+func (APIConnServeTest) Stop() error                               { return nil }
+func (APIConnServeTest) EpIndexReverse(string) []*object.Endpoints { return nil }
+func (APIConnServeTest) SvcIndexReverse(string) []*object.Service  { return nil }
+func (APIConnServeTest) Modified() int64                           { return time.Now().Unix() }
 
-func (APIConnServeTest) PodIndex(string) []*api.Pod {
-	a := []*api.Pod{{
-		ObjectMeta: meta.ObjectMeta{
-			Namespace: "podns",
-		},
-		Status: api.PodStatus{
-			PodIP: "10.240.0.1", // Remote IP set in test.ResponseWriter
-		},
-	}}
+func (APIConnServeTest) PodIndex(string) []*object.Pod {
+	a := []*object.Pod{
+		{Namespace: "podns", PodIP: "10.240.0.1"}, // Remote IP set in test.ResponseWriter
+	}
 	return a
 }
 
-var svcIndex = map[string][]*api.Service{
-	"svc1.testns": {{
-		ObjectMeta: meta.ObjectMeta{
+var svcIndex = map[string][]*object.Service{
+	"svc1.testns": {
+		{
 			Name:      "svc1",
 			Namespace: "testns",
-		},
-		Spec: api.ServiceSpec{
 			Type:      api.ServiceTypeClusterIP,
 			ClusterIP: "10.0.0.1",
-			Ports: []api.ServicePort{{
-				Name:     "http",
-				Protocol: "tcp",
-				Port:     80,
-			}},
+			Ports: []api.ServicePort{
+				{Name: "http", Protocol: "tcp", Port: 80},
+			},
 		},
-	}},
-	"svc6.testns": {{
-		ObjectMeta: meta.ObjectMeta{
+	},
+	"svc6.testns": {
+		{
 			Name:      "svc6",
 			Namespace: "testns",
-		},
-		Spec: api.ServiceSpec{
 			Type:      api.ServiceTypeClusterIP,
 			ClusterIP: "1234:abcd::1",
-			Ports: []api.ServicePort{{
-				Name:     "http",
-				Protocol: "tcp",
-				Port:     80,
-			}},
+			Ports: []api.ServicePort{
+				{Name: "http", Protocol: "tcp", Port: 80},
+			},
 		},
-	}},
-	"hdls1.testns": {{
-		ObjectMeta: meta.ObjectMeta{
+	},
+	"hdls1.testns": {
+		{
 			Name:      "hdls1",
 			Namespace: "testns",
-		},
-		Spec: api.ServiceSpec{
 			Type:      api.ServiceTypeClusterIP,
 			ClusterIP: api.ClusterIPNone,
 		},
-	}},
-	"external.testns": {{
-		ObjectMeta: meta.ObjectMeta{
-			Name:      "external",
-			Namespace: "testns",
-		},
-		Spec: api.ServiceSpec{
+	},
+	"external.testns": {
+		{
+			Name:         "external",
+			Namespace:    "testns",
 			ExternalName: "ext.interwebs.test",
-			Ports: []api.ServicePort{{
-				Name:     "http",
-				Protocol: "tcp",
-				Port:     80,
-			}},
-			Type: api.ServiceTypeExternalName,
+			Type:         api.ServiceTypeExternalName,
+			Ports: []api.ServicePort{
+				{Name: "http", Protocol: "tcp", Port: 80},
+			},
 		},
-	}},
+	},
 }
 
-func (APIConnServeTest) SvcIndex(s string) []*api.Service {
-	return svcIndex[s]
-}
+func (APIConnServeTest) SvcIndex(s string) []*object.Service { return svcIndex[s] }
 
-func (APIConnServeTest) ServiceList() []*api.Service {
-	var svcs []*api.Service
+func (APIConnServeTest) ServiceList() []*object.Service {
+	var svcs []*object.Service
 	for _, svc := range svcIndex {
 		svcs = append(svcs, svc...)
 	}
 	return svcs
 }
 
-var epsIndex = map[string][]*api.Endpoints{
+var epsIndex = map[string][]*object.Endpoints{
 	"svc1.testns": {{
-		Subsets: []api.EndpointSubset{
+		Subsets: []object.EndpointSubset{
 			{
-				Addresses: []api.EndpointAddress{
-					{
-						IP:       "172.0.0.1",
-						Hostname: "ep1a",
-					},
+				Addresses: []object.EndpointAddress{
+					{IP: "172.0.0.1", Hostname: "ep1a"},
 				},
-				Ports: []api.EndpointPort{
-					{
-						Port:     80,
-						Protocol: "tcp",
-						Name:     "http",
-					},
+				Ports: []object.EndpointPort{
+					{Port: 80, Protocol: "tcp", Name: "http"},
 				},
 			},
 		},
-		ObjectMeta: meta.ObjectMeta{
-			Name:      "svc1",
-			Namespace: "testns",
-		},
+		Name:      "svc1",
+		Namespace: "testns",
 	}},
 	"hdls1.testns": {{
-		Subsets: []api.EndpointSubset{
+		Subsets: []object.EndpointSubset{
 			{
-				Addresses: []api.EndpointAddress{
-					{
-						IP: "172.0.0.2",
-					},
-					{
-						IP: "172.0.0.3",
-					},
-					{
-						IP: "5678:abcd::1",
-					},
-					{
-						IP: "5678:abcd::2",
-					},
+				Addresses: []object.EndpointAddress{
+					{IP: "172.0.0.2"},
+					{IP: "172.0.0.3"},
+					{IP: "5678:abcd::1"},
+					{IP: "5678:abcd::2"},
 				},
-				Ports: []api.EndpointPort{
-					{
-						Port:     80,
-						Protocol: "tcp",
-						Name:     "http",
-					},
+				Ports: []object.EndpointPort{
+					{Port: 80, Protocol: "tcp", Name: "http"},
 				},
 			},
 		},
-		ObjectMeta: meta.ObjectMeta{
-			Name:      "hdls1",
-			Namespace: "testns",
-		},
+		Name:      "hdls1",
+		Namespace: "testns",
 	}},
 }
 
-func (APIConnServeTest) EpIndex(s string) []*api.Endpoints {
+func (APIConnServeTest) EpIndex(s string) []*object.Endpoints {
 	return epsIndex[s]
 }
 
-func (APIConnServeTest) EndpointsList() []*api.Endpoints {
-	var eps []*api.Endpoints
+func (APIConnServeTest) EndpointsList() []*object.Endpoints {
+	var eps []*object.Endpoints
 	for _, ep := range epsIndex {
 		eps = append(eps, ep...)
 	}
 	return eps
-
 }
 
-func (APIConnServeTest) GetNodeByName(name string) (*api.Node, error) {
-	return &api.Node{
-		ObjectMeta: meta.ObjectMeta{
-			Name: "test.node.foo.bar",
-		},
+func (APIConnServeTest) GetNodeByName(name string) (*object.Node, error) {
+	return &object.Node{
+		Name: "test.node.foo.bar",
 	}, nil
 }
 
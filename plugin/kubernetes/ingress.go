@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// findIngresses returns the load balancer addresses of the ingresses that have a rule for the
+// name in state. A rule host may have a wildcard as its first label, i.e. "*.example.org", exact
+// hosts are preferred over wildcards. The boolean is true when there is such an ingress, even if
+// it doesn't have an address (yet).
+func (k *Kubernetes) findIngresses(state request.Request) ([]msg.Service, bool) {
+	name := strings.ToLower(strings.TrimSuffix(state.Name(), "."))
+
+	ingresses := k.APIConn.IngressIndex(name)
+	if len(ingresses) == 0 {
+		i, end := dns.NextLabel(name, 0)
+		if end {
+			return nil, false
+		}
+		ingresses = k.APIConn.IngressIndex("*." + name[i:])
+	}
+
+	zonePath := msg.Path(state.Zone, "coredns")
+	var services []msg.Service
+	found := false
+	for _, ing := range ingresses {
+		if !k.namespaceExposed(ing.Namespace) {
+			continue
+		}
+		found = true
+		for _, addr := range ing.Addresses {
+			s := msg.Service{Host: addr, TTL: k.ttl}
+			s.Key = strings.Join([]string{zonePath, "ingress", ing.Namespace, ing.Name}, "/")
+			services = append(services, s)
+		}
+	}
+	return services, found
+}
+
+// IngressIndex returns the ingresses that have a rule for host.
+func (dns *dnsControl) IngressIndex(host string) (ings []*object.Ingress) {
+	if dns.ingLister == nil {
+		return nil
+	}
+	os, err := dns.ingLister.ByIndex(ingressHostIndex, host)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		i, ok := o.(*object.Ingress)
+		if !ok {
+			continue
+		}
+		ings = append(ings, i)
+	}
+	return ings
+}
+
+func ingressHostIndexFunc(obj interface{}) ([]string, error) {
+	i, ok := obj.(*object.Ingress)
+	if !ok {
+		return nil, errors.New("obj was not an *object.Ingress")
+	}
+	return i.Hosts, nil
+}
+
+func ingressListFunc(c *kubernetes.Clientset, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if s != nil {
+			opts.LabelSelector = s.String()
+		}
+		listV1, err := c.ExtensionsV1beta1().Ingresses(ns).List(opts)
+		if err != nil {
+			return nil, err
+		}
+		return listV1, err
+	}
+}
+
+func ingressWatchFunc(c *kubernetes.Clientset, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		if s != nil {
+			options.LabelSelector = s.String()
+		}
+		w, err := c.ExtensionsV1beta1().Ingresses(ns).Watch(options)
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+	api "k8s.io/api/core/v1"
+)
+
+var ingressTestCases = []test.Case{
+	{
+		Qname: "www.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("www.example.org.	5	IN	A	1.2.3.4"),
+		},
+	},
+	{
+		Qname: "WWW.example.org.", Qtype: dns.TypeAAAA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.AAAA("WWW.example.org.	5	IN	AAAA	1:2::3"),
+		},
+	},
+	// Wildcard rule host.
+	{
+		Qname: "app.apps.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("app.apps.example.org.	5	IN	A	1.2.3.5"),
+		},
+	},
+	// No address (yet).
+	{
+		Qname: "pending.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.org.	303	IN	SOA	ns.dns.example.org. hostmaster.example.org. 1499347823 7200 1800 86400 60"),
+		},
+	},
+	// In a namespace that is not exposed.
+	{
+		Qname: "hidden.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.org.	303	IN	SOA	ns.dns.example.org. hostmaster.example.org. 1499347823 7200 1800 86400 60"),
+		},
+	},
+	{
+		Qname: "nope.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.org.	303	IN	SOA	ns.dns.example.org. hostmaster.example.org. 1499347823 7200 1800 86400 60"),
+		},
+	},
+	// Services still work.
+	{
+		Qname: "svc1.testns.svc.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svc1.testns.svc.example.org.	5	IN	A	10.0.0.1"),
+		},
+	},
+}
+
+func TestServeDNSIngress(t *testing.T) {
+	k := New([]string{"example.org."})
+	k.APIConn = &APIConnServeTest{}
+	k.Namespaces = map[string]bool{"testns": true}
+	k.opts.initIngressCache = true
+	k.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	ctx := context.TODO()
+
+	for i, tc := range ingressTestCases {
+		r := tc.Msg()
+
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := k.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		test.SortAndCheck(t, resp, tc)
+	}
+}
+
+func TestKubernetesParseIngress(t *testing.T) {
+	c := caddy.NewTestController("dns", "kubernetes cluster.local {\ningress\n}")
+	k, err := kubernetesParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !k.opts.initIngressCache {
+		t.Errorf("Expected ingress cache to be enabled")
+	}
+
+	c = caddy.NewTestController("dns", "kubernetes cluster.local {\ningress yes\n}")
+	if _, err := kubernetesParse(c); err == nil {
+		t.Errorf("Expected error, got none")
+	}
+}
+
+func TestIngressHostIndexFunc(t *testing.T) {
+	idx, err := ingressHostIndexFunc(ingressIndex["www.example.org"][0])
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(idx) != 2 || idx[0] != "www.example.org" || idx[1] != "www2.example.org" {
+		t.Errorf("Expected [www.example.org www2.example.org], got %v", idx)
+	}
+	if _, err := ingressHostIndexFunc(&api.Pod{}); err == nil {
+		t.Errorf("Expected error, got none")
+	}
+}
+
+var ingressIndex = map[string][]*object.Ingress{
+	"www.example.org": {{
+		Name: "www", Namespace: "testns",
+		Hosts:     []string{"www.example.org", "www2.example.org"},
+		Addresses: []string{"1.2.3.4", "1:2::3"},
+	}},
+	"*.apps.example.org": {{
+		Name: "apps", Namespace: "testns",
+		Hosts:     []string{"*.apps.example.org"},
+		Addresses: []string{"1.2.3.5"},
+	}},
+	"pending.example.org": {{
+		Name: "pending", Namespace: "testns",
+		Hosts: []string{"pending.example.org"},
+	}},
+	"hidden.example.org": {{
+		Name: "hidden", Namespace: "otherns",
+		Hosts:     []string{"hidden.example.org"},
+		Addresses: []string{"1.2.3.6"},
+	}},
+}
+
+func (APIConnServeTest) IngressIndex(host string) []*object.Ingress { return ingressIndex[host] }
@@ -0,0 +1,335 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeconfigReload is how often the kubeconfig file is checked for changes.
+const kubeconfigReload = 5 * time.Second
+
+// kubeconfigTransport is the transport used when connecting with a kubeconfig file. It holds a round
+// tripper with the credentials from the file, and replaces it when the file or the credential files
+// it refers to change, or when the credentials from an exec plugin expire, so rotated certificates
+// and tokens are used without restarting CoreDNS.
+type kubeconfigTransport struct {
+	path    string
+	context string
+	host    string
+
+	sync.RWMutex
+	rt    http.RoundTripper
+	files map[string]fileStamp
+	exec  bool      // credentials come from an exec plugin
+	exp   time.Time // when the exec plugin credentials expire, zero if they don't
+	stale bool      // exec plugin credentials were rejected by the API server
+
+	stop chan struct{}
+}
+
+// fileStamp is used to see if a file changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// kubeconfig is a client config loaded from a kubeconfig file.
+type kubeconfig struct {
+	*rest.Config
+	files []string  // the kubeconfig and the credential files it refers to
+	exec  bool      // credentials come from an exec plugin
+	exp   time.Time // when the exec plugin credentials expire
+}
+
+// loadKubeconfig returns the client config for context in the kubeconfig file path. If context is
+// empty the current context of the file is used. Credentials of an exec plugin are filled in.
+func loadKubeconfig(path, context string) (*kubeconfig, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: path}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	cfg, err := cc.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := cc.RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	k := &kubeconfig{Config: cfg, files: []string{path}}
+	if context == "" {
+		context = raw.CurrentContext
+	}
+	ctx, ok := raw.Contexts[context]
+	if !ok {
+		return k, nil
+	}
+	if c, ok := raw.Clusters[ctx.Cluster]; ok {
+		k.files = append(k.files, c.CertificateAuthority)
+	}
+	if a, ok := raw.AuthInfos[ctx.AuthInfo]; ok {
+		k.files = append(k.files, a.TokenFile, a.ClientCertificate, a.ClientKey)
+	}
+
+	e, err := execPlugin(path, ctx.AuthInfo)
+	if err != nil || e == nil {
+		return k, err
+	}
+	cred, err := e.credentials()
+	if err != nil {
+		return nil, err
+	}
+	k.exec = true
+	if s := cred.Status; s.Token != "" {
+		k.BearerToken = s.Token
+	} else {
+		k.TLSClientConfig.CertData, k.TLSClientConfig.CertFile = []byte(s.ClientCertificateData), ""
+		k.TLSClientConfig.KeyData, k.TLSClientConfig.KeyFile = []byte(s.ClientKeyData), ""
+	}
+	if cred.Status.ExpirationTimestamp != nil {
+		k.exp = *cred.Status.ExpirationTimestamp
+	}
+	return k, nil
+}
+
+// newKubeconfigTransport returns a kubeconfigTransport for the kubeconfig file path and the client
+// config that uses it.
+func newKubeconfigTransport(path, context string) (*kubeconfigTransport, *rest.Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil, err
+	}
+	k, err := loadKubeconfig(path, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	rt, err := rest.TransportFor(k.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t := &kubeconfigTransport{
+		path:    path,
+		context: context,
+		host:    k.Host,
+		rt:      rt,
+		files:   stamps(k.files),
+		exec:    k.exec,
+		exp:     k.exp,
+		stop:    make(chan struct{}),
+	}
+
+	// All TLS and authentication settings live in the transport, the client config only says where
+	// to connect to.
+	cc := &rest.Config{
+		Host:      k.Host,
+		APIPath:   k.APIPath,
+		Prefix:    k.Prefix,
+		Transport: t,
+	}
+	return t, cc, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *kubeconfigTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.RLock()
+	rt, exec := t.rt, t.exec
+	t.RUnlock()
+	resp, err := rt.RoundTrip(req)
+	if err == nil && exec && resp.StatusCode == http.StatusUnauthorized {
+		t.Lock()
+		t.stale = true
+		t.Unlock()
+	}
+	return resp, err
+}
+
+// Run checks the kubeconfig file for changes until Stop is called.
+func (t *kubeconfigTransport) Run() {
+	ticker := time.NewTicker(kubeconfigReload)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			if err := t.reload(); err != nil {
+				log.Errorf("Failed to reload kubeconfig %s: %s", t.path, err)
+			}
+		}
+	}
+}
+
+// Stop stops checking the kubeconfig file.
+func (t *kubeconfigTransport) Stop() { close(t.stop) }
+
+// reload re-reads the kubeconfig file if it, or one of the files it refers to, changed since the last
+// time it was checked, and runs the exec plugin again when its credentials are about to expire or were
+// rejected. If the new config can't be used, the previous credentials are kept until the files change
+// again.
+func (t *kubeconfigTransport) reload() error {
+	if _, err := os.Stat(t.path); err != nil {
+		return err
+	}
+	t.Lock()
+	files := make([]string, 0, len(t.files))
+	for f := range t.files {
+		files = append(files, f)
+	}
+	current := stamps(files)
+	changed := !equalStamps(current, t.files)
+	expired := t.stale || !t.exp.IsZero() && time.Now().Add(kubeconfigReload).After(t.exp)
+	t.files = current
+	t.Unlock()
+	if !changed && !expired {
+		return nil
+	}
+
+	k, err := loadKubeconfig(t.path, t.context)
+	if err != nil {
+		return err
+	}
+	if k.Host != t.host {
+		return fmt.Errorf("server changed from %s to %s, a restart is needed to use it", t.host, k.Host)
+	}
+	rt, err := rest.TransportFor(k.Config)
+	if err != nil {
+		return err
+	}
+
+	t.Lock()
+	t.rt = rt
+	t.files = stamps(k.files)
+	t.exec, t.exp, t.stale = k.exec, k.exp, false
+	t.Unlock()
+
+	log.Infof("Reloaded credentials from kubeconfig %s", t.path)
+	return nil
+}
+
+// stamps returns the stamps of files, empty names are skipped. A file that can't be read gets the
+// zero stamp, so it is seen as changed when it shows up again.
+func stamps(files []string) map[string]fileStamp {
+	s := make(map[string]fileStamp, len(files))
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			s[f] = fileStamp{}
+			continue
+		}
+		s[f] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return s
+}
+
+func equalStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for f, s := range a {
+		if o, ok := b[f]; !ok || !o.modTime.Equal(s.modTime) || o.size != s.size {
+			return false
+		}
+	}
+	return true
+}
+
+// execConfig is the exec section of a user in a kubeconfig file. The client-go we use predates exec
+// plugins, so we read the section and run the plugin ourselves.
+type execConfig struct {
+	APIVersion string   `json:"apiVersion"`
+	Command    string   `json:"command"`
+	Args       []string `json:"args"`
+	Env        []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"env"`
+}
+
+// execCredential is what an exec plugin prints.
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Status     *struct {
+		ExpirationTimestamp   *time.Time `json:"expirationTimestamp"`
+		Token                 string     `json:"token"`
+		ClientCertificateData string     `json:"clientCertificateData"`
+		ClientKeyData         string     `json:"clientKeyData"`
+	} `json:"status"`
+}
+
+// execPlugin returns the exec plugin of user in the kubeconfig file path, or nil if it has none.
+func execPlugin(path, user string) (*execConfig, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := struct {
+		Users []struct {
+			Name string `json:"name"`
+			User struct {
+				Exec *execConfig `json:"exec"`
+			} `json:"user"`
+		} `json:"users"`
+	}{}
+	if err := yaml.Unmarshal(buf, &raw); err != nil {
+		return nil, err
+	}
+	for _, u := range raw.Users {
+		if u.Name != user || u.User.Exec == nil {
+			continue
+		}
+		e := u.User.Exec
+		// A relative command with a path in it is relative to the kubeconfig file.
+		if !filepath.IsAbs(e.Command) && strings.ContainsRune(e.Command, filepath.Separator) {
+			e.Command = filepath.Join(filepath.Dir(path), e.Command)
+		}
+		return e, nil
+	}
+	return nil, nil
+}
+
+// credentials runs the exec plugin and returns the credentials it printed.
+func (e *execConfig) credentials() (*execCredential, error) {
+	cmd := exec.Command(e.Command, e.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range e.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	info := fmt.Sprintf(`{"apiVersion":%q,"kind":"ExecCredential","spec":{"interactive":false}}`, e.APIVersion)
+	cmd.Env = append(cmd.Env, "KUBERNETES_EXEC_INFO="+info)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("exec plugin %s: %s", e.Command, err)
+	}
+	cred := &execCredential{}
+	if err := json.Unmarshal(out, cred); err != nil {
+		return nil, fmt.Errorf("exec plugin %s: %s", e.Command, err)
+	}
+	if cred.APIVersion != e.APIVersion {
+		return nil, fmt.Errorf("exec plugin %s: returned version %q, expected %q", e.Command, cred.APIVersion, e.APIVersion)
+	}
+	if cred.Status == nil || cred.Status.Token == "" && (cred.Status.ClientCertificateData == "" || cred.Status.ClientKeyData == "") {
+		return nil, errors.New("exec plugin " + e.Command + ": no token or client certificate returned")
+	}
+	return cred, nil
+}
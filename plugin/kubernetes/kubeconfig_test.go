@@ -0,0 +1,265 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: alice
+  user:
+    token: %s
+- name: bob
+  user:
+    token: bob-token
+contexts:
+- name: alice
+  context:
+    cluster: test
+    user: alice
+- name: bob
+  context:
+    cluster: test
+    user: bob
+current-context: alice
+`
+
+func TestKubeconfigTransport(t *testing.T) {
+	auth := ""
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "coredns-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubeconfig")
+
+	write := func(server, token string) {
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(testKubeconfig, server, token)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(tr *kubeconfigTransport) string {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return auth
+	}
+
+	write(s.URL, "alice-token")
+
+	tr, cc, err := newKubeconfigTransport(path, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if cc.Host != s.URL {
+		t.Errorf("Expected host %s, got %s", s.URL, cc.Host)
+	}
+	if a := get(tr); a != "Bearer alice-token" {
+		t.Errorf("Expected alice's token, got %q", a)
+	}
+
+	// New credentials are picked up when the file changes.
+	write(s.URL, "alice-new-token")
+	if err := tr.reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if a := get(tr); a != "Bearer alice-new-token" {
+		t.Errorf("Expected alice's new token, got %q", a)
+	}
+
+	// A different server is not used, the credentials are kept.
+	write("https://10.0.0.1:6443", "alice-other-token")
+	if err := tr.reload(); err == nil {
+		t.Errorf("Expected error for changed server, got none")
+	}
+	if a := get(tr); a != "Bearer alice-new-token" {
+		t.Errorf("Expected alice's new token, got %q", a)
+	}
+
+	// Explicit context.
+	tr, _, err = newKubeconfigTransport(path, "bob")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if a := get(tr); a != "Bearer bob-token" {
+		t.Errorf("Expected bob's token, got %q", a)
+	}
+
+	if _, _, err := newKubeconfigTransport(path, "carol"); err == nil {
+		t.Errorf("Expected error for unknown context, got none")
+	}
+	if _, _, err := newKubeconfigTransport(filepath.Join(dir, "missing"), ""); err == nil {
+		t.Errorf("Expected error for missing file, got none")
+	}
+}
+
+const testKubeconfigCredentials = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: file
+  user:
+    tokenFile: token
+- name: exec
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: ./plugin.sh
+      env:
+      - name: TOKEN_FILE
+        value: %s
+contexts:
+- name: file
+  context:
+    cluster: test
+    user: file
+- name: exec
+  context:
+    cluster: test
+    user: exec
+current-context: file
+`
+
+const testExecPlugin = `#!/bin/sh
+echo '{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"'$(cat $TOKEN_FILE)'","expirationTimestamp":"2006-01-02T15:04:05Z"}}'
+`
+
+func TestKubeconfigCredentials(t *testing.T) {
+	auth := ""
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "coredns-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubeconfig")
+	token := filepath.Join(dir, "token")
+
+	write := func(name, content string, mode os.FileMode) {
+		if err := ioutil.WriteFile(name, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(tr *kubeconfigTransport) string {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return auth
+	}
+
+	write(path, fmt.Sprintf(testKubeconfigCredentials, s.URL, token), 0600)
+	write(filepath.Join(dir, "plugin.sh"), testExecPlugin, 0700)
+	write(token, "file-token", 0600)
+
+	// The token file is watched, not only the kubeconfig.
+	tr, _, err := newKubeconfigTransport(path, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if a := get(tr); a != "Bearer file-token" {
+		t.Errorf("Expected the token from the file, got %q", a)
+	}
+	write(token, "file-new-token", 0600)
+	if err := tr.reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if a := get(tr); a != "Bearer file-new-token" {
+		t.Errorf("Expected the new token from the file, got %q", a)
+	}
+
+	// The exec plugin is run again when its credentials have expired.
+	tr, _, err = newKubeconfigTransport(path, "exec")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if a := get(tr); a != "Bearer file-new-token" {
+		t.Errorf("Expected the token from the exec plugin, got %q", a)
+	}
+	write(token, "exec-token", 0600)
+	if err := tr.reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if a := get(tr); a != "Bearer exec-token" {
+		t.Errorf("Expected the new token from the exec plugin, got %q", a)
+	}
+}
+
+func TestKubernetesParseKubeconfig(t *testing.T) {
+	tests := []struct {
+		input           string
+		shouldErr       bool
+		expectedFile    string
+		expectedContext string
+	}{
+		{`kubernetes cluster.local {
+	kubeconfig /etc/kubeconfig
+}`, false, "/etc/kubeconfig", ""},
+		{`kubernetes cluster.local {
+	kubeconfig /etc/kubeconfig admin@test
+}`, false, "/etc/kubeconfig", "admin@test"},
+		{`kubernetes cluster.local {
+	kubeconfig
+}`, true, "", ""},
+		{`kubernetes cluster.local {
+	kubeconfig /etc/kubeconfig admin@test extra
+}`, true, "", ""},
+		{`kubernetes cluster.local {
+	kubeconfig /etc/kubeconfig
+	endpoint https://10.0.0.1:6443
+}`, true, "", ""},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		k, err := kubernetesParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if k.KubeConfigFile != tc.expectedFile {
+			t.Errorf("Test %d: expected kubeconfig %q, got %q", i, tc.expectedFile, k.KubeConfigFile)
+		}
+		if k.KubeContext != tc.expectedContext {
+			t.Errorf("Test %d: expected context %q, got %q", i, tc.expectedContext, k.KubeContext)
+		}
+	}
+}
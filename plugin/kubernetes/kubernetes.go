@@ -11,6 +11,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/healthcheck"
@@ -37,10 +38,13 @@ type Kubernetes struct {
 	APICertAuth      string
 	APIClientCert    string
 	APIClientKey     string
+	KubeConfigFile   string
+	KubeContext      string
 	APIConn          dnsController
 	Namespaces       map[string]bool
 	podMode          string
 	endpointNameMode bool
+	topology         string
 	Fall             fall.F
 	ttl              uint32
 	opts             dnsControlOpts
@@ -49,10 +53,12 @@ type Kubernetes struct {
 	interfaceAddrsFunc func() net.IP
 	autoPathSearch     []string // Local search path from /etc/resolv.conf. Needed for autopath.
 	TransferTo         []string
+	kubeconfig         *kubeconfigTransport
 }
 
-// New returns a initialized Kubernetes. It default interfaceAddrFunc to return 127.0.0.1. All other
-// values default to their zero value, primaryZoneIndex will thus point to the first zone.
+// New returns a initialized Kubernetes. It default interfaceAddrFunc to return 127.0.0.1 and watches
+// endpoints. All other values default to their zero value, primaryZoneIndex will thus point to the first
+// zone.
 func New(zones []string) *Kubernetes {
 	k := new(Kubernetes)
 	k.Zones = zones
@@ -60,6 +66,7 @@ func New(zones []string) *Kubernetes {
 	k.interfaceAddrsFunc = func() net.IP { return net.ParseIP("127.0.0.1") }
 	k.podMode = podModeDisabled
 	k.ttl = defaultTTL
+	k.opts = dnsControlOpts{initEndpointsCache: true, resyncPeriod: defaultResyncPeriod}
 
 	return k
 }
@@ -159,6 +166,17 @@ func (k *Kubernetes) getClientConfig() (*rest.Config, error) {
 	clusterinfo := clientcmdapi.Cluster{}
 	authinfo := clientcmdapi.AuthInfo{}
 
+	// Connect to API with the credentials from a kubeconfig file
+	if k.KubeConfigFile != "" {
+		t, cc, err := newKubeconfigTransport(k.KubeConfigFile, k.KubeContext)
+		if err != nil {
+			return nil, err
+		}
+		k.kubeconfig = t
+		cc.ContentType = "application/vnd.kubernetes.protobuf"
+		return cc, nil
+	}
+
 	// Connect to API from in cluster
 	if len(k.APIServerList) == 0 {
 		cc, err := rest.InClusterConfig()
@@ -260,7 +278,16 @@ func (k *Kubernetes) InitKubeCache() (err error) {
 		k.opts.selector = selector
 	}
 
-	k.opts.initPodCache = k.podMode == podModeVerified
+	// Topology aware answers need the pod of the client, and for zones the nodes.
+	k.opts.initPodCache = k.podMode == podModeVerified || k.topology != ""
+	k.opts.initNodeCache = k.topology == topologyZone
+
+	if k.opts.initEndpointsCache {
+		k.opts.sliceClient, err = endpointSliceClient(kubeClient, config)
+		if err != nil {
+			return fmt.Errorf("failed to create endpointslice client: %q", err)
+		}
+	}
 
 	k.APIConn = newdnsController(kubeClient, k.opts)
 
@@ -269,6 +296,12 @@ func (k *Kubernetes) InitKubeCache() (err error) {
 
 // Records looks up services in kubernetes.
 func (k *Kubernetes) Records(state request.Request, exact bool) ([]msg.Service, error) {
+	if k.opts.initIngressCache {
+		if services, ok := k.findIngresses(state); ok {
+			return services, nil
+		}
+	}
+
 	r, e := parseRequest(state)
 	if e != nil {
 		return nil, e
@@ -290,16 +323,16 @@ func (k *Kubernetes) Records(state request.Request, exact bool) ([]msg.Service,
 		return pods, err
 	}
 
-	services, err := k.findServices(r, state.Zone)
+	services, err := k.findServices(r, state.Zone, k.local(state))
 	return services, err
 }
 
-func endpointHostname(addr api.EndpointAddress, endpointNameMode bool) string {
+func endpointHostname(addr object.EndpointAddress, endpointNameMode bool) string {
 	if addr.Hostname != "" {
 		return strings.ToLower(addr.Hostname)
 	}
-	if endpointNameMode && addr.TargetRef != nil && addr.TargetRef.Name != "" {
-		return addr.TargetRef.Name
+	if endpointNameMode && addr.TargetRefName != "" {
+		return addr.TargetRefName
 	}
 	if strings.Contains(addr.IP, ".") {
 		return strings.Replace(addr.IP, ".", "-", -1)
@@ -355,12 +388,12 @@ func (k *Kubernetes) findPods(r recordRequest, zone string) (pods []msg.Service,
 		}
 
 		// exclude pods in the process of termination
-		if !p.ObjectMeta.DeletionTimestamp.IsZero() {
+		if p.Deleting {
 			continue
 		}
 
 		// check for matching ip and namespace
-		if ip == p.Status.PodIP && match(namespace, p.Namespace) {
+		if ip == p.PodIP && match(namespace, p.Namespace) {
 			s := msg.Service{Key: strings.Join([]string{zonePath, Pod, namespace, podname}, "/"), Host: ip, TTL: k.ttl}
 			pods = append(pods, s)
 
@@ -370,8 +403,9 @@ func (k *Kubernetes) findPods(r recordRequest, zone string) (pods []msg.Service,
 	return pods, err
 }
 
-// findServices returns the services matching r from the cache.
-func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.Service, err error) {
+// findServices returns the services matching r from the cache. If local is not nil, only the endpoints
+// of a headless service for which local returns true are returned, unless there are none.
+func (k *Kubernetes) findServices(r recordRequest, zone string, local func(object.EndpointAddress) bool) (services []msg.Service, err error) {
 	zonePath := msg.Path(zone, "coredns")
 
 	err = errNoItems
@@ -383,18 +417,18 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 	}
 
 	var (
-		endpointsListFunc func() []*api.Endpoints
-		endpointsList     []*api.Endpoints
-		serviceList       []*api.Service
+		endpointsListFunc func() []*object.Endpoints
+		endpointsList     []*object.Endpoints
+		serviceList       []*object.Service
 	)
 
 	if wildcard(r.service) || wildcard(r.namespace) {
 		serviceList = k.APIConn.ServiceList()
-		endpointsListFunc = func() []*api.Endpoints { return k.APIConn.EndpointsList() }
+		endpointsListFunc = func() []*object.Endpoints { return k.APIConn.EndpointsList() }
 	} else {
-		idx := r.service + "." + r.namespace
+		idx := object.ServiceKey(r.service, r.namespace)
 		serviceList = k.APIConn.SvcIndex(idx)
-		endpointsListFunc = func() []*api.Endpoints { return k.APIConn.EpIndex(idx) }
+		endpointsListFunc = func() []*object.Endpoints { return k.APIConn.EpIndex(idx) }
 	}
 
 	for _, svc := range serviceList {
@@ -410,12 +444,13 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 		}
 
 		// Endpoint query or headless service
-		if svc.Spec.ClusterIP == api.ClusterIPNone || r.endpoint != "" {
+		if svc.ClusterIP == api.ClusterIPNone || r.endpoint != "" {
 			if endpointsList == nil {
 				endpointsList = endpointsListFunc()
 			}
+			var all, near []msg.Service
 			for _, ep := range endpointsList {
-				if ep.ObjectMeta.Name != svc.Name || ep.ObjectMeta.Namespace != svc.Namespace {
+				if ep.Name != svc.Name || ep.Namespace != svc.Namespace {
 					continue
 				}
 
@@ -431,7 +466,7 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 						}
 
 						for _, p := range eps.Ports {
-							if !(match(r.port, p.Name) && match(r.protocol, string(p.Protocol))) {
+							if !(match(r.port, p.Name) && match(r.protocol, p.Protocol)) {
 								continue
 							}
 							s := msg.Service{Host: addr.IP, Port: int(p.Port), TTL: k.ttl}
@@ -439,17 +474,24 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 
 							err = nil
 
-							services = append(services, s)
+							all = append(all, s)
+							if local != nil && r.endpoint == "" && local(addr) {
+								near = append(near, s)
+							}
 						}
 					}
 				}
 			}
+			if len(near) > 0 {
+				all = near
+			}
+			services = append(services, all...)
 			continue
 		}
 
 		// External service
-		if svc.Spec.Type == api.ServiceTypeExternalName {
-			s := msg.Service{Key: strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/"), Host: svc.Spec.ExternalName, TTL: k.ttl}
+		if svc.Type == api.ServiceTypeExternalName {
+			s := msg.Service{Key: strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/"), Host: svc.ExternalName, TTL: k.ttl}
 			if t, _ := s.HostType(); t == dns.TypeCNAME {
 				s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/")
 				services = append(services, s)
@@ -460,14 +502,14 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 		}
 
 		// ClusterIP service
-		for _, p := range svc.Spec.Ports {
+		for _, p := range svc.Ports {
 			if !(match(r.port, p.Name) && match(r.protocol, string(p.Protocol))) {
 				continue
 			}
 
 			err = nil
 
-			s := msg.Service{Host: svc.Spec.ClusterIP, Port: int(p.Port), TTL: k.ttl}
+			s := msg.Service{Host: svc.ClusterIP, Port: int(p.Port), TTL: k.ttl}
 			s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/")
 
 			services = append(services, s)
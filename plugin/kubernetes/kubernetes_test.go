@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -48,7 +49,7 @@ func TestEndpointHostname(t *testing.T) {
 		{"10.11.12.13", "", "hello-abcde", "hello-abcde", true},
 	}
 	for _, test := range tests {
-		result := endpointHostname(api.EndpointAddress{IP: test.ip, Hostname: test.hostname, TargetRef: &api.ObjectReference{Name: test.podName}}, test.endpointNameMode)
+		result := endpointHostname(object.EndpointAddress{IP: test.ip, Hostname: test.hostname, TargetRefName: test.podName}, test.endpointNameMode)
 		if result != test.expected {
 			t.Errorf("Expected endpoint name for (ip:%v hostname:%v) to be '%v', but got '%v'", test.ip, test.hostname, test.expected, result)
 		}
@@ -57,116 +58,93 @@ func TestEndpointHostname(t *testing.T) {
 
 type APIConnServiceTest struct{}
 
-func (APIConnServiceTest) HasSynced() bool                        { return true }
-func (APIConnServiceTest) Run()                                   { return }
-func (APIConnServiceTest) Stop() error                            { return nil }
-func (APIConnServiceTest) PodIndex(string) []*api.Pod             { return nil }
-func (APIConnServiceTest) SvcIndexReverse(string) []*api.Service  { return nil }
-func (APIConnServiceTest) EpIndexReverse(string) []*api.Endpoints { return nil }
-func (APIConnServiceTest) Modified() int64                        { return 0 }
+func (APIConnServiceTest) HasSynced() bool                           { return true }
+func (APIConnServiceTest) Run()                                      { return }
+func (APIConnServiceTest) Stop() error                               { return nil }
+func (APIConnServiceTest) PodIndex(string) []*object.Pod             { return nil }
+func (APIConnServiceTest) SvcIndexReverse(string) []*object.Service  { return nil }
+func (APIConnServiceTest) EpIndexReverse(string) []*object.Endpoints { return nil }
+func (APIConnServiceTest) Modified() int64                           { return 0 }
+func (APIConnServiceTest) IngressIndex(string) []*object.Ingress     { return nil }
 
-func (APIConnServiceTest) SvcIndex(string) []*api.Service {
-	svcs := []*api.Service{
+func (APIConnServiceTest) SvcIndex(string) []*object.Service {
+	svcs := []*object.Service{
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: "10.0.0.1",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-			},
+			Name:      "svc1",
+			Namespace: "testns",
+			ClusterIP: "10.0.0.1",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: api.ClusterIPNone,
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
+			ClusterIP: api.ClusterIPNone,
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "external",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ExternalName: "coredns.io",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-				Type: api.ServiceTypeExternalName,
-			},
+			Name:         "external",
+			Namespace:    "testns",
+			ExternalName: "coredns.io",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
+			Type: api.ServiceTypeExternalName,
 		},
 	}
 	return svcs
 }
 
-func (APIConnServiceTest) ServiceList() []*api.Service {
-	svcs := []*api.Service{
+func (APIConnServiceTest) ServiceList() []*object.Service {
+	svcs := []*object.Service{
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: "10.0.0.1",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-			},
+			Name:      "svc1",
+			Namespace: "testns",
+			ClusterIP: "10.0.0.1",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: api.ClusterIPNone,
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
+			ClusterIP: api.ClusterIPNone,
 		},
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "external",
-				Namespace: "testns",
-			},
-			Spec: api.ServiceSpec{
-				ExternalName: "coredns.io",
-				Ports: []api.ServicePort{{
-					Name:     "http",
-					Protocol: "tcp",
-					Port:     80,
-				}},
-				Type: api.ServiceTypeExternalName,
-			},
+			Name:         "external",
+			Namespace:    "testns",
+			ExternalName: "coredns.io",
+			Ports: []api.ServicePort{{
+				Name:     "http",
+				Protocol: "tcp",
+				Port:     80,
+			}},
+			Type: api.ServiceTypeExternalName,
 		},
 	}
 	return svcs
 }
 
-func (APIConnServiceTest) EpIndex(string) []*api.Endpoints {
+func (APIConnServiceTest) EpIndex(string) []*object.Endpoints {
 	n := "test.node.foo.bar"
 
-	eps := []*api.Endpoints{
+	eps := []*object.Endpoints{
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP:       "172.0.0.1",
 							Hostname: "ep1a",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -175,20 +153,18 @@ func (APIConnServiceTest) EpIndex(string) []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
+			Name:      "svc1",
+			Namespace: "testns",
 		},
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP: "172.0.0.2",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -197,20 +173,18 @@ func (APIConnServiceTest) EpIndex(string) []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
 		},
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP: "172.0.0.3",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -219,18 +193,16 @@ func (APIConnServiceTest) EpIndex(string) []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
 		},
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP:       "10.9.8.7",
-							NodeName: &n,
+							NodeName: n,
 						},
 					},
 				},
@@ -240,20 +212,20 @@ func (APIConnServiceTest) EpIndex(string) []*api.Endpoints {
 	return eps
 }
 
-func (APIConnServiceTest) EndpointsList() []*api.Endpoints {
+func (APIConnServiceTest) EndpointsList() []*object.Endpoints {
 	n := "test.node.foo.bar"
 
-	eps := []*api.Endpoints{
+	eps := []*object.Endpoints{
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP:       "172.0.0.1",
 							Hostname: "ep1a",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -262,20 +234,18 @@ func (APIConnServiceTest) EndpointsList() []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "svc1",
-				Namespace: "testns",
-			},
+			Name:      "svc1",
+			Namespace: "testns",
 		},
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP: "172.0.0.2",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -284,20 +254,18 @@ func (APIConnServiceTest) EndpointsList() []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
 		},
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP: "172.0.0.3",
 						},
 					},
-					Ports: []api.EndpointPort{
+					Ports: []object.EndpointPort{
 						{
 							Port:     80,
 							Protocol: "tcp",
@@ -306,18 +274,16 @@ func (APIConnServiceTest) EndpointsList() []*api.Endpoints {
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "hdls1",
-				Namespace: "testns",
-			},
+			Name:      "hdls1",
+			Namespace: "testns",
 		},
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP:       "10.9.8.7",
-							NodeName: &n,
+							NodeName: n,
 						},
 					},
 				},
@@ -327,11 +293,9 @@ func (APIConnServiceTest) EndpointsList() []*api.Endpoints {
 	return eps
 }
 
-func (APIConnServiceTest) GetNodeByName(name string) (*api.Node, error) {
-	return &api.Node{
-		ObjectMeta: meta.ObjectMeta{
-			Name: "test.node.foo.bar",
-		},
+func (APIConnServiceTest) GetNodeByName(name string) (*object.Node, error) {
+	return &object.Node{
+		Name: "test.node.foo.bar",
 	}, nil
 }
 
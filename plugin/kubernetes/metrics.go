@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CacheObjects is the number of objects in the caches, per type.
+var CacheObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "kubernetes",
+	Name:      "cache_objects",
+	Help:      "Gauge of the number of objects in the caches.",
+}, []string{"type"})
+
+// cacheType returns the type label of obj for CacheObjects, or the empty string if we don't
+// count obj.
+func cacheType(obj interface{}) string {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	switch obj.(type) {
+	case *object.Service:
+		return "services"
+	case *object.Endpoints:
+		return "endpoints"
+	case *object.Pod:
+		return "pods"
+	case *object.Ingress:
+		return "ingresses"
+	}
+	return ""
+}
+
+var once sync.Once
@@ -13,27 +13,11 @@ func isDefaultNS(name, zone string) bool {
 }
 
 func (k *Kubernetes) nsAddr() *dns.A {
-	var (
-		svcName      string
-		svcNamespace string
-	)
-
 	rr := new(dns.A)
 	localIP := k.interfaceAddrsFunc()
 	rr.A = localIP
 
-FindEndpoint:
-	for _, ep := range k.APIConn.EpIndexReverse(localIP.String()) {
-		for _, eps := range ep.Subsets {
-			for _, addr := range eps.Addresses {
-				if localIP.Equal(net.ParseIP(addr.IP)) {
-					svcNamespace = ep.ObjectMeta.Namespace
-					svcName = ep.ObjectMeta.Name
-					break FindEndpoint
-				}
-			}
-		}
-	}
+	svcName, svcNamespace := k.localService(localIP)
 
 	if len(svcName) == 0 {
 		rr.Hdr.Name = defaultNSName
@@ -44,10 +28,10 @@ FindEndpoint:
 FindService:
 	for _, svc := range k.APIConn.ServiceList() {
 		if svcName == svc.Name && svcNamespace == svc.Namespace {
-			if svc.Spec.ClusterIP == api.ClusterIPNone {
+			if svc.ClusterIP == api.ClusterIPNone {
 				rr.A = localIP
 			} else {
-				rr.A = net.ParseIP(svc.Spec.ClusterIP)
+				rr.A = net.ParseIP(svc.ClusterIP)
 			}
 			break FindService
 		}
@@ -3,59 +3,55 @@ package kubernetes
 import (
 	"testing"
 
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+
 	api "k8s.io/api/core/v1"
-	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type APIConnTest struct{}
 
-func (APIConnTest) HasSynced() bool                       { return true }
-func (APIConnTest) Run()                                  { return }
-func (APIConnTest) Stop() error                           { return nil }
-func (APIConnTest) PodIndex(string) []*api.Pod            { return nil }
-func (APIConnTest) SvcIndex(string) []*api.Service        { return nil }
-func (APIConnTest) SvcIndexReverse(string) []*api.Service { return nil }
-func (APIConnTest) EpIndex(string) []*api.Endpoints       { return nil }
-func (APIConnTest) EndpointsList() []*api.Endpoints       { return nil }
-func (APIConnTest) Modified() int64                       { return 0 }
+func (APIConnTest) HasSynced() bool                          { return true }
+func (APIConnTest) Run()                                     { return }
+func (APIConnTest) Stop() error                              { return nil }
+func (APIConnTest) PodIndex(string) []*object.Pod            { return nil }
+func (APIConnTest) SvcIndex(string) []*object.Service        { return nil }
+func (APIConnTest) SvcIndexReverse(string) []*object.Service { return nil }
+func (APIConnTest) EpIndex(string) []*object.Endpoints       { return nil }
+func (APIConnTest) EndpointsList() []*object.Endpoints       { return nil }
+func (APIConnTest) Modified() int64                          { return 0 }
+func (APIConnTest) IngressIndex(string) []*object.Ingress    { return nil }
 
-func (APIConnTest) ServiceList() []*api.Service {
-	svcs := []*api.Service{
+func (APIConnTest) ServiceList() []*object.Service {
+	svcs := []*object.Service{
 		{
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "dns-service",
-				Namespace: "kube-system",
-			},
-			Spec: api.ServiceSpec{
-				ClusterIP: "10.0.0.111",
-			},
+			Name:      "dns-service",
+			Namespace: "kube-system",
+			ClusterIP: "10.0.0.111",
 		},
 	}
 	return svcs
 }
 
-func (APIConnTest) EpIndexReverse(string) []*api.Endpoints {
-	eps := []*api.Endpoints{
+func (APIConnTest) EpIndexReverse(string) []*object.Endpoints {
+	eps := []*object.Endpoints{
 		{
-			Subsets: []api.EndpointSubset{
+			Subsets: []object.EndpointSubset{
 				{
-					Addresses: []api.EndpointAddress{
+					Addresses: []object.EndpointAddress{
 						{
 							IP: "127.0.0.1",
 						},
 					},
 				},
 			},
-			ObjectMeta: meta.ObjectMeta{
-				Name:      "dns-service",
-				Namespace: "kube-system",
-			},
+			Name:      "dns-service",
+			Namespace: "kube-system",
 		},
 	}
 	return eps
 }
 
-func (APIConnTest) GetNodeByName(name string) (*api.Node, error) { return &api.Node{}, nil }
+func (APIConnTest) GetNodeByName(name string) (*object.Node, error) { return &object.Node{}, nil }
 func (APIConnTest) GetNamespaceByName(name string) (*api.Namespace, error) {
 	return &api.Namespace{}, nil
 }
@@ -0,0 +1,99 @@
+package object
+
+import (
+	api "k8s.io/api/core/v1"
+)
+
+// Endpoints is a stripped down api.Endpoints with only the items we need for CoreDNS. An
+// EndpointSlice is converted to an Endpoints as well; Name is then the name of its service, and
+// Slice the name of the slice. A service can have several slices, all have the same Index.
+type Endpoints struct {
+	Version   string
+	Name      string
+	Slice     string
+	Namespace string
+	Index     string
+	IndexIP   []string
+	Subsets   []EndpointSubset
+}
+
+// EndpointSubset is a group of addresses with a common set of ports. The
+// expanded set of endpoints is the Cartesian product of Addresses x Ports.
+type EndpointSubset struct {
+	Addresses []EndpointAddress
+	Ports     []EndpointPort
+}
+
+// EndpointAddress is a tuple that describes single IP address.
+type EndpointAddress struct {
+	IP            string
+	Hostname      string
+	NodeName      string
+	TargetRefName string
+}
+
+// EndpointPort is a tuple that describes a single port.
+type EndpointPort struct {
+	Port     int32
+	Name     string
+	Protocol string
+}
+
+// EndpointsKey return a string using for the index.
+func EndpointsKey(name, namespace string) string { return name + "." + namespace }
+
+// ToEndpoints converts an api.Endpoints to a *Endpoints. Only the ready addresses are kept.
+func ToEndpoints(obj interface{}) interface{} {
+	end, ok := obj.(*api.Endpoints)
+	if !ok {
+		return obj
+	}
+
+	e := &Endpoints{
+		Version:   end.GetResourceVersion(),
+		Name:      end.GetName(),
+		Namespace: end.GetNamespace(),
+		Index:     EndpointsKey(end.GetName(), end.GetNamespace()),
+		Subsets:   make([]EndpointSubset, len(end.Subsets)),
+	}
+	for i, eps := range end.Subsets {
+		sub := EndpointSubset{
+			Addresses: make([]EndpointAddress, len(eps.Addresses)),
+		}
+		if len(eps.Ports) > 0 {
+			sub.Ports = make([]EndpointPort, len(eps.Ports))
+		}
+
+		for j, a := range eps.Addresses {
+			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname}
+			if a.NodeName != nil {
+				ea.NodeName = *a.NodeName
+			}
+			if a.TargetRef != nil {
+				ea.TargetRefName = a.TargetRef.Name
+			}
+			sub.Addresses[j] = ea
+			e.IndexIP = append(e.IndexIP, a.IP)
+		}
+
+		for k, p := range eps.Ports {
+			sub.Ports[k] = EndpointPort{Port: p.Port, Name: p.Name, Protocol: string(p.Protocol)}
+		}
+
+		e.Subsets[i] = sub
+	}
+
+	return e
+}
+
+// GetNamespace implements the Object interface.
+func (e *Endpoints) GetNamespace() string { return e.Namespace }
+
+// GetName implements the Object interface. For an EndpointSlice this is the name of the slice, so
+// every slice is cached separately.
+func (e *Endpoints) GetName() string {
+	if e.Slice != "" {
+		return e.Slice
+	}
+	return e.Name
+}
@@ -0,0 +1,144 @@
+package object
+
+import (
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The vendored client-go predates EndpointSlices, the types below hold the fields of the
+// discovery.k8s.io/v1beta1 API we use.
+
+// SliceGroupVersion is the API group and version of the EndpointSlices we watch.
+var SliceGroupVersion = schema.GroupVersion{Group: "discovery.k8s.io", Version: "v1beta1"}
+
+// LabelServiceName is the label of an EndpointSlice with the name of its service.
+const LabelServiceName = "kubernetes.io/service-name"
+
+// EndpointSlice is a discovery.k8s.io/v1beta1 EndpointSlice.
+type EndpointSlice struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+	AddressType     string          `json:"addressType"`
+	Endpoints       []SliceEndpoint `json:"endpoints"`
+	Ports           []SlicePort     `json:"ports"`
+}
+
+// SliceEndpoint is an endpoint in an EndpointSlice.
+type SliceEndpoint struct {
+	Addresses  []string `json:"addresses"`
+	Conditions struct {
+		Ready *bool `json:"ready,omitempty"`
+	} `json:"conditions,omitempty"`
+	Hostname  *string              `json:"hostname,omitempty"`
+	TargetRef *api.ObjectReference `json:"targetRef,omitempty"`
+	Topology  map[string]string    `json:"topology,omitempty"`
+	NodeName  *string              `json:"nodeName,omitempty"`
+}
+
+// SlicePort is a port in an EndpointSlice.
+type SlicePort struct {
+	Name     *string       `json:"name,omitempty"`
+	Protocol *api.Protocol `json:"protocol,omitempty"`
+	Port     *int32        `json:"port,omitempty"`
+}
+
+// EndpointSliceList is a list of EndpointSlices.
+type EndpointSliceList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []EndpointSlice `json:"items"`
+}
+
+// DeepCopyObject implements the runtime.Object interface.
+func (s *EndpointSlice) DeepCopyObject() runtime.Object {
+	c := *s
+	s.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	c.Endpoints = make([]SliceEndpoint, len(s.Endpoints))
+	for i, e := range s.Endpoints {
+		c.Endpoints[i] = e
+		c.Endpoints[i].Addresses = append([]string(nil), e.Addresses...)
+		if e.Topology != nil {
+			c.Endpoints[i].Topology = make(map[string]string, len(e.Topology))
+			for k, v := range e.Topology {
+				c.Endpoints[i].Topology[k] = v
+			}
+		}
+	}
+	c.Ports = append([]SlicePort(nil), s.Ports...)
+	return &c
+}
+
+// DeepCopyObject implements the runtime.Object interface.
+func (l *EndpointSliceList) DeepCopyObject() runtime.Object {
+	c := &EndpointSliceList{TypeMeta: l.TypeMeta}
+	l.ListMeta.DeepCopyInto(&c.ListMeta)
+	for i := range l.Items {
+		c.Items = append(c.Items, *l.Items[i].DeepCopyObject().(*EndpointSlice))
+	}
+	return c
+}
+
+// ToEndpointSlice converts an EndpointSlice to a *Endpoints. Only the ready endpoints are kept, and
+// slices with FQDN addresses are left empty.
+func ToEndpointSlice(obj interface{}) interface{} {
+	slice, ok := obj.(*EndpointSlice)
+	if !ok {
+		return obj
+	}
+
+	service := slice.Labels[LabelServiceName]
+	e := &Endpoints{
+		Version:   slice.GetResourceVersion(),
+		Name:      service,
+		Slice:     slice.GetName(),
+		Namespace: slice.GetNamespace(),
+		Index:     EndpointsKey(service, slice.GetNamespace()),
+	}
+	if slice.AddressType != "IPv4" && slice.AddressType != "IPv6" {
+		return e
+	}
+
+	sub := EndpointSubset{}
+	for _, end := range slice.Endpoints {
+		if end.Conditions.Ready != nil && !*end.Conditions.Ready {
+			continue
+		}
+		ea := EndpointAddress{}
+		if end.Hostname != nil {
+			ea.Hostname = *end.Hostname
+		}
+		if end.NodeName != nil {
+			ea.NodeName = *end.NodeName
+		} else {
+			ea.NodeName = end.Topology["kubernetes.io/hostname"]
+		}
+		if end.TargetRef != nil {
+			ea.TargetRefName = end.TargetRef.Name
+		}
+		for _, ip := range end.Addresses {
+			ea.IP = ip
+			sub.Addresses = append(sub.Addresses, ea)
+			e.IndexIP = append(e.IndexIP, ip)
+		}
+	}
+	for _, p := range slice.Ports {
+		if p.Port == nil {
+			continue
+		}
+		ep := EndpointPort{Port: *p.Port}
+		if p.Name != nil {
+			ep.Name = *p.Name
+		}
+		if p.Protocol != nil {
+			ep.Protocol = string(*p.Protocol)
+		}
+		sub.Ports = append(sub.Ports, ep)
+	}
+	if len(sub.Addresses) > 0 {
+		e.Subsets = []EndpointSubset{sub}
+	}
+
+	return e
+}
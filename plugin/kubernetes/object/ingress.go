@@ -0,0 +1,54 @@
+package object
+
+import (
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// Ingress is a stripped down extensions.Ingress with only the items we need for CoreDNS.
+type Ingress struct {
+	Version   string
+	Name      string
+	Namespace string
+	Hosts     []string // lowercased rule hosts without the trailing dot
+	Addresses []string // load balancer IPs or hostnames
+}
+
+// ToIngress converts an extensions.Ingress to a *Ingress.
+func ToIngress(obj interface{}) interface{} {
+	ing, ok := obj.(*extensions.Ingress)
+	if !ok {
+		return obj
+	}
+
+	i := &Ingress{
+		Version:   ing.GetResourceVersion(),
+		Name:      ing.GetName(),
+		Namespace: ing.GetNamespace(),
+	}
+	for _, r := range ing.Spec.Rules {
+		if r.Host == "" {
+			continue
+		}
+		i.Hosts = append(i.Hosts, strings.ToLower(strings.TrimSuffix(r.Host, ".")))
+	}
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		addr := lb.IP
+		if addr == "" {
+			addr = lb.Hostname
+		}
+		if addr == "" {
+			continue
+		}
+		i.Addresses = append(i.Addresses, addr)
+	}
+
+	return i
+}
+
+// GetNamespace implements the Object interface.
+func (i *Ingress) GetNamespace() string { return i.Namespace }
+
+// GetName implements the Object interface.
+func (i *Ingress) GetName() string { return i.Name }
@@ -0,0 +1,49 @@
+package object
+
+import (
+	api "k8s.io/api/core/v1"
+)
+
+// Node is a stripped down api.Node with only the items we need for CoreDNS.
+type Node struct {
+	Version string
+	Name    string
+	Labels  map[string]string // only the topology labels
+}
+
+// topologyLabels are the node labels we keep, they tell in which zone and region a node is.
+var topologyLabels = []string{
+	"topology.kubernetes.io/zone",
+	"topology.kubernetes.io/region",
+	"failure-domain.beta.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/region",
+}
+
+// ToNode converts an api.Node to a *Node.
+func ToNode(obj interface{}) interface{} {
+	node, ok := obj.(*api.Node)
+	if !ok {
+		return obj
+	}
+
+	n := &Node{
+		Version: node.GetResourceVersion(),
+		Name:    node.GetName(),
+	}
+	for _, l := range topologyLabels {
+		if v, ok := node.Labels[l]; ok {
+			if n.Labels == nil {
+				n.Labels = map[string]string{}
+			}
+			n.Labels[l] = v
+		}
+	}
+
+	return n
+}
+
+// GetNamespace implements the Object interface.
+func (n *Node) GetNamespace() string { return "" }
+
+// GetName implements the Object interface.
+func (n *Node) GetName() string { return n.Name }
@@ -0,0 +1,36 @@
+// Package object holds functions that convert the objects from the k8s API into
+// more memory efficient structures.
+//
+// Adding new fields to any of the structures defined in service.go, endpoint.go
+// and pod.go should be done with care as these structures are stored in the
+// caches; keeping them small keeps the memory usage of CoreDNS in check on large
+// clusters.
+package object
+
+import (
+	"k8s.io/client-go/tools/cache"
+)
+
+// ToFunc converts one empty interface to another.
+type ToFunc func(interface{}) interface{}
+
+// Object is implemented by all the objects in this package.
+type Object interface {
+	GetNamespace() string
+	GetName() string
+}
+
+// KeyFunc returns the key of obj. It returns the same keys as cache.MetaNamespaceKeyFunc, but
+// also works for the objects in this package, and for cache.DeletedFinalStateUnknown.
+func KeyFunc(obj interface{}) (string, error) {
+	switch o := obj.(type) {
+	case cache.DeletedFinalStateUnknown:
+		return o.Key, nil
+	case Object:
+		if o.GetNamespace() == "" {
+			return o.GetName(), nil
+		}
+		return o.GetNamespace() + "/" + o.GetName(), nil
+	}
+	return cache.MetaNamespaceKeyFunc(obj)
+}
@@ -0,0 +1,259 @@
+package object
+
+import (
+	"testing"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestToService(t *testing.T) {
+	svc := &api.Service{
+		ObjectMeta: meta.ObjectMeta{Name: "svc1", Namespace: "testns", ResourceVersion: "12"},
+		Spec: api.ServiceSpec{
+			Type:        api.ServiceTypeLoadBalancer,
+			ClusterIP:   "10.0.0.1",
+			ExternalIPs: []string{"1.2.3.5"},
+			Ports:       []api.ServicePort{{Name: "http", Protocol: "TCP", Port: 80}},
+		},
+		Status: api.ServiceStatus{
+			LoadBalancer: api.LoadBalancerStatus{Ingress: []api.LoadBalancerIngress{{IP: "1.2.3.4"}, {Hostname: "lb.example.org"}}},
+		},
+	}
+
+	s, ok := ToService(svc).(*Service)
+	if !ok {
+		t.Fatalf("Expected *Service, got %T", ToService(svc))
+	}
+	if s.Name != "svc1" || s.Namespace != "testns" || s.Index != "svc1.testns" || s.Version != "12" {
+		t.Errorf("Unexpected meta data: %+v", s)
+	}
+	if s.ClusterIP != "10.0.0.1" || s.Type != api.ServiceTypeLoadBalancer || len(s.Ports) != 1 {
+		t.Errorf("Unexpected spec: %+v", s)
+	}
+	if len(s.ExternalIPs) != 2 || s.ExternalIPs[0] != "1.2.3.4" || s.ExternalIPs[1] != "1.2.3.5" {
+		t.Errorf("Expected external IPs [1.2.3.4 1.2.3.5], got %v", s.ExternalIPs)
+	}
+
+	// Converting twice is a noop.
+	if s1 := ToService(s); s1 != s {
+		t.Errorf("Expected the same *Service, got %v", s1)
+	}
+}
+
+func TestToEndpoints(t *testing.T) {
+	node := "node1"
+	ep := &api.Endpoints{
+		ObjectMeta: meta.ObjectMeta{Name: "svc1", Namespace: "testns"},
+		Subsets: []api.EndpointSubset{{
+			Addresses: []api.EndpointAddress{
+				{IP: "172.0.0.1", Hostname: "ep1a", NodeName: &node},
+				{IP: "172.0.0.2", TargetRef: &api.ObjectReference{Name: "pod2"}},
+			},
+			NotReadyAddresses: []api.EndpointAddress{{IP: "172.0.0.3"}},
+			Ports:             []api.EndpointPort{{Name: "http", Protocol: "TCP", Port: 80}},
+		}},
+	}
+
+	e := ToEndpoints(ep).(*Endpoints)
+	if e.Index != "svc1.testns" {
+		t.Errorf("Expected index svc1.testns, got %s", e.Index)
+	}
+	if len(e.IndexIP) != 2 || e.IndexIP[0] != "172.0.0.1" || e.IndexIP[1] != "172.0.0.2" {
+		t.Errorf("Expected IP index [172.0.0.1 172.0.0.2], got %v", e.IndexIP)
+	}
+	if len(e.Subsets) != 1 || len(e.Subsets[0].Addresses) != 2 {
+		t.Fatalf("Expected 1 subset with 2 addresses, got %+v", e.Subsets)
+	}
+	a := e.Subsets[0].Addresses
+	if a[0].Hostname != "ep1a" || a[0].NodeName != "node1" || a[1].TargetRefName != "pod2" {
+		t.Errorf("Unexpected addresses: %+v", a)
+	}
+	p := e.Subsets[0].Ports
+	if len(p) != 1 || p[0].Name != "http" || p[0].Protocol != "TCP" || p[0].Port != 80 {
+		t.Errorf("Unexpected ports: %+v", p)
+	}
+}
+
+func TestToEndpointSlice(t *testing.T) {
+	name, port, proto := "http", int32(80), api.Protocol("TCP")
+	ready, notReady := true, false
+	host, node := "ep1a", "node1"
+	slice := &EndpointSlice{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      "svc1-abcde",
+			Namespace: "testns",
+			Labels:    map[string]string{LabelServiceName: "svc1"},
+		},
+		AddressType: "IPv4",
+		Endpoints: []SliceEndpoint{
+			{Addresses: []string{"172.0.0.1"}, Hostname: &host, NodeName: &node},
+			{Addresses: []string{"172.0.0.2"}, TargetRef: &api.ObjectReference{Name: "pod2"}, Topology: map[string]string{"kubernetes.io/hostname": "node2"}},
+			{Addresses: []string{"172.0.0.3"}},
+		},
+		Ports: []SlicePort{{Name: &name, Protocol: &proto, Port: &port}, {Name: &name}},
+	}
+	slice.Endpoints[0].Conditions.Ready = &ready
+	slice.Endpoints[2].Conditions.Ready = &notReady
+
+	e := ToEndpointSlice(slice).(*Endpoints)
+	if e.Name != "svc1" || e.Index != "svc1.testns" || e.GetName() != "svc1-abcde" {
+		t.Errorf("Unexpected meta data: %+v", e)
+	}
+	if len(e.IndexIP) != 2 || e.IndexIP[0] != "172.0.0.1" || e.IndexIP[1] != "172.0.0.2" {
+		t.Errorf("Expected IP index [172.0.0.1 172.0.0.2], got %v", e.IndexIP)
+	}
+	if len(e.Subsets) != 1 || len(e.Subsets[0].Addresses) != 2 {
+		t.Fatalf("Expected 1 subset with 2 addresses, got %+v", e.Subsets)
+	}
+	a := e.Subsets[0].Addresses
+	if a[0].Hostname != "ep1a" || a[0].NodeName != "node1" || a[1].TargetRefName != "pod2" || a[1].NodeName != "node2" {
+		t.Errorf("Unexpected addresses: %+v", a)
+	}
+	p := e.Subsets[0].Ports
+	if len(p) != 1 || p[0].Name != "http" || p[0].Protocol != "TCP" || p[0].Port != 80 {
+		t.Errorf("Unexpected ports: %+v", p)
+	}
+
+	slice.AddressType = "FQDN"
+	if e := ToEndpointSlice(slice).(*Endpoints); len(e.Subsets) != 0 {
+		t.Errorf("Expected no subsets for an FQDN slice, got %+v", e.Subsets)
+	}
+}
+
+func TestToNode(t *testing.T) {
+	node := &api.Node{
+		ObjectMeta: meta.ObjectMeta{
+			Name: "node1",
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone": "zone-a",
+				"kubernetes.io/os":            "linux",
+			},
+		},
+		Status: api.NodeStatus{Images: []api.ContainerImage{{Names: []string{"coredns"}}}},
+	}
+
+	n := ToNode(node).(*Node)
+	if n.Name != "node1" || n.GetName() != "node1" {
+		t.Errorf("Expected node1, got %+v", n)
+	}
+	if len(n.Labels) != 1 || n.Labels["topology.kubernetes.io/zone"] != "zone-a" {
+		t.Errorf("Expected only the zone label, got %v", n.Labels)
+	}
+}
+
+func TestToPod(t *testing.T) {
+	now := meta.Now()
+	pod := &api.Pod{
+		ObjectMeta: meta.ObjectMeta{Name: "pod1", Namespace: "testns", DeletionTimestamp: &now},
+		Spec:       api.PodSpec{NodeName: "node1"},
+		Status:     api.PodStatus{PodIP: "10.240.0.1"},
+	}
+
+	p := ToPod(pod).(*Pod)
+	if p.PodIP != "10.240.0.1" || p.NodeName != "node1" || !p.Deleting {
+		t.Errorf("Unexpected pod: %+v", p)
+	}
+
+	pod.DeletionTimestamp = nil
+	if p = ToPod(pod).(*Pod); p.Deleting {
+		t.Errorf("Expected pod not to be deleting")
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	tests := []struct {
+		obj      interface{}
+		expected string
+	}{
+		{&Service{Name: "svc1", Namespace: "testns"}, "testns/svc1"},
+		{&api.Service{ObjectMeta: meta.ObjectMeta{Name: "svc1", Namespace: "testns"}}, "testns/svc1"},
+		{&Pod{Name: "pod1"}, "pod1"},
+		{cache.DeletedFinalStateUnknown{Key: "testns/svc2"}, "testns/svc2"},
+	}
+	for i, tc := range tests {
+		key, err := KeyFunc(tc.obj)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if key != tc.expected {
+			t.Errorf("Test %d: expected key %s, got %s", i, tc.expected, key)
+		}
+	}
+}
+
+func TestIndexerInformer(t *testing.T) {
+	fw := watch.NewFake()
+	lw := &cache.ListWatch{
+		ListFunc: func(meta.ListOptions) (runtime.Object, error) {
+			return &api.ServiceList{Items: []api.Service{
+				{ObjectMeta: meta.ObjectMeta{Name: "svc1", Namespace: "testns", ResourceVersion: "1"}, Spec: api.ServiceSpec{ClusterIP: "10.0.0.1"}},
+			}}, nil
+		},
+		WatchFunc: func(meta.ListOptions) (watch.Interface, error) { return fw, nil },
+	}
+
+	added := make(chan interface{}, 10)
+	deleted := make(chan interface{}, 10)
+	h := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { added <- obj },
+		DeleteFunc: func(obj interface{}) { deleted <- obj },
+	}
+	indexer, controller := NewIndexerInformer(lw, &api.Service{}, 0, h, cache.Indexers{}, ToService)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go controller.Run(stop)
+
+	select {
+	case obj := <-added:
+		if _, ok := obj.(*Service); !ok {
+			t.Fatalf("Expected *Service, got %T", obj)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the service to be added")
+	}
+
+	fw.Delete(&api.Service{ObjectMeta: meta.ObjectMeta{Name: "svc1", Namespace: "testns", ResourceVersion: "2"}})
+	select {
+	case obj := <-deleted:
+		if _, ok := obj.(*Service); !ok {
+			t.Fatalf("Expected *Service, got %T", obj)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the service to be deleted")
+	}
+
+	if keys := indexer.ListKeys(); len(keys) != 0 {
+		t.Errorf("Expected empty cache, got %v", keys)
+	}
+}
+
+func TestToIngress(t *testing.T) {
+	ing := &extensions.Ingress{
+		ObjectMeta: meta.ObjectMeta{Name: "www", Namespace: "testns", ResourceVersion: "3"},
+		Spec: extensions.IngressSpec{
+			Rules: []extensions.IngressRule{{Host: "WWW.example.org."}, {}},
+		},
+		Status: extensions.IngressStatus{
+			LoadBalancer: api.LoadBalancerStatus{Ingress: []api.LoadBalancerIngress{{IP: "1.2.3.4"}, {Hostname: "lb.example.net"}, {}}},
+		},
+	}
+
+	i := ToIngress(ing).(*Ingress)
+	if i.GetName() != "www" || i.GetNamespace() != "testns" || i.Version != "3" {
+		t.Errorf("Unexpected ingress: %+v", i)
+	}
+	if len(i.Hosts) != 1 || i.Hosts[0] != "www.example.org" {
+		t.Errorf("Expected [www.example.org], got %v", i.Hosts)
+	}
+	if len(i.Addresses) != 2 || i.Addresses[0] != "1.2.3.4" || i.Addresses[1] != "lb.example.net" {
+		t.Errorf("Expected [1.2.3.4 lb.example.net], got %v", i.Addresses)
+	}
+}
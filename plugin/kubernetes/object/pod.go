@@ -0,0 +1,43 @@
+package object
+
+import (
+	api "k8s.io/api/core/v1"
+)
+
+// Pod is a stripped down api.Pod with only the items we need for CoreDNS.
+type Pod struct {
+	Version   string
+	PodIP     string
+	Name      string
+	Namespace string
+	NodeName  string
+	Deleting  bool
+}
+
+// ToPod converts an api.Pod to a *Pod.
+func ToPod(obj interface{}) interface{} {
+	pod, ok := obj.(*api.Pod)
+	if !ok {
+		return obj
+	}
+
+	p := &Pod{
+		Version:   pod.GetResourceVersion(),
+		PodIP:     pod.Status.PodIP,
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+		NodeName:  pod.Spec.NodeName,
+	}
+	t := pod.ObjectMeta.DeletionTimestamp
+	if t != nil && !t.IsZero() {
+		p.Deleting = true
+	}
+
+	return p
+}
+
+// GetNamespace implements the Object interface.
+func (p *Pod) GetNamespace() string { return p.Namespace }
+
+// GetName implements the Object interface.
+func (p *Pod) GetName() string { return p.Name }
@@ -0,0 +1,61 @@
+package object
+
+import (
+	api "k8s.io/api/core/v1"
+)
+
+// Service is a stripped down api.Service with only the items we need for CoreDNS.
+type Service struct {
+	Version      string
+	Name         string
+	Namespace    string
+	Index        string
+	ClusterIP    string
+	Type         api.ServiceType
+	ExternalName string
+	Ports        []api.ServicePort
+
+	// ExternalIPs are the load balancer ingress IPs and the external IPs of the service.
+	ExternalIPs []string
+}
+
+// ServiceKey return a string using for the index.
+func ServiceKey(name, namespace string) string { return name + "." + namespace }
+
+// ToService converts an api.Service to a *Service.
+func ToService(obj interface{}) interface{} {
+	svc, ok := obj.(*api.Service)
+	if !ok {
+		return obj
+	}
+
+	s := &Service{
+		Version:      svc.GetResourceVersion(),
+		Name:         svc.GetName(),
+		Namespace:    svc.GetNamespace(),
+		Index:        ServiceKey(svc.GetName(), svc.GetNamespace()),
+		ClusterIP:    svc.Spec.ClusterIP,
+		Type:         svc.Spec.Type,
+		ExternalName: svc.Spec.ExternalName,
+	}
+
+	if len(svc.Spec.Ports) > 0 {
+		s.Ports = make([]api.ServicePort, len(svc.Spec.Ports))
+		copy(s.Ports, svc.Spec.Ports)
+	}
+
+	for _, lb := range svc.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			s.ExternalIPs = append(s.ExternalIPs, lb.IP)
+		}
+	}
+	s.ExternalIPs = append(s.ExternalIPs, svc.Spec.ExternalIPs...)
+
+	return s
+}
+
+// GetNamespace implements the Object interface.
+func (s *Service) GetNamespace() string { return s.Namespace }
+
+// GetName implements the Object interface.
+func (s *Service) GetName() string { return s.Name }
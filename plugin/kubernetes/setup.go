@@ -11,6 +11,7 @@ import (
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -61,10 +62,15 @@ func setup(c *caddy.Controller) error {
 // RegisterKubeCache registers KubeCache start and stop functions with Caddy
 func (k *Kubernetes) RegisterKubeCache(c *caddy.Controller) {
 	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, CacheObjects) })
+
 		go k.APIConn.Run()
 		if k.APIProxy != nil {
 			k.APIProxy.Run()
 		}
+		if k.kubeconfig != nil {
+			go k.kubeconfig.Run()
+		}
 		synced := false
 		for synced == false {
 			synced = k.APIConn.HasSynced()
@@ -78,6 +84,9 @@ func (k *Kubernetes) RegisterKubeCache(c *caddy.Controller) {
 		if k.APIProxy != nil {
 			k.APIProxy.Stop()
 		}
+		if k.kubeconfig != nil {
+			k.kubeconfig.Stop()
+		}
 		return k.APIConn.Stop()
 	})
 }
@@ -187,6 +196,16 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 				continue
 			}
 			return nil, c.ArgErr()
+		case "kubeconfig": // path [context]
+			args := c.RemainingArgs()
+			if len(args) == 1 || len(args) == 2 {
+				k8s.KubeConfigFile = args[0]
+				if len(args) == 2 {
+					k8s.KubeContext = args[1]
+				}
+				continue
+			}
+			return nil, c.ArgErr()
 		case "resyncperiod":
 			args := c.RemainingArgs()
 			if len(args) > 0 {
@@ -241,6 +260,22 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 				return nil, c.Errf("transfer from is not supported with this plugin")
 			}
 			k8s.TransferTo = tos
+		case "ingress":
+			if len(c.RemainingArgs()) != 0 {
+				return nil, c.ArgErr()
+			}
+			k8s.opts.initIngressCache = true
+		case "topology":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return nil, c.ArgErr()
+			}
+			switch args[0] {
+			case topologyNode, topologyZone:
+				k8s.topology = args[0]
+			default:
+				return nil, fmt.Errorf("wrong value for topology: %s, must be one of: node, zone", args[0])
+			}
 		case "noendpoints":
 			if len(c.RemainingArgs()) != 0 {
 				return nil, c.ArgErr()
@@ -250,6 +285,10 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 			return nil, c.Errf("unknown property '%s'", c.Val())
 		}
 	}
+
+	if k8s.KubeConfigFile != "" && (len(k8s.APIServerList) > 0 || k8s.APIClientCert != "") {
+		return nil, c.Errf("kubeconfig can not be used together with endpoint or tls")
+	}
 	return k8s, nil
 }
 
@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"github.com/coredns/coredns/request"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+)
+
+const (
+	// topologyNode prefers the endpoints on the node of the client.
+	topologyNode = "node"
+	// topologyZone prefers the endpoints in the zone of the node of the client.
+	topologyZone = "zone"
+
+	// LabelTopologyZone is the zone label that replaces LabelZone, both are checked.
+	LabelTopologyZone = "topology.kubernetes.io/zone"
+)
+
+// local returns a function that reports whether an endpoint address is close to the client in
+// state. It returns nil if topology aware answers are disabled, or when the client isn't a pod
+// we know of.
+func (k *Kubernetes) local(state request.Request) func(object.EndpointAddress) bool {
+	if k.topology == "" {
+		return nil
+	}
+
+	node := ""
+	for _, p := range k.APIConn.PodIndex(state.IP()) {
+		if p.NodeName != "" {
+			node = p.NodeName
+			break
+		}
+	}
+	if node == "" {
+		return nil
+	}
+
+	if k.topology == topologyNode {
+		return func(addr object.EndpointAddress) bool {
+			return addr.NodeName == node
+		}
+	}
+
+	zone := k.nodeZone(node)
+	if zone == "" {
+		return nil
+	}
+	return func(addr object.EndpointAddress) bool {
+		return addr.NodeName != "" && k.nodeZone(addr.NodeName) == zone
+	}
+}
+
+// nodeZone returns the zone of the node with name, or the empty string if it can't be found.
+func (k *Kubernetes) nodeZone(name string) string {
+	node, err := k.APIConn.GetNodeByName(name)
+	if err != nil || node == nil {
+		return ""
+	}
+	if z := node.Labels[LabelTopologyZone]; z != "" {
+		return z
+	}
+	return node.Labels[LabelZone]
+}
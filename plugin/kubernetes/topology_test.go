@@ -0,0 +1,187 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+	api "k8s.io/api/core/v1"
+)
+
+// APIConnTopologyTest has a client pod on node1 and a headless service with endpoints spread over
+// three nodes in two zones.
+type APIConnTopologyTest struct {
+	APIConnServeTest
+	node string // node of the client pod, if empty the client is unknown
+}
+
+func (a APIConnTopologyTest) PodIndex(ip string) []*object.Pod {
+	if a.node == "" || ip != "10.240.0.1" {
+		return nil
+	}
+	return []*object.Pod{{
+		Name:      "client",
+		Namespace: "testns",
+		NodeName:  a.node,
+		PodIP:     ip,
+	}}
+}
+
+func (APIConnTopologyTest) SvcIndex(s string) []*object.Service {
+	if s != "hdls.testns" {
+		return nil
+	}
+	return []*object.Service{{
+		Name:      "hdls",
+		Namespace: "testns",
+		ClusterIP: api.ClusterIPNone,
+	}}
+}
+
+func (APIConnTopologyTest) EpIndex(s string) []*object.Endpoints {
+	if s != "hdls.testns" {
+		return nil
+	}
+	node1, node2, node3 := "node1", "node2", "node3"
+	return []*object.Endpoints{{
+		Name:      "hdls",
+		Namespace: "testns",
+		Subsets: []object.EndpointSubset{{
+			Addresses: []object.EndpointAddress{
+				{IP: "172.0.0.1", NodeName: node1},
+				{IP: "172.0.0.2", NodeName: node2},
+				{IP: "172.0.0.3", NodeName: node3},
+				{IP: "172.0.0.4"},
+			},
+			Ports: []object.EndpointPort{{Port: 80, Protocol: "tcp", Name: "http"}},
+		}},
+	}}
+}
+
+func (APIConnTopologyTest) GetNodeByName(name string) (*object.Node, error) {
+	zones := map[string]map[string]string{
+		"node1": {LabelTopologyZone: "zone-a"},
+		"node2": {LabelZone: "zone-a"},
+		"node3": {LabelTopologyZone: "zone-b"},
+		"node4": {LabelTopologyZone: "zone-c"},
+	}
+	l, ok := zones[name]
+	if !ok {
+		return &object.Node{}, fmt.Errorf("node not found")
+	}
+	return &object.Node{Name: name, Labels: l}, nil
+}
+
+func TestTopology(t *testing.T) {
+	tests := []struct {
+		topology string
+		node     string
+		qname    string
+		expected []string
+	}{
+		{"", "node1", "hdls.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3", "172.0.0.4"}},
+		{"node", "node1", "hdls.testns.svc.cluster.local.", []string{"172.0.0.1"}},
+		{"zone", "node1", "hdls.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2"}},
+		{"zone", "node3", "hdls.testns.svc.cluster.local.", []string{"172.0.0.3"}},
+		// Unknown client.
+		{"node", "", "hdls.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3", "172.0.0.4"}},
+		// No endpoints close to the client.
+		{"node", "node4", "hdls.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3", "172.0.0.4"}},
+		{"zone", "node4", "hdls.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3", "172.0.0.4"}},
+		// Endpoint queries are not filtered.
+		{"node", "node1", "172-0-0-3.hdls.testns.svc.cluster.local.", []string{"172.0.0.3"}},
+	}
+
+	for i, tc := range tests {
+		k := New([]string{"cluster.local."})
+		k.APIConn = &APIConnTopologyTest{node: tc.node}
+		k.Namespaces = map[string]bool{"testns": true}
+		k.topology = tc.topology
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: m, Zone: "cluster.local."}
+
+		services, err := k.Records(state, false)
+		if err != nil {
+			t.Errorf("Test %d: unexpected error: %s", i, err)
+			continue
+		}
+		hosts := []string{}
+		for _, s := range services {
+			hosts = append(hosts, s.Host)
+		}
+		sort.Strings(hosts)
+		if fmt.Sprint(hosts) != fmt.Sprint(tc.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, hosts)
+		}
+	}
+}
+
+func TestTopologyServeDNS(t *testing.T) {
+	k := New([]string{"cluster.local."})
+	k.APIConn = &APIConnTopologyTest{node: "node2"}
+	k.Namespaces = map[string]bool{"testns": true}
+	k.topology = topologyNode
+
+	m := new(dns.Msg)
+	m.SetQuestion("hdls.testns.svc.cluster.local.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := k.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Fatalf("Expected 1 answer, got %d", len(rec.Msg.Answer))
+	}
+	if a := rec.Msg.Answer[0].(*dns.A).A.String(); a != "172.0.0.2" {
+		t.Errorf("Expected 172.0.0.2, got %s", a)
+	}
+}
+
+func TestKubernetesParseTopology(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  string
+	}{
+		{`kubernetes cluster.local`, false, ""},
+		{`kubernetes cluster.local {
+	topology node
+}`, false, topologyNode},
+		{`kubernetes cluster.local {
+	topology zone
+}`, false, topologyZone},
+		{`kubernetes cluster.local {
+	topology region
+}`, true, ""},
+		{`kubernetes cluster.local {
+	topology
+}`, true, ""},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		k, err := kubernetesParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if k.topology != tc.expected {
+			t.Errorf("Test %d: expected topology %q, got %q", i, tc.expected, k.topology)
+		}
+	}
+}
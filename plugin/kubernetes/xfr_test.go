@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/kubernetes/object"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
 	"golang.org/x/net/context"
@@ -192,10 +193,11 @@ func TestEndpointsEquivalent(t *testing.T) {
 	}
 
 	for i, tc := range tests {
-		if tc.equiv && !endpointsEquivalent(tc.a, tc.b) {
+		a, b := object.ToEndpoints(tc.a).(*object.Endpoints), object.ToEndpoints(tc.b).(*object.Endpoints)
+		if tc.equiv && !endpointsEquivalent(a, b) {
 			t.Errorf("Test %d: expected endpoints to be equivalent and they are not.", i)
 		}
-		if !tc.equiv && endpointsEquivalent(tc.a, tc.b) {
+		if !tc.equiv && endpointsEquivalent(a, b) {
 			t.Errorf("Test %d: expected endpoints to be seen as different but they were not.", i)
 		}
 	}
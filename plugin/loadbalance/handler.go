@@ -2,6 +2,8 @@
 package loadbalance
 
 import (
+	"strings"
+
 	"github.com/coredns/coredns/plugin"
 
 	"github.com/miekg/dns"
@@ -21,3 +23,39 @@ func (rr RoundRobin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.
 
 // Name implements the Handler interface.
 func (rr RoundRobin) Name() string { return "loadbalance" }
+
+// LoadBalance is plugin to rewrite responses for "load balancing" with a weighted or health aware
+// selection of the address records.
+type LoadBalance struct {
+	Next plugin.Handler
+
+	zones   []string
+	policy  string
+	weights *weights    // nil when weights come from TXT side records
+	txt     *txtWeights // cached TXT side records, nil when weights come from a file
+	max     int         // maximum number of address records to return, 0 means all
+	health  *health     // nil when not health checking
+}
+
+// probed returns true if the address in rr is health checked: the owner name must be in one of our zones,
+// and, with a weight file, the address must be listed for that name.
+func (lb *LoadBalance) probed(rr dns.RR) bool {
+	name := strings.ToLower(rr.Header().Name)
+	if plugin.Zones(lb.zones).Matches(name) == "" {
+		return false
+	}
+	if lb.weights == nil {
+		return true
+	}
+	_, ok := lb.weights.weight(name, addressOf(rr))
+	return ok
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (lb *LoadBalance) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	lw := &LoadBalanceResponseWriter{ResponseWriter: w, lb: lb, ctx: ctx}
+	return plugin.NextOrFailure(lb.Name(), lb.Next, ctx, lw, r)
+}
+
+// Name implements the Handler interface.
+func (lb *LoadBalance) Name() string { return "loadbalance" }
@@ -0,0 +1,145 @@
+package loadbalance
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// health keeps track of the health of the addresses we've seen in replies. Every interval each address
+// is probed with a TCP connect or an HTTP GET, by at most hcConcurrency probes at the same time. At most
+// hcMaxTargets addresses are tracked, and addresses that haven't been seen for a while are forgotten.
+type health struct {
+	proto    string // "tcp" or "http"
+	port     string
+	path     string
+	interval time.Duration
+
+	client *http.Client
+
+	sync.Mutex
+	targets map[string]*target
+
+	stop chan struct{}
+}
+
+type target struct {
+	healthy bool
+	seen    time.Time
+}
+
+func newHealth(proto, port, path string, interval time.Duration) *health {
+	return &health{
+		proto:    proto,
+		port:     port,
+		path:     path,
+		interval: interval,
+		client:   &http.Client{Timeout: hcTimeout},
+		targets:  map[string]*target{},
+		stop:     make(chan struct{}),
+	}
+}
+
+// healthy returns true if addr is healthy. Addresses we haven't probed yet are considered healthy, as
+// are addresses we don't have room for.
+func (h *health) healthy(addr string) bool {
+	h.Lock()
+	defer h.Unlock()
+	t, ok := h.targets[addr]
+	if !ok {
+		if len(h.targets) < hcMaxTargets {
+			h.targets[addr] = &target{healthy: true, seen: time.Now()}
+		}
+		return true
+	}
+	t.seen = time.Now()
+	return t.healthy
+}
+
+func (h *health) start() {
+	go func() {
+		tick := time.NewTicker(h.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-tick.C:
+				h.probe()
+			}
+		}
+	}()
+}
+
+func (h *health) close() { close(h.stop) }
+
+// probe probes all targets, targets that haven't been seen in hcExpire are removed.
+func (h *health) probe() {
+	h.Lock()
+	addrs := make([]string, 0, len(h.targets))
+	for addr, t := range h.targets {
+		if time.Since(t.seen) > hcExpire {
+			delete(h.targets, addr)
+			HealthcheckFailureCount.DeleteLabelValues(addr)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	h.Unlock()
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < hcConcurrency && i < len(addrs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range work {
+				err := h.check(addr)
+				if err != nil {
+					HealthcheckFailureCount.WithLabelValues(addr).Add(1)
+				}
+				h.Lock()
+				if t, ok := h.targets[addr]; ok {
+					t.healthy = err == nil
+				}
+				h.Unlock()
+			}
+		}()
+	}
+	for _, addr := range addrs {
+		work <- addr
+	}
+	close(work)
+	wg.Wait()
+}
+
+// check probes a single address.
+func (h *health) check(addr string) error {
+	hostport := net.JoinHostPort(addr, h.port)
+	if h.proto == "tcp" {
+		conn, err := net.DialTimeout("tcp", hostport, hcTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	resp, err := h.client.Get("http://" + hostport + h.path)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unhealthy status: %s", resp.Status)
+	}
+	return nil
+}
+
+const (
+	hcTimeout     = 1 * time.Second
+	hcExpire      = 10 * time.Minute
+	hcConcurrency = 16
+	hcMaxTargets  = 1000
+)
@@ -0,0 +1,90 @@
+package loadbalance
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestHealthTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	h := newHealth("tcp", port, "/", time.Second)
+	if !h.healthy("127.0.0.1") {
+		t.Errorf("Expected unprobed address to be healthy")
+	}
+	h.probe()
+	if !h.healthy("127.0.0.1") {
+		t.Errorf("Expected 127.0.0.1 to be healthy")
+	}
+
+	l.Close()
+	h.probe()
+	if h.healthy("127.0.0.1") {
+		t.Errorf("Expected 127.0.0.1 to be unhealthy")
+	}
+}
+
+func TestHealthHTTP(t *testing.T) {
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+
+	h := newHealth("http", port, "/healthz", time.Second)
+	h.healthy("127.0.0.1")
+	h.probe()
+	if !h.healthy("127.0.0.1") {
+		t.Errorf("Expected 127.0.0.1 to be healthy")
+	}
+
+	status = http.StatusServiceUnavailable
+	h.probe()
+	if h.healthy("127.0.0.1") {
+		t.Errorf("Expected 127.0.0.1 to be unhealthy")
+	}
+}
+
+func TestHealthMaxTargets(t *testing.T) {
+	h := newHealth("tcp", "80", "/", time.Second)
+	for i := 0; i < hcMaxTargets+10; i++ {
+		h.healthy("10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256))
+	}
+	if len(h.targets) != hcMaxTargets {
+		t.Errorf("Expected %d targets, got %d", hcMaxTargets, len(h.targets))
+	}
+}
+
+func TestProbed(t *testing.T) {
+	lb := &LoadBalance{zones: []string{"example.org."}}
+	if !lb.probed(test.A("web.example.org. 300 IN A 10.0.0.1")) {
+		t.Errorf("Expected address in our zone to be probed")
+	}
+	if lb.probed(test.A("web.example.net. 300 IN A 10.0.0.1")) {
+		t.Errorf("Expected address outside our zones not to be probed")
+	}
+
+	lb.weights = newWeights("")
+	lb.weights.w = map[string]map[string]uint32{"web.example.org.": {"10.0.0.1": 1}}
+	if !lb.probed(test.A("web.example.org. 300 IN A 10.0.0.1")) {
+		t.Errorf("Expected address in the weight file to be probed")
+	}
+	if lb.probed(test.A("web.example.org. 300 IN A 10.0.0.2")) {
+		t.Errorf("Expected address not in the weight file not to be probed")
+	}
+}
@@ -2,9 +2,14 @@
 package loadbalance
 
 import (
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
 
 	"github.com/miekg/dns"
+	"golang.org/x/net/context"
 )
 
 // RoundRobinResponseWriter is a response writer that shuffles A and AAAA records.
@@ -85,3 +90,132 @@ func (r *RoundRobinResponseWriter) Hijack() {
 	r.ResponseWriter.Hijack()
 	return
 }
+
+// LoadBalanceResponseWriter is a response writer that orders, filters and limits the address records in
+// the answer according to the LoadBalance configuration.
+type LoadBalanceResponseWriter struct {
+	dns.ResponseWriter
+	lb  *LoadBalance
+	ctx context.Context
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (r *LoadBalanceResponseWriter) WriteMsg(res *dns.Msg) error {
+	if res.Rcode != dns.RcodeSuccess {
+		return r.ResponseWriter.WriteMsg(res)
+	}
+
+	res.Answer = r.balance(res.Answer)
+	res.Ns = roundRobin(res.Ns)
+	res.Extra = roundRobin(res.Extra)
+	return r.ResponseWriter.WriteMsg(res)
+}
+
+// balance is like roundRobin, but applies the policy, health checks and maximum to the address records.
+func (r *LoadBalanceResponseWriter) balance(in []dns.RR) []dns.RR {
+	cname := []dns.RR{}
+	address := []dns.RR{}
+	mx := []dns.RR{}
+	rest := []dns.RR{}
+	for _, rr := range in {
+		switch rr.Header().Rrtype {
+		case dns.TypeCNAME:
+			cname = append(cname, rr)
+		case dns.TypeA, dns.TypeAAAA:
+			address = append(address, rr)
+		case dns.TypeMX:
+			mx = append(mx, rr)
+		default:
+			rest = append(rest, rr)
+		}
+	}
+
+	address = r.healthy(address)
+
+	switch r.lb.policy {
+	case weighted:
+		address = weightedShuffle(address, r.weight(address))
+	default:
+		roundRobinShuffle(address)
+	}
+	if r.lb.max > 0 && len(address) > r.lb.max {
+		address = address[:r.lb.max]
+	}
+	roundRobinShuffle(mx)
+
+	out := append(cname, rest...)
+	out = append(out, address...)
+	out = append(out, mx...)
+	return out
+}
+
+// healthy removes the records whose addresses are unhealthy. If none are healthy all are returned.
+// Addresses that aren't probed are healthy.
+func (r *LoadBalanceResponseWriter) healthy(address []dns.RR) []dns.RR {
+	if r.lb.health == nil {
+		return address
+	}
+	up := make([]dns.RR, 0, len(address))
+	for _, rr := range address {
+		if !r.lb.probed(rr) || r.lb.health.healthy(addressOf(rr)) {
+			up = append(up, rr)
+		}
+	}
+	if len(up) == 0 {
+		return address
+	}
+	return up
+}
+
+// weight returns a function that returns the weight of an address record. When no weight file is
+// configured the weights are looked up in the TXT records of the _weight label of the owner name.
+func (r *LoadBalanceResponseWriter) weight(address []dns.RR) func(dns.RR) uint32 {
+	if r.lb.weights != nil {
+		return func(rr dns.RR) uint32 {
+			if x, ok := r.lb.weights.weight(strings.ToLower(rr.Header().Name), addressOf(rr)); ok {
+				return x
+			}
+			return defaultWeight
+		}
+	}
+
+	txt := map[string]map[string]uint32{}
+	return func(rr dns.RR) uint32 {
+		name := strings.ToLower(rr.Header().Name)
+		w, ok := txt[name]
+		if !ok {
+			w = r.lb.txt.weights(name, r.lookupTXTWeights)
+			txt[name] = w
+		}
+		if x, ok := w[addressOf(rr)]; ok {
+			return x
+		}
+		return defaultWeight
+	}
+}
+
+// lookupTXTWeights queries the next plugin for the TXT records at _weight.name.
+func (r *LoadBalanceResponseWriter) lookupTXTWeights(name string) map[string]uint32 {
+	req := new(dns.Msg)
+	req.SetQuestion(weightLabel+name, dns.TypeTXT)
+
+	nw := nonwriter.New(r.ResponseWriter)
+	if _, err := plugin.NextOrFailure(r.lb.Name(), r.lb.Next, r.ctx, nw, req); err != nil || nw.Msg == nil {
+		return nil
+	}
+	return parseTXTWeights(nw.Msg.Answer)
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (r *LoadBalanceResponseWriter) Write(buf []byte) (int, error) {
+	log.Warning("LoadBalance called with Write: not balancing records")
+	n, err := r.ResponseWriter.Write(buf)
+	return n, err
+}
+
+const (
+	roundRobinPolicy = "round_robin"
+	weighted         = "weighted"
+
+	weightLabel = "_weight."
+)
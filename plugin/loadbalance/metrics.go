@@ -0,0 +1,21 @@
+package loadbalance
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	HealthcheckFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "loadbalance",
+		Name:      "healthcheck_failure_count_total",
+		Help:      "Counter of the number of failed healthchecks per address.",
+	}, []string{"address"})
+)
+
+var once sync.Once
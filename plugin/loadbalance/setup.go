@@ -1,8 +1,16 @@
 package loadbalance
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/log"
+
 	"github.com/mholt/caddy"
 )
 
@@ -14,13 +22,199 @@ func init() {
 }
 
 func setup(c *caddy.Controller) error {
-	for c.Next() {
-		// TODO(miek): block and option parsing
+	lb, reload, err := loadBalanceParse(c)
+	if err != nil {
+		return plugin.Error("loadbalance", err)
+	}
+
+	// Plain round robin, keep using the simple (and fast) handler.
+	if lb.policy == roundRobinPolicy && lb.max == 0 && lb.health == nil {
+		dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+			return RoundRobin{Next: next}
+		})
+		return nil
 	}
 
+	parseChan := make(chan bool)
+
+	c.OnStartup(func() error {
+		once.Do(func() {
+			metrics.MustRegister(c, HealthcheckFailureCount)
+		})
+
+		if lb.health != nil {
+			lb.health.start()
+		}
+		if lb.txt != nil {
+			go func() {
+				ticker := time.NewTicker(reload)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-parseChan:
+						return
+					case <-ticker.C:
+						lb.txt.prune()
+					}
+				}
+			}()
+		}
+		if lb.weights == nil {
+			return nil
+		}
+		if err := lb.weights.readWeights(); err != nil {
+			return plugin.Error("loadbalance", err)
+		}
+		go func() {
+			ticker := time.NewTicker(reload)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-parseChan:
+					return
+				case <-ticker.C:
+					if err := lb.weights.readWeights(); err != nil {
+						log.Warningf("Failed to reload weights from %q: %s", lb.weights.path, err)
+					}
+				}
+			}
+		}()
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(parseChan)
+		if lb.health != nil {
+			lb.health.close()
+		}
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
-		return RoundRobin{Next: next}
+		lb.Next = next
+		return lb
 	})
 
 	return nil
 }
+
+func loadBalanceParse(c *caddy.Controller) (*LoadBalance, time.Duration, error) {
+	lb := &LoadBalance{policy: roundRobinPolicy}
+	reload := defaultReload
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, 0, plugin.ErrOnce
+		}
+		i++
+
+		lb.zones = make([]string, len(c.ServerBlockKeys))
+		for j, z := range c.ServerBlockKeys {
+			lb.zones[j] = plugin.Host(z).Normalize()
+		}
+
+		args := c.RemainingArgs()
+		if len(args) > 0 {
+			switch args[0] {
+			case roundRobinPolicy:
+				if len(args) > 1 {
+					return nil, 0, c.ArgErr()
+				}
+			case weighted:
+				lb.policy = weighted
+				if len(args) > 2 {
+					return nil, 0, c.ArgErr()
+				}
+				if len(args) == 2 {
+					lb.weights = newWeights(args[1])
+				}
+			default:
+				return nil, 0, fmt.Errorf("unknown policy: %s", args[0])
+			}
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "reload":
+				if !c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, 0, err
+				}
+				if d <= 0 {
+					return nil, 0, fmt.Errorf("reload must be positive: %s", d)
+				}
+				reload = d
+			case "max_records":
+				if !c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil {
+					return nil, 0, err
+				}
+				if n <= 0 {
+					return nil, 0, fmt.Errorf("max_records must be positive: %d", n)
+				}
+				lb.max = n
+			case "health_check":
+				h, err := parseHealthCheck(c)
+				if err != nil {
+					return nil, 0, err
+				}
+				lb.health = h
+			default:
+				return nil, 0, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if lb.policy == weighted && lb.weights == nil {
+		lb.txt = newTXTWeights(reload)
+	}
+	return lb, reload, nil
+}
+
+// parseHealthCheck parses: health_check tcp|http PORT [PATH] [INTERVAL]
+func parseHealthCheck(c *caddy.Controller) (*health, error) {
+	args := c.RemainingArgs()
+	if len(args) < 2 || len(args) > 4 {
+		return nil, c.ArgErr()
+	}
+	proto := args[0]
+	if proto != "tcp" && proto != "http" {
+		return nil, fmt.Errorf("unknown health check protocol: %s", proto)
+	}
+	port, err := strconv.ParseUint(args[1], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check port: %s", args[1])
+	}
+
+	path := "/"
+	interval := defaultInterval
+	for _, a := range args[2:] {
+		if strings.HasPrefix(a, "/") {
+			if proto != "http" {
+				return nil, fmt.Errorf("path is only valid for http health checks: %s", a)
+			}
+			path = a
+			continue
+		}
+		d, err := time.ParseDuration(a)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("health check interval must be positive: %s", d)
+		}
+		interval = d
+	}
+	return newHealth(proto, strconv.FormatUint(port, 10), path, interval), nil
+}
+
+const (
+	defaultReload   = 30 * time.Second
+	defaultInterval = 10 * time.Second
+)
@@ -0,0 +1,96 @@
+package loadbalance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupLoadBalance(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedPolicy string
+		expectedFile   string
+		expectedMax    int
+		expectedHealth bool
+	}{
+		// positive
+		{`loadbalance`, false, roundRobinPolicy, "", 0, false},
+		{`loadbalance round_robin`, false, roundRobinPolicy, "", 0, false},
+		{`loadbalance weighted`, false, weighted, "", 0, false},
+		{`loadbalance weighted /etc/weights`, false, weighted, "/etc/weights", 0, false},
+		{`loadbalance round_robin {
+			max_records 2
+		}`, false, roundRobinPolicy, "", 2, false},
+		{`loadbalance {
+			health_check tcp 80
+		}`, false, roundRobinPolicy, "", 0, true},
+		{`loadbalance weighted /etc/weights {
+			reload 10s
+			health_check http 8080 /healthz 5s
+		}`, false, weighted, "/etc/weights", 0, true},
+		// negative
+		{`loadbalance random`, true, "", "", 0, false},
+		{`loadbalance round_robin /etc/weights`, true, "", "", 0, false},
+		{`loadbalance {
+			max_records 0
+		}`, true, "", "", 0, false},
+		{`loadbalance {
+			health_check udp 53
+		}`, true, "", "", 0, false},
+		{`loadbalance {
+			health_check tcp 80 /healthz
+		}`, true, "", "", 0, false},
+		{`loadbalance {
+			blaat
+		}`, true, "", "", 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		lb, _, err := loadBalanceParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if lb.policy != test.expectedPolicy {
+			t.Errorf("Test %d: expected policy %s, got %s", i, test.expectedPolicy, lb.policy)
+		}
+		file := ""
+		if lb.weights != nil {
+			file = lb.weights.path
+		}
+		if file != test.expectedFile {
+			t.Errorf("Test %d: expected weight file %q, got %q", i, test.expectedFile, file)
+		}
+		if lb.max != test.expectedMax {
+			t.Errorf("Test %d: expected max_records %d, got %d", i, test.expectedMax, lb.max)
+		}
+		if (lb.health != nil) != test.expectedHealth {
+			t.Errorf("Test %d: expected health check to be %t", i, test.expectedHealth)
+		}
+	}
+}
+
+func TestSetupHealthCheck(t *testing.T) {
+	c := caddy.NewTestController("dns", `loadbalance {
+		health_check http 8080 /healthz 5s
+	}`)
+	lb, _, err := loadBalanceParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	h := lb.health
+	if h.proto != "http" || h.port != "8080" || h.path != "/healthz" || h.interval != 5*time.Second {
+		t.Errorf("Unexpected health check: %s %s %s %s", h.proto, h.port, h.path, h.interval)
+	}
+}
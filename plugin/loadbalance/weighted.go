@@ -0,0 +1,253 @@
+package loadbalance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+// weights holds the weight for each address of a name. It is read from a file that is re-read when
+// it changes.
+type weights struct {
+	path string
+
+	sync.RWMutex
+	w map[string]map[string]uint32 // name -> address -> weight
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
+}
+
+func newWeights(path string) *weights {
+	return &weights{path: path, w: map[string]map[string]uint32{}}
+}
+
+// weight returns the weight for addr of name and true, or false if the name or address isn't known.
+func (w *weights) weight(name, addr string) (uint32, bool) {
+	w.RLock()
+	defer w.RUnlock()
+	a, ok := w.w[name]
+	if !ok {
+		return 0, false
+	}
+	x, ok := a[addr]
+	return x, ok
+}
+
+// readWeights re-reads the weight file if its size or modification time changed.
+func (w *weights) readWeights() error {
+	file, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if w.mtime.Equal(stat.ModTime()) && w.size == stat.Size() {
+		return nil
+	}
+
+	m, err := parseWeights(file)
+	if err != nil {
+		return err
+	}
+
+	w.Lock()
+	w.w = m
+	w.Unlock()
+
+	w.mtime = stat.ModTime()
+	w.size = stat.Size()
+	return nil
+}
+
+// parseWeights parses a weight file. Each line has a name, an address and a weight:
+//
+//	www.example.org. 10.0.0.1 3
+//
+// Empty lines and anything after a '#' are ignored.
+func parseWeights(r io.Reader) (map[string]map[string]uint32, error) {
+	m := map[string]map[string]uint32{}
+
+	scanner := bufio.NewScanner(r)
+	for l := 1; scanner.Scan(); l++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return nil, fmt.Errorf("line %d: expected NAME ADDRESS WEIGHT, got %q", l, line)
+		}
+		ip := net.ParseIP(f[1])
+		if ip == nil {
+			return nil, fmt.Errorf("line %d: not an IP address: %q", l, f[1])
+		}
+		x, err := strconv.ParseUint(f[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid weight: %q", l, f[2])
+		}
+		name := plugin.Name(f[0]).Normalize()
+		if _, ok := m[name]; !ok {
+			m[name] = map[string]uint32{}
+		}
+		m[name][ip.String()] = uint32(x)
+	}
+	return m, scanner.Err()
+}
+
+// txtWeights caches the weights from the TXT side records of names, so the next plugin is queried at
+// most once every ttl for each name. At most txtMaxNames names are cached.
+type txtWeights struct {
+	ttl time.Duration
+
+	sync.Mutex
+	m map[string]txtEntry
+}
+
+type txtEntry struct {
+	w       map[string]uint32
+	expires time.Time
+}
+
+func newTXTWeights(ttl time.Duration) *txtWeights {
+	return &txtWeights{ttl: ttl, m: map[string]txtEntry{}}
+}
+
+// weights returns the weights of name. If they aren't cached, or have expired, they are looked up
+// with lookup.
+func (t *txtWeights) weights(name string, lookup func(string) map[string]uint32) map[string]uint32 {
+	now := time.Now()
+	t.Lock()
+	e, ok := t.m[name]
+	t.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.w
+	}
+
+	w := lookup(name)
+	t.Lock()
+	if ok || len(t.m) < txtMaxNames {
+		t.m[name] = txtEntry{w: w, expires: now.Add(t.ttl)}
+	}
+	t.Unlock()
+	return w
+}
+
+// prune removes the expired names.
+func (t *txtWeights) prune() {
+	now := time.Now()
+	t.Lock()
+	defer t.Unlock()
+	for name, e := range t.m {
+		if now.After(e.expires) {
+			delete(t.m, name)
+		}
+	}
+}
+
+// parseTXTWeights parses the TXT side records of a name. Each string holds an address and a weight,
+// i.e. "10.0.0.1 3".
+func parseTXTWeights(rrs []dns.RR) map[string]uint32 {
+	m := map[string]uint32{}
+	for _, r := range rrs {
+		t, ok := r.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		for _, s := range t.Txt {
+			f := strings.Fields(s)
+			if len(f) != 2 {
+				continue
+			}
+			ip := net.ParseIP(f[0])
+			if ip == nil {
+				continue
+			}
+			x, err := strconv.ParseUint(f[1], 10, 32)
+			if err != nil {
+				continue
+			}
+			m[ip.String()] = uint32(x)
+		}
+	}
+	return m
+}
+
+// weightedShuffle orders records by repeatedly making a weighted random pick from the remaining
+// records. Records with a weight of zero are removed, unless all of them have a zero weight.
+func weightedShuffle(records []dns.RR, weight func(dns.RR) uint32) []dns.RR {
+	if len(records) < 2 {
+		return records
+	}
+
+	type weighted struct {
+		rr dns.RR
+		w  uint32
+	}
+	ws := make([]weighted, 0, len(records))
+	total := uint64(0)
+	for _, r := range records {
+		w := weight(r)
+		if w == 0 {
+			continue
+		}
+		ws = append(ws, weighted{r, w})
+		total += uint64(w)
+	}
+	if len(ws) == 0 {
+		return records
+	}
+
+	out := make([]dns.RR, 0, len(ws))
+	for len(ws) > 0 {
+		pick := uint64(rand.Int63n(int64(total)))
+		i := 0
+		for ; i < len(ws)-1; i++ {
+			if pick < uint64(ws[i].w) {
+				break
+			}
+			pick -= uint64(ws[i].w)
+		}
+		out = append(out, ws[i].rr)
+		total -= uint64(ws[i].w)
+		ws = append(ws[:i], ws[i+1:]...)
+	}
+	return out
+}
+
+// addressOf returns the address in an A or AAAA record as a string.
+func addressOf(r dns.RR) string {
+	switch x := r.(type) {
+	case *dns.A:
+		return x.A.String()
+	case *dns.AAAA:
+		return x.AAAA.String()
+	}
+	return ""
+}
+
+const (
+	// defaultWeight is the weight of an address that doesn't have one.
+	defaultWeight = 1
+	// txtMaxNames is the maximum number of names for which TXT side records are cached.
+	txtMaxNames = 10000
+)
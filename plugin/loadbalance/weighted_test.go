@@ -0,0 +1,132 @@
+package loadbalance
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+func TestParseWeights(t *testing.T) {
+	const file = `
+# comment
+www.example.org  10.0.0.1 3
+WWW.example.org. 10.0.0.2 1 # trailing comment
+www.example.org. 2001:db8::1 0
+`
+	m, err := parseWeights(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if x := m["www.example.org."]["10.0.0.1"]; x != 3 {
+		t.Errorf("Expected weight 3, got %d", x)
+	}
+	if x := m["www.example.org."]["10.0.0.2"]; x != 1 {
+		t.Errorf("Expected weight 1, got %d", x)
+	}
+	if _, ok := m["www.example.org."]["2001:db8::1"]; !ok {
+		t.Errorf("Expected weight for 2001:db8::1")
+	}
+
+	for _, bad := range []string{"www.example.org. 10.0.0.1", "www.example.org. a.b.c.d 1", "www.example.org. 10.0.0.1 -1"} {
+		if _, err := parseWeights(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q, got none", bad)
+		}
+	}
+}
+
+func TestWeightedShuffle(t *testing.T) {
+	rrs := []dns.RR{
+		test.A("www.example.org. 300 IN A 10.0.0.1"),
+		test.A("www.example.org. 300 IN A 10.0.0.2"),
+		test.A("www.example.org. 300 IN A 10.0.0.3"),
+	}
+	w := map[string]uint32{"10.0.0.1": 9, "10.0.0.2": 1, "10.0.0.3": 0}
+	weight := func(rr dns.RR) uint32 { return w[addressOf(rr)] }
+
+	first := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		out := weightedShuffle(rrs, weight)
+		if len(out) != 2 {
+			t.Fatalf("Expected zero weight record to be removed, got %d records", len(out))
+		}
+		first[addressOf(out[0])]++
+	}
+	// 10.0.0.1 should come first about 90% of the time.
+	if first["10.0.0.1"] < 800 {
+		t.Errorf("Expected 10.0.0.1 to be picked first most of the time, got %d out of 1000", first["10.0.0.1"])
+	}
+}
+
+func TestLoadBalanceTXTWeights(t *testing.T) {
+	lb := &LoadBalance{policy: weighted, txt: newTXTWeights(defaultReload), max: 1, Next: weightHandler()}
+
+	for i := 0; i < 10; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("www.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		lb.ServeDNS(context.TODO(), rec, req)
+
+		if len(rec.Msg.Answer) != 2 {
+			t.Fatalf("Expected CNAME and 1 address record, got %d records", len(rec.Msg.Answer))
+		}
+		if rec.Msg.Answer[0].Header().Rrtype != dns.TypeCNAME {
+			t.Errorf("Expected CNAME to come first")
+		}
+		// 10.0.0.1 has a zero weight, 10.0.0.2 should always be returned.
+		if a := addressOf(rec.Msg.Answer[1]); a != "10.0.0.2" {
+			t.Errorf("Expected 10.0.0.2, got %s", a)
+		}
+	}
+}
+
+func weightHandler() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Qtype {
+		case dns.TypeTXT:
+			m.Answer = []dns.RR{test.TXT(`_weight.web.example.org. 300 IN TXT "10.0.0.1 0" "10.0.0.2 5"`)}
+		default:
+			m.Answer = []dns.RR{
+				test.A("web.example.org. 300 IN A 10.0.0.1"),
+				test.CNAME("www.example.org. 300 IN CNAME web.example.org."),
+				test.A("web.example.org. 300 IN A 10.0.0.2"),
+			}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestTXTWeightsCached(t *testing.T) {
+	txt := newTXTWeights(time.Minute)
+	lookups := 0
+	lookup := func(name string) map[string]uint32 {
+		lookups++
+		return map[string]uint32{"10.0.0.1": 3}
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := txt.weights("web.example.org.", lookup); w["10.0.0.1"] != 3 {
+			t.Errorf("Expected weight 3 for 10.0.0.1, got %d", w["10.0.0.1"])
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected 1 lookup, got %d", lookups)
+	}
+
+	txt.ttl = 0
+	txt.m = map[string]txtEntry{}
+	txt.weights("web.example.org.", lookup)
+	txt.prune()
+	if len(txt.m) != 0 {
+		t.Errorf("Expected expired names to be pruned, got %d", len(txt.m))
+	}
+}
@@ -0,0 +1,236 @@
+// Package multicluster implements a plugin that serves the services of several Kubernetes clusters
+// from a single clusterset zone.
+package multicluster
+
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/kubernetes"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// MultiCluster watches several Kubernetes clusters. Names in the clusterset zone are answered with the
+// records of all clusters combined, names in the zone of a single cluster, i.e. <cluster>.<zone>, with
+// the records of that cluster only.
+type MultiCluster struct {
+	Next     plugin.Handler
+	Zones    []string
+	Upstream upstream.Upstream
+	Fall     fall.F
+
+	clusters []*cluster
+	ttl      uint32 // if not zero, overrides the TTL of the clusters
+}
+
+// cluster is a single Kubernetes cluster, with its own connection to the API.
+type cluster struct {
+	name string
+	k    *kubernetes.Kubernetes
+}
+
+// New returns a new, empty, MultiCluster.
+func New(zones []string) *MultiCluster { return &MultiCluster{Zones: zones} }
+
+// ServeDNS implements the plugin.Handler interface.
+func (m *MultiCluster) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	opt := plugin.Options{}
+	state := request.Request{W: w, Req: r, Context: ctx}
+
+	zone := plugin.Zones(m.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
+	}
+	state.Zone = zone
+
+	var (
+		records []dns.RR
+		extra   []dns.RR
+		err     error
+	)
+
+	switch state.QType() {
+	case dns.TypeA:
+		records, err = plugin.A(m, zone, state, nil, opt)
+	case dns.TypeAAAA:
+		records, err = plugin.AAAA(m, zone, state, nil, opt)
+	case dns.TypeTXT:
+		records, err = plugin.TXT(m, zone, state, opt)
+	case dns.TypeCNAME:
+		records, err = plugin.CNAME(m, zone, state, opt)
+	case dns.TypePTR:
+		records, err = plugin.PTR(m, zone, state, opt)
+	case dns.TypeSRV:
+		records, extra, err = plugin.SRV(m, zone, state, opt)
+	case dns.TypeSOA:
+		records, err = plugin.SOA(m, zone, state, opt)
+	case dns.TypeNS:
+		if state.Name() == zone {
+			records, extra, err = plugin.NS(m, zone, state, opt)
+			break
+		}
+		fallthrough
+	default:
+		// Do a fake A lookup, so we can distinguish between NODATA and NXDOMAIN
+		_, err = plugin.A(m, zone, state, nil, opt)
+	}
+
+	if m.IsNameError(err) {
+		if m.Fall.Through(state.Name()) {
+			return plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
+		}
+		return plugin.BackendError(m, zone, dns.RcodeNameError, state, nil /* err */, opt)
+	}
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	if len(records) == 0 {
+		return plugin.BackendError(m, zone, dns.RcodeSuccess, state, nil, opt)
+	}
+
+	a := new(dns.Msg)
+	a.SetReply(r)
+	a.Authoritative, a.RecursionAvailable, a.Compress = true, true, true
+	a.Answer = append(a.Answer, records...)
+	a.Extra = append(a.Extra, extra...)
+
+	a = dnsutil.Dedup(a)
+
+	state.SizeAndDo(a)
+	a, _ = state.Scrub(a)
+	w.WriteMsg(a)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (m *MultiCluster) Name() string { return "multicluster" }
+
+// Services implements the plugin.ServiceBackend interface.
+func (m *MultiCluster) Services(state request.Request, exact bool, opt plugin.Options) ([]msg.Service, error) {
+	clusters, state := m.route(state)
+	switch state.QType() {
+	case dns.TypeTXT, dns.TypeNS:
+		// These are about the zone, not about services, the first cluster can answer them.
+		clusters = clusters[:1]
+	}
+	return m.merge(clusters, func(k *kubernetes.Kubernetes) ([]msg.Service, error) {
+		return k.Services(state, exact, opt)
+	})
+}
+
+// Reverse implements the plugin.ServiceBackend interface.
+func (m *MultiCluster) Reverse(state request.Request, exact bool, opt plugin.Options) ([]msg.Service, error) {
+	return m.merge(m.clusters, func(k *kubernetes.Kubernetes) ([]msg.Service, error) {
+		return k.Reverse(state, exact, opt)
+	})
+}
+
+// Records implements the plugin.ServiceBackend interface.
+func (m *MultiCluster) Records(state request.Request, exact bool) ([]msg.Service, error) {
+	clusters, state := m.route(state)
+	return m.merge(clusters, func(k *kubernetes.Kubernetes) ([]msg.Service, error) {
+		return k.Records(state, exact)
+	})
+}
+
+// Lookup implements the plugin.ServiceBackend interface.
+func (m *MultiCluster) Lookup(state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	return m.Upstream.Lookup(state, name, typ)
+}
+
+// IsNameError implements the plugin.ServiceBackend interface.
+func (m *MultiCluster) IsNameError(err error) bool { return m.clusters[0].k.IsNameError(err) }
+
+// Serial implements the plugin.Transferer interface. It returns the serial of the cluster that
+// changed last.
+func (m *MultiCluster) Serial(state request.Request) uint32 {
+	serial := uint32(0)
+	for _, c := range m.clusters {
+		if s := c.k.Serial(state); s > serial {
+			serial = s
+		}
+	}
+	return serial
+}
+
+// MinTTL implements the plugin.Transferer interface.
+func (m *MultiCluster) MinTTL(state request.Request) uint32 { return 30 }
+
+// Transfer implements the plugin.Transferer interface. Zone transfers are not supported.
+func (m *MultiCluster) Transfer(ctx context.Context, state request.Request) (int, error) {
+	return dns.RcodeRefused, nil
+}
+
+// Health implements the health.Healther interface. We are healthy when all clusters are.
+func (m *MultiCluster) Health() bool {
+	for _, c := range m.clusters {
+		if !c.k.Health() {
+			return false
+		}
+	}
+	return true
+}
+
+// route returns the clusters that should answer the query in state, and the state to give them. A
+// name in the zone of a cluster, i.e. svc.ns.svc.<cluster>.<zone>, is only answered by that cluster.
+func (m *MultiCluster) route(state request.Request) ([]*cluster, request.Request) {
+	base, err := dnsutil.TrimZone(state.Name(), state.Zone)
+	if err != nil {
+		return m.clusters, state
+	}
+	labels := dns.SplitDomainName(base)
+	if len(labels) == 0 {
+		return m.clusters, state
+	}
+	last := labels[len(labels)-1]
+	for _, c := range m.clusters {
+		if c.name == last {
+			state.Zone = dnsutil.Join([]string{c.name, state.Zone})
+			return []*cluster{c}, state
+		}
+	}
+	return m.clusters, state
+}
+
+// merge calls f for each of the clusters and returns all services found. An error is only returned
+// when none of the clusters has the name, a name error is returned only if all clusters returned one.
+func (m *MultiCluster) merge(clusters []*cluster, f func(*kubernetes.Kubernetes) ([]msg.Service, error)) ([]msg.Service, error) {
+	var (
+		services []msg.Service
+		nameErr  error
+		otherErr error
+		found    bool
+	)
+	for _, c := range clusters {
+		s, err := f(c.k)
+		if err != nil {
+			if c.k.IsNameError(err) {
+				nameErr = err
+			} else if otherErr == nil {
+				otherErr = err
+			}
+			continue
+		}
+		found = true
+		services = append(services, s...)
+	}
+	if !found {
+		if otherErr != nil {
+			return nil, otherErr
+		}
+		return nil, nameErr
+	}
+
+	if m.ttl > 0 {
+		for i := range services {
+			services[i].TTL = m.ttl
+		}
+	}
+	return services, nil
+}
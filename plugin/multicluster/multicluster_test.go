@@ -0,0 +1,184 @@
+package multicluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMultiCluster(t *testing.T) {
+	m := New([]string{"clusterset.local."})
+	m.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	m.clusters = []*cluster{
+		newTestCluster("east", east),
+		newTestCluster("west", west),
+	}
+
+	ctx := context.TODO()
+	for i, tc := range multiClusterTestCases {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := m.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			continue
+		}
+		if w.Msg == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		test.SortAndCheck(t, w.Msg, tc)
+	}
+}
+
+var multiClusterTestCases = []test.Case{
+	// Combined view.
+	{
+		Qname: "svc1.testns.svc.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svc1.testns.svc.clusterset.local.	5	IN	A	10.0.0.1"),
+			test.A("svc1.testns.svc.clusterset.local.	5	IN	A	10.1.0.1"),
+		},
+	},
+	{
+		Qname: "hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.1"),
+			test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.1.0.1"),
+		},
+	},
+	{
+		Qname: "svc2.testns.svc.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svc2.testns.svc.clusterset.local.	5	IN	A	10.1.0.2"),
+		},
+	},
+	{
+		Qname: "svc2.testns.svc.clusterset.local.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	300	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 30"),
+		},
+	},
+	{
+		Qname: "svc3.testns.svc.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	300	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 30"),
+		},
+	},
+	// A single cluster.
+	{
+		Qname: "svc1.testns.svc.east.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svc1.testns.svc.east.clusterset.local.	5	IN	A	10.0.0.1"),
+		},
+	},
+	{
+		Qname: "hdls1.testns.svc.west.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("hdls1.testns.svc.west.clusterset.local.	5	IN	A	172.1.0.1"),
+		},
+	},
+	{
+		Qname: "svc2.testns.svc.east.clusterset.local.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	300	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 30"),
+		},
+	},
+	// Apex.
+	{
+		Qname: "dns-version.clusterset.local.", Qtype: dns.TypeTXT, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.TXT(`dns-version.clusterset.local.	28800	IN	TXT	"1.0.1"`),
+		},
+	},
+}
+
+func newTestCluster(name string, a *apiConn) *cluster {
+	k := kubernetes.New([]string{name + ".clusterset.local."})
+	k.APIConn = a
+	return &cluster{name: name, k: k}
+}
+
+type apiConn struct {
+	svcs map[string][]*object.Service
+	eps  map[string][]*object.Endpoints
+}
+
+var (
+	ports   = []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}}
+	epPorts = []object.EndpointPort{{Name: "http", Protocol: "tcp", Port: 80}}
+)
+
+var east = &apiConn{
+	svcs: map[string][]*object.Service{
+		"svc1.testns":  {{Name: "svc1", Namespace: "testns", Type: api.ServiceTypeClusterIP, ClusterIP: "10.0.0.1", Ports: ports}},
+		"hdls1.testns": {{Name: "hdls1", Namespace: "testns", Type: api.ServiceTypeClusterIP, ClusterIP: api.ClusterIPNone, Ports: ports}},
+	},
+	eps: map[string][]*object.Endpoints{
+		"hdls1.testns": {{
+			Name: "hdls1", Namespace: "testns",
+			Subsets: []object.EndpointSubset{{Addresses: []object.EndpointAddress{{IP: "172.0.0.1", Hostname: "ep1"}}, Ports: epPorts}},
+		}},
+	},
+}
+
+var west = &apiConn{
+	svcs: map[string][]*object.Service{
+		"svc1.testns":  {{Name: "svc1", Namespace: "testns", Type: api.ServiceTypeClusterIP, ClusterIP: "10.1.0.1", Ports: ports}},
+		"svc2.testns":  {{Name: "svc2", Namespace: "testns", Type: api.ServiceTypeClusterIP, ClusterIP: "10.1.0.2", Ports: ports}},
+		"hdls1.testns": {{Name: "hdls1", Namespace: "testns", Type: api.ServiceTypeClusterIP, ClusterIP: api.ClusterIPNone, Ports: ports}},
+	},
+	eps: map[string][]*object.Endpoints{
+		"hdls1.testns": {{
+			Name: "hdls1", Namespace: "testns",
+			Subsets: []object.EndpointSubset{{Addresses: []object.EndpointAddress{{IP: "172.1.0.1", Hostname: "ep1"}}, Ports: epPorts}},
+		}},
+	},
+}
+
+func (a *apiConn) HasSynced() bool                           { return true }
+func (a *apiConn) Run()                                      { return }
+func (a *apiConn) Stop() error                               { return nil }
+func (a *apiConn) EpIndexReverse(string) []*object.Endpoints { return nil }
+func (a *apiConn) SvcIndexReverse(string) []*object.Service  { return nil }
+func (a *apiConn) Modified() int64                           { return 1499347823 }
+func (a *apiConn) IngressIndex(string) []*object.Ingress     { return nil }
+func (a *apiConn) PodIndex(string) []*object.Pod             { return nil }
+func (a *apiConn) SvcIndex(s string) []*object.Service       { return a.svcs[s] }
+func (a *apiConn) EpIndex(s string) []*object.Endpoints      { return a.eps[s] }
+
+func (a *apiConn) GetNodeByName(name string) (*object.Node, error) {
+	return nil, errors.New("node not found")
+}
+
+func (a *apiConn) GetNamespaceByName(name string) (*api.Namespace, error) {
+	if name != "testns" {
+		return nil, errors.New("namespace not found")
+	}
+	return &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: name}}, nil
+}
+
+func (a *apiConn) ServiceList() []*object.Service {
+	var svcs []*object.Service
+	for _, svc := range a.svcs {
+		svcs = append(svcs, svc...)
+	}
+	return svcs
+}
+
+func (a *apiConn) EndpointsList() []*object.Endpoints {
+	var eps []*object.Endpoints
+	for _, ep := range a.eps {
+		eps = append(eps, ep...)
+	}
+	return eps
+}
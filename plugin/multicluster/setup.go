@@ -0,0 +1,202 @@
+package multicluster
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/kubernetes"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("multicluster", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	m, err := multiclusterParse(c)
+	if err != nil {
+		return plugin.Error("multicluster", err)
+	}
+
+	for _, cl := range m.clusters {
+		if err := cl.k.InitKubeCache(); err != nil {
+			return plugin.Error("multicluster", fmt.Errorf("cluster %q: %s", cl.name, err))
+		}
+		cl.k.RegisterKubeCache(c)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		m.Next = next
+		return m
+	})
+
+	return nil
+}
+
+func multiclusterParse(c *caddy.Controller) (*MultiCluster, error) {
+	var (
+		m          *MultiCluster
+		namespaces []string
+	)
+	tls := map[string][]string{}
+	kubeconfig := map[string][]string{}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		zones := make([]string, len(c.ServerBlockKeys))
+		copy(zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			zones = args
+		}
+		for j := range zones {
+			zones[j] = plugin.Host(zones[j]).Normalize()
+		}
+		m = New(zones)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "cluster":
+				args := c.RemainingArgs()
+				if len(args) < 1 {
+					return nil, c.ArgErr()
+				}
+				name := args[0]
+				if !clusterName.MatchString(name) || reserved[name] {
+					return nil, fmt.Errorf("invalid cluster name: %q", name)
+				}
+				for _, cl := range m.clusters {
+					if cl.name == name {
+						return nil, fmt.Errorf("duplicate cluster: %q", name)
+					}
+				}
+				k := kubernetes.New(nil)
+				k.APIServerList = args[1:]
+				m.clusters = append(m.clusters, &cluster{name: name, k: k})
+
+			case "tls": // name cert key cacertfile
+				args := c.RemainingArgs()
+				if len(args) != 4 {
+					return nil, c.ArgErr()
+				}
+				tls[args[0]] = args[1:]
+
+			case "kubeconfig": // name path [context]
+				args := c.RemainingArgs()
+				if len(args) != 2 && len(args) != 3 {
+					return nil, c.ArgErr()
+				}
+				kubeconfig[args[0]] = args[1:]
+
+			case "namespaces":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				namespaces = append(namespaces, args...)
+
+			case "upstream":
+				u, err := upstream.NewUpstream(c.RemainingArgs())
+				if err != nil {
+					return nil, err
+				}
+				m.Upstream = u
+
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				t, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if t < 5 || t > 3600 {
+					return nil, c.Errf("ttl must be in range [5, 3600]: %d", t)
+				}
+				m.ttl = uint32(t)
+
+			case "fallthrough":
+				m.Fall.SetZonesFromArgs(c.RemainingArgs())
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(m.clusters) == 0 {
+		return nil, fmt.Errorf("no clusters defined")
+	}
+
+	// The zone of each cluster is a sub zone of the first non-reverse zone.
+	primary := ""
+	for _, z := range m.Zones {
+		if dnsutil.IsReverse(z) == 0 {
+			primary = z
+			break
+		}
+	}
+	if primary == "" {
+		return nil, fmt.Errorf("non-reverse zone name must be used")
+	}
+
+	incluster := 0
+	for _, cl := range m.clusters {
+		if kc, ok := kubeconfig[cl.name]; ok {
+			if len(cl.k.APIServerList) > 0 {
+				return nil, fmt.Errorf("kubeconfig for cluster %q, which has endpoints", cl.name)
+			}
+			cl.k.KubeConfigFile = kc[0]
+			if len(kc) == 2 {
+				cl.k.KubeContext = kc[1]
+			}
+			delete(kubeconfig, cl.name)
+		}
+		if len(cl.k.APIServerList) == 0 && cl.k.KubeConfigFile == "" {
+			incluster++
+		}
+		cl.k.Zones = []string{dnsutil.Join([]string{cl.name, primary})}
+		for _, ns := range namespaces {
+			cl.k.Namespaces[ns] = true
+		}
+		if t, ok := tls[cl.name]; ok {
+			if len(cl.k.APIServerList) == 0 {
+				return nil, fmt.Errorf("tls for cluster %q, which has no endpoints", cl.name)
+			}
+			cl.k.APIClientCert, cl.k.APIClientKey, cl.k.APICertAuth = t[0], t[1], t[2]
+			delete(tls, cl.name)
+		}
+	}
+	for name := range tls {
+		return nil, fmt.Errorf("tls for unknown cluster %q", name)
+	}
+	for name := range kubeconfig {
+		return nil, fmt.Errorf("kubeconfig for unknown cluster %q", name)
+	}
+	if incluster > 1 {
+		return nil, fmt.Errorf("only one cluster can connect in-cluster")
+	}
+
+	return m, nil
+}
+
+// clusterName is the syntax of a cluster name, it's used as a label in the cluster's zone.
+var clusterName = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// reserved are the cluster names that can't be used, as they clash with the names in the
+// clusterset zone.
+var reserved = map[string]bool{kubernetes.Svc: true, kubernetes.Pod: true, "dns": true, "dns-version": true}
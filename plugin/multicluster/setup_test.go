@@ -0,0 +1,127 @@
+package multicluster
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input            string
+		shouldErr        bool
+		expectedClusters []string
+		expectedZones    []string
+		expectedTTL      uint32
+	}{
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			cluster west https://10.1.0.1:443
+		}`, false, []string{"east", "west"}, []string{"east.clusterset.local.", "west.clusterset.local."}, 0},
+		{`multicluster clusterset.local {
+			cluster local
+			cluster west https://10.1.0.1:443
+			tls west cert key cacert
+			namespaces default
+			ttl 30
+		}`, false, []string{"local", "west"}, []string{"local.clusterset.local.", "west.clusterset.local."}, 30},
+		{`multicluster 10.in-addr.arpa clusterset.local {
+			cluster east https://10.0.0.1:443
+		}`, false, []string{"east"}, []string{"east.clusterset.local."}, 0},
+		{`multicluster clusterset.local {
+			cluster local
+			cluster east
+			cluster west
+			kubeconfig east /etc/coredns/kubeconfig east-admin
+			kubeconfig west /etc/coredns/kubeconfig
+		}`, false, []string{"local", "east", "west"}, []string{"local.clusterset.local.", "east.clusterset.local.", "west.clusterset.local."}, 0},
+		// No clusters.
+		{`multicluster clusterset.local`, true, nil, nil, 0},
+		// Only reverse zones.
+		{`multicluster 10.in-addr.arpa {
+			cluster east https://10.0.0.1:443
+		}`, true, nil, nil, 0},
+		// Invalid cluster names.
+		{`multicluster clusterset.local {
+			cluster svc https://10.0.0.1:443
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster East https://10.0.0.1:443
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			cluster east https://10.1.0.1:443
+		}`, true, nil, nil, 0},
+		// Two in-cluster clusters.
+		{`multicluster clusterset.local {
+			cluster east
+			cluster west
+		}`, true, nil, nil, 0},
+		// TLS for an unknown or in-cluster cluster.
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			tls west cert key cacert
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster east
+			tls east cert key cacert
+		}`, true, nil, nil, 0},
+		// Kubeconfig for an unknown cluster or one with endpoints.
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			kubeconfig west /etc/coredns/kubeconfig
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			kubeconfig east /etc/coredns/kubeconfig
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			ttl 1
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+			blaat
+		}`, true, nil, nil, 0},
+		{`multicluster clusterset.local {
+			cluster east https://10.0.0.1:443
+		}
+		multicluster clusterset.local {
+			cluster west https://10.1.0.1:443
+		}`, true, nil, nil, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		m, err := multiclusterParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found %s for input %s", i, err, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if len(m.clusters) != len(test.expectedClusters) {
+			t.Errorf("Test %d, expected %d clusters for input %s, got: %d", i, len(test.expectedClusters), test.input, len(m.clusters))
+			continue
+		}
+		for j, cl := range m.clusters {
+			if cl.name != test.expectedClusters[j] {
+				t.Errorf("Test %d, expected cluster %q for input %s, got: %q", i, test.expectedClusters[j], test.input, cl.name)
+			}
+			if cl.k.Zones[0] != test.expectedZones[j] {
+				t.Errorf("Test %d, expected zone %q for input %s, got: %q", i, test.expectedZones[j], test.input, cl.k.Zones[0])
+			}
+		}
+		if m.ttl != test.expectedTTL {
+			t.Errorf("Test %d, expected ttl %d for input %s, got: %d", i, test.expectedTTL, test.input, m.ttl)
+		}
+	}
+}
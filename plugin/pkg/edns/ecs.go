@@ -0,0 +1,126 @@
+package edns
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Subnet returns the EDNS0 client subnet (ECS) option from m's OPT record, or nil if m
+// doesn't carry one.
+func Subnet(m *dns.Msg) *dns.EDNS0_SUBNET {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, s := range o.Option {
+		if e, ok := s.(*dns.EDNS0_SUBNET); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// NewSubnet returns an ECS option for ip, where the address is truncated to v4 or v6 bits depending
+// on the family of ip. The scope prefix length is set to zero, as is required for queries.
+func NewSubnet(ip net.IP, v4, v6 uint8) *dns.EDNS0_SUBNET {
+	e := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET}
+	if ip4 := ip.To4(); ip4 != nil {
+		e.Family = 1
+		e.SourceNetmask = v4
+		e.Address = ip4.Mask(net.CIDRMask(int(v4), 32))
+		return e
+	}
+	e.Family = 2
+	e.SourceNetmask = v6
+	e.Address = ip.To16().Mask(net.CIDRMask(int(v6), 128))
+	return e
+}
+
+// TruncateSubnet lowers the source prefix length of e to v4 or v6 bits, when e's source prefix length
+// is larger. The address is masked accordingly. An option with an unknown family is left alone.
+func TruncateSubnet(e *dns.EDNS0_SUBNET, v4, v6 uint8) {
+	switch e.Family {
+	case 1:
+		if e.SourceNetmask > v4 {
+			e.SourceNetmask = v4
+		}
+		e.Address = e.Address.Mask(net.CIDRMask(int(e.SourceNetmask), 32))
+	case 2:
+		if e.SourceNetmask > v6 {
+			e.SourceNetmask = v6
+		}
+		e.Address = e.Address.Mask(net.CIDRMask(int(e.SourceNetmask), 128))
+	}
+}
+
+// SetSubnet adds e to m, replacing any existing ECS option. If m doesn't have an OPT record, one
+// is added.
+func SetSubnet(m *dns.Msg, e *dns.EDNS0_SUBNET) {
+	o := m.IsEdns0()
+	if o == nil {
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		o = m.IsEdns0()
+	}
+	for i, s := range o.Option {
+		if _, ok := s.(*dns.EDNS0_SUBNET); ok {
+			o.Option[i] = e
+			return
+		}
+	}
+	o.Option = append(o.Option, e)
+}
+
+// RemoveSubnet removes the ECS option from m. It returns true if an option was removed.
+func RemoveSubnet(m *dns.Msg) bool {
+	o := m.IsEdns0()
+	if o == nil {
+		return false
+	}
+	j := 0
+	for _, s := range o.Option {
+		if _, ok := s.(*dns.EDNS0_SUBNET); ok {
+			continue
+		}
+		o.Option[j] = s
+		j++
+	}
+	removed := j < len(o.Option)
+	o.Option = o.Option[:j]
+	return removed
+}
+
+type subnetKey struct{}
+
+// WithReplySubnet returns a context that carries e. A plugin that removes the ECS option from a reply,
+// because the client didn't send one, first copies it to e with SaveReplySubnet. This lets a plugin
+// earlier in the chain, such as cache, learn the scope the reply is valid for.
+func WithReplySubnet(ctx context.Context, e *dns.EDNS0_SUBNET) context.Context {
+	return context.WithValue(ctx, subnetKey{}, e)
+}
+
+// SaveReplySubnet copies the ECS option in m to the option carried by ctx. It does nothing when
+// m has no ECS option or ctx doesn't carry one.
+func SaveReplySubnet(ctx context.Context, m *dns.Msg) {
+	e, ok := ctx.Value(subnetKey{}).(*dns.EDNS0_SUBNET)
+	if !ok {
+		return
+	}
+	if s := Subnet(m); s != nil {
+		*e = *s
+	}
+}
+
+// RemoveOPT removes the OPT record from m.
+func RemoveOPT(m *dns.Msg) {
+	j := 0
+	for _, rr := range m.Extra {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+		m.Extra[j] = rr
+		j++
+	}
+	m.Extra = m.Extra[:j]
+}
@@ -0,0 +1,82 @@
+package edns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNewSubnet(t *testing.T) {
+	tests := []struct {
+		ip      string
+		family  uint16
+		netmask uint8
+		address string
+	}{
+		{"10.1.2.3", 1, 24, "10.1.2.0"},
+		{"2001:db8:1:2::1", 2, 56, "2001:db8:1::"},
+	}
+
+	for i, tc := range tests {
+		e := NewSubnet(net.ParseIP(tc.ip), 24, 56)
+		if e.Family != tc.family {
+			t.Errorf("Test %d: expected family %d, got %d", i, tc.family, e.Family)
+		}
+		if e.SourceNetmask != tc.netmask {
+			t.Errorf("Test %d: expected source netmask %d, got %d", i, tc.netmask, e.SourceNetmask)
+		}
+		if !e.Address.Equal(net.ParseIP(tc.address)) {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.address, e.Address)
+		}
+	}
+}
+
+func TestTruncateSubnet(t *testing.T) {
+	e := NewSubnet(net.ParseIP("10.1.2.3"), 32, 128)
+	TruncateSubnet(e, 16, 48)
+	if e.SourceNetmask != 16 {
+		t.Errorf("expected source netmask %d, got %d", 16, e.SourceNetmask)
+	}
+	if !e.Address.Equal(net.ParseIP("10.1.0.0")) {
+		t.Errorf("expected address %s, got %s", "10.1.0.0", e.Address)
+	}
+
+	// Smaller prefixes are left alone.
+	TruncateSubnet(e, 24, 48)
+	if e.SourceNetmask != 16 {
+		t.Errorf("expected source netmask %d, got %d", 16, e.SourceNetmask)
+	}
+}
+
+func TestSetRemoveSubnet(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+
+	if Subnet(m) != nil {
+		t.Fatal("expected no subnet option")
+	}
+	if RemoveSubnet(m) {
+		t.Error("expected nothing to be removed")
+	}
+
+	SetSubnet(m, NewSubnet(net.ParseIP("10.1.2.3"), 24, 56))
+	SetSubnet(m, NewSubnet(net.ParseIP("10.4.5.6"), 24, 56))
+	if l := len(m.IsEdns0().Option); l != 1 {
+		t.Fatalf("expected 1 option, got %d", l)
+	}
+	e := Subnet(m)
+	if e == nil {
+		t.Fatal("expected subnet option")
+	}
+	if !e.Address.Equal(net.ParseIP("10.4.5.0")) {
+		t.Errorf("expected address %s, got %s", "10.4.5.0", e.Address)
+	}
+
+	if !RemoveSubnet(m) {
+		t.Error("expected subnet option to be removed")
+	}
+	if Subnet(m) != nil {
+		t.Error("expected no subnet option")
+	}
+}
@@ -0,0 +1,48 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// root holds the DS records of the key signing keys of the root zone, as published by IANA.
+var root = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// RootAnchors returns the trust anchors of the root zone.
+func RootAnchors() []dns.RR {
+	rrs := make([]dns.RR, len(root))
+	for i, s := range root {
+		rrs[i], _ = dns.NewRR(s)
+	}
+	return rrs
+}
+
+// ParseAnchors parses the trust anchors in r, which holds DS and DNSKEY records in zone file format. File
+// is only used in error messages.
+func ParseAnchors(r io.Reader, file string) ([]dns.RR, error) {
+	var rrs []dns.RR
+	for x := range dns.ParseZone(r, ".", file) {
+		if x.Error != nil {
+			return nil, x.Error
+		}
+		switch x.RR.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			rrs = append(rrs, x.RR)
+		default:
+			return nil, fmt.Errorf("trust anchor must be a DS or DNSKEY record: %s", x.RR)
+		}
+	}
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("no trust anchors in %s", file)
+	}
+	return rrs, nil
+}
+
+// ParseAnchor parses a single trust anchor in s.
+func ParseAnchor(s string) ([]dns.RR, error) { return ParseAnchors(strings.NewReader(s), "anchor") }
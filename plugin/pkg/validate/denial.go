@@ -0,0 +1,355 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// denial validates the proof in the authority section of m that name doesn't exist (NXDOMAIN), or has no
+// records of type qtype (NODATA).
+func (v *Validator) denial(ctx context.Context, m *dns.Msg, name string, qtype uint16) (Result, error) {
+	sets := rrsets(m.Ns)
+	proof := false
+	for _, set := range sets {
+		switch set.qtype {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		if !dns.IsSubDomain(set.name, name) && set.qtype == dns.TypeSOA {
+			continue
+		}
+		res, err := v.rrset(ctx, set, nil, nil)
+		if res != Secure {
+			return res, err
+		}
+		proof = proof || set.qtype != dns.TypeSOA
+	}
+	if !proof {
+		if res, err := v.unsigned(ctx, name); res != Bogus {
+			return res, err
+		}
+		return Bogus, fmt.Errorf("no proof of non-existence for %s %s", name, dns.TypeToString[qtype])
+	}
+
+	nsec, nsec3 := records(m.Ns)
+	for _, n := range nsec3 {
+		if n.Iterations > maxIterations {
+			// RFC 9276, section 3.2: too expensive to check, treat the zone as unsigned.
+			return Insecure, nil
+		}
+	}
+
+	ok, optout := false, false
+	if m.Rcode == dns.RcodeNameError {
+		ok = nxdomainNSEC(nsec, name)
+		if !ok {
+			ok, optout = nxdomainNSEC3(nsec3, name)
+		}
+	} else {
+		ok = nodataNSEC(nsec, name, qtype)
+		if !ok {
+			ok, optout = nodataNSEC3(nsec3, name, qtype)
+		}
+	}
+	if !ok {
+		return Bogus, fmt.Errorf("no proof of non-existence for %s %s", name, dns.TypeToString[qtype])
+	}
+	if optout {
+		// The name may be an unsigned delegation, skipped by the NSEC3 chain.
+		return Insecure, nil
+	}
+	return Secure, nil
+}
+
+// wildcard validates the proof in auth that name, which has records expanded from a wildcard with labels
+// labels, doesn't exist itself.
+func (v *Validator) wildcard(ctx context.Context, auth []dns.RR, name string, labels int) (Result, error) {
+	for _, set := range rrsets(auth) {
+		if set.qtype != dns.TypeNSEC && set.qtype != dns.TypeNSEC3 {
+			continue
+		}
+		if res, err := v.rrset(ctx, set, nil, nil); res != Secure {
+			return res, err
+		}
+	}
+
+	nsec, nsec3 := records(auth)
+	for _, n := range nsec {
+		if covers(n, name) {
+			return Secure, nil
+		}
+	}
+	closer := ancestor(name, labels+1)
+	for _, n := range nsec3 {
+		if cover(n, closer) {
+			if n.Flags&optOut != 0 {
+				return Insecure, nil
+			}
+			return Secure, nil
+		}
+	}
+	return Bogus, fmt.Errorf("no proof that %s is expanded from a wildcard", name)
+}
+
+// delegation returns true if the NSEC or NSEC3 records in auth show that name is a delegation, or may be
+// one, in case of an opt-out NSEC3.
+func delegation(auth []dns.RR, name string) bool {
+	nsec, nsec3 := records(auth)
+	for _, n := range nsec {
+		if equal(n.Hdr.Name, name) {
+			return hasType(n.TypeBitMap, dns.TypeNS) && !hasType(n.TypeBitMap, dns.TypeSOA)
+		}
+	}
+	for _, n := range nsec3 {
+		if n.Match(name) {
+			return hasType(n.TypeBitMap, dns.TypeNS) && !hasType(n.TypeBitMap, dns.TypeSOA)
+		}
+	}
+	if _, _, optout, ok := closestEncloser(nsec3, name); ok && optout {
+		return true
+	}
+	return false
+}
+
+// nodataNSEC returns true if nsec proves that name has no records of type qtype.
+func nodataNSEC(nsec []*dns.NSEC, name string, qtype uint16) bool {
+	for _, n := range nsec {
+		if equal(n.Hdr.Name, name) {
+			if nodata(n.TypeBitMap, qtype) {
+				return true
+			}
+			continue
+		}
+		// An empty non-terminal: name has no records, but names below it exist.
+		if covers(n, name) && dns.IsSubDomain(name, n.NextDomain) {
+			return true
+		}
+	}
+
+	// The name is expanded from a wildcard, that doesn't have qtype.
+	ce := closestEncloserNSEC(nsec, name)
+	if ce == "" {
+		return false
+	}
+	wildcard := "*." + ce
+	if ce == "." {
+		wildcard = "*."
+	}
+	for _, n := range nsec {
+		if equal(n.Hdr.Name, wildcard) && nodata(n.TypeBitMap, qtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// nxdomainNSEC returns true if nsec proves that name doesn't exist: there is no such name and no
+// wildcard that could have been expanded.
+func nxdomainNSEC(nsec []*dns.NSEC, name string) bool {
+	ce := closestEncloserNSEC(nsec, name)
+	if ce == "" {
+		return false
+	}
+	wildcard := "*." + ce
+	if ce == "." {
+		wildcard = "*."
+	}
+	for _, n := range nsec {
+		if covers(n, wildcard) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestEncloserNSEC returns the closest encloser of name, if one of nsec proves name doesn't exist.
+func closestEncloserNSEC(nsec []*dns.NSEC, name string) string {
+	for _, n := range nsec {
+		if !covers(n, name) || dns.IsSubDomain(name, n.NextDomain) {
+			continue
+		}
+		l := dns.CompareDomainName(name, n.Hdr.Name)
+		if l1 := dns.CompareDomainName(name, n.NextDomain); l1 > l {
+			l = l1
+		}
+		return ancestor(name, l)
+	}
+	return ""
+}
+
+// nodataNSEC3 returns true if nsec3 proves that name has no records of type qtype. Optout is true if the
+// proof relies on an opt-out NSEC3 record.
+func nodataNSEC3(nsec3 []*dns.NSEC3, name string, qtype uint16) (ok, optout bool) {
+	for _, n := range nsec3 {
+		if n.Match(name) {
+			if nodata(n.TypeBitMap, qtype) {
+				return true, false
+			}
+			return false, false
+		}
+	}
+
+	ce, _, optout, ok := closestEncloser(nsec3, name)
+	if !ok {
+		return false, false
+	}
+	// RFC 5155, section 8.6: no DS for an unsigned delegation, skipped by an opt-out NSEC3.
+	if qtype == dns.TypeDS && optout {
+		return true, true
+	}
+	// Section 8.7: the name is expanded from a wildcard, that doesn't have qtype.
+	for _, n := range nsec3 {
+		if n.Match("*."+ce) && nodata(n.TypeBitMap, qtype) {
+			return true, optout
+		}
+	}
+	return false, false
+}
+
+// nxdomainNSEC3 returns true if nsec3 proves name doesn't exist. Optout is true if the proof relies on
+// an opt-out NSEC3 record.
+func nxdomainNSEC3(nsec3 []*dns.NSEC3, name string) (ok, optout bool) {
+	ce, _, optout, ok := closestEncloser(nsec3, name)
+	if !ok {
+		return false, false
+	}
+	for _, n := range nsec3 {
+		if cover(n, "*."+ce) {
+			return true, optout
+		}
+	}
+	return false, false
+}
+
+// closestEncloser returns the closest encloser of name and the next closer name, as proven by nsec3
+// (RFC 5155, section 8.3). Optout is true if the NSEC3 covering the next closer name has opt-out set.
+func closestEncloser(nsec3 []*dns.NSEC3, name string) (ce, closer string, optout, ok bool) {
+	if len(nsec3) == 0 {
+		return "", "", false, false
+	}
+	for closer = name; closer != "."; closer = ce {
+		ce = parent(closer)
+		for _, n := range nsec3 {
+			if !n.Match(ce) {
+				continue
+			}
+			for _, n1 := range nsec3 {
+				if cover(n1, closer) {
+					return ce, closer, n1.Flags&optOut != 0, true
+				}
+			}
+			return "", "", false, false
+		}
+	}
+	return "", "", false, false
+}
+
+// cover returns true if the hash of name sorts between the owner and next hash of n, NSEC3.Cover also
+// returns true for the owner itself.
+func cover(n *dns.NSEC3, name string) bool { return n.Cover(name) && !n.Match(name) }
+
+// nodata returns true if the type bitmap of an NSEC or NSEC3 record for a name proves there are no
+// records of type qtype.
+func nodata(bitmap []uint16, qtype uint16) bool {
+	if hasType(bitmap, qtype) || hasType(bitmap, dns.TypeCNAME) {
+		return false
+	}
+	// RFC 6840, section 4.1: the parent side of a delegation can only prove the absence of DS records.
+	if hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeSOA) && qtype != dns.TypeDS {
+		return false
+	}
+	return true
+}
+
+func hasType(bitmap []uint16, qtype uint16) bool {
+	for _, t := range bitmap {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if name sorts between the owner and next name of n, in canonical order.
+func covers(n *dns.NSEC, name string) bool {
+	owner, next := n.Hdr.Name, n.NextDomain
+	if compare(owner, name) >= 0 {
+		return false
+	}
+	// The last NSEC of a zone points back to the apex.
+	return compare(name, next) < 0 || compare(next, owner) <= 0
+}
+
+// compare compares a and b in canonical DNS name order (RFC 4034, section 6.1).
+func compare(a, b string) int {
+	la := dns.SplitDomainName(a)
+	lb := dns.SplitDomainName(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(strings.ToLower(unescape(la[i])), strings.ToLower(unescape(lb[j]))); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// unescape returns the octets of label l, which is in presentation format.
+func unescape(l string) string {
+	if !strings.Contains(l, `\`) {
+		return l
+	}
+	b := make([]byte, 0, len(l))
+	for i := 0; i < len(l); i++ {
+		if l[i] != '\\' || i+1 == len(l) {
+			b = append(b, l[i])
+			continue
+		}
+		if i+3 < len(l) && isDigit(l[i+1]) && isDigit(l[i+2]) && isDigit(l[i+3]) {
+			b = append(b, (l[i+1]-'0')*100+(l[i+2]-'0')*10+(l[i+3]-'0'))
+			i += 3
+			continue
+		}
+		b = append(b, l[i+1])
+		i++
+	}
+	return string(b)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// ancestor returns the name made of the last labels labels of name.
+func ancestor(name string, labels int) string {
+	idx := dns.Split(name)
+	if labels <= 0 || len(idx) == 0 {
+		return "."
+	}
+	if labels >= len(idx) {
+		return name
+	}
+	return name[idx[len(idx)-labels]:]
+}
+
+// records returns the NSEC and NSEC3 records in rrs.
+func records(rrs []dns.RR) ([]*dns.NSEC, []*dns.NSEC3) {
+	var (
+		nsec  []*dns.NSEC
+		nsec3 []*dns.NSEC3
+	)
+	for _, r := range rrs {
+		switch x := r.(type) {
+		case *dns.NSEC:
+			nsec = append(nsec, x)
+		case *dns.NSEC3:
+			nsec3 = append(nsec3, x)
+		}
+	}
+	return nsec, nsec3
+}
+
+const (
+	optOut        = 1   // opt-out flag of an NSEC3 record.
+	maxIterations = 150 // NSEC3 records with more iterations are not checked.
+)
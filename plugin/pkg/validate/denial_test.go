@@ -0,0 +1,155 @@
+package validate
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3Chain returns an NSEC3 chain for names in zone. Types are the types of each name.
+func nsec3Chain(zone string, names map[string][]uint16, flags uint8) []*dns.NSEC3 {
+	type h struct {
+		hash  string
+		types []uint16
+	}
+	var hashes []h
+	for n, types := range names {
+		hashes = append(hashes, h{dns.HashName(n, dns.SHA1, 0, ""), types})
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].hash < hashes[j].hash })
+
+	chain := make([]*dns.NSEC3, len(hashes))
+	for i, x := range hashes {
+		chain[i] = &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: x.hash + "." + zone, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 3600},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			NextDomain: hashes[(i+1)%len(hashes)].hash,
+			TypeBitMap: x.types,
+		}
+	}
+	return chain
+}
+
+func TestNSEC3(t *testing.T) {
+	names := map[string][]uint16{
+		"example.org.":          {dns.TypeSOA, dns.TypeNS, dns.TypeA},
+		"a.example.org.":        {dns.TypeA},
+		"sub.example.org.":      {dns.TypeNS},
+		"*.wild.example.org.":   {dns.TypeA},
+		"wild.example.org.":     {},
+		"cname.example.org.":    {dns.TypeCNAME},
+		"unsigned.example.org.": {dns.TypeNS},
+	}
+	chain := nsec3Chain("example.org.", names, 0)
+
+	if ok, _ := nxdomainNSEC3(chain, "b.example.org."); !ok {
+		t.Errorf("Expected NXDOMAIN proof for b.example.org.")
+	}
+	if ok, _ := nxdomainNSEC3(chain, "x.a.example.org."); !ok {
+		t.Errorf("Expected NXDOMAIN proof for x.a.example.org.")
+	}
+	if ok, _ := nxdomainNSEC3(chain, "a.example.org."); ok {
+		t.Errorf("Expected no NXDOMAIN proof for existing a.example.org.")
+	}
+	if ok, _ := nxdomainNSEC3(chain, "foo.wild.example.org."); ok {
+		t.Errorf("Expected no NXDOMAIN proof for name covered by wildcard")
+	}
+
+	if ok, _ := nodataNSEC3(chain, "a.example.org.", dns.TypeMX); !ok {
+		t.Errorf("Expected NODATA proof for a.example.org. MX")
+	}
+	if ok, _ := nodataNSEC3(chain, "a.example.org.", dns.TypeA); ok {
+		t.Errorf("Expected no NODATA proof for a.example.org. A")
+	}
+	if ok, _ := nodataNSEC3(chain, "cname.example.org.", dns.TypeA); ok {
+		t.Errorf("Expected no NODATA proof for a CNAME")
+	}
+	if ok, _ := nodataNSEC3(chain, "sub.example.org.", dns.TypeA); ok {
+		t.Errorf("Expected no NODATA proof for A at a delegation")
+	}
+	if ok, _ := nodataNSEC3(chain, "sub.example.org.", dns.TypeDS); !ok {
+		t.Errorf("Expected NODATA proof for DS at a delegation")
+	}
+	if ok, _ := nodataNSEC3(chain, "foo.wild.example.org.", dns.TypeMX); !ok {
+		t.Errorf("Expected NODATA proof for foo.wild.example.org. MX")
+	}
+
+	if !delegation(rrs(chain), "sub.example.org.") {
+		t.Errorf("Expected sub.example.org. to be a delegation")
+	}
+	if delegation(rrs(chain), "a.example.org.") {
+		t.Errorf("Expected a.example.org. not to be a delegation")
+	}
+
+	// Opt-out: names not in the chain may be unsigned delegations.
+	chain = nsec3Chain("example.org.", names, optOut)
+	if ok, optout := nxdomainNSEC3(chain, "b.example.org."); !ok || !optout {
+		t.Errorf("Expected opt-out NXDOMAIN proof for b.example.org., got %t %t", ok, optout)
+	}
+	if ok, optout := nodataNSEC3(chain, "b.example.org.", dns.TypeDS); !ok || !optout {
+		t.Errorf("Expected opt-out NODATA proof for b.example.org. DS, got %t %t", ok, optout)
+	}
+	if !delegation(rrs(chain), "b.example.org.") {
+		t.Errorf("Expected b.example.org. to be a possible delegation")
+	}
+}
+
+func rrs(chain []*dns.NSEC3) []dns.RR {
+	rrs := make([]dns.RR, len(chain))
+	for i, n := range chain {
+		rrs[i] = n
+	}
+	return rrs
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		owner, next, name string
+		want              bool
+	}{
+		{"a.example.org.", "d.example.org.", "b.example.org.", true},
+		{"a.example.org.", "d.example.org.", "a.example.org.", false},
+		{"a.example.org.", "d.example.org.", "d.example.org.", false},
+		{"a.example.org.", "d.example.org.", "x.b.example.org.", true},
+		{"a.example.org.", "d.example.org.", "e.example.org.", false},
+		// Last NSEC in the zone.
+		{"z.example.org.", "example.org.", "zz.example.org.", true},
+		{"z.example.org.", "example.org.", "b.example.org.", false},
+		{"A.example.org.", "D.example.org.", "b.EXAMPLE.org.", true},
+		{"a.example.org.", "d.example.org.", `\098.example.org.`, true},
+	}
+	for i, tc := range tests {
+		n := &dns.NSEC{Hdr: dns.RR_Header{Name: tc.owner}, NextDomain: tc.next}
+		if got := covers(n, tc.name); got != tc.want {
+			t.Errorf("Test %d: expected %t for %s in (%s, %s), got %t", i, tc.want, tc.name, tc.owner, tc.next, got)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Canonical order from RFC 4034, section 6.1.
+	names := []string{
+		"example.",
+		"a.example.",
+		"yljkjljk.a.example.",
+		"Z.a.example.",
+		"zABC.a.EXAMPLE.",
+		"z.example.",
+		`\001.z.example.`,
+		"*.z.example.",
+		`\200.z.example.`,
+	}
+	for i := 0; i < len(names)-1; i++ {
+		if c := compare(names[i], names[i+1]); c >= 0 {
+			t.Errorf("Expected %s < %s, got %d", names[i], names[i+1], c)
+		}
+		if c := compare(names[i+1], names[i]); c <= 0 {
+			t.Errorf("Expected %s > %s, got %d", names[i+1], names[i], c)
+		}
+	}
+	if compare("a.example.", "A.EXAMPLE.") != 0 {
+		t.Errorf("Expected names to compare equal ignoring case")
+	}
+}
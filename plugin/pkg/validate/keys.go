@@ -0,0 +1,245 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// entry is what we know about the keys of a zone.
+type entry struct {
+	zone   string
+	keys   []*dns.DNSKEY
+	result Result
+	err    error
+	expire time.Time
+}
+
+// zoneKeys returns the validated keys of zone. If the result isn't Secure there are no keys: the zone
+// is not signed (Insecure), not below a trust anchor (Indeterminate) or its keys can't be validated (Bogus).
+func (v *Validator) zoneKeys(ctx context.Context, zone string) ([]*dns.DNSKEY, Result, error) {
+	if e := v.cached(zone); e != nil {
+		return e.keys, e.result, e.err
+	}
+	keys, res, ttl, err := v.fetchKeys(ctx, zone)
+	v.keys.Add(cache.Hash([]byte(zone)), &entry{zone: zone, keys: keys, result: res, err: err, expire: v.now().Add(ttl)})
+	return keys, res, err
+}
+
+func (v *Validator) cached(zone string) *entry {
+	i, ok := v.keys.Get(cache.Hash([]byte(zone)))
+	if !ok {
+		return nil
+	}
+	e := i.(*entry)
+	if e.zone != zone || v.now().After(e.expire) {
+		return nil
+	}
+	return e
+}
+
+// fetchKeys retrieves and validates the keys of zone. It also returns for how long the result can be cached.
+func (v *Validator) fetchKeys(ctx context.Context, zone string) ([]*dns.DNSKEY, Result, time.Duration, error) {
+	if v.insecure(zone) {
+		return nil, Insecure, maxTTL, nil
+	}
+	dss, ok := v.anchors[zone]
+	if !ok {
+		if v.anchor(zone) == "" {
+			return nil, Indeterminate, maxTTL, nil
+		}
+		ds, cut, res, err := v.ds(ctx, zone)
+		switch res {
+		case Bogus:
+			return nil, Bogus, bogusTTL, err
+		case Insecure, Indeterminate:
+			return nil, res, maxTTL, nil
+		}
+		if len(ds) == 0 {
+			if cut {
+				return nil, Insecure, maxTTL, nil
+			}
+			return nil, Bogus, bogusTTL, fmt.Errorf("no DS records for %s", zone)
+		}
+		dss = ds
+	}
+
+	dss = supported(dss)
+	if len(dss) == 0 {
+		// RFC 4035, section 5.2: a zone with only unsupported algorithms is treated as unsigned.
+		return nil, Insecure, maxTTL, nil
+	}
+
+	m, err := v.lookup(ctx, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, Bogus, bogusTTL, fmt.Errorf("failed to get DNSKEY records for %s: %s", zone, err)
+	}
+	var (
+		keys []*dns.DNSKEY
+		rrs  []dns.RR
+		sigs []*dns.RRSIG
+	)
+	ttl := maxTTL
+	for _, r := range m.Answer {
+		if !equal(r.Header().Name, zone) {
+			continue
+		}
+		switch x := r.(type) {
+		case *dns.DNSKEY:
+			keys = append(keys, x)
+			rrs = append(rrs, x)
+			if d := time.Duration(x.Hdr.Ttl) * time.Second; d < ttl {
+				ttl = d
+			}
+		case *dns.RRSIG:
+			if x.TypeCovered == dns.TypeDNSKEY {
+				sigs = append(sigs, x)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, Bogus, bogusTTL, fmt.Errorf("no DNSKEY records for %s", zone)
+	}
+
+	// One of the keys that matches a DS must sign the DNSKEY RRset.
+	err = fmt.Errorf("no DNSKEY for %s matches its DS records", zone)
+	for _, ds := range dss {
+		for _, k := range keys {
+			if k.Flags&dns.ZONE == 0 || k.KeyTag() != ds.KeyTag || k.Algorithm != ds.Algorithm {
+				continue
+			}
+			if d := k.ToDS(ds.DigestType); d == nil || !strings.EqualFold(d.Digest, ds.Digest) {
+				continue
+			}
+			for _, sig := range sigs {
+				if sig.KeyTag != ds.KeyTag || sig.Algorithm != ds.Algorithm {
+					continue
+				}
+				if err = v.verify(sig, []*dns.DNSKEY{k}, rrs); err == nil {
+					return signingKeys(keys), Secure, ttl, nil
+				}
+			}
+		}
+	}
+	return nil, Bogus, bogusTTL, err
+}
+
+// signingKeys returns the keys that can sign records in a zone.
+func signingKeys(keys []*dns.DNSKEY) []*dns.DNSKEY {
+	zk := make([]*dns.DNSKEY, 0, len(keys))
+	for _, k := range keys {
+		if k.Flags&dns.ZONE != 0 && k.Flags&dns.REVOKE == 0 {
+			zk = append(zk, k)
+		}
+	}
+	return zk
+}
+
+// ds returns the validated DS records of name. If there are none, cut tells if name is a delegation
+// (without DS records) in its parent zone.
+func (v *Validator) ds(ctx context.Context, name string) (dss []*dns.DS, cut bool, res Result, err error) {
+	// Validating the DS records may need the DS records of the parent, and so on. Responses that point
+	// the wrong way could make this loop.
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth > maxDepth {
+		return nil, false, Bogus, fmt.Errorf("chain of trust for %s is too long", name)
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	m, err := v.lookup(ctx, name, dns.TypeDS)
+	if err != nil {
+		return nil, false, Bogus, fmt.Errorf("failed to get DS records for %s: %s", name, err)
+	}
+	if len(m.Question) == 0 {
+		m.Question = []dns.Question{{Name: name, Qtype: dns.TypeDS, Qclass: dns.ClassINET}}
+	}
+	res, err = v.response(ctx, m)
+	if res != Secure {
+		return nil, false, res, err
+	}
+	for _, r := range m.Answer {
+		if ds, ok := r.(*dns.DS); ok && equal(ds.Hdr.Name, name) {
+			dss = append(dss, ds)
+		}
+	}
+	if len(dss) > 0 {
+		return dss, true, Secure, nil
+	}
+	return nil, delegation(m.Ns, name), Secure, nil
+}
+
+// unsigned returns Insecure if name is proven to be in a zone that is not signed, and Bogus if it is in a
+// signed zone, i.e. records for name should have been signed.
+func (v *Validator) unsigned(ctx context.Context, name string) (Result, error) {
+	anchor := v.anchor(name)
+	if anchor == "" {
+		return Indeterminate, nil
+	}
+	if v.insecure(name) {
+		return Insecure, nil
+	}
+	name = strings.ToLower(name)
+
+	// Walk from the trust anchor down to name, looking for a delegation without DS records.
+	labels := dns.Split(name)
+	for i := len(labels) - dns.CountLabel(anchor) - 1; i >= 0; i-- {
+		z := name[labels[i]:]
+		if e := v.cached(z); e != nil {
+			if e.result != Secure {
+				return e.result, e.err
+			}
+			continue
+		}
+		dss, cut, res, err := v.ds(ctx, z)
+		if res != Secure {
+			return res, err
+		}
+		if len(dss) == 0 && cut {
+			v.keys.Add(cache.Hash([]byte(z)), &entry{zone: z, result: Insecure, expire: v.now().Add(maxTTL)})
+			return Insecure, nil
+		}
+	}
+	return Bogus, fmt.Errorf("missing signature for %s", name)
+}
+
+// supported returns the DS records with an algorithm and digest type we support.
+func supported(dss []*dns.DS) []*dns.DS {
+	s := make([]*dns.DS, 0, len(dss))
+	for _, ds := range dss {
+		if algorithms[ds.Algorithm] && digests[ds.DigestType] {
+			s = append(s, ds)
+		}
+	}
+	return s
+}
+
+var (
+	algorithms = map[uint8]bool{
+		dns.RSASHA1:          true,
+		dns.RSASHA1NSEC3SHA1: true,
+		dns.RSASHA256:        true,
+		dns.RSASHA512:        true,
+		dns.ECDSAP256SHA256:  true,
+		dns.ECDSAP384SHA384:  true,
+		dns.ED25519:          true,
+	}
+	digests = map[uint8]bool{
+		dns.SHA1:   true,
+		dns.SHA256: true,
+		dns.SHA384: true,
+	}
+)
+
+type depthKey struct{}
+
+const (
+	maxDepth = 64 // maximum number of DS lookups needed for one validation.
+
+	maxTTL   = 1 * time.Hour   // maximum time we cache keys, or the absence of them.
+	bogusTTL = 1 * time.Minute // time we cache keys that can't be validated.
+)
@@ -0,0 +1,332 @@
+// Package validate implements DNSSEC validation of responses, as described in RFC 4035. A Validator
+// builds the chain of trust from a set of trust anchors down to the records in a response, fetching
+// the DS and DNSKEY records it needs with a Lookup function, and caches the keys it has validated.
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Result is the outcome of validating a response.
+type Result int
+
+const (
+	// Indeterminate means there is no trust anchor for the response, it can't be validated.
+	Indeterminate Result = iota
+	// Insecure means the response, or part of it, is proven to come from a zone that is not signed.
+	Insecure
+	// Secure means all records in the response, and the proof of non-existence if any, are validated.
+	Secure
+	// Bogus means the response should have been signed, but the signatures can't be validated.
+	Bogus
+)
+
+// String returns the string representation of r.
+func (r Result) String() string {
+	switch r {
+	case Indeterminate:
+		return "indeterminate"
+	case Insecure:
+		return "insecure"
+	case Secure:
+		return "secure"
+	case Bogus:
+		return "bogus"
+	}
+	return fmt.Sprintf("Result(%d)", int(r))
+}
+
+// Lookup returns the response for name and qtype, including the DNSSEC records: the query must have
+// the DO bit set, and the CD bit if the response comes from a validating resolver. It is used to fetch
+// the DS and DNSKEY records of the chain of trust.
+type Lookup func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+// Validator validates responses against a set of trust anchors.
+type Validator struct {
+	lookup   Lookup
+	anchors  map[string][]*dns.DS
+	negative []string // negative trust anchors
+
+	keys *cache.Cache // validated keys, or the reason there are none, per zone.
+
+	// Testing.
+	now func() time.Time
+}
+
+// New returns a Validator that uses anchors as its trust anchors, and lookup to fetch the records
+// it needs. An anchor is a DS or a DNSKEY record.
+func New(anchors []dns.RR, lookup Lookup) (*Validator, error) {
+	v := &Validator{
+		lookup:  lookup,
+		anchors: make(map[string][]*dns.DS),
+		keys:    cache.New(keyCap),
+		now:     time.Now,
+	}
+	for _, a := range anchors {
+		name := strings.ToLower(a.Header().Name)
+		switch x := a.(type) {
+		case *dns.DS:
+			v.anchors[name] = append(v.anchors[name], x)
+		case *dns.DNSKEY:
+			ds := x.ToDS(dns.SHA256)
+			if ds == nil {
+				return nil, fmt.Errorf("unsupported trust anchor: %s", a)
+			}
+			v.anchors[name] = append(v.anchors[name], ds)
+		default:
+			return nil, fmt.Errorf("trust anchor must be a DS or DNSKEY record: %s", a)
+		}
+	}
+	if len(v.anchors) == 0 {
+		return nil, fmt.Errorf("no trust anchors")
+	}
+	return v, nil
+}
+
+// AddNegativeAnchors adds zones as negative trust anchors (RFC 7646): names in them are not validated and
+// are treated as Insecure, for zones that are known to have a broken chain of trust.
+func (v *Validator) AddNegativeAnchors(zones ...string) {
+	for _, z := range zones {
+		v.negative = append(v.negative, strings.ToLower(dns.Fqdn(z)))
+	}
+}
+
+// Validate validates the response m. For a Bogus result the error tells why. The response is expected
+// to be complete: when the answer is a CNAME chain, all records of the chain are in the answer section,
+// and the authority section holds the records of the response for the last name in the chain.
+func (v *Validator) Validate(ctx context.Context, m *dns.Msg) (Result, error) {
+	if len(m.Question) == 0 {
+		return Indeterminate, nil
+	}
+	if v.anchor(m.Question[0].Name) == "" {
+		return Indeterminate, nil
+	}
+	if v.insecure(m.Question[0].Name) {
+		return Insecure, nil
+	}
+	return v.response(ctx, m)
+}
+
+// anchor returns the trust anchor for name, which is the closest one enclosing it, or the empty string
+// if there is none.
+func (v *Validator) anchor(name string) string {
+	name = strings.ToLower(name)
+	for {
+		if _, ok := v.anchors[name]; ok {
+			return name
+		}
+		if name == "." {
+			return ""
+		}
+		name = parent(name)
+	}
+}
+
+// insecure returns true if name is below a negative trust anchor.
+func (v *Validator) insecure(name string) bool {
+	for _, z := range v.negative {
+		if dns.IsSubDomain(z, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parent returns the parent of name, name must not be the root.
+func parent(name string) string {
+	i, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[i:]
+}
+
+func (v *Validator) response(ctx context.Context, m *dns.Msg) (Result, error) {
+	q := m.Question[0]
+	result := Secure
+
+	sets := rrsets(m.Answer)
+	for _, set := range sets {
+		res, err := v.rrset(ctx, set, sets, m.Ns)
+		if res == Bogus {
+			return Bogus, err
+		}
+		if res < result {
+			result = res
+		}
+	}
+
+	// Follow the CNAME chain to the name the answer is for.
+	name := q.Name
+	for i := 0; i < len(sets) && q.Qtype != dns.TypeCNAME; i++ {
+		target := ""
+		for _, set := range sets {
+			if set.qtype == dns.TypeCNAME && equal(set.name, name) {
+				target = set.rrs[0].(*dns.CNAME).Target
+				break
+			}
+		}
+		if target == "" {
+			break
+		}
+		name = target
+	}
+	for _, set := range sets {
+		if equal(set.name, name) && (set.qtype == q.Qtype || q.Qtype == dns.TypeANY) {
+			return result, nil
+		}
+	}
+
+	if result != Secure {
+		return result, nil
+	}
+	return v.denial(ctx, m, name, q.Qtype)
+}
+
+// rrset validates set, which is one of the RRsets of the answer section in answer. The authority section
+// auth is used for the proof that is needed when set is expanded from a wildcard.
+func (v *Validator) rrset(ctx context.Context, set *rrset, answer []*rrset, auth []dns.RR) (Result, error) {
+	if len(set.sigs) == 0 {
+		if set.qtype == dns.TypeCNAME && synthesized(set, answer) {
+			return Secure, nil
+		}
+		return v.unsigned(ctx, set.name)
+	}
+
+	err := fmt.Errorf("no valid signature for %s %s", set.name, dns.TypeToString[set.qtype])
+	for _, sig := range set.sigs {
+		if !dns.IsSubDomain(sig.SignerName, set.name) {
+			continue
+		}
+		keys, res, kerr := v.zoneKeys(ctx, strings.ToLower(sig.SignerName))
+		switch res {
+		case Indeterminate, Insecure:
+			return res, nil
+		case Bogus:
+			err = kerr
+			continue
+		}
+		if verr := v.verify(sig, keys, set.rrs); verr != nil {
+			err = verr
+			continue
+		}
+		if expanded(set.name, sig) {
+			// Expanded from a wildcard, there must be proof the name itself doesn't exist.
+			return v.wildcard(ctx, auth, set.name, int(sig.Labels))
+		}
+		return Secure, nil
+	}
+	return Bogus, err
+}
+
+// expanded returns true if sig shows that the records of name are expanded from a wildcard.
+func expanded(name string, sig *dns.RRSIG) bool {
+	labels := dns.CountLabel(name)
+	if strings.HasPrefix(name, "*.") {
+		labels--
+	}
+	return int(sig.Labels) < labels
+}
+
+// verify verifies sig over rrs with one of keys.
+func (v *Validator) verify(sig *dns.RRSIG, keys []*dns.DNSKEY, rrs []dns.RR) error {
+	if !sig.ValidityPeriod(v.now()) {
+		return fmt.Errorf("signature for %s %s is expired or not yet valid", sig.Header().Name, dns.TypeToString[sig.TypeCovered])
+	}
+	err := fmt.Errorf("no key for signature of %s %s with key tag %d", sig.Header().Name, dns.TypeToString[sig.TypeCovered], sig.KeyTag)
+	for _, k := range keys {
+		if k.Algorithm != sig.Algorithm || k.KeyTag() != sig.KeyTag {
+			continue
+		}
+		if err = sig.Verify(k, rrs); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("signature for %s %s does not verify: %s", sig.Header().Name, dns.TypeToString[sig.TypeCovered], err)
+	}
+	return err
+}
+
+// synthesized returns true if the CNAME in set is synthesized from a signed DNAME in answer.
+func synthesized(set *rrset, answer []*rrset) bool {
+	cname := set.rrs[0].(*dns.CNAME)
+	for _, a := range answer {
+		if a.qtype != dns.TypeDNAME || len(a.sigs) == 0 || !dns.IsSubDomain(a.name, set.name) || equal(a.name, set.name) {
+			continue
+		}
+		dname := a.rrs[0].(*dns.DNAME)
+		prefix := set.name[:len(set.name)-len(a.name)]
+		if equal(prefix+dname.Target, cname.Target) {
+			return true
+		}
+	}
+	return false
+}
+
+// rrset is a set of records with the same owner name and type, and their signatures.
+type rrset struct {
+	name  string
+	qtype uint16
+	rrs   []dns.RR
+	sigs  []*dns.RRSIG
+}
+
+// rrsets groups rrs into RRsets. Signatures that don't cover any of the records are dropped.
+func rrsets(rrs []dns.RR) []*rrset {
+	var sets []*rrset
+	find := func(name string, qtype uint16) *rrset {
+		for _, s := range sets {
+			if s.qtype == qtype && equal(s.name, name) {
+				return s
+			}
+		}
+		return nil
+	}
+	for _, r := range rrs {
+		h := r.Header()
+		if h.Rrtype == dns.TypeRRSIG || h.Rrtype == dns.TypeOPT {
+			continue
+		}
+		if s := find(h.Name, h.Rrtype); s != nil {
+			s.rrs = append(s.rrs, r)
+			continue
+		}
+		sets = append(sets, &rrset{name: h.Name, qtype: h.Rrtype, rrs: []dns.RR{r}})
+	}
+	for _, r := range rrs {
+		sig, ok := r.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		if s := find(sig.Header().Name, sig.TypeCovered); s != nil {
+			s.sigs = append(s.sigs, sig)
+		}
+	}
+	return sets
+}
+
+// Strip returns rrs without the DNSSEC records, unless they are of type qtype. It is used for replies to
+// clients that didn't set the DO bit.
+func Strip(rrs []dns.RR, qtype uint16) []dns.RR {
+	s := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		switch t := rr.Header().Rrtype; t {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if t != qtype {
+				continue
+			}
+		}
+		s = append(s, rr)
+	}
+	return s
+}
+
+func equal(a, b string) bool { return strings.EqualFold(a, b) }
+
+const keyCap = 10000 // number of zones we cache keys for.
@@ -0,0 +1,242 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// hierarchy is a signed root and org. zone, with a signed delegation to example.org. and an unsigned one
+// to insecure.org.
+type hierarchy struct {
+	zones map[string]*file.Zone
+	root  *test.Key
+}
+
+func newHierarchy(t *testing.T) *hierarchy {
+	rootKey, orgKey, exampleKey := test.NewKey("."), test.NewKey("org."), test.NewKey("example.org.")
+
+	root := []dns.RR{
+		test.SOA(". 3600 IN SOA a.root. hostmaster.root. 1 7200 3600 1209600 3600"),
+		test.NS(". 3600 IN NS a.root."),
+		test.A("a.root. 3600 IN A 127.0.0.1"),
+		test.NS("org. 3600 IN NS ns.org."),
+		test.A("ns.org. 3600 IN A 127.0.0.2"),
+		orgKey.DS(),
+	}
+	org := []dns.RR{
+		test.SOA("org. 3600 IN SOA ns.org. hostmaster.org. 1 7200 3600 1209600 3600"),
+		test.NS("org. 3600 IN NS ns.org."),
+		test.A("ns.org. 3600 IN A 127.0.0.2"),
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+		exampleKey.DS(),
+		test.NS("insecure.org. 3600 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 3600 IN A 127.0.0.4"),
+	}
+	example := []dns.RR{
+		test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+		test.A("example.org. 3600 IN A 127.0.0.53"),
+		test.CNAME("www.example.org. 3600 IN CNAME example.org."),
+		test.A("*.wild.example.org. 3600 IN A 127.0.0.54"),
+		test.A("a.ent.example.org. 3600 IN A 127.0.0.55"),
+	}
+	insecure := []dns.RR{
+		test.SOA("insecure.org. 3600 IN SOA ns.insecure.org. hostmaster.insecure.org. 1 7200 3600 1209600 3600"),
+		test.NS("insecure.org. 3600 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 3600 IN A 127.0.0.4"),
+		test.A("insecure.org. 3600 IN A 127.0.0.53"),
+	}
+
+	h := &hierarchy{zones: map[string]*file.Zone{}, root: rootKey}
+	h.add(t, ".", test.SignZone(".", root, rootKey))
+	h.add(t, "org.", test.SignZone("org.", org, orgKey))
+	h.add(t, "example.org.", test.SignZone("example.org.", example, exampleKey))
+	h.add(t, "insecure.org.", insecure)
+	return h
+}
+
+func (h *hierarchy) add(t *testing.T, origin string, rrs []dns.RR) {
+	z := file.NewZone(origin, "stdin")
+	for _, r := range rrs {
+		if err := z.Insert(r); err != nil {
+			t.Fatalf("Failed to insert %s: %s", r, err)
+		}
+	}
+	h.zones[origin] = z
+}
+
+// lookup asks the zone that is authoritative for name, the parent zone for DS records.
+func (h *hierarchy) lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	n := name
+	if qtype == dns.TypeDS && n != "." {
+		n = parent(n)
+	}
+	for ; ; n = parent(n) {
+		if _, ok := h.zones[n]; ok || n == "." {
+			break
+		}
+	}
+	f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{n: h.zones[n]}, Names: []string{n}}}
+
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(4096, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	f.ServeDNS(ctx, rec, m)
+	return rec.Msg, nil
+}
+
+func (h *hierarchy) validator(t *testing.T, anchors ...dns.RR) *Validator {
+	if len(anchors) == 0 {
+		anchors = []dns.RR{h.root.DS()}
+	}
+	v, err := New(anchors, h.lookup)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	h := newHierarchy(t)
+	v := h.validator(t)
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		rcode int
+		want  Result
+	}{
+		{"example.org.", dns.TypeA, dns.RcodeSuccess, Secure},
+		{"www.example.org.", dns.TypeA, dns.RcodeSuccess, Secure},
+		{"foo.wild.example.org.", dns.TypeA, dns.RcodeSuccess, Secure},
+		{"nope.example.org.", dns.TypeA, dns.RcodeNameError, Secure},
+		{"example.org.", dns.TypeMX, dns.RcodeSuccess, Secure},
+		{"ent.example.org.", dns.TypeA, dns.RcodeSuccess, Secure},
+		{"foo.wild.example.org.", dns.TypeMX, dns.RcodeSuccess, Secure},
+		{"example.org.", dns.TypeDNSKEY, dns.RcodeSuccess, Secure},
+		{"abc.org.", dns.TypeA, dns.RcodeNameError, Secure},
+		{"insecure.org.", dns.TypeA, dns.RcodeSuccess, Insecure},
+		{"nope.insecure.org.", dns.TypeA, dns.RcodeNameError, Insecure},
+	}
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m, _ := h.lookup(ctx, tc.qname, tc.qtype)
+		if m.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d for %s, got %d", i, tc.rcode, tc.qname, m.Rcode)
+		}
+		res, err := v.Validate(ctx, m)
+		if res != tc.want {
+			t.Errorf("Test %d: expected %s for %s %s, got %s (%v)", i, tc.want, tc.qname, dns.TypeToString[tc.qtype], res, err)
+		}
+	}
+}
+
+func TestValidateBogus(t *testing.T) {
+	h := newHierarchy(t)
+	v := h.validator(t)
+	ctx := context.TODO()
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		tamper func(m *dns.Msg)
+	}{
+		// Changed address.
+		{"example.org.", dns.TypeA, func(m *dns.Msg) { m.Answer[0].(*dns.A).A[3] = 1 }},
+		// Stripped signatures.
+		{"example.org.", dns.TypeA, func(m *dns.Msg) { m.Answer = m.Answer[:1] }},
+		// Stripped proof of non-existence.
+		{"nope.example.org.", dns.TypeA, func(m *dns.Msg) { m.Ns = m.Ns[:2] }},
+		// Name that does exist, denied with the NSEC of another name.
+		{"nope.example.org.", dns.TypeA, func(m *dns.Msg) { m.Question[0].Name = "www.example.org." }},
+		// Wildcard answer for a name that exists.
+		{"foo.wild.example.org.", dns.TypeA, func(m *dns.Msg) { m.Ns = nil }},
+	}
+	for i, tc := range tests {
+		m, _ := h.lookup(ctx, tc.qname, tc.qtype)
+		tc.tamper(m)
+		if res, err := v.Validate(ctx, m); res != Bogus {
+			t.Errorf("Test %d: expected bogus for %s, got %s", i, tc.qname, res)
+		} else if err == nil {
+			t.Errorf("Test %d: expected error for bogus %s", i, tc.qname)
+		}
+	}
+
+	// A wrong trust anchor makes everything bogus.
+	v = h.validator(t, test.NewKey(".").DS())
+	m, _ := h.lookup(ctx, "example.org.", dns.TypeA)
+	if res, _ := v.Validate(ctx, m); res != Bogus {
+		t.Errorf("Expected bogus with wrong trust anchor, got %s", res)
+	}
+}
+
+func TestValidateAnchor(t *testing.T) {
+	h := newHierarchy(t)
+	ctx := context.TODO()
+
+	// A DNSKEY as trust anchor, for a zone below the root.
+	dnskey, _ := h.lookup(ctx, "example.org.", dns.TypeDNSKEY)
+	v := h.validator(t, dnskey.Answer[0])
+
+	m, _ := h.lookup(ctx, "example.org.", dns.TypeA)
+	if res, err := v.Validate(ctx, m); res != Secure {
+		t.Errorf("Expected secure, got %s (%v)", res, err)
+	}
+	m, _ = h.lookup(ctx, "insecure.org.", dns.TypeA)
+	if res, _ := v.Validate(ctx, m); res != Indeterminate {
+		t.Errorf("Expected indeterminate, got %s", res)
+	}
+}
+
+func TestParseAnchors(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		{root[0], false},
+		{strings.Join(root, "\n"), false},
+		{"example.org. IN DNSKEY 257 3 13 aGVsbG8=", false},
+		{"example.org. IN A 127.0.0.1", true},
+		{"example.org. IN DS 1 2", true},
+		{"", true},
+	}
+	for i, tc := range tests {
+		_, err := ParseAnchor(tc.input)
+		if tc.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error for %q", i, tc.input)
+		}
+		if !tc.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error for %q, got %s", i, tc.input, err)
+		}
+	}
+	if len(RootAnchors()) != len(root) {
+		t.Errorf("Expected %d root anchors, got %d", len(root), len(RootAnchors()))
+	}
+}
+
+func TestValidateNegativeAnchor(t *testing.T) {
+	h := newHierarchy(t)
+	v := h.validator(t)
+	v.AddNegativeAnchors("example.org")
+	ctx := context.TODO()
+
+	m, _ := h.lookup(ctx, "example.org.", dns.TypeA)
+	m.Answer[0].(*dns.A).A[3] = 1
+	if res, _ := v.Validate(ctx, m); res != Insecure {
+		t.Errorf("Expected insecure below negative trust anchor, got %s", res)
+	}
+	m, _ = h.lookup(ctx, "abc.org.", dns.TypeA)
+	if res, err := v.Validate(ctx, m); res != Secure {
+		t.Errorf("Expected secure outside negative trust anchor, got %s (%v)", res, err)
+	}
+}
@@ -3,7 +3,11 @@ package proxy
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
+	"sync"
+	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/pb"
 	"github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/trace"
@@ -14,12 +18,13 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 )
 
 type grpcClient struct {
 	dialOpts []grpc.DialOption
-	clients  map[string]pb.DnsServiceClient
+	clients  map[string]*grpcStream
 	conns    []*grpc.ClientConn
 	upstream *staticUpstream
 }
@@ -32,7 +37,7 @@ func newGrpcClient(tls *tls.Config, u *staticUpstream) *grpcClient {
 	} else {
 		g.dialOpts = append(g.dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tls)))
 	}
-	g.clients = map[string]pb.DnsServiceClient{}
+	g.clients = map[string]*grpcStream{}
 
 	return g
 }
@@ -44,7 +49,12 @@ func (g *grpcClient) Exchange(ctx context.Context, addr string, state request.Re
 	}
 
 	if cl, ok := g.clients[addr]; ok {
-		reply, err := cl.Query(ctx, &pb.DnsPacket{Msg: msg})
+		p := &pb.DnsPacket{Msg: msg}
+		if g.upstream.sendClient {
+			// Tell the upstream who the query is for, so it sees the real client.
+			p.Client = net.JoinHostPort(state.IP(), state.Port())
+		}
+		reply, err := cl.exchange(ctx, p)
 		if err != nil {
 			return nil, err
 		}
@@ -63,7 +73,7 @@ func (g *grpcClient) Transport() string { return "tcp" }
 func (g *grpcClient) Protocol() string { return "grpc" }
 
 func (g *grpcClient) OnShutdown(p *Proxy) error {
-	g.clients = map[string]pb.DnsServiceClient{}
+	g.clients = map[string]*grpcStream{}
 	for i, conn := range g.conns {
 		err := conn.Close()
 		if err != nil {
@@ -92,9 +102,133 @@ func (g *grpcClient) OnStartup(p *Proxy) error {
 		if err != nil {
 			log.Warningf("Skipping gRPC host '%s' due to Dial error: %s\n", host.Name, err)
 		} else {
-			g.clients[host.Name] = pb.NewDnsServiceClient(conn)
+			g.clients[host.Name] = newGrpcStream(host.Name, pb.NewDnsServiceClient(conn))
 			g.conns = append(g.conns, conn)
 		}
 	}
 	return nil
 }
+
+// grpcStream sends the queries for an upstream over a single gRPC stream, that is opened when the first
+// query is sent and reopened after it breaks. Replies are matched to their query with the packet's Id.
+// If the upstream doesn't implement streaming, every query is sent with its own Query call. So do the
+// queries sent while the stream has as many queries in flight as an upstream allows.
+type grpcStream struct {
+	addr   string
+	client pb.DnsServiceClient
+
+	sync.Mutex
+	stream  pb.DnsService_StreamClient
+	cancel  context.CancelFunc
+	id      uint64
+	pending map[uint64]chan *pb.DnsPacket // nil for queries we no longer wait for
+	unary   bool
+}
+
+func newGrpcStream(addr string, client pb.DnsServiceClient) *grpcStream {
+	return &grpcStream{addr: addr, client: client, pending: map[uint64]chan *pb.DnsPacket{}}
+}
+
+// exchange sends p to the upstream and returns the reply.
+func (s *grpcStream) exchange(ctx context.Context, p *pb.DnsPacket) (*pb.DnsPacket, error) {
+	s.Lock()
+	if s.unary || len(s.pending) >= dnsserver.MaxStreamQueries {
+		s.Unlock()
+		return s.client.Query(ctx, p)
+	}
+	if s.stream == nil {
+		if err := s.open(); err != nil {
+			s.Unlock()
+			return nil, err
+		}
+	}
+	s.id++
+	p.Id = s.id
+	c := make(chan *pb.DnsPacket, 1)
+	s.pending[p.Id] = c
+	stream := s.stream
+	err := stream.Send(p)
+	s.Unlock()
+	if err != nil {
+		s.reset(stream, err)
+		return nil, err
+	}
+
+	timeout := time.NewTimer(defaultTimeout)
+	defer timeout.Stop()
+	select {
+	case reply, ok := <-c:
+		if !ok {
+			return nil, fmt.Errorf("grpc stream to %s closed", s.addr)
+		}
+		return reply, nil
+	case <-ctx.Done():
+		s.forget(p.Id)
+		return nil, ctx.Err()
+	case <-timeout.C:
+		s.forget(p.Id)
+		return nil, fmt.Errorf("grpc stream to %s: timeout waiting for reply", s.addr)
+	}
+}
+
+// open opens the stream and starts receiving from it. The lock must be held.
+func (s *grpcStream) open() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := s.client.Stream(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.stream, s.cancel = stream, cancel
+	go s.receive(stream)
+	return nil
+}
+
+// receive hands the replies on stream to the queries waiting for them, until the stream breaks.
+func (s *grpcStream) receive(stream pb.DnsService_StreamClient) {
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			s.reset(stream, err)
+			return
+		}
+		s.Lock()
+		c, ok := s.pending[reply.Id]
+		delete(s.pending, reply.Id)
+		s.Unlock()
+		if ok && c != nil {
+			c <- reply
+		}
+	}
+}
+
+// reset closes stream after it failed with err, the queries waiting for a reply on it get an error.
+func (s *grpcStream) reset(stream pb.DnsService_StreamClient, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.stream != stream {
+		return // already reset
+	}
+	if grpc.Code(err) == codes.Unimplemented {
+		log.Infof("gRPC upstream %s doesn't support streaming, using single queries", s.addr)
+		s.unary = true
+	}
+	s.cancel()
+	for id, c := range s.pending {
+		delete(s.pending, id)
+		if c != nil {
+			close(c)
+		}
+	}
+	s.stream = nil
+}
+
+// forget stops waiting for the reply to the query with id. The query still counts as in flight until
+// its reply arrives, as it does for the upstream.
+func (s *grpcStream) forget(id uint64) {
+	s.Lock()
+	if _, ok := s.pending[id]; ok {
+		s.pending[id] = nil
+	}
+	s.Unlock()
+}
@@ -2,8 +2,10 @@ package proxy
 
 import (
 	"fmt"
+	"net"
 	"testing"
 
+	"github.com/coredns/coredns/pb"
 	"github.com/coredns/coredns/plugin/pkg/healthcheck"
 	"github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/test"
@@ -11,6 +13,8 @@ import (
 
 	"github.com/miekg/dns"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
 )
 
@@ -177,6 +181,78 @@ func TestGRPCRunAQueryOnSecureLinkWithInvalidCert(t *testing.T) {
 	}
 }
 
+// clientServer is a gRPC DNS server that only answers Query, and remembers the client of the last query.
+type clientServer struct{ client chan string }
+
+func (cs *clientServer) Query(ctx context.Context, p *pb.DnsPacket) (*pb.DnsPacket, error) {
+	cs.client <- p.Client
+	return &pb.DnsPacket{Msg: p.Msg, Id: p.Id}, nil
+}
+
+func (cs *clientServer) Stream(pb.DnsService_StreamServer) error {
+	return grpc.Errorf(codes.Unimplemented, "no streaming")
+}
+
+func (cs *clientServer) Batch(context.Context, *pb.DnsBatch) (*pb.DnsBatch, error) {
+	return nil, grpc.Errorf(codes.Unimplemented, "no batches")
+}
+
+func TestGRPCSendClient(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	cs := &clientServer{client: make(chan string, 1)}
+	srv := grpc.NewServer()
+	pb.RegisterDnsServiceServer(srv, cs)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	for _, sendClient := range []bool{false, true} {
+		upstream := &staticUpstream{
+			from: ".",
+			HealthCheck: healthcheck.HealthCheck{
+				Hosts: []*healthcheck.UpstreamHost{{Name: l.Addr().String()}},
+			},
+			sendClient: sendClient,
+		}
+		g := newGrpcClient(nil, upstream)
+		upstream.ex = g
+
+		p := &Proxy{}
+		p.Upstreams = &[]Upstream{upstream}
+		if err := g.OnStartup(p); err != nil {
+			t.Fatalf("Error starting grpc client exchanger: %s", err)
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+		// The first queries find out the server doesn't stream, after that they are sent with Query.
+		client, ok := "", false
+		for i := 0; i < 5 && !ok; i++ {
+			g.Exchange(context.TODO(), l.Addr().String(), state)
+			select {
+			case client = <-cs.client:
+				ok = true
+			default:
+			}
+		}
+		if !ok {
+			t.Fatalf("Expected a query with send_client %t, got none", sendClient)
+		}
+
+		expected := ""
+		if sendClient {
+			expected = "10.240.0.1:40212"
+		}
+		if client != expected {
+			t.Errorf("Expected client %q with send_client %t, got %q", expected, sendClient, client)
+		}
+		g.OnShutdown(p)
+	}
+}
+
 // discard is a Logger that outputs nothing.
 type discardV2 struct{}
 
@@ -218,3 +294,14 @@ q8HFtDjT4pBpB8AfuzwtUZ/zJ5atwxa5+ahcqRnK2kX2RSINfyEy43FZjLlvjcGa
 UIRTUJK1JKg=
 -----END CERTIFICATE-----`
 )
+
+func TestGrpcStreamForget(t *testing.T) {
+	s := newGrpcStream("127.0.0.1:53", nil)
+	s.pending[1] = make(chan *pb.DnsPacket, 1)
+
+	// The upstream still counts a query we gave up on, so must we.
+	s.forget(1)
+	if len(s.pending) != 1 {
+		t.Errorf("Expected the forgotten query to be in flight, got %d queries", len(s.pending))
+	}
+}
@@ -21,6 +21,7 @@ type staticUpstream struct {
 
 	IgnoredSubDomains []string
 	ex                Exchanger
+	sendClient        bool // send the client's address to gRPC upstreams
 }
 
 // NewStaticUpstreams parses the configuration input and sets up
@@ -185,6 +186,11 @@ func parseBlock(c *caddyfile.Dispenser, u *staticUpstream) error {
 			return fmt.Errorf("%s: %s", errInvalidProtocol, encArgs[0])
 		}
 
+	case "send_client":
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		u.sendClient = true
 	default:
 		return c.Errf("unknown property '%s'", c.Val())
 	}
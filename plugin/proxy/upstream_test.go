@@ -138,6 +138,21 @@ proxy . 8.8.8.8:53 {
 		},
 		{
 			`
+proxy . 8.8.8.8:53 {
+	protocol grpc insecure
+	send_client
+}`,
+			false,
+		},
+		{
+			`
+proxy . 8.8.8.8:53 {
+	send_client yes
+}`,
+			true,
+		},
+		{
+			`
 proxy . 8.8.8.8:53 {
 	protocol grpc a b c d
 }`,
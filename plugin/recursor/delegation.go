@@ -0,0 +1,119 @@
+package recursor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// cut is a zone cut: the name servers of zone, and the addresses we know for them.
+type cut struct {
+	zone   string
+	ns     []string
+	addrs  []string
+	expire time.Time
+}
+
+// delegations caches the zone cuts we have seen in referrals, with the glue that came with them.
+type delegations struct {
+	c   *cache.Cache
+	now func() time.Time
+}
+
+func newDelegations(size int) *delegations {
+	return &delegations{c: cache.New(size), now: time.Now}
+}
+
+// get returns the cut for zone, or nil if we don't have it.
+func (d *delegations) get(zone string) *cut {
+	i, ok := d.c.Get(cache.Hash([]byte(zone)))
+	if !ok {
+		return nil
+	}
+	c := i.(*cut)
+	if c.zone != zone || d.now().After(c.expire) {
+		return nil
+	}
+	return c
+}
+
+// add caches c for ttl.
+func (d *delegations) add(c *cut, ttl time.Duration) {
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	c.expire = d.now().Add(ttl)
+	d.c.Add(cache.Hash([]byte(c.zone)), c)
+}
+
+// closest returns the closest cut we know of that encloses name, or nil if we only know the root.
+func (d *delegations) closest(name string) *cut {
+	name = strings.ToLower(name)
+	for name != "." {
+		if c := d.get(name); c != nil {
+			return c
+		}
+		i, end := dns.NextLabel(name, 0)
+		if end {
+			break
+		}
+		name = name[i:]
+	}
+	return nil
+}
+
+// referral returns the cut in the referral m, received from a server for zone for a query for qname.
+// It returns nil if m is not a referral to a zone below zone. Only glue that is in zone is used.
+func referral(m *dns.Msg, zone, qname string) (*cut, uint32) {
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) > 0 {
+		return nil, 0
+	}
+	c := &cut{}
+	ttl := uint32(maxTTL / time.Second)
+	for _, r := range m.Ns {
+		ns, ok := r.(*dns.NS)
+		if !ok {
+			continue
+		}
+		owner := strings.ToLower(ns.Hdr.Name)
+		if owner == zone || !dns.IsSubDomain(zone, owner) || !dns.IsSubDomain(owner, qname) {
+			continue
+		}
+		if c.zone != "" && c.zone != owner {
+			continue
+		}
+		c.zone = owner
+		c.ns = append(c.ns, strings.ToLower(ns.Ns))
+		if ns.Hdr.Ttl < ttl {
+			ttl = ns.Hdr.Ttl
+		}
+	}
+	if c.zone == "" {
+		return nil, 0
+	}
+	for _, r := range m.Extra {
+		h := r.Header()
+		if !dns.IsSubDomain(zone, h.Name) || !contains(c.ns, strings.ToLower(h.Name)) {
+			continue
+		}
+		switch x := r.(type) {
+		case *dns.A:
+			c.addrs = append(c.addrs, x.A.String())
+		case *dns.AAAA:
+			c.addrs = append(c.addrs, x.AAAA.String())
+		}
+	}
+	return c, ttl
+}
+
+func contains(s []string, x string) bool {
+	for _, y := range s {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package recursor
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// exchange sends a query for name and qtype to the server at addr. With 0x20 the case of the letters in
+// name is randomized, and the reply must have the same question; the names in the reply are restored.
+func (r *Recursor) exchange(addr, name string, qtype uint16) (*dns.Msg, error) {
+	qname := name
+	if r.use0x20 {
+		qname = randomCase(name)
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(qname, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(udpSize, true)
+
+	server := net.JoinHostPort(addr, r.port)
+	QueryCount.WithLabelValues(server).Add(1)
+
+	c := &dns.Client{Net: "udp", Timeout: timeout}
+	ret, _, err := c.Exchange(m, server)
+	if err == nil && ret.Truncated {
+		c.Net = "tcp"
+		ret, _, err = c.Exchange(m, server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ret.Question) != 1 || ret.Question[0].Name != qname || ret.Question[0].Qtype != qtype {
+		return nil, errMismatch
+	}
+
+	if qname != name {
+		restore(ret, qname, name)
+	}
+	return ret, nil
+}
+
+// randomCase returns name with the case of each letter chosen at random.
+func randomCase(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			if rand.Intn(2) == 0 {
+				b[i] = c | 0x20
+			} else {
+				b[i] = c &^ 0x20
+			}
+		}
+	}
+	return string(b)
+}
+
+// restore replaces qname by name in m, where names equal qname.
+func restore(m *dns.Msg, qname, name string) {
+	m.Question[0].Name = name
+	for _, s := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range s {
+			if h := rr.Header(); h.Name == qname {
+				h.Name = name
+			}
+		}
+	}
+}
+
+var errMismatch = errors.New("question in reply does not match query")
+
+const (
+	udpSize = 4096
+	timeout = 2 * time.Second
+)
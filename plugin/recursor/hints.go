@@ -0,0 +1,65 @@
+package recursor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// rootServers are the IPv4 addresses of the root servers, a.root-servers.net. to m.root-servers.net.
+var rootServers = []string{
+	"198.41.0.4",
+	"170.247.170.2",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+}
+
+// parseHints parses the root hints in r, which is in the format of named.root: the NS records of the
+// root zone and the addresses of the name servers. It returns the addresses. File is only used in
+// error messages.
+func parseHints(r io.Reader, file string) ([]string, error) {
+	ns := map[string]bool{}
+	var addrs []dns.RR
+	for x := range dns.ParseZone(r, ".", file) {
+		if x.Error != nil {
+			return nil, x.Error
+		}
+		switch x.RR.Header().Rrtype {
+		case dns.TypeNS:
+			if x.RR.Header().Name != "." {
+				return nil, fmt.Errorf("root hint is not for the root zone: %s", x.RR)
+			}
+			ns[strings.ToLower(x.RR.(*dns.NS).Ns)] = true
+		case dns.TypeA, dns.TypeAAAA:
+			addrs = append(addrs, x.RR)
+		}
+	}
+
+	var hints []string
+	for _, a := range addrs {
+		if !ns[strings.ToLower(a.Header().Name)] {
+			continue
+		}
+		switch x := a.(type) {
+		case *dns.A:
+			hints = append(hints, x.A.String())
+		case *dns.AAAA:
+			hints = append(hints, x.AAAA.String())
+		}
+	}
+	if len(hints) == 0 {
+		return nil, fmt.Errorf("no root server addresses in %s", file)
+	}
+	return hints, nil
+}
@@ -0,0 +1,27 @@
+package recursor
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	QueryCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "recursor",
+		Name:      "query_count_total",
+		Help:      "Counter of queries sent per name server.",
+	}, []string{"to"})
+	ValidationCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "recursor",
+		Name:      "validation_count_total",
+		Help:      "Counter of DNSSEC validations per result.",
+	}, []string{"result"})
+)
+
+var once sync.Once
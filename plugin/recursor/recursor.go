@@ -0,0 +1,124 @@
+// Package recursor implements a plugin that resolves queries iteratively, starting at the root servers,
+// and optionally validates the answers with DNSSEC.
+package recursor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/validate"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Recursor is a plugin that resolves queries by following the referrals from the root servers down to
+// the servers that are authoritative for the name.
+type Recursor struct {
+	Next  plugin.Handler
+	Zones []string
+
+	root        *cut // the root hints
+	delegations *delegations
+	validator   *validate.Validator
+	minimise    bool // QNAME minimisation
+	use0x20     bool
+
+	port string // port the name servers listen on, only changed in tests.
+}
+
+// New returns a new Recursor for zones, that starts at the default root servers.
+func New(zones []string) *Recursor {
+	return &Recursor{
+		Zones:       zones,
+		root:        &cut{zone: ".", addrs: rootServers},
+		delegations: newDelegations(delegationCap),
+		minimise:    true,
+		use0x20:     true,
+		port:        "53",
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (r *Recursor) ServeDNS(ctx context.Context, w dns.ResponseWriter, req *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: req}
+	if plugin.Zones(r.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, req)
+	}
+
+	m, err := r.newResolution().resolve(ctx, state.Name(), state.QType())
+	if err != nil {
+		return dns.RcodeServerFailure, err
+	}
+
+	ad := false
+	if r.validator != nil && !req.CheckingDisabled {
+		res, verr := r.validator.Validate(ctx, m)
+		ValidationCount.WithLabelValues(res.String()).Add(1)
+		if res == validate.Bogus {
+			return dns.RcodeServerFailure, fmt.Errorf("bogus answer for %s %s: %s", state.Name(), state.Type(), verr)
+		}
+		ad = res == validate.Secure
+	}
+
+	ret := new(dns.Msg)
+	ret.SetReply(req)
+	ret.Rcode = m.Rcode
+	ret.RecursionAvailable, ret.Compress = true, true
+	ret.AuthenticatedData = ad && (state.Do() || req.AuthenticatedData)
+	ret.Answer, ret.Ns = m.Answer, authority(m)
+	if !state.Do() {
+		ret.Answer = validate.Strip(ret.Answer, state.QType())
+		ret.Ns = validate.Strip(ret.Ns, state.QType())
+	}
+
+	state.SizeAndDo(ret)
+	ret, _ = state.Scrub(ret)
+	w.WriteMsg(ret)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (r *Recursor) Name() string { return "recursor" }
+
+// lookup implements validate.Lookup.
+func (r *Recursor) lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	return r.newResolution().resolve(ctx, name, qtype)
+}
+
+// closest returns the closest zone cut we know of that encloses name.
+func (r *Recursor) closest(name string) *cut {
+	if c := r.delegations.closest(name); c != nil {
+		return c
+	}
+	return r.root
+}
+
+// authority returns the authority section for the reply to m. For an answer only the proof that
+// wildcard expanded records need is kept.
+func authority(m *dns.Msg) []dns.RR {
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) == 0 {
+		return m.Ns
+	}
+	var ns []dns.RR
+	for _, rr := range m.Ns {
+		switch t := rr.Header().Rrtype; t {
+		case dns.TypeNSEC, dns.TypeNSEC3:
+			ns = append(ns, rr)
+		case dns.TypeRRSIG:
+			if c := rr.(*dns.RRSIG).TypeCovered; c == dns.TypeNSEC || c == dns.TypeNSEC3 {
+				ns = append(ns, rr)
+			}
+		}
+	}
+	return ns
+}
+
+func ttlDuration(ttl uint32) time.Duration { return time.Duration(ttl) * time.Second }
+
+const (
+	delegationCap = 10000          // number of zone cuts we cache.
+	maxTTL        = 24 * time.Hour // maximum time we cache a zone cut.
+)
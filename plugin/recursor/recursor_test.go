@@ -0,0 +1,416 @@
+package recursor
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/validate"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// authServers are stand-in authoritative servers, built with the file plugin. They listen on 127.0.0.1
+// to 127.0.0.4, all on the same port.
+type authServers struct {
+	port    string
+	servers []*dns.Server
+
+	mu      sync.Mutex
+	queries map[string][]dns.Question // per address
+}
+
+var addrs = []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4"}
+
+func newAuthServers(t *testing.T, zones map[string]map[string]*file.Zone) *authServers {
+	for try := 0; try < 10; try++ {
+		s := &authServers{queries: map[string][]dns.Question{}}
+		if err := s.listen(zones); err == nil {
+			return s
+		}
+		s.Close()
+	}
+	t.Fatalf("Failed to find a free port on %v", addrs)
+	return nil
+}
+
+func (s *authServers) listen(zones map[string]map[string]*file.Zone) error {
+	for _, addr := range addrs {
+		pc, err := net.ListenPacket("udp", net.JoinHostPort(addr, s.port))
+		if err != nil {
+			return err
+		}
+		if s.port == "" || s.port == "0" {
+			_, s.port, _ = net.SplitHostPort(pc.LocalAddr().String())
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort(addr, s.port))
+		if err != nil {
+			pc.Close()
+			return err
+		}
+
+		f := file.File{Zones: file.Zones{Z: zones[addr]}}
+		for origin := range zones[addr] {
+			f.Zones.Names = append(f.Zones.Names, origin)
+		}
+		h := s.handler(addr, f)
+
+		for _, srv := range []*dns.Server{{PacketConn: pc, Handler: h}, {Listener: ln, Handler: h}} {
+			started := make(chan struct{})
+			srv.NotifyStartedFunc = func() { close(started) }
+			go srv.ActivateAndServe()
+			<-started
+			s.servers = append(s.servers, srv)
+		}
+	}
+	return nil
+}
+
+func (s *authServers) handler(addr string, f file.File) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		s.mu.Lock()
+		s.queries[addr] = append(s.queries[addr], r.Question[0])
+		s.mu.Unlock()
+
+		rcode, _ := f.ServeDNS(context.TODO(), w, r)
+		if !plugin.ClientWrite(rcode) {
+			m := new(dns.Msg)
+			m.SetRcode(r, rcode)
+			w.WriteMsg(m)
+		}
+	}
+}
+
+// questions returns the questions the server at addr received, and forgets them.
+func (s *authServers) questions(addr string) []dns.Question {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.queries[addr]
+	delete(s.queries, addr)
+	return q
+}
+
+func (s *authServers) Close() {
+	for _, srv := range s.servers {
+		srv.Shutdown()
+	}
+}
+
+func zone(t *testing.T, origin string, rrs []dns.RR) *file.Zone {
+	z := file.NewZone(origin, "stdin")
+	for _, r := range rrs {
+		if err := z.Insert(r); err != nil {
+			t.Fatalf("Failed to insert %s: %s", r, err)
+		}
+	}
+	return z
+}
+
+// hierarchy starts the servers for a root zone, org., and a few zones below org. It returns the servers
+// and the key of the root zone.
+func hierarchy(t *testing.T) (*authServers, *test.Key) {
+	rootKey, orgKey, exampleKey := test.NewKey("."), test.NewKey("org."), test.NewKey("example.org.")
+	bogusKey := test.NewKey("bogus.org.")
+
+	root := test.SignZone(".", []dns.RR{
+		test.SOA(". 303 IN SOA a.root. hostmaster.root. 1 7200 3600 1209600 3600"),
+		test.NS(". 303 IN NS a.root."),
+		test.A("a.root. 303 IN A 127.0.0.1"),
+		test.NS("org. 303 IN NS ns.org."),
+		test.A("ns.org. 303 IN A 127.0.0.2"),
+		orgKey.DS(),
+	}, rootKey)
+	org := test.SignZone("org.", []dns.RR{
+		test.SOA("org. 303 IN SOA ns.org. hostmaster.org. 1 7200 3600 1209600 3600"),
+		test.NS("org. 303 IN NS ns.org."),
+		test.A("ns.org. 303 IN A 127.0.0.2"),
+		test.NS("example.org. 303 IN NS ns.example.org."),
+		test.A("ns.example.org. 303 IN A 127.0.0.3"),
+		exampleKey.DS(),
+		test.NS("insecure.org. 303 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 303 IN A 127.0.0.4"),
+		// No glue, the name server is in another zone.
+		test.NS("other.org. 303 IN NS ns2.example.org."),
+		test.NS("bogus.org. 303 IN NS ns.bogus.org."),
+		test.A("ns.bogus.org. 303 IN A 127.0.0.4"),
+		test.NewKey("bogus.org.").DS(),
+	}, orgKey)
+	example := test.SignZone("example.org.", []dns.RR{
+		test.SOA("example.org. 303 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+		test.NS("example.org. 303 IN NS ns.example.org."),
+		test.A("ns.example.org. 303 IN A 127.0.0.3"),
+		test.A("ns2.example.org. 303 IN A 127.0.0.4"),
+		test.A("example.org. 303 IN A 127.0.0.53"),
+		test.CNAME("www.example.org. 303 IN CNAME example.org."),
+		test.CNAME("alias.example.org. 303 IN CNAME www.other.org."),
+		test.A("a.b.c.example.org. 303 IN A 127.0.0.54"),
+	}, exampleKey)
+	other := []dns.RR{
+		test.SOA("other.org. 303 IN SOA ns2.example.org. hostmaster.other.org. 1 7200 3600 1209600 3600"),
+		test.NS("other.org. 303 IN NS ns2.example.org."),
+		test.A("www.other.org. 303 IN A 127.0.0.55"),
+	}
+	insecure := []dns.RR{
+		test.SOA("insecure.org. 303 IN SOA ns.insecure.org. hostmaster.insecure.org. 1 7200 3600 1209600 3600"),
+		test.NS("insecure.org. 303 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 303 IN A 127.0.0.4"),
+		test.A("insecure.org. 303 IN A 127.0.0.56"),
+	}
+	// Signed with a key that doesn't match the DS in org.
+	bogus := test.SignZone("bogus.org.", []dns.RR{
+		test.SOA("bogus.org. 303 IN SOA ns.bogus.org. hostmaster.bogus.org. 1 7200 3600 1209600 3600"),
+		test.NS("bogus.org. 303 IN NS ns.bogus.org."),
+		test.A("ns.bogus.org. 303 IN A 127.0.0.4"),
+		test.A("bogus.org. 303 IN A 127.0.0.57"),
+	}, bogusKey)
+
+	s := newAuthServers(t, map[string]map[string]*file.Zone{
+		"127.0.0.1": {".": zone(t, ".", root)},
+		"127.0.0.2": {"org.": zone(t, "org.", org)},
+		"127.0.0.3": {"example.org.": zone(t, "example.org.", example)},
+		"127.0.0.4": {
+			"other.org.":    zone(t, "other.org.", other),
+			"insecure.org.": zone(t, "insecure.org.", insecure),
+			"bogus.org.":    zone(t, "bogus.org.", bogus),
+		},
+	})
+	return s, rootKey
+}
+
+func newRecursor(s *authServers) *Recursor {
+	r := New([]string{"."})
+	r.root = &cut{zone: ".", addrs: []string{"127.0.0.1"}}
+	r.port = s.port
+	return r
+}
+
+func TestRecursor(t *testing.T) {
+	s, _ := hierarchy(t)
+	defer s.Close()
+	r := newRecursor(s)
+
+	tests := []test.Case{
+		{
+			Qname: "example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("example.org. 303 IN A 127.0.0.53")},
+		},
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("example.org. 303 IN A 127.0.0.53"),
+				test.CNAME("www.example.org. 303 IN CNAME example.org."),
+			},
+		},
+		{
+			// CNAME to another zone, with a name server that has no glue.
+			Qname: "alias.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("alias.example.org. 303 IN CNAME www.other.org."),
+				test.A("www.other.org. 303 IN A 127.0.0.55"),
+			},
+		},
+		{
+			Qname: "a.b.c.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("a.b.c.example.org. 303 IN A 127.0.0.54")},
+		},
+		{
+			Qname: "nope.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("example.org. 303 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600")},
+		},
+		{
+			Qname: "insecure.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("insecure.org. 303 IN A 127.0.0.56")},
+		},
+	}
+
+	ctx := context.TODO()
+	for _, tc := range tests {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Expected no error for %s, got %s", tc.Qname, err)
+			continue
+		}
+		if !rec.Msg.RecursionAvailable || rec.Msg.AuthenticatedData {
+			t.Errorf("Expected RA and no AD for %s, got %v", tc.Qname, rec.Msg)
+		}
+		test.SortAndCheck(t, rec.Msg, tc)
+	}
+}
+
+func TestRecursorDelegations(t *testing.T) {
+	s, _ := hierarchy(t)
+	defer s.Close()
+	r := newRecursor(s)
+	ctx := context.TODO()
+
+	for _, name := range []string{"example.org.", "www.example.org."} {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		if _, err := r.ServeDNS(ctx, &test.ResponseWriter{}, m); err != nil {
+			t.Fatalf("Expected no error for %s, got %s", name, err)
+		}
+		q := s.questions("127.0.0.1")
+		if name == "example.org." && len(q) == 0 {
+			t.Errorf("Expected queries to the root server for %s", name)
+		}
+		if name == "www.example.org." && len(q) != 0 {
+			t.Errorf("Expected the cached delegation to be used for %s, root got %v", name, q)
+		}
+	}
+}
+
+func TestRecursorMinimise(t *testing.T) {
+	s, _ := hierarchy(t)
+	defer s.Close()
+	ctx := context.TODO()
+
+	for _, minimise := range []bool{true, false} {
+		r := newRecursor(s)
+		r.minimise = minimise
+		m := new(dns.Msg)
+		m.SetQuestion("a.b.c.example.org.", dns.TypeA)
+		if _, err := r.ServeDNS(ctx, &test.ResponseWriter{}, m); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		for _, addr := range []string{"127.0.0.1", "127.0.0.2"} {
+			for _, q := range s.questions(addr) {
+				full := strings.EqualFold(q.Name, "a.b.c.example.org.")
+				if minimise && full {
+					t.Errorf("Expected minimised queries to %s, got %s", addr, q.Name)
+				}
+				if !minimise && !full {
+					t.Errorf("Expected full name in queries to %s, got %s", addr, q.Name)
+				}
+			}
+		}
+		s.questions("127.0.0.3")
+	}
+}
+
+func TestRecursor0x20(t *testing.T) {
+	s, _ := hierarchy(t)
+	defer s.Close()
+	r := newRecursor(s)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg.Question[0].Name != "www.example.org." || rec.Msg.Answer[0].Header().Name != "www.example.org." {
+		t.Errorf("Expected names in reply to be restored, got %v", rec.Msg)
+	}
+
+	mixed := false
+	for _, q := range s.questions("127.0.0.3") {
+		mixed = mixed || q.Name != strings.ToLower(q.Name)
+	}
+	if !mixed {
+		t.Errorf("Expected queries with randomized case")
+	}
+}
+
+func TestRecursorDNSSEC(t *testing.T) {
+	s, rootKey := hierarchy(t)
+	defer s.Close()
+	r := newRecursor(s)
+	v, err := validate.New([]dns.RR{rootKey.DS()}, r.lookup)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	r.validator = v
+
+	tests := []struct {
+		qname  string
+		do     bool
+		ad     bool
+		cd     bool
+		rcode  int
+		wantAD bool
+		sigs   bool
+	}{
+		{qname: "example.org.", do: true, wantAD: true, sigs: true},
+		{qname: "example.org."},
+		{qname: "example.org.", ad: true, wantAD: true},
+		{qname: "alias.example.org.", do: true, sigs: true}, // other.org. is not signed
+		{qname: "nope.example.org.", do: true, rcode: dns.RcodeNameError, wantAD: true, sigs: true},
+		{qname: "insecure.org.", do: true},
+		{qname: "bogus.org.", do: true, rcode: dns.RcodeServerFailure},
+		{qname: "bogus.org.", do: true, cd: true, sigs: true},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+		m.AuthenticatedData, m.CheckingDisabled = tc.ad, tc.cd
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := r.ServeDNS(ctx, rec, m)
+		if tc.rcode == dns.RcodeServerFailure {
+			if rcode != dns.RcodeServerFailure || err == nil {
+				t.Errorf("Test %d: expected SERVFAIL and an error for %s, got %d", i, tc.qname, rcode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.qname, err)
+			continue
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d for %s, got %d", i, tc.rcode, tc.qname, rec.Msg.Rcode)
+		}
+		if rec.Msg.AuthenticatedData != tc.wantAD {
+			t.Errorf("Test %d: expected AD %t for %s, got %t", i, tc.wantAD, tc.qname, rec.Msg.AuthenticatedData)
+		}
+		sigs := false
+		for _, rr := range append(rec.Msg.Answer, rec.Msg.Ns...) {
+			sigs = sigs || rr.Header().Rrtype == dns.TypeRRSIG
+		}
+		if sigs != tc.sigs {
+			t.Errorf("Test %d: expected signatures %t for %s, got %t", i, tc.sigs, tc.qname, sigs)
+		}
+	}
+}
+
+func TestRecursorServerFailure(t *testing.T) {
+	s, _ := hierarchy(t)
+	defer s.Close()
+	r := newRecursor(s)
+	// 127.0.0.4 is not a root server, it refuses our queries.
+	r.root = &cut{zone: ".", addrs: []string{"127.0.0.4", "127.0.0.1"}}
+	r.minimise = false
+
+	for i := 0; i < 5; i++ {
+		r.delegations = newDelegations(delegationCap)
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if len(rec.Msg.Answer) != 1 {
+			t.Errorf("Expected answer, got %v", rec.Msg)
+		}
+	}
+
+	r.root = &cut{zone: ".", addrs: []string{"127.0.0.4"}}
+	r.delegations = newDelegations(delegationCap)
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if rcode, err := r.ServeDNS(context.TODO(), &test.ResponseWriter{}, m); err == nil || rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL and an error, got %d", rcode)
+	}
+}
@@ -0,0 +1,239 @@
+package recursor
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// resolution is the state of resolving one query: how many queries we may still send, and how deep we
+// are in resolving the addresses of name servers that came without glue.
+type resolution struct {
+	r       *Recursor
+	queries int
+	depth   int
+}
+
+func (r *Recursor) newResolution() *resolution { return &resolution{r: r, queries: maxQueries} }
+
+// resolve resolves name and qtype, following CNAMEs to other zones. The answer section of the returned
+// message holds the CNAME chain and the answer, the authority section comes from the last response.
+func (s *resolution) resolve(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	qname := name
+	var chain []dns.RR
+	for i := 0; i <= maxCNAME; i++ {
+		m, err := s.iterate(ctx, strings.ToLower(name), qtype)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, m.Answer...)
+		target := follow(m.Answer, name, qtype)
+		if target == "" || m.Rcode != dns.RcodeSuccess {
+			m.Question = []dns.Question{{Name: qname, Qtype: qtype, Qclass: dns.ClassINET}}
+			m.Answer = chain
+			return m, nil
+		}
+		name = target
+	}
+	return nil, fmt.Errorf("more than %d CNAMEs for %s", maxCNAME, qname)
+}
+
+// iterate resolves name and qtype by following the referrals from the closest zone cut we know of,
+// until a server gives an answer. With QNAME minimisation (RFC 9156) the servers only see one more
+// label of name than they need to, until we reach the zone of name.
+func (s *resolution) iterate(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	start := name
+	if qtype == dns.TypeDS && name != "." {
+		// DS records live in the parent zone.
+		start = parent(name)
+	}
+	c := s.r.closest(start)
+	known := c.zone // the deepest name we know is in the zone of c
+	minimise := s.r.minimise
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		qname, qt := name, qtype
+		if minimise {
+			if qname = child(name, known); qname != name {
+				qt = dns.TypeA
+			}
+		}
+
+		m, err := s.query(ctx, c, qname, qt)
+		if err != nil {
+			return nil, err
+		}
+
+		if nc, ttl := referral(m, c.zone, qname); nc != nil {
+			if qtype == dns.TypeDS && nc.zone == name {
+				return nil, fmt.Errorf("referral to %s for DS query", name)
+			}
+			s.r.delegations.add(nc, ttlDuration(ttl))
+			c, known = nc, nc.zone
+			continue
+		}
+		if qname == name {
+			return m, nil
+		}
+
+		// The minimised name is not a zone cut. If it doesn't exist or is an alias, the servers may not
+		// handle minimised queries as we'd like, so ask for the full name.
+		if m.Rcode != dns.RcodeSuccess || follow(m.Answer, qname, qt) != "" {
+			minimise = false
+			continue
+		}
+		known = qname
+	}
+}
+
+// query sends the query for name and qtype to the servers of c, until one of them gives a usable reply.
+// Records in the reply that are not in the zone of c are removed.
+func (s *resolution) query(ctx context.Context, c *cut, name string, qtype uint16) (*dns.Msg, error) {
+	addrs, err := s.addrs(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fmt.Errorf("no name servers for %s", c.zone)
+	for _, i := range rand.Perm(len(addrs)) {
+		if s.queries <= 0 {
+			return nil, errBudget
+		}
+		s.queries--
+
+		m, xerr := s.r.exchange(addrs[i], name, qtype)
+		if xerr != nil {
+			err = xerr
+			continue
+		}
+		switch m.Rcode {
+		case dns.RcodeServerFailure, dns.RcodeRefused, dns.RcodeNotImplemented, dns.RcodeFormatError:
+			err = fmt.Errorf("%s from %s for %s %s", dns.RcodeToString[m.Rcode], addrs[i], name, dns.TypeToString[qtype])
+			continue
+		}
+		m.Answer = inZone(m.Answer, c.zone)
+		m.Ns = inZone(m.Ns, c.zone)
+		m.Extra = inZone(m.Extra, c.zone)
+		return m, nil
+	}
+	return nil, err
+}
+
+// addrs returns the addresses of the name servers of c. Names that came without glue are resolved, and
+// the addresses are cached with the cut.
+func (s *resolution) addrs(ctx context.Context, c *cut) ([]string, error) {
+	if len(c.addrs) > 0 {
+		return c.addrs, nil
+	}
+	if s.depth >= maxDepth {
+		return nil, fmt.Errorf("too many levels of name servers without glue for %s", c.zone)
+	}
+	s.depth++
+	defer func() { s.depth-- }()
+
+	var addrs []string
+	for _, ns := range c.ns {
+		if dns.IsSubDomain(c.zone, ns) {
+			// Needs glue, without it we can't get there.
+			continue
+		}
+		m, err := s.resolve(ctx, ns, dns.TypeA)
+		if err == errBudget {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		for _, rr := range m.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				addrs = append(addrs, a.A.String())
+			}
+		}
+		if len(addrs) > 0 {
+			break
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for the name servers of %s", c.zone)
+	}
+	s.r.delegations.add(&cut{zone: c.zone, ns: c.ns, addrs: addrs}, c.expire.Sub(s.r.delegations.now()))
+	return addrs, nil
+}
+
+// follow returns the name the CNAMEs in answer lead to from name, when answer has no records of type qtype
+// for that name. It returns the empty string if the answer is complete.
+func follow(answer []dns.RR, name string, qtype uint16) string {
+	if qtype == dns.TypeCNAME || qtype == dns.TypeANY {
+		return ""
+	}
+	followed := false
+	for i := 0; i <= len(answer); i++ {
+		target := ""
+		for _, rr := range answer {
+			h := rr.Header()
+			if !strings.EqualFold(h.Name, name) {
+				continue
+			}
+			if h.Rrtype == qtype {
+				return ""
+			}
+			if c, ok := rr.(*dns.CNAME); ok {
+				target = c.Target
+			}
+		}
+		if target == "" {
+			break
+		}
+		name, followed = target, true
+	}
+	if !followed {
+		return ""
+	}
+	return name
+}
+
+// child returns the name with one label more than ancestor, on the way to name.
+func child(name, ancestor string) string {
+	labels := dns.Split(name)
+	n := dns.CountLabel(ancestor) + 1
+	if n >= len(labels) {
+		return name
+	}
+	return name[labels[len(labels)-n]:]
+}
+
+// parent returns the parent of name, name must not be the root.
+func parent(name string) string {
+	i, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[i:]
+}
+
+// inZone returns the records of rrs that are in zone.
+func inZone(rrs []dns.RR, zone string) []dns.RR {
+	in := rrs[:0]
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeOPT || dns.IsSubDomain(zone, rr.Header().Name) {
+			in = append(in, rr)
+		}
+	}
+	return in
+}
+
+var errBudget = errors.New("too many queries")
+
+const (
+	maxQueries = 100 // maximum number of queries we send to resolve one query.
+	maxCNAME   = 8   // maximum length of a CNAME chain.
+	maxDepth   = 4   // maximum nesting of resolving name servers without glue.
+)
@@ -0,0 +1,133 @@
+package recursor
+
+import (
+	"os"
+	"path"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/validate"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func init() {
+	caddy.RegisterPlugin("recursor", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	r, err := recursorParse(c)
+	if err != nil {
+		return plugin.Error("recursor", err)
+	}
+
+	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, QueryCount, ValidationCount) })
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		r.Next = next
+		return r
+	})
+
+	return nil
+}
+
+func recursorParse(c *caddy.Controller) (*Recursor, error) {
+	var (
+		r       *Recursor
+		anchors []dns.RR
+	)
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		zones := make([]string, len(c.ServerBlockKeys))
+		copy(zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			zones = args
+		}
+		for j := range zones {
+			zones[j] = plugin.Host(zones[j]).Normalize()
+		}
+		r = New(zones)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "roothints":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				f, err := os.Open(configPath(config, args[0]))
+				if err != nil {
+					return nil, err
+				}
+				hints, err := parseHints(f, args[0])
+				f.Close()
+				if err != nil {
+					return nil, err
+				}
+				r.root = &cut{zone: ".", addrs: hints}
+			case "dnssec":
+				args := c.RemainingArgs()
+				switch len(args) {
+				case 0:
+					anchors = validate.RootAnchors()
+				case 1:
+					f, err := os.Open(configPath(config, args[0]))
+					if err != nil {
+						return nil, err
+					}
+					anchors, err = validate.ParseAnchors(f, args[0])
+					f.Close()
+					if err != nil {
+						return nil, err
+					}
+				default:
+					return nil, c.ArgErr()
+				}
+			case "no_qname_minimization":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				r.minimise = false
+			case "no_0x20":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				r.use0x20 = false
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if anchors != nil {
+		v, err := validate.New(anchors, r.lookup)
+		if err != nil {
+			return nil, err
+		}
+		r.validator = v
+	}
+	return r, nil
+}
+
+// configPath returns p, with the root from config prepended if p is relative.
+func configPath(config *dnsserver.Config, p string) string {
+	if !path.IsAbs(p) && config.Root != "" {
+		p = path.Join(config.Root, p)
+	}
+	return p
+}
@@ -0,0 +1,106 @@
+package recursor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+const hints = `.                        3600000      NS    A.ROOT-SERVERS.NET.
+A.ROOT-SERVERS.NET.      3600000      A     198.41.0.4
+A.ROOT-SERVERS.NET.      3600000      AAAA  2001:503:ba3e::2:30
+; not a root server
+ns.example.org.          3600000      A     127.0.0.1
+`
+
+func TestSetupRecursor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hintsFile := filepath.Join(dir, "named.root")
+	anchorFile := filepath.Join(dir, "anchor")
+	ioutil.WriteFile(hintsFile, []byte(hints), 0644)
+	ioutil.WriteFile(anchorFile, []byte(". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D\n"), 0644)
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+		hints     []string
+		dnssec    bool
+		minimise  bool
+		use0x20   bool
+	}{
+		// positive
+		{`recursor`, false, []string{}, rootServers, false, true, true},
+		{`recursor example.org`, false, []string{"example.org."}, rootServers, false, true, true},
+		{`recursor {
+			roothints ` + hintsFile + `
+			dnssec
+		}`, false, []string{}, []string{"198.41.0.4", "2001:503:ba3e::2:30"}, true, true, true},
+		{`recursor {
+			dnssec ` + anchorFile + `
+			no_qname_minimization
+			no_0x20
+		}`, false, []string{}, rootServers, true, false, false},
+		// negative
+		{`recursor {
+			roothints
+		}`, true, nil, nil, false, false, false},
+		{`recursor {
+			roothints /does/not/exist
+		}`, true, nil, nil, false, false, false},
+		{`recursor {
+			roothints ` + anchorFile + `
+		}`, true, nil, nil, false, false, false},
+		{`recursor {
+			dnssec ` + hintsFile + `
+		}`, true, nil, nil, false, false, false},
+		{`recursor {
+			dnssec a b
+		}`, true, nil, nil, false, false, false},
+		{`recursor {
+			no_0x20 yes
+		}`, true, nil, nil, false, false, false},
+		{`recursor {
+			forward 8.8.8.8
+		}`, true, nil, nil, false, false, false},
+		{`recursor
+		recursor`, true, nil, nil, false, false, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		r, err := recursorParse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if strings.Join(r.Zones, ",") != strings.Join(test.zones, ",") {
+			t.Errorf("Test %d: expected zones %v, got %v", i, test.zones, r.Zones)
+		}
+		if strings.Join(r.root.addrs, ",") != strings.Join(test.hints, ",") {
+			t.Errorf("Test %d: expected root hints %v, got %v", i, test.hints, r.root.addrs)
+		}
+		if (r.validator != nil) != test.dnssec {
+			t.Errorf("Test %d: expected dnssec %t", i, test.dnssec)
+		}
+		if r.minimise != test.minimise || r.use0x20 != test.use0x20 {
+			t.Errorf("Test %d: expected minimise %t and 0x20 %t, got %t and %t", i, test.minimise, test.use0x20, r.minimise, r.use0x20)
+		}
+	}
+}
@@ -135,10 +135,16 @@ func newEdns0Rule(mode string, args ...string) (Rule, error) {
 	case Append:
 	case Replace:
 	case Set:
+	case Unset:
 	default:
 		return nil, fmt.Errorf("invalid action: %q", action)
 	}
 
+	// unset only needs to know what to remove.
+	if action == Unset {
+		return newEdns0UnsetRule(mode, ruleType, args[2:]...)
+	}
+
 	switch ruleType {
 	case "local":
 		if len(args) != 4 {
@@ -439,11 +445,75 @@ func (rule *edns0SubnetRule) GetResponseRule() ResponseRule {
 	return ResponseRule{}
 }
 
+// edns0UnsetRule is a rewrite rule that removes EDNS0 options.
+type edns0UnsetRule struct {
+	mode string
+	code uint16
+}
+
+func newEdns0UnsetRule(mode, ruleType string, args ...string) (*edns0UnsetRule, error) {
+	switch ruleType {
+	case "local":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("EDNS0 local unset rules require exactly one arg")
+		}
+		c, err := strconv.ParseUint(args[0], 0, 16)
+		if err != nil {
+			return nil, err
+		}
+		return &edns0UnsetRule{mode: mode, code: uint16(c)}, nil
+	case "nsid":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("EDNS0 NSID rules do not accept args")
+		}
+		return &edns0UnsetRule{mode: mode, code: dns.EDNS0NSID}, nil
+	case "subnet":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("EDNS0 subnet unset rules do not accept args")
+		}
+		return &edns0UnsetRule{mode: mode, code: dns.EDNS0SUBNET}, nil
+	}
+	return nil, fmt.Errorf("invalid rule type %q", ruleType)
+}
+
+// Rewrite will remove the EDNS0 options with rule's code from the request.
+func (rule *edns0UnsetRule) Rewrite(w dns.ResponseWriter, r *dns.Msg) Result {
+	o := r.IsEdns0()
+	if o == nil {
+		return RewriteIgnored
+	}
+
+	result := RewriteIgnored
+	j := 0
+	for _, s := range o.Option {
+		if s.Option() == rule.code {
+			result = RewriteDone
+			continue
+		}
+		o.Option[j] = s
+		j++
+	}
+	o.Option = o.Option[:j]
+
+	return result
+}
+
+// Mode returns the processing mode
+func (rule *edns0UnsetRule) Mode() string {
+	return rule.mode
+}
+
+// GetResponseRule return a rule to rewrite the response with. Currently not implemented.
+func (rule *edns0UnsetRule) GetResponseRule() ResponseRule {
+	return ResponseRule{}
+}
+
 // These are all defined actions.
 const (
 	Replace = "replace"
 	Set     = "set"
 	Append  = "append"
+	Unset   = "unset"
 )
 
 // Supported local EDNS0 variables
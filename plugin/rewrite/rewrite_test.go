@@ -102,6 +102,11 @@ func TestNewRule(t *testing.T) {
 		{[]string{"edns0", "subnet", "set", "24", "56"}, false, reflect.TypeOf(&edns0SubnetRule{})},
 		{[]string{"edns0", "subnet", "append", "24", "56"}, false, reflect.TypeOf(&edns0SubnetRule{})},
 		{[]string{"edns0", "subnet", "replace", "24", "56"}, false, reflect.TypeOf(&edns0SubnetRule{})},
+		{[]string{"edns0", "subnet", "unset"}, false, reflect.TypeOf(&edns0UnsetRule{})},
+		{[]string{"edns0", "subnet", "unset", "24"}, true, nil},
+		{[]string{"edns0", "local", "unset", "0xffee"}, false, reflect.TypeOf(&edns0UnsetRule{})},
+		{[]string{"edns0", "local", "unset"}, true, nil},
+		{[]string{"edns0", "nsid", "unset"}, false, reflect.TypeOf(&edns0UnsetRule{})},
 		{[]string{"unknown-action", "name", "a.com", "b.com"}, true, nil},
 		{[]string{"stop", "name", "a.com", "b.com"}, false, reflect.TypeOf(&nameRule{})},
 		{[]string{"continue", "name", "a.com", "b.com"}, false, reflect.TypeOf(&nameRule{})},
@@ -646,3 +651,62 @@ func TestRewriteEDNS0Subnet(t *testing.T) {
 		}
 	}
 }
+
+func TestRewriteEDNS0Unset(t *testing.T) {
+	rw := Rewrite{
+		Next:     plugin.HandlerFunc(msgPrinter),
+		noRevert: true,
+	}
+
+	tests := []struct {
+		fromOpts []dns.EDNS0
+		args     []string
+		toOpts   []dns.EDNS0
+	}{
+		{
+			[]dns.EDNS0{&dns.EDNS0_SUBNET{Code: 0x8, Family: 0x1, SourceNetmask: 0x18, Address: []byte{0x0A, 0xF0, 0x00, 0x00}}},
+			[]string{"subnet", "unset"},
+			[]dns.EDNS0{},
+		},
+		{
+			[]dns.EDNS0{
+				&dns.EDNS0_SUBNET{Code: 0x8, Family: 0x1, SourceNetmask: 0x18, Address: []byte{0x0A, 0xF0, 0x00, 0x00}},
+				&dns.EDNS0_LOCAL{Code: 0xffee, Data: []byte{0xab, 0xcd}},
+			},
+			[]string{"local", "unset", "0xffee"},
+			[]dns.EDNS0{&dns.EDNS0_SUBNET{Code: 0x8, Family: 0x1, SourceNetmask: 0x18, Address: []byte{0x0A, 0xF0, 0x00, 0x00}}},
+		},
+		{
+			[]dns.EDNS0{&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: ""}},
+			[]string{"subnet", "unset"},
+			[]dns.EDNS0{&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: ""}},
+		},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		o := m.IsEdns0()
+		o.Option = append(o.Option, tc.fromOpts...)
+
+		r, err := newEdns0Rule("stop", tc.args...)
+		if err != nil {
+			t.Errorf("Error creating test rule: %s", err)
+			continue
+		}
+		rw.Rules = []Rule{r}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rw.ServeDNS(ctx, rec, m)
+
+		o = rec.Msg.IsEdns0()
+		if o == nil {
+			t.Errorf("Test %d: EDNS0 options not set", i)
+			continue
+		}
+		if !optsEqual(o.Option, tc.toOpts) {
+			t.Errorf("Test %d: Expected %v but got %v", i, tc.toOpts, o)
+		}
+	}
+}
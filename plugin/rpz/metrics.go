@@ -0,0 +1,19 @@
+package rpz
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HitCount is the counter of policy hits.
+var HitCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "rpz",
+	Name:      "hits_total",
+	Help:      "Counter of queries that hit a policy, per policy zone, trigger and action.",
+}, []string{"zone", "trigger", "action"})
+
+var once sync.Once
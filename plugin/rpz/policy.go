@@ -0,0 +1,302 @@
+package rpz
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/miekg/dns"
+)
+
+// Triggers as defined in the RPZ draft (draft-vixie-dnsop-dns-rpz).
+const (
+	triggerClientIP   = "client-ip"
+	triggerQName      = "qname"
+	triggerResponseIP = "response-ip"
+	triggerNSDName    = "nsdname"
+)
+
+// Name suffixes that select a trigger other than QNAME.
+const (
+	suffixClientIP = ".rpz-client-ip"
+	suffixIP       = ".rpz-ip"
+	suffixNSDName  = ".rpz-nsdname"
+	suffixNSIP     = ".rpz-nsip"
+)
+
+type action int
+
+const (
+	actionNXDomain action = iota
+	actionNoData
+	actionPassthru
+	actionDrop
+	actionTCPOnly
+	actionLocalData
+)
+
+func (a action) String() string {
+	switch a {
+	case actionNXDomain:
+		return "nxdomain"
+	case actionNoData:
+		return "nodata"
+	case actionPassthru:
+		return "passthru"
+	case actionDrop:
+		return "drop"
+	case actionTCPOnly:
+		return "tcp-only"
+	case actionLocalData:
+		return "local-data"
+	}
+	return ""
+}
+
+// rule is a trigger from a policy zone, together with the action to take when it matches.
+type rule struct {
+	trigger string
+	owner   string // owner name of the rule in the policy zone
+	action  action
+	data    []dns.RR // records for local data, their owner is rewritten when used
+}
+
+type ipRule struct {
+	net  *net.IPNet
+	rule *rule
+}
+
+// index holds the rules of a policy zone, sorted by trigger.
+type index struct {
+	qname      map[string]*rule // exact names
+	wildcard   map[string]*rule // *.name, keyed by name
+	clientIP   []ipRule
+	responseIP []ipRule
+	nsdname    map[string]*rule
+	nsWildcard map[string]*rule
+	soa        []dns.RR // SOA of the policy zone, for in the authority section of negative answers
+}
+
+// post returns true if the index has rules that can only be checked with the response in hand.
+func (i *index) post() bool {
+	return len(i.responseIP) > 0 || len(i.nsdname) > 0 || len(i.nsWildcard) > 0
+}
+
+// policy is a response policy zone. The zone is loaded and kept up to date by the file plugin, the
+// index of rules is rebuilt after every reload or transfer of the zone.
+type policy struct {
+	zone   *file.Zone
+	origin string
+
+	mu  sync.Mutex   // serializes updates
+	idx atomic.Value // *index
+}
+
+func newPolicy(z *file.Zone, origin string) *policy {
+	p := &policy{zone: z, origin: origin}
+	p.update(z)
+	z.OnTransfer = p.update
+	return p
+}
+
+// index returns the current index of p.
+func (p *policy) index() *index { return p.idx.Load().(*index) }
+
+// update rebuilds the index from the records of z.
+func (p *policy) update(z *file.Zone) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	idx := newIndex()
+	if z.SOASerialIfDefined() != -1 {
+		idx = buildIndex(z.All(), p.origin)
+	}
+	p.idx.Store(idx)
+}
+
+func newIndex() *index {
+	return &index{
+		qname:      map[string]*rule{},
+		wildcard:   map[string]*rule{},
+		nsdname:    map[string]*rule{},
+		nsWildcard: map[string]*rule{},
+	}
+}
+
+// buildIndex returns the index of the rules in rrs, which are the records of the policy zone origin.
+func buildIndex(rrs []dns.RR, origin string) *index {
+	idx := newIndex()
+	rules := map[string]*rule{}
+	owners := []string{}
+	for _, rr := range rrs {
+		switch rr.Header().Rrtype {
+		case dns.TypeSOA:
+			idx.soa = []dns.RR{rr}
+			continue
+		case dns.TypeNS, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeDNSKEY:
+			continue
+		}
+		owner := strings.ToLower(rr.Header().Name)
+		if owner == origin || !dns.IsSubDomain(origin, owner) {
+			continue
+		}
+		r, ok := rules[owner]
+		if !ok {
+			r = &rule{owner: owner, action: actionLocalData}
+			rules[owner] = r
+			owners = append(owners, owner)
+		}
+		r.data = append(r.data, rr)
+	}
+
+	for _, owner := range owners {
+		r := rules[owner]
+		r.action = ruleAction(r.data)
+		if r.action != actionLocalData {
+			r.data = nil
+		}
+
+		name := strings.TrimSuffix(owner, "."+origin)
+		if origin == "." {
+			name = strings.TrimSuffix(owner, ".")
+		}
+		switch {
+		case strings.HasSuffix(name, suffixClientIP):
+			n, err := decodeIP(strings.TrimSuffix(name, suffixClientIP))
+			if err != nil {
+				continue
+			}
+			r.trigger = triggerClientIP
+			idx.clientIP = append(idx.clientIP, ipRule{n, r})
+		case strings.HasSuffix(name, suffixIP):
+			n, err := decodeIP(strings.TrimSuffix(name, suffixIP))
+			if err != nil {
+				continue
+			}
+			r.trigger = triggerResponseIP
+			idx.responseIP = append(idx.responseIP, ipRule{n, r})
+		case strings.HasSuffix(name, suffixNSDName):
+			r.trigger = triggerNSDName
+			add(idx.nsdname, idx.nsWildcard, strings.TrimSuffix(name, suffixNSDName)+".", r)
+		case strings.HasSuffix(name, suffixNSIP):
+			// NSIP triggers are not supported.
+		default:
+			r.trigger = triggerQName
+			add(idx.qname, idx.wildcard, name+".", r)
+		}
+	}
+	return idx
+}
+
+// add adds r for name to exact, or to wildcard if name is a wildcard.
+func add(exact, wildcard map[string]*rule, name string, r *rule) {
+	if strings.HasPrefix(name, "*.") {
+		wildcard[name[2:]] = r
+		return
+	}
+	exact[name] = r
+}
+
+// ruleAction returns the action the records of a rule encode.
+func ruleAction(rrs []dns.RR) action {
+	if len(rrs) != 1 {
+		return actionLocalData
+	}
+	c, ok := rrs[0].(*dns.CNAME)
+	if !ok {
+		return actionLocalData
+	}
+	switch strings.ToLower(c.Target) {
+	case ".":
+		return actionNXDomain
+	case "*.":
+		return actionNoData
+	case "rpz-passthru.":
+		return actionPassthru
+	case "rpz-drop.":
+		return actionDrop
+	case "rpz-tcp-only.":
+		return actionTCPOnly
+	}
+	return actionLocalData
+}
+
+// decodeIP decodes the prefix length and reversed address of an IP trigger: 32.1.2.0.192 is 192.0.2.1/32,
+// and 48.zz.2.2001 is 2001:2::/48.
+func decodeIP(s string) (*net.IPNet, error) {
+	labels := strings.Split(s, ".")
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("no address in %q", s)
+	}
+	bits, err := strconv.Atoi(labels[0])
+	if err != nil {
+		return nil, fmt.Errorf("bad prefix length in %q", s)
+	}
+	addr := labels[1:]
+	for i, j := 0, len(addr)-1; i < j; i, j = i+1, j-1 {
+		addr[i], addr[j] = addr[j], addr[i]
+	}
+
+	size := 32
+	ip := net.ParseIP(strings.Join(addr, ".")).To4()
+	if ip == nil {
+		size = 128
+		a := strings.Replace(strings.Join(addr, ":"), "zz", "", 1)
+		if strings.HasPrefix(a, ":") {
+			a = ":" + a
+		}
+		if strings.HasSuffix(a, ":") {
+			a += ":"
+		}
+		ip = net.ParseIP(a)
+	}
+	if ip == nil || bits < 0 || bits > size {
+		return nil, fmt.Errorf("bad address in %q", s)
+	}
+	mask := net.CIDRMask(bits, size)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// matchName returns the rule for name in exact or wildcard: an exact match wins, otherwise the
+// longest matching wildcard.
+func matchName(exact, wildcard map[string]*rule, name string) *rule {
+	name = strings.ToLower(name)
+	if r, ok := exact[name]; ok {
+		return r
+	}
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		if r, ok := wildcard[name[off:]]; ok {
+			return r
+		}
+	}
+	if r, ok := wildcard["."]; ok && name != "." {
+		return r
+	}
+	return nil
+}
+
+// matchIP returns the rule with the longest prefix in rules that contains one of ips.
+func matchIP(rules []ipRule, ips ...net.IP) *rule {
+	var (
+		best *rule
+		bits = -1
+	)
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		for _, r := range rules {
+			if len(r.net.IP) != len(ip) || !r.net.Contains(ip) {
+				continue
+			}
+			if ones, _ := r.net.Mask.Size(); ones > bits {
+				best, bits = r.rule, ones
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,252 @@
+// Package rpz implements response policy zones.
+package rpz
+
+import (
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// RPZ applies the policies from response policy zones to the queries it sees.
+type RPZ struct {
+	Next     plugin.Handler
+	Zones    []string
+	policies []*policy
+}
+
+// hit is a rule that matched, in a policy.
+type hit struct {
+	p *policy
+	r *rule
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rp *RPZ) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(rp.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+	}
+
+	// Triggers that need only the query: client-IP and QNAME.
+	var (
+		pre  *hit
+		post []*policy // policies before pre that have triggers on the response
+	)
+	ip := net.ParseIP(state.IP())
+	for _, p := range rp.policies {
+		idx := p.index()
+		if rl := matchIP(idx.clientIP, ip); rl != nil {
+			pre = &hit{p, rl}
+			break
+		}
+		if rl := matchName(idx.qname, idx.wildcard, state.Name()); rl != nil {
+			pre = &hit{p, rl}
+			break
+		}
+		if idx.post() {
+			post = append(post, p)
+		}
+	}
+
+	if len(post) == 0 {
+		if pre == nil {
+			return plugin.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+		}
+		return rp.apply(ctx, w, r, pre, nil)
+	}
+
+	// Triggers that need the response: response-IP and NSDNAME.
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(rp.Name(), rp.Next, ctx, nw, r)
+	if nw.Msg == nil {
+		return rcode, err
+	}
+	if h := rp.postMatch(ctx, w, nw.Msg, post); h != nil {
+		return rp.apply(ctx, w, r, h, nw.Msg)
+	}
+	if pre != nil {
+		return rp.apply(ctx, w, r, pre, nw.Msg)
+	}
+	w.WriteMsg(nw.Msg)
+	return dns.RcodeSuccess, err
+}
+
+// postMatch returns the first hit for the response-IP and NSDNAME triggers of policies on the response m.
+func (rp *RPZ) postMatch(ctx context.Context, w dns.ResponseWriter, m *dns.Msg, policies []*policy) *hit {
+	var ips []net.IP
+	for _, rr := range m.Answer {
+		switch a := rr.(type) {
+		case *dns.A:
+			ips = append(ips, a.A)
+		case *dns.AAAA:
+			ips = append(ips, a.AAAA)
+		}
+	}
+
+	var ns []string
+	resolved := false
+	for _, p := range policies {
+		idx := p.index()
+		if rl := matchIP(idx.responseIP, ips...); rl != nil {
+			return &hit{p, rl}
+		}
+		if len(idx.nsdname) == 0 && len(idx.nsWildcard) == 0 {
+			continue
+		}
+		if !resolved {
+			ns, resolved = rp.nameservers(ctx, w, m), true
+		}
+		for _, n := range ns {
+			if rl := matchName(idx.nsdname, idx.nsWildcard, n); rl != nil {
+				return &hit{p, rl}
+			}
+		}
+	}
+	return nil
+}
+
+// nameservers returns the names of the name servers of the zone m is an answer from. They are taken
+// from m if it has them, otherwise we look for them with NS queries.
+func (rp *RPZ) nameservers(ctx context.Context, w dns.ResponseWriter, m *dns.Msg) []string {
+	asked := m.Question[0]
+	name := asked.Name
+	for i := 0; i < maxNSLookups; i++ {
+		if ns := nsNames(m.Answer, name); len(ns) > 0 {
+			return ns
+		}
+		if ns := nsNames(m.Ns, ""); len(ns) > 0 {
+			return ns
+		}
+		zone := ""
+		for _, rr := range m.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				zone = soa.Hdr.Name
+			}
+		}
+		switch {
+		case zone != "" && !strings.EqualFold(zone, name):
+			name = zone
+		case asked.Qtype != dns.TypeNS:
+			// Ask for the NS records of name itself.
+		case name == ".":
+			return nil
+		default:
+			i, _ := dns.NextLabel(name, 0)
+			name = name[i:]
+		}
+		if m = rp.lookup(ctx, w, name, dns.TypeNS); m == nil {
+			return nil
+		}
+		asked = m.Question[0]
+	}
+	return nil
+}
+
+// nsNames returns the targets of the NS records in rrs, owned by name if it isn't empty.
+func nsNames(rrs []dns.RR, name string) []string {
+	var ns []string
+	for _, rr := range rrs {
+		if n, ok := rr.(*dns.NS); ok && (name == "" || strings.EqualFold(n.Hdr.Name, name)) {
+			ns = append(ns, n.Ns)
+		}
+	}
+	return ns
+}
+
+// lookup sends a query for name and qtype down the plugin chain and returns the reply.
+func (rp *RPZ) lookup(ctx context.Context, w dns.ResponseWriter, name string, qtype uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	nw := nonwriter.New(w)
+	plugin.NextOrFailure(rp.Name(), rp.Next, ctx, nw, m)
+	return nw.Msg
+}
+
+// apply takes the action of the rule in h for the query r. Resp is the response from the rest of the
+// chain, or nil if we haven't asked for it.
+func (rp *RPZ) apply(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, h *hit, resp *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	log.Infof("RPZ %s hit for %s %s from %s: %s trigger %s, %s", h.p.origin, state.Name(), state.Type(), state.IP(), h.r.trigger, h.r.owner, h.r.action)
+	HitCount.WithLabelValues(h.p.origin, h.r.trigger, h.r.action.String()).Inc()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.RecursionAvailable = true
+
+	switch h.r.action {
+	case actionDrop:
+		return 0, nil
+	case actionPassthru:
+		return rp.passthru(ctx, w, r, resp)
+	case actionTCPOnly:
+		if state.Proto() == "tcp" {
+			return rp.passthru(ctx, w, r, resp)
+		}
+		m.Truncated = true
+	case actionNXDomain:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = h.p.index().soa
+	case actionNoData:
+		m.Ns = h.p.index().soa
+	case actionLocalData:
+		m.Answer = rp.localData(ctx, w, state, h.r.data)
+		if len(m.Answer) == 0 {
+			m.Ns = h.p.index().soa
+		}
+	}
+
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// localData returns the records from data that answer the query, with their owner set to the query
+// name. A CNAME in data is followed through the rest of the chain.
+func (rp *RPZ) localData(ctx context.Context, w dns.ResponseWriter, state request.Request, data []dns.RR) []dns.RR {
+	qname, qtype := state.Name(), state.QType()
+	var answer []dns.RR
+	for _, rr := range data {
+		rtype := rr.Header().Rrtype
+		if rtype != qtype && rtype != dns.TypeCNAME && qtype != dns.TypeANY {
+			continue
+		}
+		rr = dns.Copy(rr)
+		rr.Header().Name = qname
+		if c, ok := rr.(*dns.CNAME); ok {
+			if strings.HasPrefix(c.Target, "*.") {
+				// CNAME *.example.net. rewrites the query name to qname.example.net.
+				c.Target = qname + c.Target[2:]
+			}
+			if qtype != dns.TypeCNAME && qtype != dns.TypeANY {
+				if m := rp.lookup(ctx, w, c.Target, qtype); m != nil {
+					return append([]dns.RR{c}, m.Answer...)
+				}
+				return []dns.RR{c}
+			}
+		}
+		answer = append(answer, rr)
+	}
+	return answer
+}
+
+// passthru answers r with resp, or with the answer from the rest of the chain if resp is nil.
+func (rp *RPZ) passthru(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, resp *dns.Msg) (int, error) {
+	if resp == nil {
+		return plugin.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+	}
+	w.WriteMsg(resp)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the Handler interface.
+func (rp *RPZ) Name() string { return "rpz" }
+
+const maxNSLookups = 8 // maximum number of NS queries to find the name servers for an NSDNAME trigger.
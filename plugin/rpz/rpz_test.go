@@ -0,0 +1,234 @@
+package rpz
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+const policyZone = `$ORIGIN rpz.example.
+$TTL 3600
+@                    IN SOA localhost. hostmaster.rpz.example. 1 3600 600 86400 60
+@                    IN NS  localhost.
+blocked.example.org  IN CNAME .
+nodata.example.org   IN CNAME *.
+*.wild.example.org   IN CNAME .
+drop.example.org     IN CNAME rpz-drop.
+tcp.example.org      IN CNAME rpz-tcp-only.
+local.example.org    IN A     127.0.0.2
+local.example.org    IN TXT   "blocked by policy"
+alias.example.org    IN CNAME www.example.org.
+ok.example.org       IN CNAME rpz-passthru.
+64.zz.fe80.rpz-client-ip IN CNAME .
+24.0.100.51.198.rpz-ip   IN CNAME .
+ns.evil.net.rpz-nsdname  IN CNAME .
+`
+
+// second is a policy zone of lower precedence, its ok.example.org rule loses from the passthru above.
+const second = `$ORIGIN rpz2.example.
+$TTL 3600
+@                    IN SOA localhost. hostmaster.rpz2.example. 1 3600 600 86400 60
+ok.example.org       IN CNAME .
+www.example.org      IN A     127.0.0.3
+`
+
+const exampleOrg = `$ORIGIN example.org.
+$TTL 3600
+@                    IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 60
+@                    IN NS  ns.example.org.
+ns                   IN A   192.0.2.53
+www                  IN A   192.0.2.1
+ok                   IN A   192.0.2.2
+bad-ip               IN A   198.51.100.7
+blocked              IN A   192.0.2.4
+`
+
+const evilOrg = `$ORIGIN evil.org.
+$TTL 3600
+@                    IN SOA ns.evil.net. hostmaster.evil.org. 1 3600 600 86400 60
+@                    IN NS  ns.evil.net.
+www                  IN A   192.0.2.5
+`
+
+func parse(t *testing.T, zone, origin string) *file.Zone {
+	z, err := file.Parse(strings.NewReader(zone), origin, "stdin", 0)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %s", origin, err)
+	}
+	return z
+}
+
+func newRPZ(t *testing.T) *RPZ {
+	return &RPZ{
+		Zones: []string{"."},
+		Next: file.File{Zones: file.Zones{
+			Z: map[string]*file.Zone{
+				"example.org.": parse(t, exampleOrg, "example.org."),
+				"evil.org.":    parse(t, evilOrg, "evil.org."),
+			},
+			Names: []string{"example.org.", "evil.org."},
+		}},
+		policies: []*policy{
+			newPolicy(parse(t, policyZone, "rpz.example."), "rpz.example."),
+			newPolicy(parse(t, second, "rpz2.example."), "rpz2.example."),
+		},
+	}
+}
+
+// tcpWriter is a test.ResponseWriter for a client that uses TCP.
+type tcpWriter struct{ test.ResponseWriter }
+
+func (t *tcpWriter) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("10.240.0.1"), Port: 40212}
+}
+
+func TestRPZ(t *testing.T) {
+	rp := newRPZ(t)
+
+	tests := []struct {
+		qname   string
+		qtype   uint16
+		w       dns.ResponseWriter
+		rcode   int
+		answer  []string
+		soa     bool // SOA of the policy zone in the authority section
+		dropped bool
+		tc      bool
+	}{
+		// No policy.
+		{qname: "ns.example.org.", qtype: dns.TypeA, answer: []string{"ns.example.org.\t3600\tIN\tA\t192.0.2.53"}},
+		// QNAME triggers.
+		{qname: "blocked.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{qname: "nodata.example.org.", qtype: dns.TypeA, soa: true},
+		{qname: "a.b.wild.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{qname: "drop.example.org.", qtype: dns.TypeA, dropped: true},
+		{qname: "tcp.example.org.", qtype: dns.TypeA, tc: true},
+		{qname: "tcp.example.org.", qtype: dns.TypeA, w: &tcpWriter{}, rcode: dns.RcodeNameError},
+		{qname: "local.example.org.", qtype: dns.TypeA, answer: []string{"local.example.org.\t3600\tIN\tA\t127.0.0.2"}},
+		{qname: "local.example.org.", qtype: dns.TypeAAAA, soa: true},
+		{qname: "alias.example.org.", qtype: dns.TypeA, answer: []string{
+			"alias.example.org.\t3600\tIN\tCNAME\twww.example.org.",
+			"www.example.org.\t3600\tIN\tA\t192.0.2.1",
+		}},
+		// The first policy zone wins, even if its action is passthru.
+		{qname: "ok.example.org.", qtype: dns.TypeA, answer: []string{"ok.example.org.\t3600\tIN\tA\t192.0.2.2"}},
+		// Response-IP trigger.
+		{qname: "bad-ip.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		// NSDNAME trigger, from a policy zone before the QNAME trigger of the second.
+		{qname: "www.evil.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError, soa: true},
+		{qname: "www.example.org.", qtype: dns.TypeA, answer: []string{"www.example.org.\t3600\tIN\tA\t127.0.0.3"}},
+		// Client-IP trigger.
+		{qname: "www.example.org.", qtype: dns.TypeA, w: &test.ResponseWriter6{}, rcode: dns.RcodeNameError, soa: true},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		w := tc.w
+		if w == nil {
+			w = &test.ResponseWriter{}
+		}
+		rec := dnstest.NewRecorder(w)
+		if _, err := rp.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if tc.dropped {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected %s to be dropped, got %v", i, tc.qname, rec.Msg)
+			}
+			continue
+		}
+		if rec.Msg == nil {
+			t.Errorf("Test %d: expected a reply for %s", i, tc.qname)
+			continue
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d for %s, got %d", i, tc.rcode, tc.qname, rec.Msg.Rcode)
+		}
+		if rec.Msg.Truncated != tc.tc {
+			t.Errorf("Test %d: expected TC %t for %s", i, tc.tc, tc.qname)
+		}
+		if tc.answer != nil {
+			if len(rec.Msg.Answer) != len(tc.answer) {
+				t.Errorf("Test %d: expected %d answers for %s, got %v", i, len(tc.answer), tc.qname, rec.Msg.Answer)
+				continue
+			}
+			for j, rr := range rec.Msg.Answer {
+				if rr.String() != tc.answer[j] {
+					t.Errorf("Test %d: expected answer %q, got %q", i, tc.answer[j], rr.String())
+				}
+			}
+		}
+		soa := len(rec.Msg.Ns) == 1 && rec.Msg.Ns[0].Header().Name == "rpz.example."
+		if soa != tc.soa {
+			t.Errorf("Test %d: expected policy SOA %t for %s, got %v", i, tc.soa, tc.qname, rec.Msg.Ns)
+		}
+	}
+}
+
+func TestPolicyUpdate(t *testing.T) {
+	z := parse(t, second, "rpz2.example.")
+	p := newPolicy(z, "rpz2.example.")
+	idx := p.index()
+	if _, ok := idx.qname["ok.example.org."]; !ok {
+		t.Fatalf("Expected rule for ok.example.org.")
+	}
+	if p.index() != idx {
+		t.Errorf("Expected the index not to be rebuilt without a change")
+	}
+
+	// A reload or transfer of the zone rebuilds the index.
+	z1 := parse(t, strings.Replace(second, "ok.example.org", "new.example.org", 1), "rpz2.example.")
+	z.Apex, z.Tree = z1.Apex, z1.Tree
+	z.OnTransfer(z)
+	idx = p.index()
+	if _, ok := idx.qname["new.example.org."]; !ok {
+		t.Errorf("Expected rule for new.example.org.")
+	}
+	if _, ok := idx.qname["ok.example.org."]; ok {
+		t.Errorf("Expected no rule for ok.example.org.")
+	}
+
+	// An empty secondary zone has an empty index.
+	if p := newPolicy(file.NewZone("rpz3.example.", "stdin"), "rpz3.example."); len(p.index().qname) != 0 {
+		t.Errorf("Expected an empty index")
+	}
+}
+
+func TestDecodeIP(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"32.1.2.0.192", "192.0.2.1/32"},
+		{"24.0.2.0.192", "192.0.2.0/24"},
+		{"128.1.zz.db8.2001", "2001:db8::1/128"},
+		{"48.zz.db8.2001", "2001:db8::/48"},
+		{"128.1.zz", "::1/128"},
+		{"64.zz.1", "1::/64"},
+		{"33.1.2.0.192", ""},
+		{"x.1.2.0.192", ""},
+		{"32", ""},
+	}
+	for _, tc := range tests {
+		n, err := decodeIP(tc.in)
+		if tc.out == "" {
+			if err == nil {
+				t.Errorf("Expected error for %s, got %s", tc.in, n)
+			}
+			continue
+		}
+		if err != nil || n.String() != tc.out {
+			t.Errorf("Expected %s for %s, got %s (%v)", tc.out, tc.in, n, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package rpz
+
+import (
+	"os"
+	"path"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+
+	"github.com/mholt/caddy"
+)
+
+func init() {
+	caddy.RegisterPlugin("rpz", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	rp, err := rpzParse(c)
+	if err != nil {
+		return plugin.Error("rpz", err)
+	}
+
+	// Load the policy zones and keep them up to date.
+	for _, p := range rp.policies {
+		z := p.zone
+		c.OnStartup(func() error {
+			z.StartupOnce.Do(func() {
+				if len(z.TransferFrom) == 0 {
+					z.Reload()
+					return
+				}
+				z.TransferIn()
+				go func() {
+					z.Update()
+				}()
+			})
+			return nil
+		})
+		if len(z.TransferFrom) == 0 {
+			c.OnShutdown(z.OnShutdown)
+		}
+	}
+
+	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, HitCount) })
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rp.Next = next
+		return rp
+	})
+
+	return nil
+}
+
+func rpzParse(c *caddy.Controller) (*RPZ, error) {
+	rp := &RPZ{}
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rp.Zones = make([]string, len(c.ServerBlockKeys))
+		copy(rp.Zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			rp.Zones = args
+		}
+		for j := range rp.Zones {
+			rp.Zones[j] = plugin.Host(rp.Zones[j]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "file":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				origin := plugin.Host(args[0]).Normalize()
+				fileName := args[1]
+				if !path.IsAbs(fileName) && config.Root != "" {
+					fileName = path.Join(config.Root, fileName)
+				}
+				reader, err := os.Open(fileName)
+				if err != nil {
+					return nil, err
+				}
+				z, err := file.Parse(reader, origin, fileName, 0)
+				reader.Close()
+				if err != nil {
+					return nil, err
+				}
+				rp.policies = append(rp.policies, newPolicy(z, origin))
+			case "secondary":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				origin := plugin.Host(args[0]).Normalize()
+				z := file.NewZone(origin, "stdin")
+				for _, from := range args[1:] {
+					addr, err := dnsutil.ParseHostPort(from, "53")
+					if err != nil {
+						return nil, err
+					}
+					z.TransferFrom = append(z.TransferFrom, addr)
+				}
+				rp.policies = append(rp.policies, newPolicy(z, origin))
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(rp.policies) == 0 {
+		return nil, c.Err("no policy zones")
+	}
+	return rp, nil
+}
@@ -0,0 +1,78 @@
+package rpz
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupRPZ(t *testing.T) {
+	name, rm, err := test.TempFile(".", policyZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+		policies  []string
+	}{
+		// positive
+		{`rpz {
+			file rpz.example ` + name + `
+		}`, false, []string{}, []string{"rpz.example."}},
+		{`rpz example.org {
+			secondary rpz2.example 10.0.0.1 10.0.0.2:5353
+			file rpz.example ` + name + `
+		}`, false, []string{"example.org."}, []string{"rpz2.example.", "rpz.example."}},
+		// negative
+		{`rpz`, true, nil, nil},
+		{`rpz {
+			file rpz.example
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example /does/not/exist
+		}`, true, nil, nil},
+		{`rpz {
+			secondary rpz.example
+		}`, true, nil, nil},
+		{`rpz {
+			policy rpz.example
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example ` + name + `
+		}
+		rpz`, true, nil, nil},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		rp, err := rpzParse(c)
+
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, tc.input, err)
+			continue
+		}
+		if len(rp.Zones) != len(tc.zones) || (len(tc.zones) > 0 && rp.Zones[0] != tc.zones[0]) {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.zones, rp.Zones)
+		}
+		if len(rp.policies) != len(tc.policies) {
+			t.Fatalf("Test %d: expected %d policy zones, got %d", i, len(tc.policies), len(rp.policies))
+		}
+		for j, p := range rp.policies {
+			if p.origin != tc.policies[j] {
+				t.Errorf("Test %d: expected policy zone %s, got %s", i, tc.policies[j], p.origin)
+			}
+		}
+	}
+}
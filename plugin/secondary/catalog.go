@@ -0,0 +1,140 @@
+package secondary
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// Members holds the zones we are a secondary for, because they are listed in a catalog zone (RFC 9432).
+// Members are added and removed whenever one of the catalog zones is transferred.
+type Members struct {
+	static map[string]bool // zones from the configuration, they are never taken from a catalog
+
+	sync.RWMutex
+	z      map[string]*member
+	names  []string
+	closed bool
+}
+
+type member struct {
+	catalog string // catalog zone that lists this member
+	id      string // the member's ID in that catalog
+	zone    *file.Zone
+}
+
+// newMembers returns a new Members, the zones in static are never added as member.
+func newMembers(static []string) *Members {
+	m := &Members{static: map[string]bool{}, z: map[string]*member{}}
+	for _, s := range static {
+		m.static[s] = true
+	}
+	return m
+}
+
+// match returns the member zone that is the closest match for qname, or nil if there isn't one.
+func (m *Members) match(qname string) (string, *file.Zone) {
+	m.RLock()
+	defer m.RUnlock()
+	origin := plugin.Zones(m.names).Matches(qname)
+	if origin == "" {
+		return "", nil
+	}
+	return origin, m.z[origin].zone
+}
+
+// update makes the members of the catalog zone cz with origin catalog match the ones listed in it.
+// New members are configured like the catalog zone and get transferred, members that are no longer
+// listed are stopped and removed. A member that gets a new ID is transferred again.
+func (m *Members) update(catalog string, cz *file.Zone) {
+	listed, err := file.CatalogMembers(cz)
+	if err != nil {
+		log.Errorf("Not updating the members of catalog `%s': %s", catalog, err)
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return
+	}
+
+	for origin, mb := range m.z {
+		if mb.catalog != catalog {
+			continue
+		}
+		if id, ok := listed[origin]; ok && id == mb.id {
+			continue
+		}
+		delete(m.z, origin)
+		go mb.zone.OnShutdown()
+		if mb.zone.CacheFile != "" {
+			os.Remove(mb.zone.CacheFile)
+		}
+		log.Infof("Removed zone `%s' of catalog `%s'", origin, catalog)
+	}
+
+	for origin, id := range listed {
+		if _, ok := m.z[origin]; ok {
+			if m.z[origin].catalog != catalog {
+				log.Warningf("Zone `%s' of catalog `%s' is already in catalog `%s'", origin, catalog, m.z[origin].catalog)
+			}
+			continue
+		}
+		if m.static[origin] {
+			log.Warningf("Zone `%s' of catalog `%s' is already configured", origin, catalog)
+			continue
+		}
+
+		z := file.NewZone(origin, "stdin")
+		z.TransferFrom = cz.TransferFrom
+		z.TransferTo = cz.TransferTo
+		z.Upstream = cz.Upstream
+		z.Strict = cz.Strict
+		if cz.CacheFile != "" {
+			z.CacheFile = cacheFile(filepath.Dir(cz.CacheFile), origin)
+		}
+		m.z[origin] = &member{catalog: catalog, id: id, zone: z}
+		go func(origin string) {
+			if err := z.LoadCache(); err != nil {
+				log.Warningf("Failed to load `%s' from %s: %s", origin, z.CacheFile, err)
+			}
+			z.TransferIn()
+			z.Update()
+		}(origin)
+		log.Infof("Added zone `%s' of catalog `%s'", origin, catalog)
+	}
+
+	m.names = make([]string, 0, len(m.z))
+	for origin := range m.z {
+		m.names = append(m.names, origin)
+	}
+	sort.Strings(m.names)
+}
+
+// all returns all members.
+func (m *Members) all() map[string]*member {
+	m.RLock()
+	defer m.RUnlock()
+	all := make(map[string]*member, len(m.z))
+	for n, mb := range m.z {
+		all[n] = mb
+	}
+	return all
+}
+
+// OnShutdown stops and removes all members.
+func (m *Members) OnShutdown() error {
+	m.Lock()
+	defer m.Unlock()
+	for _, mb := range m.z {
+		go mb.zone.OnShutdown()
+	}
+	m.z, m.names, m.closed = map[string]*member{}, nil, true
+	return nil
+}
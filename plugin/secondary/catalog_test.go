@@ -0,0 +1,42 @@
+package secondary
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+)
+
+func TestMembersUpdate(t *testing.T) {
+	m := newMembers([]string{"example.net."})
+
+	m.update("catalog.example.", file.NewCatalog("catalog.example.", []string{"example.org.", "example.net.", "a.example.org."}, 1))
+	if _, z := m.match("www.example.org."); z == nil {
+		t.Errorf("Expected example.org. to be a member")
+	}
+	if origin, _ := m.match("www.a.example.org."); origin != "a.example.org." {
+		t.Errorf("Expected a.example.org. to match, got %q", origin)
+	}
+	if _, z := m.match("example.net."); z != nil {
+		t.Errorf("Expected example.net. not to be a member, it is configured")
+	}
+
+	// Other catalogs can't take over members.
+	m.update("other.example.", file.NewCatalog("other.example.", []string{"example.org."}, 1))
+	if m.z["example.org."].catalog != "catalog.example." {
+		t.Errorf("Expected example.org. to stay in catalog.example.")
+	}
+
+	m.update("catalog.example.", file.NewCatalog("catalog.example.", []string{"a.example.org."}, 2))
+	if origin, _ := m.match("www.example.org."); origin != "" {
+		t.Errorf("Expected example.org. to be removed, got %q", origin)
+	}
+	if len(m.names) != 1 {
+		t.Errorf("Expected 1 member, got %v", m.names)
+	}
+
+	m.OnShutdown()
+	m.update("catalog.example.", file.NewCatalog("catalog.example.", []string{"example.org."}, 3))
+	if len(m.names) != 0 {
+		t.Errorf("Expected no members after shutdown, got %v", m.names)
+	}
+}
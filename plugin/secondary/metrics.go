@@ -0,0 +1,51 @@
+package secondary
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	Serial = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "serial",
+		Help:      "The serial of a secondary zone, -1 if it hasn't been transferred.",
+	}, []string{"zone"})
+	Expired = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "expired",
+		Help:      "Set to 1 when a secondary zone expired and isn't served.",
+	}, []string{"zone"})
+	LastTransfer = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "last_transfer_timestamp_seconds",
+		Help:      "The time of the last successful transfer of a secondary zone.",
+	}, []string{"zone"})
+	LastRefresh = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "last_refresh_timestamp_seconds",
+		Help:      "The last time a secondary zone was transferred or found to be current.",
+	}, []string{"zone"})
+	NextRefresh = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "next_refresh_timestamp_seconds",
+		Help:      "The time a secondary zone will be checked for a new serial.",
+	}, []string{"zone"})
+	LastError = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "last_error_timestamp_seconds",
+		Help:      "The time of the last failed transfer or serial check of a secondary zone.",
+	}, []string{"zone"})
+)
+
+var once sync.Once
@@ -1,10 +1,32 @@
 // Package secondary implements a secondary plugin.
 package secondary
 
-import "github.com/coredns/coredns/plugin/file"
+import (
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
 
 // Secondary implements a secondary plugin that allows CoreDNS to retrieve (via AXFR)
 // zone information from a primary server.
 type Secondary struct {
 	file.File
+	members *Members // zones from catalog zones, nil if there are no catalog zones
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s Secondary) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if s.members != nil {
+		state := request.Request{W: w, Req: r}
+		qname := state.Name()
+		origin, z := s.members.match(qname)
+		if z != nil && len(origin) > len(plugin.Zones(s.Zones.Names).Matches(qname)) {
+			f := file.File{Next: s.Next, Zones: file.Zones{Z: map[string]*file.Zone{origin: z}, Names: []string{origin}}}
+			return f.ServeDNS(ctx, w, r)
+		}
+	}
+	return s.File.ServeDNS(ctx, w, r)
 }
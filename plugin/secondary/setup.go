@@ -1,9 +1,16 @@
 package secondary
 
 import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -18,17 +25,36 @@ func init() {
 }
 
 func setup(c *caddy.Controller) error {
-	zones, err := secondaryParse(c)
+	zones, conf, err := secondaryParse(c)
 	if err != nil {
 		return plugin.Error("secondary", err)
 	}
 
+	var members *Members
+	if len(conf.catalogs) > 0 {
+		members = newMembers(zones.Names)
+		for _, n := range conf.catalogs {
+			n := n
+			z := zones.Z[n]
+			z.OnTransfer = func(cz *file.Zone) { members.update(n, cz) }
+			// Stop keeping the catalog up to date, so it no longer changes the members.
+			c.OnShutdown(func() error {
+				go z.OnShutdown()
+				return nil
+			})
+		}
+		c.OnShutdown(members.OnShutdown)
+	}
+
 	// Add startup functions to retrieve the zone and keep it up to date.
 	for _, n := range zones.Names {
-		z := zones.Z[n]
+		n, z := n, zones.Z[n]
 		if len(z.TransferFrom) > 0 {
 			c.OnStartup(func() error {
 				z.StartupOnce.Do(func() {
+					if err := z.LoadCache(); err != nil {
+						log.Warningf("Failed to load `%s' from %s: %s", n, z.CacheFile, err)
+					}
 					z.TransferIn()
 					go func() {
 						z.Update()
@@ -39,17 +65,32 @@ func setup(c *caddy.Controller) error {
 		}
 	}
 
+	set := &zoneSet{zones: zones, members: members, addr: conf.status}
+	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, Serial, Expired, LastTransfer, LastRefresh, NextRefresh, LastError) })
+		return nil
+	})
+	c.OnStartup(set.OnStartup)
+	c.OnShutdown(set.OnShutdown)
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
-		return Secondary{file.File{Next: next, Zones: zones}}
+		return Secondary{File: file.File{Next: next, Zones: zones}, members: members}
 	})
 
 	return nil
 }
 
-func secondaryParse(c *caddy.Controller) (file.Zones, error) {
+// config is the configuration of the secondary plugin besides the zones.
+type config struct {
+	catalogs []string // the catalog zones
+	status   string   // address of the HTTP status listener
+}
+
+func secondaryParse(c *caddy.Controller) (file.Zones, config, error) {
 	z := make(map[string]*file.Zone)
 	names := []string{}
 	origins := []string{}
+	conf := config{}
 	upstr := upstream.Upstream{}
 	for c.Next() {
 
@@ -67,6 +108,8 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				names = append(names, origins[i])
 			}
 
+			strict, catalog := false, false
+			cacheDir := ""
 			for c.NextBlock() {
 
 				t, f := []string{}, []string{}
@@ -76,17 +119,40 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				case "transfer":
 					t, f, e = parse.Transfer(c, true)
 					if e != nil {
-						return file.Zones{}, e
+						return file.Zones{}, config{}, e
+					}
+				case "strict":
+					strict = true
+				case "catalog":
+					catalog = true
+				case "status":
+					if !c.NextArg() {
+						return file.Zones{}, config{}, c.ArgErr()
+					}
+					if _, _, err := net.SplitHostPort(c.Val()); err != nil {
+						return file.Zones{}, config{}, err
+					}
+					conf.status = c.Val()
+				case "cache":
+					if !c.NextArg() {
+						return file.Zones{}, config{}, c.ArgErr()
+					}
+					cacheDir = c.Val()
+					if root := dnsserver.GetConfig(c).Root; !filepath.IsAbs(cacheDir) && root != "" {
+						cacheDir = filepath.Join(root, cacheDir)
+					}
+					if err := os.MkdirAll(cacheDir, 0755); err != nil {
+						return file.Zones{}, config{}, c.Errf("unable to create cache directory '%s': %v", cacheDir, err)
 					}
 				case "upstream":
 					args := c.RemainingArgs()
 					var err error
 					upstr, err = upstream.NewUpstream(args)
 					if err != nil {
-						return file.Zones{}, err
+						return file.Zones{}, config{}, err
 					}
 				default:
-					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
+					return file.Zones{}, config{}, c.Errf("unknown property '%s'", c.Val())
 				}
 
 				for _, origin := range origins {
@@ -97,9 +163,32 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 						z[origin].TransferFrom = append(z[origin].TransferFrom, f...)
 					}
 					z[origin].Upstream = upstr
+					z[origin].Strict = strict
+					if cacheDir != "" {
+						z[origin].CacheFile = cacheFile(cacheDir, origin)
+					}
+				}
+			}
+
+			if catalog {
+				for _, origin := range origins {
+					if len(z[origin].TransferFrom) == 0 {
+						return file.Zones{}, config{}, c.Errf("catalog zone %s needs 'transfer from'", origin)
+					}
+					conf.catalogs = append(conf.catalogs, origin)
 				}
 			}
 		}
 	}
-	return file.Zones{Z: z, Names: names}, nil
+	return file.Zones{Z: z, Names: names}, conf, nil
+}
+
+// cacheFile returns the name of the file in dir the zone origin is saved to: db.example.org for
+// example.org. and db.root for the root zone.
+func cacheFile(dir, origin string) string {
+	name := strings.TrimSuffix(origin, ".")
+	if name == "" {
+		name = "root"
+	}
+	return filepath.Join(dir, "db."+name)
 }
@@ -37,11 +37,55 @@ func TestSecondaryParse(t *testing.T) {
 			"127.0.0.1:53",
 			[]string{"example.org."},
 		},
+		{
+			`secondary catalog.example {
+				transfer from 127.0.0.1
+				catalog
+			}`,
+			false,
+			"127.0.0.1:53",
+			[]string{"catalog.example."},
+		},
+		{
+			`secondary catalog.example {
+				catalog
+			}`,
+			true,
+			"",
+			nil,
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				cache
+			}`,
+			true,
+			"",
+			nil,
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				status 127.0.0.1:8054
+			}`,
+			false,
+			"127.0.0.1:53",
+			[]string{"example.org."},
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				status 8054
+			}`,
+			true,
+			"",
+			nil,
+		},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputFileRules)
-		s, err := secondaryParse(c)
+		s, _, err := secondaryParse(c)
 
 		if err == nil && test.shouldErr {
 			t.Fatalf("Test %d expected errors, but got no error", i)
@@ -63,3 +107,18 @@ func TestSecondaryParse(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheFile(t *testing.T) {
+	tests := []struct {
+		origin string
+		file   string
+	}{
+		{"example.org.", "/var/lib/coredns/db.example.org"},
+		{".", "/var/lib/coredns/db.root"},
+	}
+	for i, tc := range tests {
+		if f := cacheFile("/var/lib/coredns", tc.origin); f != tc.file {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.file, f)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package secondary
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// zoneSet holds the secondary zones of a server block: the configured ones and the members of
+// catalog zones. It exports their state as metrics, and as JSON over HTTP when addr is set.
+type zoneSet struct {
+	zones   file.Zones
+	members *Members
+	addr    string
+
+	ln       net.Listener
+	stop     chan struct{}
+	mu       sync.Mutex
+	reported map[string]bool // zones we exported metrics for
+}
+
+// zoneState is the state of a zone as reported over HTTP.
+type zoneState struct {
+	Zone          string `json:"zone"`
+	Catalog       string `json:"catalog,omitempty"`
+	Serial        int64  `json:"serial"`
+	Expired       bool   `json:"expired"`
+	Primary       string `json:"primary,omitempty"`
+	LastTransfer  string `json:"last_transfer,omitempty"`
+	LastRefresh   string `json:"last_refresh,omitempty"`
+	NextRefresh   string `json:"next_refresh,omitempty"`
+	Expires       string `json:"expires,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	LastErrorTime string `json:"last_error_time,omitempty"`
+
+	state file.TransferState
+}
+
+// states returns the state of all zones, sorted by zone.
+func (s *zoneSet) states() []zoneState {
+	states := []zoneState{}
+	for _, n := range s.zones.Names {
+		if z := s.zones.Z[n]; len(z.TransferFrom) > 0 {
+			states = append(states, newZoneState(n, "", z.TransferState()))
+		}
+	}
+	if s.members != nil {
+		for n, m := range s.members.all() {
+			states = append(states, newZoneState(n, m.catalog, m.zone.TransferState()))
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Zone < states[j].Zone })
+	return states
+}
+
+func newZoneState(zone, catalog string, st file.TransferState) zoneState {
+	return zoneState{
+		Zone:          zone,
+		Catalog:       catalog,
+		Serial:        st.Serial,
+		Expired:       st.Expired,
+		Primary:       st.Primary,
+		LastTransfer:  timestamp(st.LastTransfer),
+		LastRefresh:   timestamp(st.LastRefresh),
+		NextRefresh:   timestamp(st.NextRefresh),
+		Expires:       timestamp(st.Expires),
+		LastError:     st.LastError,
+		LastErrorTime: timestamp(st.LastErrorTime),
+		state:         st,
+	}
+}
+
+// timestamp formats t as RFC 3339, or returns the empty string for the zero time.
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// seconds returns t as seconds since the epoch, 0 for the zero time.
+func seconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+// report exports the state of all zones as metrics, and removes the metrics of zones we no longer have.
+func (s *zoneSet) report() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, st := range s.states() {
+		seen[st.Zone] = true
+		Serial.WithLabelValues(st.Zone).Set(float64(st.state.Serial))
+		expired := 0.0
+		if st.Expired {
+			expired = 1
+		}
+		Expired.WithLabelValues(st.Zone).Set(expired)
+		LastTransfer.WithLabelValues(st.Zone).Set(seconds(st.state.LastTransfer))
+		LastRefresh.WithLabelValues(st.Zone).Set(seconds(st.state.LastRefresh))
+		NextRefresh.WithLabelValues(st.Zone).Set(seconds(st.state.NextRefresh))
+		LastError.WithLabelValues(st.Zone).Set(seconds(st.state.LastErrorTime))
+	}
+	for zone := range s.reported {
+		if !seen[zone] {
+			deleteMetrics(zone)
+		}
+	}
+	s.reported = seen
+}
+
+func deleteMetrics(zone string) {
+	for _, g := range []interface {
+		DeleteLabelValues(...string) bool
+	}{Serial, Expired, LastTransfer, LastRefresh, NextRefresh, LastError} {
+		g.DeleteLabelValues(zone)
+	}
+}
+
+// OnStartup starts reporting the state of the zones every second, and the HTTP listener if addr is set.
+func (s *zoneSet) OnStartup() error {
+	if s.addr != "" {
+		ln, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			log.Errorf("Failed to start secondary status handler: %s", err)
+			return err
+		}
+		s.ln = ln
+		mux := http.NewServeMux()
+		mux.HandleFunc(statusPath, s.serveHTTP)
+		go func() { http.Serve(s.ln, mux) }()
+	}
+
+	s.stop = make(chan struct{})
+	s.report()
+	go func() {
+		for {
+			select {
+			case <-time.After(1 * time.Second):
+				s.report()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops reporting and removes the metrics of the zones.
+func (s *zoneSet) OnShutdown() error {
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.mu.Lock()
+	for zone := range s.reported {
+		deleteMetrics(zone)
+	}
+	s.reported = nil
+	s.mu.Unlock()
+
+	if s.ln != nil {
+		return s.ln.Close()
+	}
+	return nil
+}
+
+func (s *zoneSet) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.states())
+}
+
+// statusPath is where the state of the zones is served.
+const statusPath = "/secondary"
@@ -0,0 +1,43 @@
+package secondary
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestStatus(t *testing.T) {
+	z := file.NewZone("example.org.", "stdin")
+	z.TransferFrom = []string{"127.0.0.1:53"}
+	z.Insert(test.SOA("example.org. IN SOA ns.example.org. bla.example.org. 1234 3600 600 86400 300"))
+
+	s := &zoneSet{zones: file.Zones{Z: map[string]*file.Zone{"example.org.": z}, Names: []string{"example.org."}}}
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(rec, httptest.NewRequest("GET", statusPath, nil))
+
+	states := []zoneState{}
+	if err := json.NewDecoder(rec.Body).Decode(&states); err != nil {
+		t.Fatalf("Failed to decode status: %s", err)
+	}
+	if len(states) != 1 {
+		t.Fatalf("Expected 1 zone, got %d", len(states))
+	}
+	if states[0].Zone != "example.org." || states[0].Serial != 1234 || states[0].Expired {
+		t.Errorf("Expected zone example.org. with serial 1234, got %+v", states[0])
+	}
+
+	s.report()
+	if !s.reported["example.org."] {
+		t.Errorf("Expected metrics for example.org., got %v", s.reported)
+	}
+
+	s.zones = file.Zones{Z: map[string]*file.Zone{}}
+	s.report()
+	if len(s.reported) != 0 {
+		t.Errorf("Expected metrics of example.org. to be removed, got %v", s.reported)
+	}
+}
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"gopkg.in/yaml.v2"
+)
+
+// dataReload is how often a data file is checked for changes.
+const dataReload = 5 * time.Second
+
+// data is a key/value data file that is made available to the templates as .Data.
+type data struct {
+	path string
+
+	sync.RWMutex
+	values map[string]interface{}
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
+}
+
+// newData returns the data read from path. The format of the file is taken from the extension: a CSV
+// file (.csv) holds a key and a value on each line, a YAML file (.yaml or .yml) a mapping from keys to
+// values.
+func newData(path string) (*data, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".yaml", ".yml":
+	default:
+		return nil, fmt.Errorf("unknown data file format: %s", path)
+	}
+
+	d := &data{path: path}
+	if _, err := d.read(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Values returns the current data. The returned map must not be modified.
+func (d *data) Values() map[string]interface{} {
+	if d == nil {
+		return nil
+	}
+	d.RLock()
+	defer d.RUnlock()
+	return d.values
+}
+
+// read reads the data file if it changed since it was last read and reports if it did.
+func (d *data) read() (bool, error) {
+	fi, err := os.Stat(d.path)
+	if err != nil {
+		return false, err
+	}
+	if d.mtime.Equal(fi.ModTime()) && d.size == fi.Size() {
+		return false, nil
+	}
+
+	buf, err := ioutil.ReadFile(d.path)
+	if err != nil {
+		return false, err
+	}
+	var values map[string]interface{}
+	if strings.ToLower(filepath.Ext(d.path)) == ".csv" {
+		values, err = parseCSV(buf)
+	} else {
+		values, err = parseYAML(buf)
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", d.path, err)
+	}
+
+	d.Lock()
+	d.values = values
+	d.Unlock()
+
+	d.mtime = fi.ModTime()
+	d.size = fi.Size()
+	return true, nil
+}
+
+// reload re-reads the data file each time it changes, until stop is closed. If the new file can't be
+// parsed the previous data is kept.
+func (d *data) reload(stop chan struct{}) {
+	ticker := time.NewTicker(dataReload)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			changed, err := d.read()
+			if err != nil {
+				log.Errorf("Failed to reload template data: %s", err)
+				continue
+			}
+			if changed {
+				log.Infof("Reloaded template data from %s", d.path)
+			}
+		}
+	}
+}
+
+func parseCSV(buf []byte) (map[string]interface{}, error) {
+	r := csv.NewReader(strings.NewReader(string(buf)))
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(records))
+	for _, rec := range records {
+		values[rec[0]] = rec[1]
+	}
+	return values, nil
+}
+
+func parseYAML(buf []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(buf, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
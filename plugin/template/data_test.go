@@ -0,0 +1,104 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name      string
+		content   string
+		shouldErr bool
+		expected  map[string]interface{}
+	}{
+		{"data.csv", "# customer, address\ncust1, 10.0.0.1\ncust2,10.0.0.2\n", false,
+			map[string]interface{}{"cust1": "10.0.0.1", "cust2": "10.0.0.2"}},
+		{"data.yaml", "cust1: 10.0.0.1\ncust2:\n- 10.0.0.2\n- 10.0.0.3\n", false,
+			map[string]interface{}{"cust1": "10.0.0.1", "cust2": []interface{}{"10.0.0.2", "10.0.0.3"}}},
+		{"data.yml", "", false, map[string]interface{}{}},
+		{"fields.csv", "cust1,10.0.0.1,extra\n", true, nil},
+		{"list.yaml", "- 10.0.0.1\n", true, nil},
+		{"data.json", "{}", true, nil},
+	}
+
+	for i, tc := range tests {
+		d, err := newData(write(tc.name, tc.content))
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %s, got none", i, tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(d.Values(), tc.expected) {
+			t.Errorf("Test %d: expected %v for %s, got %v", i, tc.expected, tc.name, d.Values())
+		}
+	}
+
+	if _, err := newData(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Errorf("Expected error for missing file, got none")
+	}
+}
+
+func TestDataRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "data.csv")
+	if err := ioutil.WriteFile(p, []byte("cust1,10.0.0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := newData(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := d.read(); changed || err != nil {
+		t.Errorf("Expected no change and no error, got %t and %v", changed, err)
+	}
+
+	// A broken file keeps the old data.
+	if err := ioutil.WriteFile(p, []byte("cust1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.read(); err == nil {
+		t.Errorf("Expected error, got none")
+	}
+	if v := d.Values()["cust1"]; v != "10.0.0.1" {
+		t.Errorf("Expected old data to be kept, got %v", v)
+	}
+
+	if err := ioutil.WriteFile(p, []byte("cust1,10.0.0.11\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := d.read(); !changed || err != nil {
+		t.Errorf("Expected change and no error, got %t and %v", changed, err)
+	}
+	if v := d.Values()["cust1"]; v != "10.0.0.11" {
+		t.Errorf("Expected new data, got %v", v)
+	}
+}
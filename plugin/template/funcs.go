@@ -0,0 +1,152 @@
+package template
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	gotmpl "text/template"
+)
+
+// funcs are the helper functions available in the templates.
+var funcs = gotmpl.FuncMap{
+	"add": func(a, b interface{}) (int64, error) { return arith(a, b, func(x, y int64) int64 { return x + y }) },
+	"sub": func(a, b interface{}) (int64, error) { return arith(a, b, func(x, y int64) int64 { return x - y }) },
+	"mul": func(a, b interface{}) (int64, error) { return arith(a, b, func(x, y int64) int64 { return x * y }) },
+	"div": func(a, b interface{}) (int64, error) {
+		if y, err := toInt(b); err == nil && y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return arith(a, b, func(x, y int64) int64 { return x / y })
+	},
+	"mod": func(a, b interface{}) (int64, error) {
+		if y, err := toInt(b); err == nil && y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return arith(a, b, func(x, y int64) int64 { return x % y })
+	},
+
+	"parseInt":  func(s string, base int) (int64, error) { return strconv.ParseInt(s, base, 64) },
+	"formatInt": formatInt,
+
+	"ipAdd":     ipAdd,
+	"ipToInt":   ipToInt,
+	"ipFromInt": ipFromInt,
+
+	"replace": func(s, old, new string) string { return strings.Replace(s, old, new, -1) },
+	"hash":    hash,
+	"pick":    pick,
+}
+
+// toInt converts v, a number or a string holding a decimal number, to an int64.
+func toInt(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint32:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
+
+func arith(a, b interface{}, f func(x, y int64) int64) (int64, error) {
+	x, err := toInt(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toInt(b)
+	if err != nil {
+		return 0, err
+	}
+	return f(x, y), nil
+}
+
+// formatInt returns v in base, i.e. formatInt 255 16 returns "ff".
+func formatInt(v interface{}, base int) (string, error) {
+	i, err := toInt(v)
+	if err != nil {
+		return "", err
+	}
+	if base < 2 || base > 36 {
+		return "", fmt.Errorf("invalid base: %d", base)
+	}
+	return strconv.FormatInt(i, base), nil
+}
+
+// ipAdd returns the address n addresses after ip (before if n is negative). It fails when the result
+// falls outside the address family of ip.
+func ipAdd(ip string, n interface{}) (string, error) {
+	i, err := toInt(n)
+	if err != nil {
+		return "", err
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("not an IP address: %q", ip)
+	}
+	size := net.IPv6len
+	if v4 := addr.To4(); v4 != nil {
+		addr, size = v4, net.IPv4len
+	}
+
+	sum := new(big.Int).SetBytes(addr)
+	sum.Add(sum, big.NewInt(i))
+	if sum.Sign() < 0 || sum.BitLen() > size*8 {
+		return "", fmt.Errorf("%s + %d is out of range", ip, i)
+	}
+	buf := sum.Bytes()
+	res := make(net.IP, size)
+	copy(res[size-len(buf):], buf)
+	return res.String(), nil
+}
+
+// ipToInt returns the IPv4 address ip as a number.
+func ipToInt(ip string) (int64, error) {
+	addr := net.ParseIP(ip).To4()
+	if addr == nil {
+		return 0, fmt.Errorf("not an IPv4 address: %q", ip)
+	}
+	return int64(binary.BigEndian.Uint32(addr)), nil
+}
+
+// ipFromInt returns the IPv4 address with number v.
+func ipFromInt(v interface{}) (string, error) {
+	i, err := toInt(v)
+	if err != nil {
+		return "", err
+	}
+	if i < 0 || i > 1<<32-1 {
+		return "", fmt.Errorf("not an IPv4 address: %d", i)
+	}
+	addr := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(addr, uint32(i))
+	return addr.String(), nil
+}
+
+// hash returns the 32 bit FNV-1a hash of s.
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// pick selects one of the choices based on the hash of key, the same key always gets the same choice.
+func pick(key string, choices ...interface{}) (interface{}, error) {
+	if len(choices) == 1 {
+		// A list, e.g. from the data file.
+		if l, ok := choices[0].([]interface{}); ok {
+			choices = l
+		}
+	}
+	if len(choices) == 0 {
+		return nil, fmt.Errorf("nothing to pick from")
+	}
+	return choices[hash(key)%uint32(len(choices))], nil
+}
@@ -0,0 +1,74 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+	gotmpl "text/template"
+)
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		tmpl      string
+		expected  string
+		shouldErr bool
+	}{
+		{`{{ add 1 2 }}`, "3", false},
+		{`{{ add "10" 2 }}`, "12", false},
+		{`{{ sub 1 2 }}`, "-1", false},
+		{`{{ mul "3" "4" }}`, "12", false},
+		{`{{ div 7 2 }}`, "3", false},
+		{`{{ mod 7 2 }}`, "1", false},
+		{`{{ div 7 0 }}`, "", true},
+		{`{{ mod 7 0 }}`, "", true},
+		{`{{ add "x" 1 }}`, "", true},
+
+		{`{{ parseInt "ff" 16 }}`, "255", false},
+		{`{{ parseInt "0a" 16 | formatInt 10 }}`, "", true}, // wrong argument order
+		{`{{ formatInt (parseInt "ff" 16) 8 }}`, "377", false},
+		{`{{ formatInt 255 16 }}`, "ff", false},
+		{`{{ formatInt "10" 2 }}`, "1010", false},
+		{`{{ formatInt 10 1 }}`, "", true},
+		{`{{ parseInt "zz" 10 }}`, "", true},
+
+		{`{{ ipAdd "10.0.0.1" 1 }}`, "10.0.0.2", false},
+		{`{{ ipAdd "10.0.0.255" 1 }}`, "10.0.1.0", false},
+		{`{{ ipAdd "10.0.0.1" -2 }}`, "9.255.255.255", false},
+		{`{{ ipAdd "255.255.255.255" 1 }}`, "", true},
+		{`{{ ipAdd "0.0.0.0" -1 }}`, "", true},
+		{`{{ ipAdd "2001:db8::ffff" 1 }}`, "2001:db8::1:0", false},
+		{`{{ ipAdd "example.org" 1 }}`, "", true},
+		{`{{ ipToInt "10.0.0.1" }}`, "167772161", false},
+		{`{{ ipToInt "2001:db8::1" }}`, "", true},
+		{`{{ ipFromInt 167772161 }}`, "10.0.0.1", false},
+		{`{{ ipFromInt (add (ipToInt "10.0.0.1") 256) }}`, "10.0.1.1", false},
+		{`{{ ipFromInt 4294967296 }}`, "", true},
+
+		{`{{ replace "10-0-0-1" "-" "." }}`, "10.0.0.1", false},
+		{`{{ hash "example.org." }}`, "1439401293", false},
+		{`{{ pick "example.org." "a" "b" "c" }}`, "a", false},
+		{`{{ pick "example.com." "a" "b" "c" }}`, "b", false},
+		{`{{ pick "example.org." }}`, "", true},
+	}
+
+	for i, tc := range tests {
+		tmpl, err := gotmpl.New("test").Funcs(funcs).Parse(tc.tmpl)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse %s: %s", i, tc.tmpl, err)
+		}
+		buf := &bytes.Buffer{}
+		err = tmpl.Execute(buf, nil)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %s, got %q", i, tc.tmpl, buf.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.tmpl, err)
+			continue
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("Test %d: expected %q for %s, got %q", i, tc.expected, tc.tmpl, buf.String())
+		}
+	}
+}
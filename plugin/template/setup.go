@@ -1,6 +1,7 @@
 package template
 
 import (
+	"path"
 	"regexp"
 	gotmpl "text/template"
 
@@ -29,6 +30,20 @@ func setupTemplate(c *caddy.Controller) error {
 		return plugin.Error("template", err)
 	}
 
+	stop := make(chan struct{})
+	c.OnStartup(func() error {
+		for _, t := range handler.Templates {
+			if t.data != nil {
+				go t.data.reload(stop)
+			}
+		}
+		return nil
+	})
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		handler.Next = next
 		return handler
@@ -97,7 +112,7 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 					return handler, c.ArgErr()
 				}
 				for _, answer := range args {
-					tmpl, err := gotmpl.New("answer").Parse(answer)
+					tmpl, err := gotmpl.New("answer").Funcs(funcs).Parse(answer)
 					if err != nil {
 						return handler, c.Errf("could not compile template: %s, %v", c.Val(), err)
 					}
@@ -110,7 +125,7 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 					return handler, c.ArgErr()
 				}
 				for _, additional := range args {
-					tmpl, err := gotmpl.New("additional").Parse(additional)
+					tmpl, err := gotmpl.New("additional").Funcs(funcs).Parse(additional)
 					if err != nil {
 						return handler, c.Errf("could not compile template: %s, %v\n", c.Val(), err)
 					}
@@ -123,13 +138,28 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 					return handler, c.ArgErr()
 				}
 				for _, authority := range args {
-					tmpl, err := gotmpl.New("authority").Parse(authority)
+					tmpl, err := gotmpl.New("authority").Funcs(funcs).Parse(authority)
 					if err != nil {
 						return handler, c.Errf("could not compile template: %s, %v\n", c.Val(), err)
 					}
 					t.authority = append(t.authority, tmpl)
 				}
 
+			case "data":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return handler, c.ArgErr()
+				}
+				p := args[0]
+				if root := dnsserver.GetConfig(c).Root; !path.IsAbs(p) && root != "" {
+					p = path.Join(root, p)
+				}
+				d, err := newData(p)
+				if err != nil {
+					return handler, c.Errf("could not read data: %v", err)
+				}
+				t.data = d
+
 			case "rcode":
 				if !c.NextArg() {
 					return handler, c.ArgErr()
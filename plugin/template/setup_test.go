@@ -91,6 +91,33 @@ func TestSetupParse(t *testing.T) {
 			}`,
 			true,
 		},
+		{
+			`template ANY ANY {
+				answer "{{ unknownFunc .Name }}"
+			}`,
+			true,
+		},
+		{
+			`template ANY ANY {
+				data
+				answer "{{ .Name }} 60 IN A 10.0.0.1"
+			}`,
+			true,
+		},
+		{
+			`template ANY ANY {
+				data /highly/unlikely/to/exist.csv
+				answer "{{ .Name }} 60 IN A 10.0.0.1"
+			}`,
+			true,
+		},
+		{
+			`template ANY ANY {
+				data data.json
+				answer "{{ .Name }} 60 IN A 10.0.0.1"
+			}`,
+			true,
+		},
 		// examples
 		{
 			`template ANY A example.com {
@@ -140,6 +167,13 @@ func TestSetupParse(t *testing.T) {
 				}`,
 			false,
 		},
+		{
+			`template IN A example {
+				match ^ip-(?P<ip>[0-9-]+)[.]example[.]$
+				answer "{{ .Name }} 60 IN A {{ ipAdd (replace .Group.ip \"-\" \".\") 1 }}"
+			}`,
+			false,
+		},
 		{
 			`template ANY ANY up.stream.local {
 					answer "up.stream.local 5 IN CNAME up.river.local"
@@ -34,6 +34,7 @@ type template struct {
 	qtype      uint16
 	fall       fall.F
 	upstream   upstream.Upstream
+	data       *data
 }
 
 type templateData struct {
@@ -46,6 +47,7 @@ type templateData struct {
 	Type     string
 	Message  *dns.Msg
 	Question *dns.Question
+	Data     map[string]interface{}
 }
 
 // ServeDNS implements the plugin.Handler interface.
@@ -155,6 +157,7 @@ func (t template) match(state request.Request, zone string) (templateData, bool,
 		data.Name = state.Name()
 		data.Question = &q
 		data.Message = state.Req
+		data.Data = t.data.Values()
 		if q.Qclass != dns.ClassANY {
 			data.Class = dns.ClassToString[q.Qclass]
 		} else {
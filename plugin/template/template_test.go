@@ -2,6 +2,9 @@ package template
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 	gotmpl "text/template"
@@ -439,3 +442,69 @@ func TestMultiSection(t *testing.T) {
 }
 
 const rcodeFallthrough = 3841 // reserved for private use, used to indicate a fallthrough
+
+// TestDataTemplate verifies that templates can use the data file and the helper functions.
+func TestDataTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data := filepath.Join(dir, "customers.csv")
+	if err := ioutil.WriteFile(data, []byte("cust1,10.1.0.1\ncust2,10.1.0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := caddy.NewTestController("dns", fmt.Sprintf(`
+		template IN A customer.example {
+			match ^(?P<id>cust[0-9]+)[.]customer[.]example[.]$
+			data %s
+			answer "{{ .Name }} 60 IN A {{ index .Data .Group.id }}"
+		}
+		template IN A example {
+			match ^ip-(?P<ip>[0-9-]+)[.]example[.]$
+			answer "{{ .Name }} 60 IN A {{ ipAdd (replace .Group.ip \"-\" \".\") 1 }}"
+		}
+		template IN TXT example {
+			answer "{{ .Name }} 60 IN TXT \"{{ pick .Name \"a\" \"b\" \"c\" }} {{ formatInt (hash .Name) 16 }}\""
+		}`, data))
+	handler, err := templateParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	handler.Next = test.NextHandler(rcodeFallthrough, nil)
+
+	tests := []struct {
+		qname    string
+		qtype    uint16
+		expected string
+	}{
+		{"cust1.customer.example.", dns.TypeA, "cust1.customer.example.\t60\tIN\tA\t10.1.0.1"},
+		{"cust2.customer.example.", dns.TypeA, "cust2.customer.example.\t60\tIN\tA\t10.1.0.2"},
+		{"ip-10-0-0-1.example.", dns.TypeA, "ip-10-0-0-1.example.\t60\tIN\tA\t10.0.0.2"},
+		{"example.org.example.", dns.TypeTXT, "example.org.example.\t60\tIN\tTXT\t\"a 48078165\""},
+	}
+
+	for i, tc := range tests {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		req := &dns.Msg{Question: []dns.Question{{Name: tc.qname, Qclass: dns.ClassINET, Qtype: tc.qtype}}}
+		if _, err := handler.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(rec.Msg.Answer) != 1 {
+			t.Errorf("Test %d: expected one answer, got %v", i, rec.Msg.Answer)
+			continue
+		}
+		if rec.Msg.Answer[0].String() != tc.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expected, rec.Msg.Answer[0].String())
+		}
+	}
+
+	// An unknown customer doesn't result in a valid record.
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	req := &dns.Msg{Question: []dns.Question{{Name: "cust3.customer.example.", Qclass: dns.ClassINET, Qtype: dns.TypeA}}}
+	if code, err := handler.ServeDNS(context.TODO(), rec, req); code != dns.RcodeServerFailure || err == nil {
+		t.Errorf("Expected SERVFAIL and an error for an unknown customer, got %d and %v", code, err)
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"crypto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Key is a key that signs a zone in tests.
+type Key struct {
+	DNSKEY *dns.DNSKEY
+	signer crypto.Signer
+}
+
+// NewKey generates an ECDSA P-256 key signing key for zone.
+func NewKey(zone string) *Key {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: dns.Fqdn(zone), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		panic(err)
+	}
+	return &Key{DNSKEY: k, signer: priv.(crypto.Signer)}
+}
+
+// DS returns the DS record of k, with a SHA256 digest.
+func (k *Key) DS() *dns.DS { return k.DNSKEY.ToDS(dns.SHA256) }
+
+// Sign returns the signature of k over rrs, valid from an hour ago until a day from now.
+func (k *Key) Sign(rrs []dns.RR) *dns.RRSIG {
+	now := time.Now().UTC()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Ttl: rrs[0].Header().Ttl},
+		Algorithm:  k.DNSKEY.Algorithm,
+		KeyTag:     k.DNSKEY.KeyTag(),
+		SignerName: k.DNSKEY.Hdr.Name,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(24 * time.Hour).Unix()),
+	}
+	if err := sig.Sign(k.signer, rrs); err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+// SignZone signs the records rrs of the zone origin with k: it adds the DNSKEY of k, an NSEC chain and a
+// signature for each authoritative RRset. At a delegation only the DS and NSEC records are signed, glue
+// below a delegation is not signed and not in the NSEC chain.
+func SignZone(origin string, rrs []dns.RR, k *Key) []dns.RR {
+	rrs = append(rrs, k.DNSKEY)
+
+	cuts := map[string]bool{}
+	for _, r := range rrs {
+		if h := r.Header(); h.Rrtype == dns.TypeNS && !equalName(h.Name, origin) {
+			cuts[strings.ToLower(h.Name)] = true
+		}
+	}
+	glue := func(name string) bool {
+		for cut := range cuts {
+			if dns.IsSubDomain(cut, name) && !equalName(cut, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	types := map[string][]uint16{}
+	sets := map[string]map[uint16][]dns.RR{}
+	var names []string
+	for _, r := range rrs {
+		h := r.Header()
+		name := strings.ToLower(h.Name)
+		if glue(name) {
+			continue
+		}
+		if _, ok := sets[name]; !ok {
+			sets[name] = map[uint16][]dns.RR{}
+			names = append(names, name)
+		}
+		if _, ok := sets[name][h.Rrtype]; !ok {
+			types[name] = append(types[name], h.Rrtype)
+		}
+		sets[name][h.Rrtype] = append(sets[name][h.Rrtype], r)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalLess(names[i], names[j]) })
+
+	signed := append([]dns.RR{}, rrs...)
+	for i, name := range names {
+		bitmap := append([]uint16{dns.TypeNSEC, dns.TypeRRSIG}, types[name]...)
+		sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+		nsec := &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+			NextDomain: names[(i+1)%len(names)],
+			TypeBitMap: bitmap,
+		}
+		sets[name][dns.TypeNSEC] = []dns.RR{nsec}
+		signed = append(signed, nsec)
+
+		for _, t := range append(types[name], dns.TypeNSEC) {
+			if cuts[name] && t != dns.TypeDS && t != dns.TypeNSEC {
+				continue
+			}
+			signed = append(signed, k.Sign(sets[name][t]))
+		}
+	}
+	return signed
+}
+
+// canonicalLess returns true if a sorts before b in canonical DNS name order.
+func canonicalLess(a, b string) bool {
+	la, lb := dns.SplitDomainName(a), dns.SplitDomainName(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if x, y := strings.ToLower(la[i]), strings.ToLower(lb[j]); x != y {
+			return x < y
+		}
+	}
+	return len(la) < len(lb)
+}
+
+func equalName(a, b string) bool { return strings.EqualFold(a, b) }
@@ -16,6 +16,7 @@ func TestTLS(t *testing.T) {
 	}{
 		// positive
 		// negative
+		{`tls cert.pem key.pem`, true, "", "Wrong argument count"},
 	}
 
 	for i, test := range tests {
@@ -0,0 +1,48 @@
+package trusted
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/mholt/caddy"
+)
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+
+	if config.Transport != dnsserver.TransportGRPC {
+		return plugin.Error("trusted_proxies", fmt.Errorf("only valid for gRPC servers, not %s", config.Transport))
+	}
+
+	// Networks are consolidated over all trusted_proxies directives in the server block.
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			return plugin.Error("trusted_proxies", c.ArgErr())
+		}
+		for _, a := range args {
+			n, err := parseNet(a)
+			if err != nil {
+				return plugin.Error("trusted_proxies", fmt.Errorf("invalid network: %s", a))
+			}
+			config.TrustedProxies = append(config.TrustedProxies, n)
+		}
+	}
+	return nil
+}
+
+// parseNet parses a CIDR, or a single IP address.
+func parseNet(s string) (*net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err
+}
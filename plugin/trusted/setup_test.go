@@ -0,0 +1,74 @@
+package trusted
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		config    string
+		transport string
+		expected  []string
+		failing   bool
+	}{
+		{`trusted_proxies 10.0.0.0/8`, "grpc", []string{"10.0.0.0/8"}, false},
+		{`trusted_proxies 10.0.0.0/8 2001:db8::1`, "grpc", []string{"10.0.0.0/8", "2001:db8::1/128"}, false},
+		{"trusted_proxies 10.0.0.0/8\ntrusted_proxies 192.0.2.1", "grpc", []string{"10.0.0.0/8", "192.0.2.1/32"}, false},
+		{`trusted_proxies`, "grpc", nil, true},
+		{`trusted_proxies example.org`, "grpc", nil, true},
+		{`trusted_proxies 10.0.0.0/8`, "tls", nil, true},
+		{`trusted_proxies 10.0.0.0/8`, "dns", nil, true},
+	} {
+		c := caddy.NewTestController("dns", test.config)
+		dnsserver.GetConfig(c).Transport = test.transport
+		err := setup(c)
+		if err != nil {
+			if !test.failing {
+				t.Fatalf("Test %d: expected no errors, but got: %v", i, err)
+			}
+			continue
+		}
+		if test.failing {
+			t.Fatalf("Test %d: expected to fail but did not", i)
+		}
+		cfg := dnsserver.GetConfig(c)
+		if len(cfg.TrustedProxies) != len(test.expected) {
+			t.Fatalf("Test %d: expected %d networks, got %d", i, len(test.expected), len(cfg.TrustedProxies))
+		}
+		for j, n := range cfg.TrustedProxies {
+			if n.String() != test.expected[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, test.expected[j], n)
+			}
+		}
+	}
+}
+
+func TestParseNet(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		expected  string
+		shouldErr bool
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8", false},
+		{"10.0.0.1", "10.0.0.1/32", false},
+		{"2001:db8::/32", "2001:db8::/32", false},
+		{"2001:db8::1", "2001:db8::1/128", false},
+		{"10.0.0.0/33", "", true},
+		{"example.org", "", true},
+	} {
+		n, err := parseNet(test.input)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error, but there wasn't any", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Expected no error, but there was one: %v", i, err)
+		}
+		if err == nil && n.String() != test.expected {
+			t.Errorf("Test %d: Expected %s but got %s", i, test.expected, n)
+		}
+	}
+}
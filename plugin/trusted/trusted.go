@@ -0,0 +1,11 @@
+// Package trusted sets the proxies whose client addresses a gRPC server uses.
+package trusted
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("trusted_proxies", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
@@ -0,0 +1,19 @@
+package validator
+
+import (
+	"sync"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ValidationCount counts the validations per result.
+var ValidationCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "validator",
+	Name:      "validation_count_total",
+	Help:      "Counter of DNSSEC validations per result.",
+}, []string{"result"})
+
+var once sync.Once
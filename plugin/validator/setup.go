@@ -0,0 +1,105 @@
+package validator
+
+import (
+	"os"
+	"path"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/validate"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func init() {
+	caddy.RegisterPlugin("validator", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	v, err := validatorParse(c)
+	if err != nil {
+		return plugin.Error("validator", err)
+	}
+
+	c.OnStartup(func() error {
+		once.Do(func() { metrics.MustRegister(c, ValidationCount) })
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		v.Next = next
+		return v
+	})
+
+	return nil
+}
+
+func validatorParse(c *caddy.Controller) (*Validator, error) {
+	var (
+		zones    []string
+		anchors  []dns.RR
+		negative []string
+	)
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		zones = make([]string, len(c.ServerBlockKeys))
+		copy(zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			zones = args
+		}
+		for j := range zones {
+			zones[j] = plugin.Host(zones[j]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "trust_anchors":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				p := args[0]
+				if !path.IsAbs(p) && config.Root != "" {
+					p = path.Join(config.Root, p)
+				}
+				f, err := os.Open(p)
+				if err != nil {
+					return nil, err
+				}
+				a, err := validate.ParseAnchors(f, args[0])
+				f.Close()
+				if err != nil {
+					return nil, err
+				}
+				anchors = append(anchors, a...)
+			case "nta":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					negative = append(negative, plugin.Host(a).Normalize())
+				}
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(anchors) == 0 {
+		anchors = validate.RootAnchors()
+	}
+	return New(zones, anchors, negative)
+}
@@ -0,0 +1,77 @@
+package validator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupValidator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	anchorFile := filepath.Join(dir, "anchor")
+	badFile := filepath.Join(dir, "bad")
+	ioutil.WriteFile(anchorFile, []byte("example.org. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D\n"), 0644)
+	ioutil.WriteFile(badFile, []byte("example.org. IN A 127.0.0.1\n"), 0644)
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     int
+	}{
+		// positive
+		{`validator`, false, 0},
+		{`validator example.org example.net`, false, 2},
+		{`validator {
+			trust_anchors ` + anchorFile + `
+		}`, false, 0},
+		{`validator {
+			nta broken.example.org example.net
+			nta example.com
+		}`, false, 0},
+		// negative
+		{`validator {
+			trust_anchors
+		}`, true, 0},
+		{`validator {
+			trust_anchors /does/not/exist
+		}`, true, 0},
+		{`validator {
+			trust_anchors ` + badFile + `
+		}`, true, 0},
+		{`validator {
+			nta
+		}`, true, 0},
+		{`validator {
+			dnssec
+		}`, true, 0},
+		{`validator
+		validator`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		v, err := validatorParse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if len(v.Zones) != test.zones {
+			t.Errorf("Test %d: expected %d zones, got %d", i, test.zones, len(v.Zones))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package validator implements a plugin that validates the answers of the plugins after it, typically
+// forward or proxy, with DNSSEC.
+package validator
+
+import (
+	"fmt"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/plugin/pkg/validate"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// Validator is a plugin that sets the DO and CD bits on queries it passes on, and validates the answers
+// it gets back. The DS and DNSKEY records it needs are looked up through the next plugin too.
+type Validator struct {
+	Next  plugin.Handler
+	Zones []string
+
+	v *validate.Validator
+}
+
+// New returns a Validator for zones, that validates from anchors. Names in negative are not validated.
+func New(zones []string, anchors []dns.RR, negative []string) (*Validator, error) {
+	v := &Validator{Zones: zones}
+	var err error
+	if v.v, err = validate.New(anchors, v.lookup); err != nil {
+		return nil, err
+	}
+	v.v.AddNegativeAnchors(negative...)
+	return v, nil
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (v *Validator) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(v.Zones).Matches(state.Name()) == "" || r.CheckingDisabled {
+		return plugin.NextOrFailure(v.Name(), v.Next, ctx, w, r)
+	}
+
+	req := r.Copy()
+	req.CheckingDisabled = true
+	if o := req.IsEdns0(); o != nil {
+		o.SetDo()
+	} else {
+		req.SetEdns0(udpSize, true)
+	}
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, req)
+	if !plugin.ClientWrite(rcode) || nw.Msg == nil {
+		return rcode, err
+	}
+	m := nw.Msg
+
+	res, verr := v.v.Validate(context.WithValue(ctx, writerKey{}, w), m)
+	ValidationCount.WithLabelValues(res.String()).Add(1)
+	if res == validate.Bogus {
+		return dns.RcodeServerFailure, fmt.Errorf("bogus answer for %s %s: %s", state.Name(), state.Type(), verr)
+	}
+
+	m.CheckingDisabled = false
+	m.AuthenticatedData = res == validate.Secure && (state.Do() || r.AuthenticatedData)
+	if !state.Do() {
+		m.Answer = validate.Strip(m.Answer, state.QType())
+		m.Ns = validate.Strip(m.Ns, state.QType())
+	}
+	m.Extra = withoutOPT(m.Extra)
+	state.SizeAndDo(m)
+	m, _ = state.Scrub(m)
+
+	w.WriteMsg(m)
+	return rcode, err
+}
+
+// Name implements the plugin.Handler interface.
+func (v *Validator) Name() string { return "validator" }
+
+// lookup implements validate.Lookup, by asking the next plugin. The response writer of the query being
+// validated is in ctx, some plugins need it to know where the query came from.
+func (v *Validator) lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	w, ok := ctx.Value(writerKey{}).(dns.ResponseWriter)
+	if !ok {
+		return nil, fmt.Errorf("no response writer for lookup of %s", name)
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(udpSize, true)
+	m.CheckingDisabled = true
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, m)
+	if err != nil {
+		return nil, err
+	}
+	if nw.Msg == nil {
+		return nil, fmt.Errorf("no reply for %s %s: %s", name, dns.TypeToString[qtype], dns.RcodeToString[rcode])
+	}
+	if nw.Msg.Rcode == dns.RcodeServerFailure || nw.Msg.Rcode == dns.RcodeRefused {
+		return nil, fmt.Errorf("%s for %s %s", dns.RcodeToString[nw.Msg.Rcode], name, dns.TypeToString[qtype])
+	}
+	return nw.Msg, nil
+}
+
+// withoutOPT returns extra without the OPT record.
+func withoutOPT(extra []dns.RR) []dns.RR {
+	e := make([]dns.RR, 0, len(extra))
+	for _, rr := range extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			e = append(e, rr)
+		}
+	}
+	return e
+}
+
+type writerKey struct{}
+
+const udpSize = 4096
@@ -0,0 +1,207 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// upstream is a stand-in for a recursive resolver: it answers from the zone a query belongs to, and
+// remembers the queries it got.
+type upstream struct {
+	zones   map[string]*file.Zone
+	queries []*dns.Msg
+}
+
+func (u *upstream) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	u.queries = append(u.queries, r)
+	name := r.Question[0].Name
+	if r.Question[0].Qtype == dns.TypeDS {
+		name = parent(name)
+	}
+	for ; ; name = parent(name) {
+		if _, ok := u.zones[name]; ok || name == "." {
+			break
+		}
+	}
+	f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{name: u.zones[name]}, Names: []string{name}}}
+	return f.ServeDNS(ctx, w, r)
+}
+
+func (u *upstream) Name() string { return "upstream" }
+
+func parent(name string) string {
+	i, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[i:]
+}
+
+func zone(t *testing.T, origin string, rrs []dns.RR) *file.Zone {
+	z := file.NewZone(origin, "stdin")
+	for _, r := range rrs {
+		if err := z.Insert(r); err != nil {
+			t.Fatalf("Failed to insert %s: %s", r, err)
+		}
+	}
+	return z
+}
+
+func newUpstream(t *testing.T) (*upstream, *test.Key) {
+	rootKey, orgKey, exampleKey := test.NewKey("."), test.NewKey("org."), test.NewKey("example.org.")
+
+	root := test.SignZone(".", []dns.RR{
+		test.SOA(". 303 IN SOA a.root. hostmaster.root. 1 7200 3600 1209600 3600"),
+		test.NS(". 303 IN NS a.root."),
+		test.NS("org. 303 IN NS ns.org."),
+		orgKey.DS(),
+	}, rootKey)
+	org := test.SignZone("org.", []dns.RR{
+		test.SOA("org. 303 IN SOA ns.org. hostmaster.org. 1 7200 3600 1209600 3600"),
+		test.NS("org. 303 IN NS ns.org."),
+		test.NS("example.org. 303 IN NS ns.example.org."),
+		exampleKey.DS(),
+		test.NS("bogus.org. 303 IN NS ns.bogus.org."),
+		test.NewKey("bogus.org.").DS(),
+		test.NS("insecure.org. 303 IN NS ns.insecure.org."),
+	}, orgKey)
+	example := test.SignZone("example.org.", []dns.RR{
+		test.SOA("example.org. 303 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+		test.NS("example.org. 303 IN NS ns.example.org."),
+		test.A("example.org. 303 IN A 127.0.0.53"),
+	}, exampleKey)
+	// Signed with a key that doesn't match the DS in org.
+	bogus := test.SignZone("bogus.org.", []dns.RR{
+		test.SOA("bogus.org. 303 IN SOA ns.bogus.org. hostmaster.bogus.org. 1 7200 3600 1209600 3600"),
+		test.NS("bogus.org. 303 IN NS ns.bogus.org."),
+		test.A("bogus.org. 303 IN A 127.0.0.54"),
+	}, test.NewKey("bogus.org."))
+	insecure := []dns.RR{
+		test.SOA("insecure.org. 303 IN SOA ns.insecure.org. hostmaster.insecure.org. 1 7200 3600 1209600 3600"),
+		test.NS("insecure.org. 303 IN NS ns.insecure.org."),
+		test.A("insecure.org. 303 IN A 127.0.0.55"),
+	}
+
+	return &upstream{zones: map[string]*file.Zone{
+		".":             zone(t, ".", root),
+		"org.":          zone(t, "org.", org),
+		"example.org.":  zone(t, "example.org.", example),
+		"bogus.org.":    zone(t, "bogus.org.", bogus),
+		"insecure.org.": zone(t, "insecure.org.", insecure),
+	}}, rootKey
+}
+
+func TestValidator(t *testing.T) {
+	u, rootKey := newUpstream(t)
+	v, err := New([]string{"."}, []dns.RR{rootKey.DS()}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %s", err)
+	}
+	v.Next = u
+
+	tests := []struct {
+		qname  string
+		do     bool
+		ad     bool
+		cd     bool
+		rcode  int
+		wantAD bool
+		sigs   bool
+	}{
+		{qname: "example.org.", do: true, wantAD: true, sigs: true},
+		{qname: "example.org."},
+		{qname: "example.org.", ad: true, wantAD: true},
+		{qname: "nope.example.org.", do: true, rcode: dns.RcodeNameError, wantAD: true, sigs: true},
+		{qname: "insecure.org.", do: true},
+		{qname: "bogus.org.", do: true, rcode: dns.RcodeServerFailure},
+		{qname: "bogus.org.", do: true, cd: true, sigs: true},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+		m.AuthenticatedData, m.CheckingDisabled = tc.ad, tc.cd
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := v.ServeDNS(ctx, rec, m)
+		if tc.rcode == dns.RcodeServerFailure {
+			if rcode != dns.RcodeServerFailure || err == nil || rec.Msg != nil {
+				t.Errorf("Test %d: expected SERVFAIL and an error for %s, got %d", i, tc.qname, rcode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.qname, err)
+			continue
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d for %s, got %d", i, tc.rcode, tc.qname, rec.Msg.Rcode)
+		}
+		if rec.Msg.AuthenticatedData != tc.wantAD {
+			t.Errorf("Test %d: expected AD %t for %s, got %t", i, tc.wantAD, tc.qname, rec.Msg.AuthenticatedData)
+		}
+		if rec.Msg.CheckingDisabled != tc.cd {
+			t.Errorf("Test %d: expected CD %t for %s", i, tc.cd, tc.qname)
+		}
+		if (rec.Msg.IsEdns0() != nil) != tc.do {
+			t.Errorf("Test %d: expected OPT in reply %t for %s", i, tc.do, tc.qname)
+		}
+		sigs := false
+		for _, rr := range append(rec.Msg.Answer, rec.Msg.Ns...) {
+			sigs = sigs || rr.Header().Rrtype == dns.TypeRRSIG
+		}
+		if sigs != tc.sigs {
+			t.Errorf("Test %d: expected signatures %t for %s, got %t", i, tc.sigs, tc.qname, sigs)
+		}
+	}
+}
+
+func TestValidatorUpstreamQuery(t *testing.T) {
+	u, rootKey := newUpstream(t)
+	v, _ := New([]string{"."}, []dns.RR{rootKey.DS()}, nil)
+	v.Next = u
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	v.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+
+	// The first query is the one for the client, the others fetch the chain of trust.
+	if len(u.queries) < 2 {
+		t.Fatalf("Expected queries for the chain of trust, got %d queries", len(u.queries))
+	}
+	for _, q := range u.queries {
+		if o := q.IsEdns0(); o == nil || !o.Do() || !q.CheckingDisabled {
+			t.Errorf("Expected DO and CD on upstream query, got %v", q)
+		}
+	}
+	if m.IsEdns0() != nil || m.CheckingDisabled {
+		t.Errorf("Expected client query not to be changed, got %v", m)
+	}
+}
+
+func TestValidatorNegativeAnchor(t *testing.T) {
+	u, rootKey := newUpstream(t)
+	v, _ := New([]string{"."}, []dns.RR{rootKey.DS()}, []string{"bogus.org."})
+	v.Next = u
+
+	m := new(dns.Msg)
+	m.SetQuestion("bogus.org.", dns.TypeA)
+	m.SetEdns0(4096, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := v.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error below negative trust anchor, got %s", err)
+	}
+	if len(rec.Msg.Answer) == 0 || rec.Msg.AuthenticatedData {
+		t.Errorf("Expected answer without AD, got %v", rec.Msg)
+	}
+}
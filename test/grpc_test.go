@@ -3,13 +3,17 @@ package test
 import (
 	"io/ioutil"
 	"log"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/miekg/dns"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/pb"
 )
 
@@ -57,3 +61,257 @@ func TestGrpc(t *testing.T) {
 		t.Errorf("Expected 2 RRs in additional section, but got %d", len(d.Extra))
 	}
 }
+
+func TestGrpcStream(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	corefile := `grpc://.:0 {
+		whoami
+}
+`
+	g, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer g.Stop()
+
+	conn, err := grpc.Dial(tcp, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewDnsServiceClient(conn).Stream(context.TODO())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	// The first query is for ourselves, the others are sent on behalf of other clients. The server
+	// doesn't trust any proxies, so it should see all of them as coming from us.
+	clients := map[uint64]string{1: "", 2: "10.0.0.1", 3: "10.0.0.2:5353"}
+	for id, client := range clients {
+		m := new(dns.Msg)
+		m.SetQuestion("whoami.example.org.", dns.TypeA)
+		msg, _ := m.Pack()
+		if err := stream.Send(&pb.DnsPacket{Msg: msg, Client: client, Id: id}); err != nil {
+			t.Fatalf("Expected no error but got: %s", err)
+		}
+	}
+	stream.CloseSend()
+
+	from := ""
+	for range clients {
+		reply, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected no error but got: %s", err)
+		}
+		client, ok := clients[reply.Id]
+		if !ok {
+			t.Fatalf("Expected reply for a query we sent, got Id %d", reply.Id)
+		}
+		delete(clients, reply.Id)
+
+		d := new(dns.Msg)
+		if err := d.Unpack(reply.Msg); err != nil {
+			t.Fatalf("Expected no error but got: %s", err)
+		}
+		if len(d.Extra) != 2 {
+			t.Fatalf("Expected 2 RRs in additional section, but got %d", len(d.Extra))
+		}
+		if from == "" {
+			from = d.Extra[0].String()
+		}
+		if d.Extra[0].String() != from {
+			t.Errorf("Expected query %d for %q to be from %s, got %s", reply.Id, client, from, d.Extra[0])
+		}
+	}
+}
+
+func TestGrpcBatch(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	corefile := `grpc://.:0 {
+		whoami
+}
+`
+	g, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer g.Stop()
+
+	conn, err := grpc.Dial(tcp, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	defer conn.Close()
+
+	qnames := []string{"a.example.org.", "b.example.org.", "c.example.org."}
+	batch := &pb.DnsBatch{}
+	for _, qname := range qnames {
+		m := new(dns.Msg)
+		m.SetQuestion(qname, dns.TypeA)
+		msg, _ := m.Pack()
+		batch.Packets = append(batch.Packets, &pb.DnsPacket{Msg: msg})
+	}
+
+	reply, err := pb.NewDnsServiceClient(conn).Batch(context.TODO(), batch)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	if len(reply.Packets) != len(qnames) {
+		t.Fatalf("Expected %d replies, got %d", len(qnames), len(reply.Packets))
+	}
+	for i, p := range reply.Packets {
+		d := new(dns.Msg)
+		if err := d.Unpack(p.Msg); err != nil {
+			t.Fatalf("Expected no error but got: %s", err)
+		}
+		if d.Question[0].Name != qnames[i] {
+			t.Errorf("Expected reply %d for %s, got %s", i, qnames[i], d.Question[0].Name)
+		}
+	}
+}
+
+func TestGrpcBatchTooLarge(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	corefile := `grpc://.:0 {
+		whoami
+}
+`
+	g, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer g.Stop()
+
+	conn, err := grpc.Dial(tcp, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	defer conn.Close()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	msg, _ := m.Pack()
+	batch := &pb.DnsBatch{}
+	for i := 0; i <= dnsserver.MaxBatch; i++ {
+		batch.Packets = append(batch.Packets, &pb.DnsPacket{Msg: msg})
+	}
+
+	_, err = pb.NewDnsServiceClient(conn).Batch(context.TODO(), batch)
+	if grpc.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for a batch of %d queries, got: %v", len(batch.Packets), err)
+	}
+}
+
+func TestGrpcStreamBackpressure(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	corefile := `grpc://.:0 {
+		erratic {
+			delay 1 1s
+		}
+}
+`
+	g, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer g.Stop()
+
+	conn, err := grpc.Dial(tcp, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewDnsServiceClient(conn).Stream(context.TODO())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	// Every query is delayed, so one more than allowed would be in flight at the same time. The server
+	// holds up the last one until there is room, and answers all of them.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	msg, _ := m.Pack()
+	for i := 0; i <= dnsserver.MaxStreamQueries; i++ {
+		if err := stream.Send(&pb.DnsPacket{Msg: msg, Id: uint64(i)}); err != nil {
+			t.Fatalf("Expected no error but got: %s", err)
+		}
+	}
+	stream.CloseSend()
+
+	for i := 0; i <= dnsserver.MaxStreamQueries; i++ {
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("Expected %d replies, got an error after %d: %s", dnsserver.MaxStreamQueries+1, i, err)
+		}
+	}
+}
+
+func TestGrpcProxyClient(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	for _, trusted := range []bool{false, true} {
+		corefile := `grpc://.:0 {
+		whoami
+}
+`
+		if trusted {
+			corefile = `grpc://.:0 {
+		trusted_proxies 127.0.0.1 ::1
+		whoami
+}
+`
+		}
+		g, _, tcp, err := CoreDNSServerAndPorts(corefile)
+		if err != nil {
+			t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+		}
+		defer g.Stop()
+
+		corefile = `.:0 {
+		proxy . ` + tcp + ` {
+			protocol grpc insecure
+			send_client
+		}
+}
+`
+		p, udp, _, err := CoreDNSServerAndPorts(corefile)
+		if err != nil {
+			t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+		}
+		defer p.Stop()
+
+		co, err := dns.Dial("udp", udp)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %s", err)
+		}
+		defer co.Close()
+		_, port, _ := net.SplitHostPort(co.LocalAddr().String())
+
+		// Send a few queries, so they share the stream to the gRPC server.
+		for i := 0; i < 3; i++ {
+			m := new(dns.Msg)
+			m.SetQuestion("whoami.example.org.", dns.TypeA)
+			if err := co.WriteMsg(m); err != nil {
+				t.Fatalf("Expected no error but got: %s", err)
+			}
+			co.SetReadDeadline(time.Now().Add(5 * time.Second))
+			d, err := co.ReadMsg()
+			if err != nil {
+				t.Fatalf("Expected no error but got: %s", err)
+			}
+			if len(d.Extra) != 2 {
+				t.Fatalf("Expected 2 RRs in additional section, but got %d", len(d.Extra))
+			}
+			// A gRPC server that trusts the proxy sees our port, otherwise it sees the port of the proxy.
+			from := strconv.Itoa(int(d.Extra[1].(*dns.SRV).Port))
+			if (from == port) != trusted {
+				t.Errorf("Expected our port %s to be seen: %t, got port %s", port, trusted, from)
+			}
+		}
+	}
+}